@@ -0,0 +1,261 @@
+// Package audio serves pronunciation audio of words. Audio is fetched from the configured provider
+// once, or synthesized by an offline TTS engine when the provider has none, and kept in a
+// content-addressed blob directory within size limits, least recently played clips evicted first.
+package audio
+
+import (
+	"context"
+	"enx-api/repo"
+	"enx-api/upstream"
+	"enx-api/utils/logger"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	AccentUS = "us"
+	AccentUK = "uk"
+)
+
+// ReadLimit bounds how much of an upstream response a provider reads
+const ReadLimit = 16 << 20
+
+// evictBatchSize is how many clips are considered for eviction at once
+const evictBatchSize = 50
+
+var (
+	// ErrNotFound is returned when neither the provider nor the TTS engine has audio for the word
+	ErrNotFound = errors.New("no audio for the word")
+	// ErrTooLarge is returned for audio larger than the max file size
+	ErrTooLarge = errors.New("audio exceeds the max file size")
+)
+
+// Provider fetches pronunciation audio upstream
+type Provider interface {
+	// Name identifies the provider in the source of clips
+	Name() string
+	// Fetch returns ErrNotFound when the provider has no audio for the word
+	Fetch(ctx context.Context, word, accent string) (data []byte, contentType string, err error)
+}
+
+// Config limits the blob directory
+type Config struct {
+	Dir string
+	// MaxFileSize rejects larger clips, 0: no limit
+	MaxFileSize int64
+	// MaxTotalSize evicts the least recently played clips once the blobs grow larger, 0: no limit
+	MaxTotalSize int64
+}
+
+// Clip is stored audio ready to be served
+type Clip struct {
+	Word        string
+	Accent      string
+	Hash        string
+	ContentType string
+	Size        int64
+	Source      string
+	Path        string
+	CreatedAt   time.Time
+}
+
+// Service looks audio up, the provider and the TTS engine are optional
+type Service struct {
+	config   Config
+	blobs    *blobStore
+	provider Provider
+	tts      TTS
+	group    singleflight.Group
+	evictMu  sync.Mutex
+}
+
+// NewService creates a service storing audio in config.Dir
+func NewService(config Config, provider Provider, tts TTS) *Service {
+	return &Service{config: config, blobs: &blobStore{dir: config.Dir}, provider: provider, tts: tts}
+}
+
+var (
+	mu      sync.RWMutex
+	current *Service
+)
+
+// Init configures the service from audio.*, with the provider picked by the caller
+func Init(provider Provider) error {
+	config := Config{
+		Dir:          viper.GetString("audio.dir"),
+		MaxFileSize:  viper.GetInt64("audio.max-file-size"),
+		MaxTotalSize: viper.GetInt64("audio.max-total-size"),
+	}
+	var tts TTS
+	switch engine := viper.GetString("audio.tts"); engine {
+	case "":
+	case "stub":
+		tts = StubTTS{}
+	case "command":
+		tts = &CommandTTS{
+			Command: viper.GetString("audio.tts-command"),
+			Args:    viper.GetStringSlice("audio.tts-args"),
+			Voices:  map[string]string{AccentUS: "en-us", AccentUK: "en-gb"},
+		}
+	default:
+		return fmt.Errorf("unknown audio.tts: %s, use stub or command", engine)
+	}
+	SetService(NewService(config, provider, tts))
+
+	providerName, ttsName := "none", "none"
+	if provider != nil {
+		providerName = provider.Name()
+	}
+	if tts != nil {
+		ttsName = tts.Name()
+	}
+	logger.Infof("audio, dir: %s, provider: %s, tts: %s", config.Dir, providerName, ttsName)
+	return nil
+}
+
+// SetService replaces the service in use, e.g. with one of a test
+func SetService(s *Service) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = s
+}
+
+// Current returns the service in use
+func Current() *Service {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Get returns the clip of a word in an accent, fetching and storing it on the first request.
+// Concurrent requests of the same clip share one fetch.
+func (s *Service) Get(ctx context.Context, word, accent string) (*Clip, error) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	stored, err := repo.GetAudioClip(word, accent)
+	if err != nil {
+		logger.Errorf("failed to get audio clip, word: %s, accent: %s, error: %v", word, accent, err)
+	}
+	if stored != nil && s.blobs.exists(stored.Hash) {
+		if err := repo.TouchAudioClip(word, accent); err != nil {
+			logger.Errorf("failed to touch audio clip, word: %s, accent: %s, error: %v", word, accent, err)
+		}
+		return s.clip(stored), nil
+	}
+
+	result, err, _ := s.group.Do(word+"\x00"+accent, func() (interface{}, error) {
+		// requests of the same clip wait for this fetch, it mustn't end with the first one
+		ctx, cancel := upstream.Detach(ctx)
+		defer cancel()
+		data, contentType, source, err := s.fetch(ctx, word, accent)
+		if err != nil {
+			return nil, err
+		}
+		if s.config.MaxFileSize > 0 && int64(len(data)) > s.config.MaxFileSize {
+			return nil, ErrTooLarge
+		}
+		hash, err := s.blobs.put(data)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UnixMilli()
+		clip := &repo.AudioClip{Word: word, Accent: accent, Hash: hash, ContentType: contentType,
+			Size: int64(len(data)), Source: source, CreatedAt: now, AccessedAt: now}
+		if err := repo.SaveAudioClip(clip); err != nil {
+			return nil, err
+		}
+		s.evict(clip)
+		return s.clip(clip), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Clip), nil
+}
+
+// fetch asks the provider, then the TTS engine. Without a TTS engine the provider's error is returned.
+func (s *Service) fetch(ctx context.Context, word, accent string) (data []byte, contentType, source string, err error) {
+	err = ErrNotFound
+	if s.provider != nil {
+		if data, contentType, err = s.provider.Fetch(ctx, word, accent); err == nil {
+			return data, contentType, s.provider.Name(), nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			logger.Errorf("audio provider failed, word: %s, accent: %s, error: %v", word, accent, err)
+		}
+	}
+	if s.tts == nil {
+		return nil, "", "", err
+	}
+	data, contentType, ttsErr := s.tts.Synthesize(ctx, word, accent)
+	if ttsErr != nil {
+		logger.Errorf("tts failed, word: %s, accent: %s, error: %v", word, accent, ttsErr)
+		return nil, "", "", err
+	}
+	return data, contentType, s.tts.Name(), nil
+}
+
+// evict drops the least recently played clips, other than keep, while the blobs are over MaxTotalSize
+func (s *Service) evict(keep *repo.AudioClip) {
+	if s.config.MaxTotalSize <= 0 {
+		return
+	}
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	for {
+		total, err := repo.AudioBlobsSize()
+		if err != nil || total <= s.config.MaxTotalSize {
+			return
+		}
+		clips, err := repo.LeastRecentlyPlayedAudioClips(evictBatchSize)
+		if err != nil {
+			logger.Errorf("failed to list audio clips to evict: %v", err)
+			return
+		}
+		evicted := false
+		for i := range clips {
+			clip := &clips[i]
+			if clip.Word == keep.Word && clip.Accent == keep.Accent {
+				continue
+			}
+			if err := s.remove(clip); err != nil {
+				logger.Errorf("failed to evict audio clip, word: %s, accent: %s, error: %v", clip.Word, clip.Accent, err)
+				return
+			}
+			evicted = true
+			if total -= clip.Size; total <= s.config.MaxTotalSize {
+				break
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+func (s *Service) remove(clip *repo.AudioClip) error {
+	blobInUse, err := repo.DeleteAudioClip(clip)
+	if err != nil || blobInUse {
+		return err
+	}
+	return s.blobs.remove(clip.Hash)
+}
+
+func (s *Service) clip(stored *repo.AudioClip) *Clip {
+	return &Clip{
+		Word:        stored.Word,
+		Accent:      stored.Accent,
+		Hash:        stored.Hash,
+		ContentType: stored.ContentType,
+		Size:        stored.Size,
+		Source:      stored.Source,
+		Path:        filepath.Clean(s.blobs.path(stored.Hash)),
+		CreatedAt:   time.UnixMilli(stored.CreatedAt),
+	}
+}
@@ -0,0 +1,171 @@
+package audio
+
+import (
+	"context"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeProvider returns audio of the words it knows and counts its calls
+type fakeProvider struct {
+	audio map[string][]byte
+	calls int32
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context, word, accent string) ([]byte, string, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	if data, ok := p.audio[word]; ok {
+		return data, "audio/mpeg", nil
+	}
+	return nil, "", ErrNotFound
+}
+
+func setupAudioTest(t *testing.T, config Config, provider Provider, tts TTS) *Service {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	config.Dir = t.TempDir()
+	return NewService(config, provider, tts)
+}
+
+func TestGetFetchesOnce(t *testing.T) {
+	provider := &fakeProvider{audio: map[string][]byte{"harbor": []byte("harbor-mp3")}}
+	s := setupAudioTest(t, Config{}, provider, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Get(context.Background(), "harbor", AccentUS); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	clip, err := s.Get(context.Background(), " Harbor", AccentUS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("fetches: %d", provider.calls)
+	}
+	data, err := os.ReadFile(clip.Path)
+	if err != nil || string(data) != "harbor-mp3" {
+		t.Errorf("blob: %q, %v", data, err)
+	}
+	if clip.Source != "fake" || clip.ContentType != "audio/mpeg" || clip.Size != int64(len(data)) {
+		t.Errorf("clip: %+v", clip)
+	}
+
+	// a removed blob is fetched again
+	_ = os.Remove(clip.Path)
+	if _, err := s.Get(context.Background(), "harbor", AccentUS); err != nil || provider.calls != 2 {
+		t.Errorf("refetch, calls: %d, error: %v", provider.calls, err)
+	}
+}
+
+func TestGetOutlivesRequest(t *testing.T) {
+	provider := &fakeProvider{audio: map[string][]byte{"harbor": []byte("harbor-mp3")}}
+	s := setupAudioTest(t, Config{}, provider, nil)
+
+	// the fetch is shared with other requests of the clip, the one running it going away doesn't fail it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Get(ctx, "harbor", AccentUS); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetFallsBackToTTS(t *testing.T) {
+	provider := &fakeProvider{}
+	s := setupAudioTest(t, Config{}, provider, StubTTS{})
+	clip, err := s.Get(context.Background(), "qwzx", AccentUK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.Source != "stub-tts" || clip.ContentType != "audio/wav" {
+		t.Errorf("clip: %+v", clip)
+	}
+
+	s = setupAudioTest(t, Config{}, provider, nil)
+	if _, err := s.Get(context.Background(), "qwzx", AccentUK); !errors.Is(err, ErrNotFound) {
+		t.Errorf("without tts: %v", err)
+	}
+	if _, err := NewService(Config{Dir: t.TempDir()}, nil, nil).Get(context.Background(), "qwzx", AccentUK); !errors.Is(err, ErrNotFound) {
+		t.Errorf("without provider: %v", err)
+	}
+}
+
+func TestGetLimits(t *testing.T) {
+	provider := &fakeProvider{audio: map[string][]byte{
+		"huge": make([]byte, 101),
+		"a":    []byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		"b":    []byte("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"),
+		"c":    []byte("cccccccccccccccccccccccccccccc"),
+		"d":    []byte("dddddddddddddddddddddddddddddd"),
+	}}
+	s := setupAudioTest(t, Config{MaxFileSize: 100, MaxTotalSize: 100}, provider, nil)
+	if _, err := s.Get(context.Background(), "huge", AccentUS); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("huge: %v", err)
+	}
+
+	paths := map[string]string{}
+	for _, word := range []string{"a", "b", "c", "d"} {
+		clip, err := s.Get(context.Background(), word, AccentUS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[word] = clip.Path
+	}
+	// 120 bytes stored, a is the least recently played
+	if _, err := os.Stat(paths["a"]); !os.IsNotExist(err) {
+		t.Errorf("a wasn't evicted: %v", err)
+	}
+	if clip, _ := repo.GetAudioClip("a", AccentUS); clip != nil {
+		t.Errorf("a is still indexed")
+	}
+	for _, word := range []string{"b", "c", "d"} {
+		if _, err := os.Stat(paths[word]); err != nil {
+			t.Errorf("%s: %v", word, err)
+		}
+	}
+}
+
+func TestSharedBlob(t *testing.T) {
+	provider := &fakeProvider{audio: map[string][]byte{"colour": []byte("same"), "color": []byte("same")}}
+	s := setupAudioTest(t, Config{}, provider, nil)
+	colour, _ := s.Get(context.Background(), "colour", AccentUS)
+	color, _ := s.Get(context.Background(), "color", AccentUS)
+	if colour == nil || color == nil || colour.Path != color.Path {
+		t.Fatalf("clips: %+v, %+v", colour, color)
+	}
+
+	stored, _ := repo.GetAudioClip("colour", AccentUS)
+	if err := s.remove(stored); err != nil {
+		t.Fatal(err)
+	}
+	// color still plays the blob
+	if _, err := os.Stat(color.Path); err != nil {
+		t.Errorf("shared blob removed: %v", err)
+	}
+	stored, _ = repo.GetAudioClip("color", AccentUS)
+	if err := s.remove(stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(color.Path); !os.IsNotExist(err) {
+		t.Errorf("unused blob kept: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// TTS synthesizes speech offline when the provider has no audio for a word
+type TTS interface {
+	// Name identifies the engine in the source of clips
+	Name() string
+	Synthesize(ctx context.Context, word, accent string) (data []byte, contentType string, err error)
+}
+
+// StubTTS returns a short silent WAV for any word, for tests and setups without an engine
+type StubTTS struct{}
+
+func (StubTTS) Name() string {
+	return "stub-tts"
+}
+
+func (StubTTS) Synthesize(ctx context.Context, word, accent string) ([]byte, string, error) {
+	return silentWAV(word), "audio/wav", nil
+}
+
+// silentWAV is a 8 kHz 8-bit mono WAV of silence, one sample per letter so words differ
+func silentWAV(word string) []byte {
+	samples := bytes.Repeat([]byte{0x80}, len(word))
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(samples)))
+	buf.WriteString("WAVEfmt ")
+	// fmt chunk: size, PCM, mono, sample rate, byte rate, block align, bits per sample
+	for _, field := range []any{uint32(16), uint16(1), uint16(1), uint32(8000), uint32(8000), uint16(1), uint16(8)} {
+		_ = binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+	return buf.Bytes()
+}
+
+// CommandTTS runs a local engine such as espeak-ng that writes WAV to stdout.
+// {word} and {voice} in the arguments are replaced, the word is passed as one argument, never through a shell.
+type CommandTTS struct {
+	Command string
+	Args    []string
+	// Voices maps an accent to the engine's voice, e.g. us: en-us
+	Voices map[string]string
+}
+
+func (t *CommandTTS) Name() string {
+	return "tts:" + t.Command
+}
+
+func (t *CommandTTS) Synthesize(ctx context.Context, word, accent string) ([]byte, string, error) {
+	args := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		args[i] = strings.NewReplacer("{word}", word, "{voice}", t.Voices[accent]).Replace(arg)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Command, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("%s failed: %w: %s", t.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, "", fmt.Errorf("%s wrote no audio", t.Command)
+	}
+	return stdout.Bytes(), "audio/wav", nil
+}
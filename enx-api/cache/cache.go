@@ -0,0 +1,176 @@
+// Package cache keeps responses of upstream dictionaries, whatever the provider, in an in-process
+// LRU in front of the translation_cache table. Lookups the provider had no result for are cached
+// with a shorter TTL, failed lookups are not cached at all, and concurrent lookups of the same key
+// make a single upstream call.
+package cache
+
+import (
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"enx-api/utils/schedule"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+)
+
+// Entry is a cached upstream response
+type Entry struct {
+	Value string
+	// Found is false when the provider had no result for the key
+	Found     bool
+	ExpiresAt int64 // Unix milliseconds
+}
+
+// Loader fetches a value from upstream. found false means the provider has no result for the key,
+// which is cached with the negative TTL; an error means the lookup failed and nothing is cached.
+// Concurrent lookups of the key all wait for it, so it calls upstream with a context detached from
+// the request that happened to run it, see upstream.Detach.
+type Loader func() (value string, found bool, err error)
+
+// Cache is a two-level translation cache
+type Cache struct {
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+	memory      *lru
+	group       singleflight.Group
+	now         func() time.Time
+}
+
+// New creates a cache keeping found values for positiveTTL, misses for negativeTTL
+// and up to size entries in memory
+func New(positiveTTL, negativeTTL time.Duration, size int) *Cache {
+	return &Cache{positiveTTL: positiveTTL, negativeTTL: negativeTTL, memory: newLRU(size), now: time.Now}
+}
+
+var (
+	mu      sync.RWMutex
+	current = New(30*24*time.Hour, 24*time.Hour, 10000)
+)
+
+// Init configures the cache from translation-cache.*
+func Init() {
+	c := New(viper.GetDuration("translation-cache.positive-ttl"), viper.GetDuration("translation-cache.negative-ttl"),
+		viper.GetInt("translation-cache.memory-size"))
+	SetCache(c)
+	logger.Infof("translation cache, positive ttl: %v, negative ttl: %v, memory size: %d",
+		c.positiveTTL, c.negativeTTL, c.memory.capacity)
+}
+
+// SetCache replaces the cache in use, e.g. with one of a test
+func SetCache(c *Cache) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// Current returns the cache in use
+func Current() *Cache {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Get looks a key up in the cache in use, see Cache.Get
+func Get(provider, key string, load Loader) (Entry, error) {
+	return Current().Get(provider, key, load)
+}
+
+// Purge drops a key from the cache in use, see Cache.Purge
+func Purge(provider, key string) (int64, error) {
+	return Current().Purge(provider, key)
+}
+
+// LanguageProvider is the provider responses translated from a source into a target language are
+// cached under. English ones keep the provider they were cached under before other source languages
+// existed, which for zh-CHS is the provider itself.
+func LanguageProvider(provider, from, to string) string {
+	switch {
+	case from != language.English:
+		return provider + ":" + from + ":" + to
+	case to != language.Chinese:
+		return provider + ":" + to
+	}
+	return provider
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+func memoryKey(provider, key string) string {
+	return provider + "\x00" + key
+}
+
+// Get returns the cached response of the provider for the key, case-insensitive, and calls load
+// when there is none or it expired
+func (c *Cache) Get(provider, key string, load Loader) (Entry, error) {
+	key = normalizeKey(key)
+	id := memoryKey(provider, key)
+	if entry, ok := c.memory.get(id); ok && entry.ExpiresAt > c.now().UnixMilli() {
+		return entry, nil
+	}
+
+	result, err, _ := c.group.Do(id, func() (interface{}, error) {
+		now := c.now()
+		stored, err := repo.GetCachedTranslation(provider, key)
+		if err != nil {
+			logger.Errorf("failed to read translation cache, provider: %s, key: %s, error: %v", provider, key, err)
+		} else if stored != nil && stored.ExpiresAt > now.UnixMilli() {
+			entry := Entry{Value: stored.Value, Found: stored.Found == 1, ExpiresAt: stored.ExpiresAt}
+			c.memory.add(id, entry)
+			return entry, nil
+		}
+
+		value, found, err := load()
+		if err != nil {
+			return nil, err
+		}
+		ttl := c.positiveTTL
+		if !found {
+			ttl = c.negativeTTL
+		}
+		entry := Entry{Value: value, Found: found, ExpiresAt: now.Add(ttl).UnixMilli()}
+		record := &repo.CachedTranslation{Provider: provider, Key: key, Value: value, CreatedAt: now.UnixMilli(), ExpiresAt: entry.ExpiresAt}
+		if found {
+			record.Found = 1
+		}
+		if err := repo.SaveCachedTranslation(record); err != nil {
+			logger.Errorf("failed to write translation cache, provider: %s, key: %s, error: %v", provider, key, err)
+		}
+		c.memory.add(id, entry)
+		return entry, nil
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return result.(Entry), nil
+}
+
+// Purge drops the cached responses for a key, of one provider or of all of them when provider
+// is empty, so the next lookup goes upstream again. It returns how many stored responses were removed.
+func (c *Cache) Purge(provider, key string) (int64, error) {
+	key = normalizeKey(key)
+	c.memory.removeFunc(func(id string) bool {
+		p, k, _ := strings.Cut(id, "\x00")
+		return k == key && (provider == "" || p == provider)
+	})
+	return repo.DeleteCachedTranslations(provider, key)
+}
+
+// StartJanitor deletes expired responses, see schedule.Every
+func StartJanitor(interval time.Duration) (stop func(), err error) {
+	return schedule.Every(interval, func() {
+		count, err := repo.PruneCachedTranslations(time.Now().UnixMilli())
+		if err != nil {
+			logger.Errorf("translation cache janitor failed to prune: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Infof("translation cache janitor pruned %d expired responses", count)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setupCacheTest(t *testing.T, size int) (*Cache, *time.Time) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	now := time.Now()
+	c := New(time.Hour, time.Minute, size)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+// countingLoader returns value, found = value != "", and counts its calls
+func countingLoader(calls *int32, value string, err error) Loader {
+	return func() (string, bool, error) {
+		atomic.AddInt32(calls, 1)
+		return value, value != "", err
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, now := setupCacheTest(t, 10)
+	var calls int32
+
+	entry, err := c.Get("test", "Harbor", countingLoader(&calls, "港口", nil))
+	if err != nil || !entry.Found || entry.Value != "港口" {
+		t.Fatalf("first get: %+v, %v", entry, err)
+	}
+	// case-insensitive, served from memory
+	if entry, _ := c.Get("test", " harbor", countingLoader(&calls, "other", nil)); entry.Value != "港口" || calls != 1 {
+		t.Errorf("cached get: %+v, calls: %d", entry, calls)
+	}
+
+	// misses are cached for the shorter negative TTL
+	if entry, _ := c.Get("test", "qwzx", countingLoader(&calls, "", nil)); entry.Found {
+		t.Errorf("miss: %+v", entry)
+	}
+	*now = now.Add(30 * time.Second)
+	c.Get("test", "qwzx", countingLoader(&calls, "", nil))
+	if calls != 2 {
+		t.Errorf("miss within negative ttl, calls: %d", calls)
+	}
+	*now = now.Add(time.Minute)
+	c.Get("test", "qwzx", countingLoader(&calls, "", nil))
+	c.Get("test", "harbor", countingLoader(&calls, "港口", nil))
+	if calls != 3 {
+		t.Errorf("miss after negative ttl, calls: %d", calls)
+	}
+	*now = now.Add(time.Hour)
+	c.Get("test", "harbor", countingLoader(&calls, "港口", nil))
+	if calls != 4 {
+		t.Errorf("hit after positive ttl, calls: %d", calls)
+	}
+}
+
+func TestCacheErrorsAreNotCached(t *testing.T) {
+	c, _ := setupCacheTest(t, 10)
+	var calls int32
+	failure := errors.New("upstream unavailable")
+
+	if _, err := c.Get("test", "harbor", countingLoader(&calls, "", failure)); !errors.Is(err, failure) {
+		t.Errorf("error: %v", err)
+	}
+	if entry, err := c.Get("test", "harbor", countingLoader(&calls, "港口", nil)); err != nil || entry.Value != "港口" || calls != 2 {
+		t.Errorf("after error: %+v, %v, calls: %d", entry, err, calls)
+	}
+}
+
+func TestCacheSharedByProcessesAndPurge(t *testing.T) {
+	c, _ := setupCacheTest(t, 1)
+	var calls int32
+	c.Get("test", "harbor", countingLoader(&calls, "港口", nil))
+	c.Get("other", "harbor", countingLoader(&calls, "harbour", nil))
+	c.Get("test", "island", countingLoader(&calls, "岛", nil))
+	if c.memory.len() != 1 {
+		t.Errorf("memory entries: %d", c.memory.len())
+	}
+
+	// evicted from memory and a fresh cache, as after a restart, are served from the database
+	restarted := New(time.Hour, time.Minute, 10)
+	for _, c := range []*Cache{c, restarted} {
+		if entry, _ := c.Get("test", "harbor", countingLoader(&calls, "other", nil)); entry.Value != "港口" {
+			t.Errorf("stored entry: %+v", entry)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("calls: %d", calls)
+	}
+
+	deleted, err := restarted.Purge("", "HARBOR")
+	if err != nil || deleted != 2 {
+		t.Errorf("purge, deleted: %d, error: %v", deleted, err)
+	}
+	if entry, _ := restarted.Get("test", "harbor", countingLoader(&calls, "海港", nil)); entry.Value != "海港" || calls != 4 {
+		t.Errorf("after purge: %+v, calls: %d", entry, calls)
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	c, _ := setupCacheTest(t, 10)
+	var calls int32
+	release := make(chan struct{})
+	load := func() (string, bool, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "港口", true, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if entry, err := c.Get("test", "harbor", load); err != nil || entry.Value != "港口" {
+				t.Errorf("concurrent get: %+v, %v", entry, err)
+			}
+		}()
+	}
+	// give the lookups time to pile up behind the first one
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("upstream calls: %d", calls)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID   string `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name"`
+	Role string `gorm:"column:role"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+func main() {
+	// Command line flags
+	var username, role, dbPath string
+	flag.StringVar(&username, "username", "", "Username to set the role for")
+	flag.StringVar(&role, "role", "admin", "Role: user or admin")
+	flag.StringVar(&dbPath, "db", "/var/lib/enx-api/enx.db", "Database path")
+	flag.Parse()
+
+	if username == "" || (role != "user" && role != "admin") {
+		log.Fatal("Usage: set-role -username=<username> [-role=admin|user] [-db=<path>]")
+	}
+
+	// Open SQLite database
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	var user User
+	if err := db.Where("name = ?", username).First(&user).Error; err != nil {
+		log.Fatalf("User not found: %v", err)
+	}
+	fmt.Printf("Found user: %s (ID: %s, role: %s)\n", user.Name, user.ID, user.Role)
+
+	result := db.Model(&User{}).Where("id = ?", user.ID).Update("role", role)
+	if result.Error != nil {
+		log.Fatalf("Failed to update role: %v", result.Error)
+	}
+	fmt.Printf("✓ Role set to %s\n", role)
+}
@@ -0,0 +1,114 @@
+// Package dictionary is the structured dictionary entry providers are parsed into
+package dictionary
+
+import "strings"
+
+// Entry is everything a provider knows about a word
+type Entry struct {
+	Word       string `json:"word"`
+	UKPhonetic string `json:"uk_phonetic"` // IPA without brackets
+	USPhonetic string `json:"us_phonetic"`
+	// Senses are grouped by part of speech in the provider's order
+	Senses     []Sense   `json:"senses"`
+	Examples   []Example `json:"examples"`
+	WebPhrases []Phrase  `json:"web_phrases"`
+	// Source is the provider the entry came from, e.g. youdao-web
+	Source string `json:"source"`
+}
+
+// Sense is the meanings of a word as one part of speech
+type Sense struct {
+	// PartOfSpeech is an abbreviation such as n., vt. or adj., empty when the provider gives none
+	PartOfSpeech string   `json:"pos"`
+	Meanings     []string `json:"meanings"`
+}
+
+// Example is an example sentence and its translation
+type Example struct {
+	English string `json:"english"`
+	Chinese string `json:"chinese"`
+}
+
+// Phrase is a common phrase containing the word, as found on the web
+type Phrase struct {
+	Phrase   string   `json:"phrase"`
+	Meanings []string `json:"meanings"`
+}
+
+// New returns an empty entry of a word, with empty rather than nil lists
+func New(word, source string) *Entry {
+	return &Entry{Word: word, Senses: []Sense{}, Examples: []Example{}, WebPhrases: []Phrase{}, Source: source}
+}
+
+// Empty tells whether the provider had nothing for the word
+func (e *Entry) Empty() bool {
+	return len(e.Senses) == 0 && len(e.WebPhrases) == 0
+}
+
+// Flatten returns the flat Chinese and Pronunciation stored on words for old clients,
+// one "pos. meaning；meaning" line per sense and the US phonetic, or the UK one if there is none
+func (e *Entry) Flatten() (chinese, pronunciation string) {
+	lines := make([]string, 0, len(e.Senses))
+	for _, sense := range e.Senses {
+		line := strings.Join(sense.Meanings, "；")
+		if sense.PartOfSpeech != "" {
+			line = sense.PartOfSpeech + " " + line
+		}
+		lines = append(lines, line)
+	}
+	phonetic := e.USPhonetic
+	if phonetic == "" {
+		phonetic = e.UKPhonetic
+	}
+	if phonetic != "" {
+		pronunciation = "[" + phonetic + "]"
+	}
+	return strings.Join(lines, "\n"), pronunciation
+}
+
+// ParseSense splits a line such as "n. 港口；避风港" into its part of speech and meanings
+func ParseSense(line string) Sense {
+	line = strings.TrimSpace(line)
+	sense := Sense{Meanings: []string{}}
+	if pos, rest, ok := strings.Cut(line, " "); ok && isPartOfSpeech(pos) {
+		sense.PartOfSpeech, line = pos, rest
+	}
+	sense.Meanings = SplitMeanings(line)
+	return sense
+}
+
+// SplitMeanings splits meanings separated by Chinese or ASCII semicolons
+func SplitMeanings(s string) []string {
+	meanings := []string{}
+	for _, meaning := range strings.FieldsFunc(s, func(r rune) bool { return r == '；' || r == ';' }) {
+		if meaning = strings.TrimSpace(meaning); meaning != "" {
+			meanings = append(meanings, meaning)
+		}
+	}
+	return meanings
+}
+
+// TrimPhonetic drops the brackets or slashes around IPA
+func TrimPhonetic(s string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "[]/"))
+}
+
+// partsOfSpeech are the abbreviations dictionaries prefix senses with
+var partsOfSpeech = map[string]bool{
+	"n": true, "v": true, "vt": true, "vi": true, "adj": true, "adv": true, "prep": true, "conj": true,
+	"pron": true, "int": true, "interj": true, "art": true, "num": true, "aux": true, "abbr": true,
+	"pl": true, "det": true, "modal": true, "phr": true, "suf": true, "pref": true,
+}
+
+// isPartOfSpeech recognizes abbreviations like n., vt., adj. or the combined n.&vt.
+func isPartOfSpeech(s string) bool {
+	if !strings.HasSuffix(s, ".") {
+		return false
+	}
+	for _, part := range strings.Split(s, "&") {
+		if !strings.HasSuffix(part, ".") || !partsOfSpeech[strings.TrimSuffix(part, ".")] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package dictionary
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSense(t *testing.T) {
+	tests := []struct {
+		line string
+		want Sense
+	}{
+		{"n. 港口；避风港", Sense{"n.", []string{"港口", "避风港"}}},
+		{" vt. 庇护; 怀有 ", Sense{"vt.", []string{"庇护", "怀有"}}},
+		{"n.&vt. 停泊", Sense{"n.&vt.", []string{"停泊"}}},
+		{"【名】 （Harbor）人名", Sense{"", []string{"【名】 （Harbor）人名"}}},
+		{"e.g. not a part of speech", Sense{"", []string{"e.g. not a part of speech"}}},
+	}
+	for _, tt := range tests {
+		if got := ParseSense(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	entry := New("harbor", "test")
+	entry.UKPhonetic = "ˈhɑːbə"
+	entry.Senses = []Sense{{"n.", []string{"港口", "避风港"}}, {"", []string{"人名"}}}
+	chinese, pronunciation := entry.Flatten()
+	if chinese != "n. 港口；避风港\n人名" || pronunciation != "[ˈhɑːbə]" {
+		t.Errorf("flatten: %q, %q", chinese, pronunciation)
+	}
+
+	entry.USPhonetic = "ˈhɑːrbər"
+	if _, pronunciation := entry.Flatten(); pronunciation != "[ˈhɑːrbər]" {
+		t.Errorf("us phonetic: %q", pronunciation)
+	}
+	if New("qwzx", "test").Empty() != true || entry.Empty() {
+		t.Error("empty")
+	}
+}
@@ -2,22 +2,32 @@ package main
 
 import (
 	"context"
+	"enx-api/audio"
+	"enx-api/cache"
 	"enx-api/enx"
+	"enx-api/frequency"
 	"enx-api/handlers"
 	"enx-api/middleware"
 	"enx-api/paragraph"
+	"enx-api/repo"
+	"enx-api/sso"
+	"enx-api/suggest"
 	"enx-api/translate"
 	"enx-api/utils"
 	"enx-api/utils/logger"
+	"enx-api/utils/mail"
 	"enx-api/utils/password"
 	"enx-api/utils/sqlitex"
 	wordCount "enx-api/word"
 	"enx-api/youdao"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
@@ -37,10 +47,68 @@ func main() {
 	logger.Warnf("warnf log test %s", "test")
 	logger.Sync()
 	sqlitex.Init()
+	if err := repo.InitStore(); err != nil {
+		logger.Errorf("failed to init storage backend: %v", err)
+		os.Exit(1)
+	}
+	if err := mail.Init(); err != nil {
+		logger.Errorf("failed to init mail sender: %v", err)
+		os.Exit(1)
+	}
+	if err := middleware.InitLoginLimiter(); err != nil {
+		logger.Errorf("failed to init login limiter: %v", err)
+		os.Exit(1)
+	}
+	if err := frequency.Init(); err != nil {
+		logger.Errorf("failed to init word frequency list: %v", err)
+		os.Exit(1)
+	}
+	sso.Init()
+	cache.Init()
+	if _, err := cache.StartJanitor(viper.GetDuration("translation-cache.cleanup-interval")); err != nil {
+		logger.Errorf("invalid translation-cache.cleanup-interval: %v", err)
+		os.Exit(1)
+	}
+	var audioProvider audio.Provider
+	if viper.GetString("audio.provider") == "youdao" {
+		audioProvider = youdao.AudioProvider{}
+	}
+	if err := audio.Init(audioProvider); err != nil {
+		logger.Errorf("failed to init audio: %v", err)
+		os.Exit(1)
+	}
+	if _, err := middleware.StartSessionJanitor(viper.GetDuration("session.cleanup-interval")); err != nil {
+		logger.Errorf("invalid session.cleanup-interval: %v", err)
+		os.Exit(1)
+	}
+	if retention := viper.GetInt("history.retention-days"); retention > 0 {
+		if _, err := repo.StartHistoryJanitor(time.Duration(retention)*24*time.Hour, viper.GetDuration("history.cleanup-interval")); err != nil {
+			logger.Errorf("invalid history.cleanup-interval: %v", err)
+			os.Exit(1)
+		}
+	}
+	if _, err := repo.StartFrequencyRefresher(viper.GetDuration("frequency.refresh-interval")); err != nil {
+		logger.Errorf("invalid frequency.refresh-interval: %v", err)
+		os.Exit(1)
+	}
+	if err := suggest.Init(); err != nil {
+		logger.Errorf("failed to init spelling suggestions: %v", err)
+		os.Exit(1)
+	}
+	if _, err := suggest.StartRefresher(viper.GetDuration("suggest.refresh-interval")); err != nil {
+		logger.Errorf("invalid suggest.refresh-interval: %v", err)
+		os.Exit(1)
+	}
 
 	// ReleaseMode
 	gin.SetMode(gin.DebugMode)
 	router := gin.New()
+	// X-Forwarded-For is only taken from the proxies in front of enx-api, the client IP is the
+	// peer address otherwise, see c.ClientIP
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		logger.Errorf("invalid enx.trusted-proxies: %v", err)
+		os.Exit(1)
+	}
 
 	// Add Recovery middleware to recover from panics
 	router.Use(gin.Recovery())
@@ -83,8 +151,8 @@ func main() {
 		if isAllowed {
 			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-			c.Header("Access-Control-Allow-Headers", "Origin, X-Session-ID, X-User-ID, Content-Type, Cookie")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
+			c.Header("Access-Control-Allow-Headers", "Origin, X-Session-ID, X-User-ID, X-Page-Host, Authorization, Content-Type, Cookie")
 			c.Header("Access-Control-Expose-Headers", "Content-Length")
 			c.Header("Access-Control-Max-Age", "43200") // 12 hours
 		}
@@ -127,17 +195,54 @@ func main() {
 	authGroup.Use(middleware.SessionMiddleware())
 	{
 		// get words query count by paragraph
-		authGroup.GET("/paragraph-init", paragraph.ParagraphInit)
+		authGroup.GET("/paragraph-init", middleware.RequireScope(middleware.ScopeLookup), paragraph.ParagraphInit)
 
 		// translate
-		authGroup.GET("/translate", translate.Translate)
-		authGroup.GET("/word/:word", translate.TranslateByWord)
-		authGroup.GET("/load-count", wordCount.LoadCount)
-		authGroup.POST("/mark", MarkWord)
-		authGroup.GET("/do-search", DoSearch)
-		authGroup.GET("/third-party", DoSearchThirdParty)
+		authGroup.GET("/translate", middleware.RequireScope(middleware.ScopeLookup), translate.Translate)
+		authGroup.GET("/word/:word", middleware.RequireScope(middleware.ScopeLookup), translate.TranslateByWord)
+		authGroup.POST("/translate/text", middleware.RequireScope(middleware.ScopeLookup), translate.TranslateText)
+		authGroup.GET("/entry/:word", middleware.RequireScope(middleware.ScopeLookup), handlers.GetEntry)
+		authGroup.GET("/audio/:word", middleware.RequireScope(middleware.ScopeLookup), handlers.GetAudio)
+		authGroup.GET("/load-count", middleware.RequireScope(middleware.ScopeRead), wordCount.LoadCount)
+		authGroup.POST("/mark", middleware.RequireScope(middleware.ScopeMark), MarkWord)
+		authGroup.GET("/do-search", middleware.RequireScope(middleware.ScopeLookup), DoSearch)
+		authGroup.GET("/third-party", middleware.RequireScope(middleware.ScopeLookup), DoSearchThirdParty)
 		authGroup.GET("/wrap", Wrap)
 		authGroup.POST("/log", LogHandler)
+
+		// personal notes, tags and translations, and export of the user's words
+		authGroup.GET("/word/:word/note", middleware.RequireScope(middleware.ScopeRead), handlers.GetWordNote)
+		authGroup.PUT("/word/:word/note", middleware.RequireScope(middleware.ScopeMark), handlers.SaveWordNote)
+		authGroup.DELETE("/word/:word/note", middleware.RequireScope(middleware.ScopeMark), handlers.DeleteWordNote)
+		authGroup.GET("/export", middleware.RequireScope(middleware.ScopeExport), handlers.ExportWords)
+		authGroup.GET("/my/words", middleware.RequireScope(middleware.ScopeRead), handlers.ListMyWords)
+		authGroup.GET("/stats", middleware.RequireScope(middleware.ScopeRead), handlers.GetStats)
+		authGroup.GET("/history", middleware.RequireScope(middleware.ScopeRead), handlers.ListHistory)
+		authGroup.GET("/vocabulary", middleware.RequireScope(middleware.ScopeRead), handlers.GetVocabulary)
+		authGroup.GET("/my/highlight-policy", middleware.RequireScope(middleware.ScopeRead), handlers.GetHighlightPolicy)
+		authGroup.PUT("/my/highlight-policy", middleware.RequireScope(middleware.ScopeMark), handlers.SaveHighlightPolicy)
+		authGroup.DELETE("/my/highlight-policy", middleware.RequireScope(middleware.ScopeMark), handlers.DeleteHighlightPolicy)
+		authGroup.GET("/my/languages", middleware.RequireScope(middleware.ScopeRead), handlers.GetUserLanguages)
+		authGroup.PUT("/my/languages", middleware.RequireScope(middleware.ScopeMark), handlers.SaveUserLanguages)
+
+		// sessions and api tokens, managed from a login session only
+		authGroup.GET("/sessions", middleware.RequireSession(), handlers.ListSessions)
+		authGroup.DELETE("/sessions/:id", middleware.RequireSession(), handlers.RevokeSession)
+		authGroup.POST("/tokens", middleware.RequireSession(), handlers.CreateToken)
+		authGroup.GET("/tokens", middleware.RequireSession(), handlers.ListTokens)
+		authGroup.DELETE("/tokens/:id", middleware.RequireSession(), handlers.RevokeToken)
+		authGroup.POST("/password/change", middleware.RequireSession(), handlers.ChangePassword)
+
+		// admin: users and dictionary curation
+		admin := authGroup.Group("/admin", middleware.RequireSession(), middleware.RequireAdmin())
+		admin.GET("/users", handlers.AdminListUsers)
+		admin.PATCH("/users/:id", handlers.AdminUpdateUser)
+		admin.GET("/words", handlers.AdminListWords)
+		admin.PUT("/words/:id", handlers.AdminUpdateWord)
+		admin.DELETE("/words/:id", handlers.AdminDeleteWord)
+		admin.POST("/words/:id/merge", handlers.AdminMergeWord)
+		admin.GET("/stats", handlers.AdminStats)
+		admin.DELETE("/translation-cache/:word", handlers.AdminPurgeTranslationCache)
 	}
 
 	// API group for Kong gateway (with /api prefix)
@@ -145,28 +250,73 @@ func main() {
 	apiGroup.Use(middleware.SessionMiddleware())
 	{
 		// get words query count by paragraph
-		apiGroup.GET("/paragraph-init", paragraph.ParagraphInit)
+		apiGroup.GET("/paragraph-init", middleware.RequireScope(middleware.ScopeLookup), paragraph.ParagraphInit)
 
 		// translate
-		apiGroup.GET("/translate", translate.Translate)
-		apiGroup.GET("/word/:word", translate.TranslateByWord)
-		apiGroup.GET("/load-count", wordCount.LoadCount)
-		apiGroup.POST("/mark", MarkWord)
-		apiGroup.GET("/do-search", DoSearch)
-		apiGroup.GET("/third-party", DoSearchThirdParty)
+		apiGroup.GET("/translate", middleware.RequireScope(middleware.ScopeLookup), translate.Translate)
+		apiGroup.GET("/word/:word", middleware.RequireScope(middleware.ScopeLookup), translate.TranslateByWord)
+		apiGroup.POST("/translate/text", middleware.RequireScope(middleware.ScopeLookup), translate.TranslateText)
+		apiGroup.GET("/entry/:word", middleware.RequireScope(middleware.ScopeLookup), handlers.GetEntry)
+		apiGroup.GET("/audio/:word", middleware.RequireScope(middleware.ScopeLookup), handlers.GetAudio)
+		apiGroup.GET("/load-count", middleware.RequireScope(middleware.ScopeRead), wordCount.LoadCount)
+		apiGroup.POST("/mark", middleware.RequireScope(middleware.ScopeMark), MarkWord)
+		apiGroup.GET("/do-search", middleware.RequireScope(middleware.ScopeLookup), DoSearch)
+		apiGroup.GET("/third-party", middleware.RequireScope(middleware.ScopeLookup), DoSearchThirdParty)
 		apiGroup.GET("/wrap", Wrap)
 		apiGroup.POST("/log", LogHandler)
+
+		// personal notes, tags and translations, and export of the user's words
+		apiGroup.GET("/word/:word/note", middleware.RequireScope(middleware.ScopeRead), handlers.GetWordNote)
+		apiGroup.PUT("/word/:word/note", middleware.RequireScope(middleware.ScopeMark), handlers.SaveWordNote)
+		apiGroup.DELETE("/word/:word/note", middleware.RequireScope(middleware.ScopeMark), handlers.DeleteWordNote)
+		apiGroup.GET("/export", middleware.RequireScope(middleware.ScopeExport), handlers.ExportWords)
+		apiGroup.GET("/my/words", middleware.RequireScope(middleware.ScopeRead), handlers.ListMyWords)
+		apiGroup.GET("/stats", middleware.RequireScope(middleware.ScopeRead), handlers.GetStats)
+		apiGroup.GET("/history", middleware.RequireScope(middleware.ScopeRead), handlers.ListHistory)
+		apiGroup.GET("/vocabulary", middleware.RequireScope(middleware.ScopeRead), handlers.GetVocabulary)
+		apiGroup.GET("/my/highlight-policy", middleware.RequireScope(middleware.ScopeRead), handlers.GetHighlightPolicy)
+		apiGroup.PUT("/my/highlight-policy", middleware.RequireScope(middleware.ScopeMark), handlers.SaveHighlightPolicy)
+		apiGroup.DELETE("/my/highlight-policy", middleware.RequireScope(middleware.ScopeMark), handlers.DeleteHighlightPolicy)
+		apiGroup.GET("/my/languages", middleware.RequireScope(middleware.ScopeRead), handlers.GetUserLanguages)
+		apiGroup.PUT("/my/languages", middleware.RequireScope(middleware.ScopeMark), handlers.SaveUserLanguages)
+
+		// sessions and api tokens, managed from a login session only
+		apiGroup.GET("/sessions", middleware.RequireSession(), handlers.ListSessions)
+		apiGroup.DELETE("/sessions/:id", middleware.RequireSession(), handlers.RevokeSession)
+		apiGroup.POST("/tokens", middleware.RequireSession(), handlers.CreateToken)
+		apiGroup.GET("/tokens", middleware.RequireSession(), handlers.ListTokens)
+		apiGroup.DELETE("/tokens/:id", middleware.RequireSession(), handlers.RevokeToken)
+		apiGroup.POST("/password/change", middleware.RequireSession(), handlers.ChangePassword)
+
+		// admin: users and dictionary curation
+		admin := apiGroup.Group("/admin", middleware.RequireSession(), middleware.RequireAdmin())
+		admin.GET("/users", handlers.AdminListUsers)
+		admin.PATCH("/users/:id", handlers.AdminUpdateUser)
+		admin.GET("/words", handlers.AdminListWords)
+		admin.PUT("/words/:id", handlers.AdminUpdateWord)
+		admin.DELETE("/words/:id", handlers.AdminDeleteWord)
+		admin.POST("/words/:id/merge", handlers.AdminMergeWord)
+		admin.GET("/stats", handlers.AdminStats)
+		admin.DELETE("/translation-cache/:word", handlers.AdminPurgeTranslationCache)
 	}
 
 	// APIs not requiring authentication
 	router.POST("/login", Login)
 	router.POST("/logout", Logout)
 	router.POST("/register", Register)
+	router.POST("/password/reset", handlers.RequestPasswordReset)
+	router.POST("/password/reset/confirm", handlers.ConfirmPasswordReset)
+	router.GET("/oidc/login", sso.Login)
+	router.GET("/oidc/callback", sso.Callback)
 
 	// APIs not requiring authentication (with /api prefix for Kong gateway)
 	router.POST("/api/login", Login)
 	router.POST("/api/logout", Logout)
 	router.POST("/api/register", Register)
+	router.POST("/api/password/reset", handlers.RequestPasswordReset)
+	router.POST("/api/password/reset/confirm", handlers.ConfirmPasswordReset)
+	router.GET("/api/oidc/login", sso.Login)
+	router.GET("/api/oidc/callback", sso.Callback)
 
 	// Temporary test route - no authentication required
 	router.POST("/mark-test", MarkWord)
@@ -198,39 +348,76 @@ func main() {
 type SearchResult struct {
 	WordList []string
 	Dict     *enx.Dictionary
+	// "did you mean" spellings when key has no translation, see suggest.Suggest
+	Suggestions []string `json:"suggestions,omitempty"`
 }
 
+// DoSearch lists words starting with key, or whose meaning starts with it, along with the translation
+// of key into the user's native language
 func DoSearch(c *gin.Context) {
 	key := c.Query("key")
 	logger.Infof("key: %v", key)
-	words := enx.Search(key)
+	userId := middleware.GetUserIDFromContext(c)
+	from := repo.GetSourceLanguage(userId, c.Query("from"))
+	words, ok := searchWordList(c, from, key)
+	if !ok {
+		return
+	}
 
+	dict, err := translate.CurrentLookup().Define(c.Request.Context(), userId, from, key)
+	if err != nil {
+		translate.RespondUpstreamError(c, err)
+		return
+	}
 	result := SearchResult{}
 	result.WordList = words
-	result.Dict = enx.FindOne(key)
-	if result.Dict == nil || result.Dict.Chinese == "" {
-		// query from third party
-		epc := youdao.Query(key)
-		result.Dict = epc
-	}
+	result.Dict = dict
+	result.Suggestions = suggestSpellings(from, key, result.Dict)
 	c.JSON(200, result)
 }
 
 func DoSearchThirdParty(c *gin.Context) {
 	key := c.Query("key")
 	logger.Infof("key: %v", key)
-	words := enx.Search(key)
+	userId := middleware.GetUserIDFromContext(c)
+	from := repo.GetSourceLanguage(userId, c.Query("from"))
+	words, ok := searchWordList(c, from, key)
+	if !ok {
+		return
+	}
 
+	dict, err := translate.CurrentLookup().DefineUpstream(c.Request.Context(), userId, from, key)
+	if err != nil {
+		translate.RespondUpstreamError(c, err)
+		return
+	}
 	result := SearchResult{}
 	result.WordList = words
-
-	// query from third party
-	epc := youdao.Query(key)
-	result.Dict = epc
+	result.Dict = dict
+	result.Suggestions = suggestSpellings(from, key, dict)
 
 	c.JSON(200, result)
 }
 
+// suggestSpellings suggests other spellings of a key without a translation, none for a meaning
+func suggestSpellings(from, key string, dict *enx.Dictionary) []string {
+	if dict.Gloss != "" || repo.IsReverseSearch(key) {
+		return nil
+	}
+	return suggest.Suggest(from, key)
+}
+
+// searchWordList lists the words of a search, responding with the error when the search failed
+func searchWordList(c *gin.Context, from, key string) ([]string, bool) {
+	words, err := enx.Search(from, key)
+	if err != nil {
+		logger.Errorf("failed to search words, key: %s, error: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to search words"})
+		return nil, false
+	}
+	return words, true
+}
+
 type article struct {
 	WidthMax int `json:"-"`
 	Lines    []*line
@@ -275,7 +462,6 @@ func MarkWord(c *gin.Context) {
 	logger.Infof("MarkWord: Starting mark word request")
 
 	word := enx.Word{}
-	// set key
 	err := c.BindJSON(&word)
 	if err != nil {
 		logger.Errorf("MarkWord: Failed to bind JSON: %v", err)
@@ -286,7 +472,6 @@ func MarkWord(c *gin.Context) {
 		return
 	}
 	logger.Infof("MarkWord: Successfully parsed word: %s", word.English)
-	word.Key = strings.ToLower(word.English)
 
 	// Get user ID from session context
 	userId := middleware.GetUserIDFromContext(c)
@@ -300,24 +485,17 @@ func MarkWord(c *gin.Context) {
 		return
 	}
 
-	word.Translate(userId)
-
-	ud := enx.UserDict{}
-	ud.WordId = word.Id
-	ud.UserId = userId
-
-	// Load current state first (this is crucial!)
-	ud.IsExist()
-	logger.Infof("MarkWord: Before marking - AlreadyAcquainted: %d", ud.AlreadyAcquainted)
-
-	ud.Mark() // This will toggle the state
-
-	// Use the state after marking (no need to query again)
-	word.LoadCount = ud.QueryCount
-	word.AlreadyAcquainted = ud.AlreadyAcquainted
+	// This will toggle the state
+	source, pageHost := middleware.GetRequestSource(c)
+	marked, err := translate.CurrentLookup().Mark(c.Request.Context(), translate.Query{UserId: userId, Raw: word.English,
+		From: word.SourceLanguage, Source: source, PageHost: pageHost})
+	if err != nil {
+		translate.RespondLookupError(c, err)
+		return
+	}
 
-	logger.Infof("MarkWord: Word marked, new state AlreadyAcquainted: %d", ud.AlreadyAcquainted)
-	c.JSON(200, word)
+	logger.Infof("MarkWord: Word marked, new state AlreadyAcquainted: %d", marked.AlreadyAcquainted)
+	c.JSON(200, marked)
 }
 
 func Ping(c *gin.Context) {
@@ -348,14 +526,23 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Throttle before verifying the password, Argon2 costs 64MB per attempt
+	decision := middleware.CheckLogin(c.ClientIP(), req.Username)
+	if !decision.Allowed {
+		tooManyLoginAttempts(c, req.Username, decision.RetryAfter, decision.UnlockAt)
+		return
+	}
+
 	user := &enx.User{
 		Name:     req.Username,
 		Password: req.Password,
 	}
 
 	if user.Login() {
+		middleware.LoginSucceeded(req.Username)
+
 		// Create new session
-		session, err := middleware.CreateSession(user.Id)
+		session, err := middleware.CreateSession(user.Id, c.GetHeader("User-Agent"), c.ClientIP())
 		if err != nil {
 			logger.Errorf("failed to create session for user %s: %v", user.Name, err)
 			c.JSON(http.StatusInternalServerError, LoginResponse{
@@ -366,7 +553,8 @@ func Login(c *gin.Context) {
 		}
 
 		// Set cookie - use empty domain for cross-origin requests
-		c.SetCookie("session_id", session.ID, 24*3600, "/", "", false, true)
+		// The cookie lives as long as the session can; the server enforces the sliding expiry
+		c.SetCookie("session_id", session.ID, int(middleware.SessionAbsoluteTimeout().Seconds()), "/", "", false, true)
 
 		logger.Infof("user login success, user: %+v", user)
 		c.JSON(http.StatusOK, LoginResponse{
@@ -377,6 +565,10 @@ func Login(c *gin.Context) {
 		})
 	} else {
 		logger.Errorf("user login failed, username: %s", req.Username)
+		if unlockAt := middleware.LoginFailed(req.Username); !unlockAt.IsZero() {
+			tooManyLoginAttempts(c, req.Username, time.Until(unlockAt), unlockAt)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"error":   "invalid_credentials",
@@ -385,6 +577,24 @@ func Login(c *gin.Context) {
 	}
 }
 
+// tooManyLoginAttempts responds 429, with the unlock time if the account is locked
+func tooManyLoginAttempts(c *gin.Context, username string, retryAfter time.Duration, unlockAt time.Time) {
+	logger.Warnf("login throttled, username: %s, ip: %s, retry after: %s", username, c.ClientIP(), retryAfter)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	resp := gin.H{
+		"success":     false,
+		"error":       "too_many_attempts",
+		"message":     "Too many login attempts, please try again later",
+		"retry_after": int(math.Ceil(retryAfter.Seconds())),
+	}
+	if !unlockAt.IsZero() {
+		resp["error"] = "account_locked"
+		resp["message"] = "Account temporarily locked after too many failed logins"
+		resp["unlock_at"] = unlockAt.UnixMilli()
+	}
+	c.JSON(http.StatusTooManyRequests, resp)
+}
+
 type LogRequest struct {
 	Event     string `json:"event"`
 	Message   string `json:"message"`
@@ -448,6 +658,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if len(req.Password) < password.MinLength {
+		c.JSON(http.StatusBadRequest, RegisterResponse{
+			Success: false,
+			Message: enx.ErrWeakPassword.Error(),
+		})
+		return
+	}
+
 	// Check if username already exists
 	existingUser := enx.GeUserByName(req.Username)
 	if existingUser.Id != "" {
@@ -491,3 +709,12 @@ func Register(c *gin.Context) {
 		Message: "Registration successful",
 	})
 }
+
+// trustedProxies returns the configured proxies of enx-api, nil when there is none
+func trustedProxies() []string {
+	proxies := viper.GetStringSlice("enx.trusted-proxies")
+	if len(proxies) == 0 {
+		return nil
+	}
+	return proxies
+}
@@ -3,10 +3,15 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"enx-api/enx"
 	"enx-api/utils/logger"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/go-resty/resty/v2"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 		"enx-api/utils/sqlitex"
 )
@@ -57,3 +62,17 @@ func Test01(*testing.T) {
 	fmt.Println(nonAlphanumericRegex.ReplaceAllString("abc'def'", ""))
 	fmt.Println(nonAlphanumericRegex.ReplaceAllString("'abc'def'", ""))
 }
+
+func TestRegisterWeakPassword(t *testing.T) {
+	router := gin.New()
+	router.POST("/register", Register)
+	body := `{"username": "alice", "password": "short", "email": "alice@example.com"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), enx.ErrWeakPassword.Error()) {
+		t.Errorf("weak password, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	if enx.GeUserByName("alice").Id != "" {
+		t.Error("user created")
+	}
+}
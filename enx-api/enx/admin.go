@@ -0,0 +1,44 @@
+package enx
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"time"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// SearchUsers finds users whose name or email contains q, oldest first
+func SearchUsers(q string, offset, limit int) ([]User, int64, error) {
+	query := sqlitex.DB.Model(&User{})
+	if q != "" {
+		query = query.Where("name LIKE ? OR email LIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []User
+	err := query.Order("created_at").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+// SetUserDisabled disables or re-enables an account; disabled users can't log in
+func SetUserDisabled(userID string, disabled bool) error {
+	return sqlitex.DB.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"disabled":   disabled,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// SetUserRole sets the role of a user, RoleUser or RoleAdmin
+func SetUserRole(userID, role string) error {
+	if role != RoleUser && role != RoleAdmin {
+		return ErrInvalidRole
+	}
+	return sqlitex.DB.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+	}).Error
+}
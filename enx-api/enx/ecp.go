@@ -1,15 +1,13 @@
 package enx
 
 import (
+	"enx-api/frequency"
+	"enx-api/language"
 	"enx-api/repo"
 	"enx-api/utils/logger"
 	"enx-api/utils/sqlitex"
-	"fmt"
-	"regexp"
 	"strings"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 type Word struct {
@@ -17,11 +15,13 @@ type Word struct {
 	Id string
 	// raw paragraph item, e.g. `morning.`
 	Raw string
-	// english word, e.g. `morning`
+	// language of English, see language.Sources; SetEnglish defaults it to English
+	SourceLanguage string
+	// english word, e.g. `morning`, or a word of another source language, e.g. `über`
 	English       string
 	Chinese       string
 	Pronunciation string
-	// key is lower case of english word, e.g. `morning`
+	// key is lower case of english word by the rules of its language, e.g. `morning`, see language.Key
 	Key string
 
 	// 0: false, 1: true
@@ -30,10 +30,66 @@ type Word struct {
 	// 0: default: normal type
 	// 1: raw: this word would not be translated
 	WordType int
+
+	// personal annotations of the current user, a personal translation replaces Chinese
+	Note string
+	Tags []string
+
+	// rank in the word frequency list, 1 is the most frequent word, 0: not ranked
+	FrequencyRank int
+
+	// whether the user's highlight policy shows the word and the rule that decided it, see ApplyHighlightPolicy
+	Highlight       bool
+	HighlightReason string
+
+	// translation into the user's native language Language, see UseLanguage; Chinese stays the zh-CHS one
+	Gloss    string
+	Language string
+	// "did you mean" spellings when there is no translation of the word, see suggest.Suggest
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	// translations by target language as stored in the dictionary
+	glosses map[string]string
+}
+
+// UseLanguage picks the translation into a target language as the gloss of the word
+func (word *Word) UseLanguage(lang string) {
+	word.Language = lang
+	word.Gloss = word.glosses[lang]
 }
 
+// SetGloss sets a translation into a target language, Save and SaveGloss store it
+func (word *Word) SetGloss(lang, gloss string) {
+	if word.glosses == nil {
+		word.glosses = map[string]string{}
+	}
+	word.glosses[lang] = gloss
+	if lang == language.Chinese {
+		word.Chinese = gloss
+	}
+	if lang == word.Language {
+		word.Gloss = gloss
+	}
+}
+
+// SaveGloss stores a translation of a word already in the dictionary into another language
+func (word *Word) SaveGloss(lang, gloss string) error {
+	word.SetGloss(lang, gloss)
+	sWord := repo.GetWord(word.SourceLanguage, word.English)
+	if sWord.Id == "" {
+		return nil
+	}
+	sWord.SetGloss(lang, gloss)
+	return repo.UpdateWord(sWord)
+}
+
+// SetEnglish sets the word from a paragraph item, keeping only the characters words are made of
+// in any language, see language.Clean
 func (word *Word) SetEnglish(raw string) {
-	raw = regexp.MustCompile(`[^a-zA-Z\-'’ ]+`).ReplaceAllString(raw, "")
+	if word.SourceLanguage == "" {
+		word.SourceLanguage = language.DefaultSource
+	}
+	raw = language.Clean(raw)
 
 	english := ""
 	if strings.Contains(raw, "'s") ||
@@ -54,8 +110,8 @@ func (word *Word) SetEnglish(raw string) {
 		english = strings.TrimSuffix(english, ",")
 		english = strings.TrimPrefix(english, "(")
 		english = strings.TrimSuffix(english, ")")
-		english = regexp.MustCompile(`[^a-zA-Z\-'’ ]+`).ReplaceAllString(english, "")
-		logger.Debugf("replace non english char, raw: %s, english: %s", raw, english)
+		english = language.Clean(english)
+		logger.Debugf("replace non word char, raw: %s, english: %s", raw, english)
 	}
 
 	// if english end with - or space, remove it
@@ -68,46 +124,68 @@ func (word *Word) SetEnglish(raw string) {
 
 // count by english
 func (word *Word) CountByEnglish() int {
-	count := repo.CountByEnglish(word.English)
+	count := repo.CountByEnglish(word.SourceLanguage, word.English)
 	return count
 }
 func (word *Word) FindId() {
-	sWord := repo.GetWordByEnglish(word.English)
+	sWord := repo.GetWord(word.SourceLanguage, word.English)
 	word.Id = sWord.Id
+	word.FrequencyRank = sWord.FrequencyRank
+	word.glosses = sWord.GlossMap()
+	if word.Id == "" && word.SourceLanguage == language.English {
+		// not in the dictionary yet, rank it from the list directly, an English one
+		word.FrequencyRank = frequency.Rank(word.Key)
+	}
 }
 
 func (word *Word) LoadByEnglish() {
-	sWord := repo.GetWordByEnglish(word.English)
+	sWord := repo.GetWord(word.SourceLanguage, word.English)
 	word.Id = sWord.Id
 	word.English = sWord.English
 	word.Chinese = sWord.Chinese
 	word.Pronunciation = sWord.Pronunciation
 	word.LoadCount = sWord.LoadCount
+	word.glosses = sWord.GlossMap()
 	logger.Debugf("load by english, word: %s, id: %d", word.English, word.Id)
 }
 
+// SetEnglishField sets English and Key, an English possessive 's is dropped
 func (word *Word) SetEnglishField(english string) {
-	if strings.Contains(english, "'s") {
-		tmpKey := strings.Replace(english, "'s", "", -1)
-		word.English = tmpKey
-		word.Key = strings.ToLower(tmpKey)
-	} else if strings.Contains(english, "’s") {
-		tmpKey := strings.Replace(english, "’s", "", -1)
-		word.English = tmpKey
-		word.Key = strings.ToLower(tmpKey)
-	} else {
-		word.English = english
-		word.Key = strings.ToLower(english)
+	if word.SourceLanguage == language.English && strings.Contains(english, "'s") {
+		english = strings.Replace(english, "'s", "", -1)
+	} else if word.SourceLanguage == language.English && strings.Contains(english, "’s") {
+		english = strings.Replace(english, "’s", "", -1)
 	}
+	word.English = english
+	word.Key = language.Key(word.SourceLanguage, english)
 	logger.Infof("set english, raw: %s, english: %s, key: %s", word.Raw, word.English, word.Key)
 }
 func (word *Word) FindQueryCount(userId string) int {
-	qc, acquainted := repo.GetUserWordQueryCount(word.Id, userId)
+	ud := UserDict{UserId: userId, WordId: word.Id}
+	if !ud.IsExist() {
+		word.LoadCount = 0
+		word.AlreadyAcquainted = 0
+		return 0
+	}
 	logger.Debugf("find query count, word id: %s, word: %s, user_id: %s, query count: %d",
-		word.Id, word.English, userId, qc)
-	word.LoadCount = qc
-	word.AlreadyAcquainted = acquainted
-	return qc
+		word.Id, word.English, userId, ud.QueryCount)
+	word.LoadCount = ud.QueryCount
+	word.AlreadyAcquainted = ud.AlreadyAcquainted
+	word.ApplyNote(&ud)
+	return ud.QueryCount
+}
+
+// ApplyNote copies the user's note and tags onto the word,
+// their own translation, in their native language, is shown in preference to the shared one
+func (word *Word) ApplyNote(ud *UserDict) {
+	word.Note = ud.Note
+	word.Tags = repo.SplitTags(ud.Tags)
+	if ud.Translation != "" {
+		word.Gloss = ud.Translation
+		if word.Language == "" || word.Language == language.Chinese {
+			word.Chinese = ud.Translation
+		}
+	}
 }
 
 func (word *Word) FindLoadCountById() int {
@@ -127,17 +205,20 @@ func (word *Word) Translate(userId string) *Word {
 		return word
 	}
 
-	sWord := repo.Translate(word.English, userId)
+	sWord := repo.Translate(word.SourceLanguage, word.English, userId)
 	word.Id = sWord.Id
 	word.Chinese = sWord.Chinese
 	word.Pronunciation = sWord.Pronunciation
+	word.FrequencyRank = sWord.FrequencyRank
+	word.glosses = sWord.GlossMap()
 
 	word.LoadCount = sWord.LoadCount
 	if sWord.Id != "" {
-		// Query user_dicts from database using UUID
-		// userId needs to be converted to UUID string format
-		userIdStr := fmt.Sprintf("user-%d", userId) // Temporary: convert int userId to string
-		queryCount, _ := repo.GetUserWordQueryCount(sWord.Id, userIdStr)
+		// the user's own count over the one of the word
+		queryCount, _, err := repo.GetUserWordQueryCount(sWord.Id, userId)
+		if err != nil {
+			logger.Errorf("failed to get query count, word id: %s, user_id: %s, error: %v", sWord.Id, userId, err)
+		}
 		if queryCount > 0 {
 			word.LoadCount = queryCount
 		}
@@ -149,28 +230,34 @@ func (word *Word) Translate(userId string) *Word {
 
 func (word *Word) RemoveDuplicateWord() {
 	// count by english
-	count := repo.CountByEnglish(word.English)
+	count := repo.CountByEnglish(word.SourceLanguage, word.English)
 
 	if count > 1 {
 		// delete duplicate word
-		tmp_word := repo.GetWordByEnglishCaseSensitive(word.English)
+		tmp_word := repo.GetWordByEnglishCaseSensitive(word.SourceLanguage, word.English)
 		// Note: DeleteDuplicateWord might need to be updated to accept string ID
 		repo.DeleteDuplicateWord(word.English, tmp_word.Id)
 	}
 }
 
-func (word *Word) Save() {
+// Save stores a new word, the word keeps the id it was stored with
+func (word *Word) Save() error {
 	sWord := repo.Word{}
-	sWord.Id = uuid.NewString() // Generate UUID for new word
-	sWord.CreateDatetime = time.Now()
-	sWord.UpdateDatetime = time.Now()
+	sWord.SourceLanguage = word.SourceLanguage
 	sWord.English = word.English
 	sWord.Chinese = word.Chinese
 	sWord.Pronunciation = word.Pronunciation
 	sWord.LoadCount = word.LoadCount
-	tx := sqlitex.DB.Create(&sWord)
-	logger.Debugf("save word: %v, tx: %v", sWord, tx)
+	for lang, gloss := range word.glosses {
+		sWord.SetGloss(lang, gloss)
+	}
+	if err := repo.CreateWord(&sWord); err != nil {
+		return err
+	}
+	logger.Debugf("save word: %v", sWord)
 	word.Id = sWord.Id
+	word.FrequencyRank = sWord.FrequencyRank
+	return nil
 }
 
 func (word *Word) UpdateLoadCount() {
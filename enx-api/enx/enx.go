@@ -1,30 +1,16 @@
 package enx
 
 import (
-	"enx-api/utils/mysql"
-	"github.com/spf13/viper"
+	"enx-api/repo"
 )
 
-var enxDb *mysql.Database
+// searchLimit is how many words a search lists
+const searchLimit = 20
 
-func Search(key string) []string {
-	var result []string
-	tmp := instance().Find("select english from words where english like '" + key + "%' order by english limit 20")
-	for _, v := range tmp {
-		result = append(result, string(v["english"].([]uint8)))
-	}
-	return result
-}
-
-func instance() *mysql.Database {
-	if enxDb == nil {
-		address := viper.GetString("mysql.address")
-		user := viper.GetString("mysql.user")
-		password := viper.GetString("mysql.password")
-		mysqlConfig := mysql.Config{DatabaseName: "enx", Address: address, Username: user, Password: password}
-		enxDb = mysql.NewDatabase(mysqlConfig)
-	}
-	return enxDb
+// Search lists words of a source language starting with key, or whose meaning starts with it,
+// see repo.SearchWordList
+func Search(lang, key string) ([]string, error) {
+	return repo.SearchWordList(lang, key, searchLimit)
 }
 
 type Dictionary struct {
@@ -32,18 +18,21 @@ type Dictionary struct {
 	Chinese       string
 	Pronunciation string
 	CreateTime    string
+	// translation into Language, the user's native language; Chinese stays the zh-CHS one
+	Gloss    string
+	Language string
 }
 
-func FindOne(key string) *Dictionary {
-	var dict Dictionary
-	tmp := instance().Find("select * from words where english=? order by create_datetime limit 1", key)
-	if len(tmp) > 0 {
-		dict.English = string(tmp[0]["english"].([]uint8))
-		dict.Chinese = string(tmp[0]["chinese"].([]uint8))
-		if tmp[0]["pronunciation"] != nil {
-			dict.Pronunciation = string(tmp[0]["pronunciation"].([]uint8))
-		}
+// FindOne returns the word of a source language with its translation into a target language,
+// empty when the word isn't in the database
+func FindOne(from, to, key string) *Dictionary {
+	word := repo.GetWord(from, key)
+	dict := &Dictionary{Language: to}
+	if word.Id == "" {
+		return dict
 	}
-
-	return &dict
+	dict.English, dict.Chinese, dict.Pronunciation = word.English, word.Chinese, word.Pronunciation
+	dict.CreateTime = word.CreateDatetime.Format("2006-01-02 15:04:05")
+	dict.Gloss = word.Gloss(to)
+	return dict
 }
@@ -1,12 +1,17 @@
 package enx
 
 import (
+	"enx-api/language"
+	"enx-api/repo"
 	"enx-api/utils/logger"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
-func QueryCountInText(paragraph string, userId string) map[string]Word {
+// QueryCountInText looks the words of a paragraph up for a user. from is the language the paragraph
+// is in when known, see repo.GetSourceLanguage.
+func QueryCountInText(paragraph string, userId string, from string) map[string]Word {
 	words := paragraph
 	logger.Infof("query count, paragraph: %s, user_id: %s", words, userId)
 
@@ -14,8 +19,19 @@ func QueryCountInText(paragraph string, userId string) map[string]Word {
 	spaceRegex := regexp.MustCompile(`\s+`)
 	words = spaceRegex.ReplaceAllString(words, " ")
 
+	policy, err := repo.GetHighlightPolicy(userId)
+	if err != nil {
+		logger.Errorf("failed to get highlight policy, using the default one, user_id: %s, error: %v", userId, err)
+		policy = repo.DefaultHighlightPolicy(userId)
+	}
+	lang := repo.GetNativeLanguage(userId)
+	from = repo.GetSourceLanguage(userId, from)
+
 	wordsArray := strings.Split(words, " ")
 	response := make(map[string]Word)
+	// tokens seen capitalized in the middle of a sentence, candidates for proper nouns
+	midSentenceCapitals := make(map[string]bool)
+	sentenceStart := true
 	for _, word_raw := range wordsArray {
 		// check if word_raw is start with a digit
 		// 6-year-old
@@ -29,12 +45,19 @@ func QueryCountInText(paragraph string, userId string) map[string]Word {
 			wordObj.Raw = word_raw
 			wordObj.WordType = 1
 			response[wordObj.Raw] = wordObj
+			sentenceStart = endsSentence(word_raw)
 			continue
 		}
 
-		wordObj := Word{}
+		wordObj := Word{SourceLanguage: from}
 		wordObj.SetEnglish(word_raw)
+		// German capitalizes every noun, capitals tell proper nouns apart in English only
+		if from == language.English && !sentenceStart && wordObj.English != "" && unicode.IsUpper([]rune(wordObj.English)[0]) {
+			midSentenceCapitals[wordObj.Raw] = true
+		}
+		sentenceStart = endsSentence(word_raw)
 		wordObj.FindId()
+		wordObj.UseLanguage(lang)
 		if wordObj.Id == "" {
 			wordObj.LoadCount = 0
 			wordObj.AlreadyAcquainted = 0
@@ -45,6 +68,7 @@ func QueryCountInText(paragraph string, userId string) map[string]Word {
 			if ud.IsExist() {
 				wordObj.LoadCount = ud.QueryCount
 				wordObj.AlreadyAcquainted = ud.AlreadyAcquainted
+				wordObj.ApplyNote(&ud)
 			} else {
 				wordObj.LoadCount = 0
 				wordObj.AlreadyAcquainted = 0
@@ -56,6 +80,10 @@ func QueryCountInText(paragraph string, userId string) map[string]Word {
 
 		response[wordObj.Raw] = wordObj
 	}
+	for raw, wordObj := range response {
+		wordObj.ApplyHighlightPolicy(policy, midSentenceCapitals[raw])
+		response[raw] = wordObj
+	}
 	logger.Debug("words count: ", response)
 	return response
 }
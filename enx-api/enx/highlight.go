@@ -0,0 +1,75 @@
+package enx
+
+import (
+	"enx-api/frequency"
+	"enx-api/repo"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// reasons of a highlight decision, the first rule that applies wins
+const (
+	// HighlightRaw is a token that is not a word, e.g. a number
+	HighlightRaw = "raw"
+	// HighlightTag is a word carrying one of the policy's always shown tags
+	HighlightTag = "tag"
+	// HighlightAcquainted is a word the user marked as acquainted
+	HighlightAcquainted = "acquainted"
+	// HighlightProperNoun is a capitalized word in the middle of a sentence that isn't a ranked common word
+	HighlightProperNoun = "proper_noun"
+	// HighlightRank is a word ranked beyond the policy's max rank
+	HighlightRank = "rank"
+	// HighlightQueryCount is a word looked up fewer times than the policy's minimum
+	HighlightQueryCount = "query_count"
+	// HighlightLearning is a word the user is still learning, the only reason that highlights
+	// besides HighlightTag
+	HighlightLearning = "learning"
+)
+
+// ApplyHighlightPolicy sets Highlight and HighlightReason of the word. midSentenceCapital tells
+// whether the token was seen capitalized somewhere other than at the start of a sentence.
+func (word *Word) ApplyHighlightPolicy(policy *repo.HighlightPolicy, midSentenceCapital bool) {
+	word.Highlight, word.HighlightReason = false, ""
+	switch {
+	case word.WordType == 1:
+		word.HighlightReason = HighlightRaw
+	case hasAnyTag(word.Tags, repo.SplitTags(policy.AlwaysTags)):
+		word.Highlight, word.HighlightReason = true, HighlightTag
+	case word.AlreadyAcquainted == 1:
+		word.HighlightReason = HighlightAcquainted
+	case policy.HideProperNouns == 1 && midSentenceCapital && isProperNoun(word.English):
+		word.HighlightReason = HighlightProperNoun
+	case policy.MaxRank > 0 && word.FrequencyRank > policy.MaxRank:
+		word.HighlightReason = HighlightRank
+	case word.LoadCount == 0 || word.LoadCount < policy.MinQueryCount:
+		word.HighlightReason = HighlightQueryCount
+	default:
+		word.Highlight, word.HighlightReason = true, HighlightLearning
+	}
+}
+
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(wanted, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isProperNoun guesses from a capitalized word whether it names something: "I" and
+// words of the frequency list such as "The" or "Apple" are treated as common words
+func isProperNoun(english string) bool {
+	if english == "" || english == "I" || !unicode.IsUpper([]rune(english)[0]) {
+		return false
+	}
+	return frequency.Rank(english) == 0
+}
+
+// endsSentence tells whether the next token starts a new sentence
+func endsSentence(raw string) bool {
+	raw = strings.TrimRight(raw, `"'’”)]`)
+	return strings.HasSuffix(raw, ".") || strings.HasSuffix(raw, "!") || strings.HasSuffix(raw, "?") ||
+		strings.HasSuffix(raw, ":")
+}
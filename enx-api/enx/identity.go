@@ -0,0 +1,142 @@
+package enx
+
+import (
+	"enx-api/utils/logger"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailTaken   = errors.New("email already belongs to another user")
+	ErrUserDisabled = errors.New("user is disabled")
+)
+
+// ExternalIdentity is a user as asserted by an external identity provider
+type ExternalIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+// UserIdentity links an identity provider subject to a user
+type UserIdentity struct {
+	Issuer    string `gorm:"column:issuer;primaryKey"`
+	Subject   string `gorm:"column:subject;primaryKey"`
+	UserID    string `gorm:"column:user_id"`
+	Email     string `gorm:"column:email"`
+	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// LoginWithIdentity returns the user linked to the identity, creating and linking one on first login.
+// With linkByEmail an unlinked identity with a verified email is linked to the existing user with that email.
+func LoginWithIdentity(identity ExternalIdentity, linkByEmail bool) (*User, error) {
+	if identity.Issuer == "" || identity.Subject == "" {
+		return nil, errors.New("identity without issuer or subject")
+	}
+
+	var user *User
+	err := sqlitex.DB.Transaction(func(tx *gorm.DB) error {
+		link := UserIdentity{}
+		tx.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).Find(&link)
+		if link.UserID != "" {
+			user = &User{}
+			return tx.Where("id = ?", link.UserID).First(user).Error
+		}
+
+		existing := User{}
+		if identity.Email != "" {
+			tx.Where("email = ?", identity.Email).Find(&existing)
+		}
+		switch {
+		case existing.Id != "" && linkByEmail && identity.EmailVerified:
+			user = &existing
+			logger.Infof("linking identity to existing user by email, user: %s, subject: %s", existing.Name, identity.Subject)
+		case existing.Id != "":
+			return ErrEmailTaken
+		default:
+			created, err := createIdentityUser(tx, identity)
+			if err != nil {
+				return err
+			}
+			user = created
+			logger.Infof("user created on first identity login, user: %s, subject: %s", user.Name, identity.Subject)
+		}
+
+		return tx.Create(&UserIdentity{
+			Issuer:    identity.Issuer,
+			Subject:   identity.Subject,
+			UserID:    user.Id,
+			Email:     identity.Email,
+			CreatedAt: time.Now().UnixMilli(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	if err := sqlitex.DB.Model(&User{}).Where("id = ?", user.Id).Update("last_login_time", time.Now()).Error; err != nil {
+		logger.Errorf("failed to update last login time, user: %s, error: %v", user.Name, err)
+	}
+	return user, nil
+}
+
+// createIdentityUser creates a user without a password; it can set one through a password reset.
+// Only a verified email is kept, password reset mails go to it.
+func createIdentityUser(tx *gorm.DB, identity ExternalIdentity) (*User, error) {
+	base := identity.PreferredUsername
+	if base == "" && identity.Email != "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	// Names are unique, add a suffix until one is free
+	name := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(&User{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+
+	now := time.Now()
+	user := &User{
+		Id:         uuid.New().String(),
+		Name:       name,
+		Role:       RoleUser,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	if identity.EmailVerified {
+		user.Email = identity.Email
+	}
+	query := tx
+	if user.Email == "" {
+		// Emails are unique, leave it NULL rather than an empty string shared by every such user
+		query = tx.Omit("email")
+	}
+	if err := query.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,141 @@
+package enx
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"enx-api/utils/logger"
+	"enx-api/utils/mail"
+	"enx-api/utils/password"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrWeakPassword      = fmt.Errorf("password must be at least %d characters", password.MinLength)
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+// PasswordReset is a single-use password reset token, stored as a sha256 of the token
+type PasswordReset struct {
+	TokenHash string `gorm:"column:token_hash;primaryKey"`
+	UserID    string `gorm:"column:user_id"`
+	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
+	ExpiresAt int64  `gorm:"column:expires_at"` // Unix milliseconds
+	UsedAt    int64  `gorm:"column:used_at"`    // Unix milliseconds
+}
+
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
+
+// ChangePassword sets a new password after checking the current one
+func ChangePassword(userID, currentPassword, newPassword string) error {
+	if len(newPassword) < password.MinLength {
+		return ErrWeakPassword
+	}
+	user := GetUserByID(userID)
+	if user.Id == "" {
+		return ErrWrongPassword
+	}
+	match, err := password.VerifyPassword(currentPassword, user.Password)
+	if err != nil || !match {
+		return ErrWrongPassword
+	}
+	return setPassword(userID, newPassword)
+}
+
+// RequestPasswordReset mails a reset link to the user with the given name or email.
+// Unknown users are ignored without an error, so the response doesn't reveal which accounts exist.
+func RequestPasswordReset(nameOrEmail string) error {
+	user := User{}
+	sqlitex.DB.Where("name = ? OR email = ?", nameOrEmail, nameOrEmail).Find(&user)
+	if user.Id == "" || user.Email == "" {
+		logger.Infof("password reset requested for unknown user or user without email: %s", nameOrEmail)
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(b)
+
+	now := time.Now()
+	reset := &PasswordReset{
+		TokenHash: hashResetToken(token),
+		UserID:    user.Id,
+		CreatedAt: now.UnixMilli(),
+		ExpiresAt: now.Add(viper.GetDuration("password.reset-ttl")).UnixMilli(),
+	}
+	if err := sqlitex.DB.Create(reset).Error; err != nil {
+		return err
+	}
+
+	link := viper.GetString("password.reset-url") + "?token=" + token
+	err := mail.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your enx password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to choose a new password:\n\n%s\n\n"+
+			"The link expires in %s. If you didn't ask for a reset, ignore this mail.\n",
+			user.Name, link, viper.GetDuration("password.reset-ttl")),
+	})
+	if err != nil {
+		return err
+	}
+	logger.Infof("password reset mail sent, user: %s", user.Name)
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and returns the user id.
+// The token can only be used once; the user's other pending tokens are invalidated too.
+func ResetPassword(token, newPassword string) (string, error) {
+	if len(newPassword) < password.MinLength {
+		return "", ErrWeakPassword
+	}
+
+	now := time.Now().UnixMilli()
+	reset := PasswordReset{}
+	sqlitex.DB.Where("token_hash = ? AND used_at = 0 AND expires_at > ?", hashResetToken(token), now).Find(&reset)
+	if reset.UserID == "" {
+		return "", ErrInvalidResetToken
+	}
+
+	// Claim the token, a concurrent request with the same token updates no row
+	result := sqlitex.DB.Model(&PasswordReset{}).Where("token_hash = ? AND used_at = 0", reset.TokenHash).Update("used_at", now)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrInvalidResetToken
+	}
+	if err := sqlitex.DB.Model(&PasswordReset{}).Where("user_id = ? AND used_at = 0", reset.UserID).Update("used_at", now).Error; err != nil {
+		logger.Errorf("failed to invalidate reset tokens, user id: %s, error: %v", reset.UserID, err)
+	}
+
+	if err := setPassword(reset.UserID, newPassword); err != nil {
+		return "", err
+	}
+	return reset.UserID, nil
+}
+
+func setPassword(userID, newPassword string) error {
+	hash, err := password.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return sqlitex.DB.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":   hash,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
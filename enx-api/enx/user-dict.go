@@ -3,6 +3,7 @@ package enx
 import (
 	"enx-api/repo"
 	"enx-api/utils/logger"
+	"errors"
 )
 
 type UserDict struct {
@@ -12,6 +13,10 @@ type UserDict struct {
 	QueryCount int    `json:"query_count"`
 	// 0: false, 1: true
 	AlreadyAcquainted int `json:"already_acquainted"`
+	// personal annotations, kept by the count updates below
+	Note        string `json:"note"`
+	Tags        string `json:"tags"`
+	Translation string `json:"translation"`
 }
 
 // UpdateQueryCount updates the query count and acquainted status in database
@@ -37,7 +42,7 @@ func (ud *UserDict) Save() {
 }
 
 // Mark toggles the already_acquainted flag in database
-func (ud *UserDict) Mark() {
+func (ud *UserDict) Mark() error {
 	logger.Infof("Mark: Starting mark operation for word_id: %s, user_id: %s", ud.WordId, ud.UserId)
 
 	if ud.IsExist() {
@@ -59,26 +64,32 @@ func (ud *UserDict) Mark() {
 	err := repo.UpsertUserDict(ud.UserId, ud.WordId, ud.QueryCount, ud.AlreadyAcquainted)
 	if err != nil {
 		logger.Errorf("Mark: failed to mark user dict: %v", err)
-		return
+		return err
 	}
 	logger.Infof("Mark: Final AlreadyAcquainted state: %d", ud.AlreadyAcquainted)
+	return nil
 }
 
 // IsExist checks if user dict record exists in database
 func (ud *UserDict) IsExist() bool {
-	queryCount, alreadyAcquainted := repo.GetUserWordQueryCount(ud.WordId, ud.UserId)
-
-	// If both are 0, record might not exist (or both fields are actually 0)
-	// Repository returns 0,0 for non-existent records
-	if queryCount == 0 && alreadyAcquainted == 0 {
+	record, err := repo.GetUserDict(ud.UserId, ud.WordId)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		// a later upsert reads the record again and fails too instead of resetting it
+		logger.Errorf("failed to get user dict, word_id: %s, user_id: %s, error: %v", ud.WordId, ud.UserId, err)
+		return false
+	}
+	if err != nil {
 		logger.Debugf("user dict record not found, word_id: %s, user_id: %s",
 			ud.WordId, ud.UserId)
 		return false
 	}
 
 	// Update the struct with fetched values
-	ud.QueryCount = queryCount
-	ud.AlreadyAcquainted = alreadyAcquainted
+	ud.QueryCount = record.QueryCount
+	ud.AlreadyAcquainted = record.AlreadyAcquainted
+	ud.Note = record.Note
+	ud.Tags = record.Tags
+	ud.Translation = record.Translation
 	logger.Debugf("user dict record found, word_id: %s, user_id: %s, query_count: %d, acquainted: %d",
 		ud.WordId, ud.UserId, ud.QueryCount, ud.AlreadyAcquainted)
 	return true
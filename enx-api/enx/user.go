@@ -17,6 +17,18 @@ type User struct {
 	CreateTime    time.Time `json:"create_time" gorm:"column:created_at"`
 	UpdateTime    time.Time `json:"update_time" gorm:"column:updated_at"`
 	LastLoginTime time.Time `json:"last_login_time" gorm:"column:last_login_time"`
+	Role          string    `json:"role" gorm:"column:role;default:user"`
+	Disabled      bool      `json:"disabled" gorm:"column:disabled;default:false"`
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user may use the admin API
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin && !u.Disabled
 }
 
 func (u *User) Login() bool {
@@ -27,6 +39,11 @@ func (u *User) Login() bool {
 		return false
 	}
 
+	if tmpUser.Disabled {
+		logger.Errorf("login failed: user is disabled, username: %s", u.Name)
+		return false
+	}
+
 	// Verify password
 	match, err := password.VerifyPassword(u.Password, tmpUser.Password)
 	if err != nil {
@@ -38,12 +55,28 @@ func (u *User) Login() bool {
 		return false
 	}
 
+	// Upgrade hashes created with older Argon2 parameters while the plaintext is at hand
+	if password.NeedsRehash(tmpUser.Password) {
+		if err := setPassword(tmpUser.Id, u.Password); err != nil {
+			logger.Errorf("failed to rehash password for user: %s, error: %v", u.Name, err)
+		} else {
+			logger.Infof("password rehashed with current parameters, user: %s", u.Name)
+		}
+	}
+
 	u.Id = tmpUser.Id
 	u.LastLoginTime = tmpUser.LastLoginTime
 	logger.Infof("user login, user: %s, login success, user: %+v", u.Name, tmpUser)
 	return true
 }
 
+// GetUserByID returns the user, with an empty Id if it doesn't exist
+func GetUserByID(id string) *User {
+	user := User{}
+	sqlitex.DB.Where("id=?", id).Find(&user)
+	return &user
+}
+
 func GeUserByName(name string) *User {
 	user := User{}
 	sqlitex.DB.Where("name=?", name).Find(&user)
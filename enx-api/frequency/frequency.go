@@ -0,0 +1,118 @@
+package frequency
+
+import (
+	"bufio"
+	_ "embed"
+	"enx-api/utils/logger"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+//go:embed wordlist.txt
+var bundled string
+
+// List ranks words by frequency, 1 is the most frequent word
+type List struct {
+	ranks map[string]int
+}
+
+var (
+	mu      sync.RWMutex
+	current = mustParse(strings.NewReader(bundled))
+)
+
+// Parse reads a word list, one word per line in frequency order. Lines starting with #
+// are comments, anything after the first tab or space is ignored and repeated words keep their first rank.
+func Parse(r io.Reader) (*List, error) {
+	list := &List{ranks: map[string]int{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word := strings.ToLower(strings.Fields(line)[0])
+		if _, ok := list.ranks[word]; !ok {
+			list.ranks[word] = len(list.ranks) + 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(list.ranks) == 0 {
+		return nil, fmt.Errorf("word list is empty")
+	}
+	return list, nil
+}
+
+func mustParse(r io.Reader) *List {
+	list, err := Parse(r)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// Rank returns the rank of a word, case-insensitive, or 0 if it isn't in the list
+func (l *List) Rank(word string) int {
+	return l.ranks[strings.ToLower(strings.TrimSpace(word))]
+}
+
+// Words returns the ranked words, most frequent first
+func (l *List) Words() []string {
+	words := make([]string, len(l.ranks))
+	for word, rank := range l.ranks {
+		words[rank-1] = word
+	}
+	return words
+}
+
+// Size is the number of ranked words
+func (l *List) Size() int {
+	return len(l.ranks)
+}
+
+// Init loads the word list in frequency.file, the bundled list is used when it isn't set
+func Init() error {
+	path := viper.GetString("frequency.file")
+	if path == "" {
+		logger.Infof("using bundled word frequency list, words: %d", Current().Size())
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open word frequency list %s: %w", path, err)
+	}
+	defer f.Close()
+	list, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("failed to read word frequency list %s: %w", path, err)
+	}
+	SetList(list)
+	logger.Infof("loaded word frequency list %s, words: %d", path, list.Size())
+	return nil
+}
+
+// SetList replaces the word list, e.g. with a test double
+func SetList(list *List) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = list
+}
+
+// Current returns the word list in use
+func Current() *List {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Rank returns the rank of a word in the list in use, or 0 if it isn't ranked
+func Rank(word string) int {
+	return Current().Rank(word)
+}
@@ -0,0 +1,40 @@
+package frequency
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	list, err := Parse(strings.NewReader("# rank word list\n\nThe 23135851162\nof\t13151942776\n  and\nthe\n"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if list.Size() != 3 {
+		t.Errorf("size: %d", list.Size())
+	}
+	tests := map[string]int{"the": 1, "THE": 1, "of": 2, " and ": 3, "harbor": 0, "#": 0}
+	for word, want := range tests {
+		if got := list.Rank(word); got != want {
+			t.Errorf("rank of %q: %d, want %d", word, got, want)
+		}
+	}
+
+	if words := list.Words(); strings.Join(words, " ") != "the of and" {
+		t.Errorf("words: %v", words)
+	}
+
+	if _, err := Parse(strings.NewReader("# only comments\n\n")); err == nil {
+		t.Error("empty list parsed")
+	}
+}
+
+func TestBundledList(t *testing.T) {
+	list := Current()
+	if list.Size() < 2000 {
+		t.Errorf("bundled list size: %d", list.Size())
+	}
+	if list.Rank("the") != 1 {
+		t.Errorf("rank of the: %d", list.Rank("the"))
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"enx-api/cache"
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// AdminUpdateUserRequest changes role or disabled state, omitted fields are left as they are
+type AdminUpdateUserRequest struct {
+	Role     *string `json:"role"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// AdminUpdateWordRequest edits a word, omitted fields are left as they are
+type AdminUpdateWordRequest struct {
+	English       *string `json:"english"`
+	Chinese       *string `json:"chinese"`
+	Pronunciation *string `json:"pronunciation"`
+}
+
+// AdminMergeWordRequest merges the word in the path into another word
+type AdminMergeWordRequest struct {
+	Into string `json:"into" binding:"required"`
+}
+
+// pagination reads page (from 1) and page_size query parameters
+func pagination(c *gin.Context) (page, pageSize int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		pageSize = defaultPageSize
+	}
+	return page, pageSize
+}
+
+// AdminListUsers lists users, optionally filtered by q on name or email
+func AdminListUsers(c *gin.Context) {
+	page, pageSize := pagination(c)
+	users, total, err := enx.SearchUsers(c.Query("q"), (page-1)*pageSize, pageSize)
+	if err != nil {
+		logger.Errorf("admin failed to list users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list users"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": users, "total": total, "page": page, "page_size": pageSize})
+}
+
+// AdminUpdateUser sets the role of a user or disables the account.
+// Disabling logs the user out everywhere and revokes their API tokens.
+func AdminUpdateUser(c *gin.Context) {
+	var req AdminUpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	userID := c.Param("id")
+	user := enx.GetUserByID(userID)
+	if user.Id == "" {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "User not found"})
+		return
+	}
+	if userID == middleware.GetUserIDFromContext(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Admins can't change their own account"})
+		return
+	}
+
+	if req.Role != nil {
+		if err := enx.SetUserRole(userID, *req.Role); err != nil {
+			if errors.Is(err, enx.ErrInvalidRole) {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid role, allowed: user, admin"})
+				return
+			}
+			logger.Errorf("admin failed to set role, user id: %s, error: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to update user"})
+			return
+		}
+	}
+	if req.Disabled != nil {
+		if err := enx.SetUserDisabled(userID, *req.Disabled); err != nil {
+			logger.Errorf("admin failed to disable user, user id: %s, error: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to update user"})
+			return
+		}
+		if *req.Disabled {
+			if err := middleware.DeleteUserSessions(userID, ""); err != nil {
+				logger.Errorf("failed to delete sessions of disabled user, user id: %s, error: %v", userID, err)
+			}
+			if err := middleware.DeleteUserAPITokens(userID); err != nil {
+				logger.Errorf("failed to revoke api tokens of disabled user, user id: %s, error: %v", userID, err)
+			}
+		}
+	}
+
+	logger.Infof("admin updated user, admin: %s, user: %s, request: %+v", middleware.GetUserIDFromContext(c), user.Name, req)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": enx.GetUserByID(userID)})
+}
+
+// AdminListWords lists words, optionally filtered by q on english or chinese
+func AdminListWords(c *gin.Context) {
+	page, pageSize := pagination(c)
+	includeDeleted := c.Query("include_deleted") == "true"
+	words, total, err := repo.SearchWords(c.Query("q"), includeDeleted, (page-1)*pageSize, pageSize)
+	if err != nil {
+		logger.Errorf("admin failed to list words: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list words"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": words, "total": total, "page": page, "page_size": pageSize})
+}
+
+// AdminUpdateWord fixes the english, chinese or pronunciation of a word
+func AdminUpdateWord(c *gin.Context) {
+	var req AdminUpdateWordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	word, ok := adminFindWord(c)
+	if !ok {
+		return
+	}
+	if req.English != nil {
+		if *req.English == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "English can't be empty"})
+			return
+		}
+		if existing := repo.GetWord(word.SourceLanguage, *req.English); existing.Id != "" && existing.Id != word.Id {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Word already exists, merge it instead", "id": existing.Id})
+			return
+		}
+		word.English = *req.English
+	}
+	if req.Chinese != nil {
+		word.Chinese = *req.Chinese
+	}
+	if req.Pronunciation != nil {
+		word.Pronunciation = *req.Pronunciation
+	}
+
+	if err := repo.UpdateWord(word); err != nil {
+		logger.Errorf("admin failed to update word, id: %s, error: %v", word.Id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to update word"})
+		return
+	}
+	logger.Infof("admin updated word, admin: %s, word: %+v", middleware.GetUserIDFromContext(c), word)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": word})
+}
+
+// AdminDeleteWord soft-deletes a word
+func AdminDeleteWord(c *gin.Context) {
+	word, ok := adminFindWord(c)
+	if !ok {
+		return
+	}
+	if err := repo.DeleteWord(word.Id); err != nil {
+		logger.Errorf("admin failed to delete word, id: %s, error: %v", word.Id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete word"})
+		return
+	}
+	logger.Infof("admin deleted word, admin: %s, word: %s", middleware.GetUserIDFromContext(c), word.English)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Word deleted"})
+}
+
+// AdminMergeWord moves all lookups of a word onto another and deletes it
+func AdminMergeWord(c *gin.Context) {
+	var req AdminMergeWordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	err := repo.MergeWords(c.Param("id"), req.Into)
+	switch {
+	case errors.Is(err, repo.ErrMergeSameWord):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Word not found"})
+		return
+	case err != nil:
+		logger.Errorf("admin failed to merge words, source: %s, target: %s, error: %v", c.Param("id"), req.Into, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to merge words"})
+		return
+	}
+	logger.Infof("admin merged words, admin: %s, source: %s, target: %s", middleware.GetUserIDFromContext(c), c.Param("id"), req.Into)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Words merged"})
+}
+
+// AdminStats returns lookup statistics, top sets how many of the most looked up words to include
+func AdminStats(c *gin.Context) {
+	top, err := strconv.Atoi(c.DefaultQuery("top", "20"))
+	if err != nil || top < 1 || top > maxPageSize {
+		top = 20
+	}
+	stats, err := repo.GetLookupStats(top)
+	if err != nil {
+		logger.Errorf("admin failed to get lookup stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to get stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": stats})
+}
+
+// AdminPurgeTranslationCache drops the cached upstream responses and the stored entry of a word,
+// so the next lookup asks the provider again. provider limits the responses to one provider, e.g. youdao-api.
+func AdminPurgeTranslationCache(c *gin.Context) {
+	word := strings.TrimSpace(c.Param("word"))
+	if word == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Word is required"})
+		return
+	}
+	deleted, err := cache.Purge(c.Query("provider"), word)
+	if err == nil {
+		// the stored structured entry was parsed from the purged responses
+		err = repo.DeleteDictionaryEntry(word)
+	}
+	if err != nil {
+		logger.Errorf("admin failed to purge translation cache, word: %s, error: %v", word, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to purge translation cache"})
+		return
+	}
+	logger.Infof("admin purged translation cache, admin: %s, word: %s, provider: %s, deleted: %d",
+		middleware.GetUserIDFromContext(c), word, c.Query("provider"), deleted)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"deleted": deleted}})
+}
+
+func adminFindWord(c *gin.Context) (*repo.Word, bool) {
+	word, err := repo.GetWordByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Word not found"})
+		return nil, false
+	}
+	return word, true
+}
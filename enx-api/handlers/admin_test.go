@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"enx-api/cache"
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupAdminTest(t *testing.T) (*gin.Engine, *enx.User, *enx.User) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	admin := &enx.User{Name: "admin", Email: "admin@example.com", Role: enx.RoleAdmin}
+	user := &enx.User{Name: "alice", Email: "alice@example.com"}
+	for _, u := range []*enx.User{admin, user} {
+		if err := u.Create(); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/admin", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) }, middleware.RequireAdmin())
+	group.GET("/users", AdminListUsers)
+	group.PATCH("/users/:id", AdminUpdateUser)
+	group.PUT("/words/:id", AdminUpdateWord)
+	group.DELETE("/words/:id", AdminDeleteWord)
+	group.POST("/words/:id/merge", AdminMergeWord)
+	group.GET("/stats", AdminStats)
+	group.DELETE("/translation-cache/:word", AdminPurgeTranslationCache)
+	return router, admin, user
+}
+
+func adminRequest(router *gin.Engine, userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createTestWord(t *testing.T, english, chinese string) *repo.Word {
+	word := &repo.Word{English: english, Chinese: chinese}
+	if err := repo.CreateWord(word); err != nil {
+		t.Fatalf("create word: %v", err)
+	}
+	return word
+}
+
+func TestAdminRequiresAdminRole(t *testing.T) {
+	router, admin, user := setupAdminTest(t)
+
+	if w := adminRequest(router, user.Id, http.MethodGet, "/api/admin/users", nil); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin, status: %d", w.Code)
+	}
+	w := adminRequest(router, admin.Id, http.MethodGet, "/api/admin/users?q=ali", nil)
+	var resp struct {
+		Data  []enx.User `json:"data"`
+		Total int64      `json:"total"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Total != 1 || resp.Data[0].Name != "alice" {
+		t.Errorf("search users, status: %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	router, admin, user := setupAdminTest(t)
+	session, _ := middleware.CreateSession(user.Id, "", "")
+	_, _, _ = middleware.CreateAPIToken(user.Id, "script", []string{middleware.ScopeLookup}, 0)
+
+	if w := adminRequest(router, admin.Id, http.MethodPatch, "/api/admin/users/"+user.Id, AdminUpdateUserRequest{Disabled: boolPtr(true)}); w.Code != http.StatusOK {
+		t.Fatalf("disable user, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	if !enx.GetUserByID(user.Id).Disabled {
+		t.Errorf("user not disabled")
+	}
+	var sessions, tokens int64
+	sqlitex.DB.Model(&middleware.Session{}).Where("id = ?", session.ID).Count(&sessions)
+	sqlitex.DB.Model(&middleware.APIToken{}).Where("user_id = ?", user.Id).Count(&tokens)
+	if sessions != 0 || tokens != 0 {
+		t.Errorf("disabled user keeps sessions: %d, tokens: %d", sessions, tokens)
+	}
+
+	if w := adminRequest(router, admin.Id, http.MethodPatch, "/api/admin/users/"+admin.Id, AdminUpdateUserRequest{Disabled: boolPtr(true)}); w.Code != http.StatusBadRequest {
+		t.Errorf("admin disabled itself, status: %d", w.Code)
+	}
+}
+
+func TestAdminCurateWords(t *testing.T) {
+	router, admin, user := setupAdminTest(t)
+	garbage := createTestWord(t, "morning.", "")
+	morning := createTestWord(t, "morning", "早上")
+	_ = repo.UpsertUserDict(user.Id, garbage.Id, 2, 0)
+	_ = repo.UpsertUserDict(user.Id, morning.Id, 3, 1)
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: user.Id, WordId: garbage.Id, Kind: repo.EventLookup})
+
+	// Fix a gloss
+	w := adminRequest(router, admin.Id, http.MethodPut, "/api/admin/words/"+morning.Id, AdminUpdateWordRequest{Chinese: strPtr("早晨")})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update word, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	updated, _ := repo.GetWordByID(morning.Id)
+	if updated.Chinese != "早晨" || updated.UpdatedAt < morning.UpdatedAt {
+		t.Errorf("word not updated: %+v", updated)
+	}
+
+	// Renaming onto an existing word must be a merge
+	w = adminRequest(router, admin.Id, http.MethodPut, "/api/admin/words/"+garbage.Id, AdminUpdateWordRequest{English: strPtr("Morning")})
+	if w.Code != http.StatusConflict {
+		t.Errorf("rename to existing word, status: %d", w.Code)
+	}
+
+	w = adminRequest(router, admin.Id, http.MethodPost, "/api/admin/words/"+garbage.Id+"/merge", AdminMergeWordRequest{Into: morning.Id})
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge words, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	queryCount, acquainted, _ := repo.GetUserWordQueryCount(morning.Id, user.Id)
+	if queryCount != 5 || acquainted != 0 {
+		t.Errorf("merged user dict, query count: %d, acquainted: %d", queryCount, acquainted)
+	}
+	merged, _ := repo.GetWordByID(garbage.Id)
+	if merged.DeletedAt == nil || merged.UpdatedAt != *merged.DeletedAt {
+		t.Errorf("merged word not soft-deleted for sync: %+v", merged)
+	}
+	var wordIds []string
+	sqlitex.DB.Model(&repo.LookupEvent{}).Where("user_id = ?", user.Id).Pluck("word_id", &wordIds)
+	if len(wordIds) != 1 || wordIds[0] != morning.Id {
+		t.Errorf("lookup history not moved: %v", wordIds)
+	}
+
+	w = adminRequest(router, admin.Id, http.MethodGet, "/api/admin/stats", nil)
+	var stats struct {
+		Data repo.LookupStats `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	// the merged lookups are counted once
+	if stats.Data.Words != 1 || stats.Data.DeletedWords != 1 || stats.Data.UserDicts != 1 || stats.Data.Lookups != 5 ||
+		len(stats.Data.TopWords) != 1 || stats.Data.TopWords[0].QueryCount != 5 {
+		t.Errorf("unexpected stats: %s", w.Body.String())
+	}
+
+	if w := adminRequest(router, admin.Id, http.MethodDelete, "/api/admin/words/"+morning.Id, nil); w.Code != http.StatusOK {
+		t.Errorf("delete word, status: %d", w.Code)
+	}
+	if found := repo.GetWordByEnglish("morning"); found.Id != "" {
+		t.Errorf("deleted word still found")
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestAdminPurgeTranslationCache(t *testing.T) {
+	router, admin, user := setupAdminTest(t)
+	calls := 0
+	load := func() (string, bool, error) {
+		calls++
+		return "港口", true, nil
+	}
+	cache.Get("youdao-api", "harbor", load)
+	cache.Get("youdao-web", "harbor", load)
+
+	if w := adminRequest(router, user.Id, http.MethodDelete, "/api/admin/translation-cache/harbor", nil); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin, status: %d", w.Code)
+	}
+	w := adminRequest(router, admin.Id, http.MethodDelete, "/api/admin/translation-cache/Harbor?provider=youdao-web", nil)
+	var resp struct {
+		Data struct {
+			Deleted int `json:"deleted"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Data.Deleted != 1 {
+		t.Errorf("purge, status: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	cache.Get("youdao-api", "harbor", load)
+	cache.Get("youdao-web", "harbor", load)
+	if calls != 3 {
+		t.Errorf("upstream calls: %d", calls)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"enx-api/audio"
+	"enx-api/translate"
+	"enx-api/upstream"
+	"enx-api/utils/logger"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// audioMaxAge is how long clients may keep a clip, audio of a word doesn't change
+const audioMaxAge = "604800"
+
+// GetAudio serves the pronunciation audio of a word.
+//
+// Query parameters: accent, us (default) or uk. Responses carry the sha256 of the audio as ETag,
+// so clients revalidate with If-None-Match; Range requests are supported.
+func GetAudio(c *gin.Context) {
+	word := strings.TrimSpace(c.Param("word"))
+	if !entryWordPattern.MatchString(word) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid word"})
+		return
+	}
+	accent := c.DefaultQuery("accent", audio.AccentUS)
+	if accent != audio.AccentUS && accent != audio.AccentUK {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid accent, allowed: us, uk"})
+		return
+	}
+	service := audio.Current()
+	if service == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "message": "Audio is not available"})
+		return
+	}
+
+	clip, file, err := openAudio(c, service, word, accent)
+	var upstreamErr *upstream.Error
+	switch {
+	case errors.Is(err, audio.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "No audio for the word"})
+		return
+	case errors.Is(err, audio.ErrTooLarge):
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "Audio of the word is too large"})
+		return
+	case errors.As(err, &upstreamErr):
+		translate.RespondUpstreamError(c, err)
+		return
+	case err != nil:
+		logger.Errorf("failed to get audio, word: %s, accent: %s, error: %v", word, accent, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to get audio"})
+		return
+	}
+
+	defer file.Close()
+	c.Header("Content-Type", clip.ContentType)
+	c.Header("ETag", `"`+clip.Hash+`"`)
+	c.Header("Cache-Control", "public, max-age="+audioMaxAge)
+	http.ServeContent(c.Writer, c.Request, clip.Word, clip.CreatedAt, file)
+}
+
+// openAudio gets the clip of a word and opens its blob. A clip evicted in between is got once more,
+// which stores it again.
+func openAudio(c *gin.Context, service *audio.Service, word, accent string) (*audio.Clip, *os.File, error) {
+	for attempt := 0; ; attempt++ {
+		clip, err := service.Get(c.Request.Context(), word, accent)
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := os.Open(clip.Path)
+		if errors.Is(err, fs.ErrNotExist) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open %s: %w", clip.Path, err)
+		}
+		return clip, file, nil
+	}
+}
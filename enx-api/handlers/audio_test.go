@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"enx-api/audio"
+	"enx-api/upstream"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubAudioProvider struct {
+	err error
+}
+
+func (stubAudioProvider) Name() string {
+	return "stub"
+}
+
+func (p stubAudioProvider) Fetch(ctx context.Context, word, accent string) ([]byte, string, error) {
+	if p.err != nil {
+		return nil, "", p.err
+	}
+	if word != "harbor" {
+		return nil, "", audio.ErrNotFound
+	}
+	return []byte("harbor-" + accent), "audio/mpeg", nil
+}
+
+func setupAudioTest(t *testing.T, provider audio.Provider) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	previous := audio.Current()
+	audio.SetService(audio.NewService(audio.Config{Dir: t.TempDir()}, provider, nil))
+	t.Cleanup(func() { audio.SetService(previous) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/audio/:word", GetAudio)
+	return router
+}
+
+func TestGetAudio(t *testing.T) {
+	router := setupAudioTest(t, stubAudioProvider{})
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/audio/Harbor?accent=uk", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "harbor-uk" {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Type") != "audio/mpeg" || etag == "" || w.Header().Get("Cache-Control") == "" {
+		t.Errorf("headers: %v", w.Header())
+	}
+	if w := adminRequest(router, "user-1", http.MethodGet, "/api/audio/harbor", nil); w.Body.String() != "harbor-us" {
+		t.Errorf("default accent: %s", w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/audio/harbor?accent=uk", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("revalidation, status: %d", w.Code)
+	}
+
+	for path, status := range map[string]int{
+		"/api/audio/harbor?accent=au": http.StatusBadRequest,
+		"/api/audio/har%3Cbor":        http.StatusBadRequest,
+		"/api/audio/qwzx":             http.StatusNotFound,
+	} {
+		if w := adminRequest(router, "user-1", http.MethodGet, path, nil); w.Code != status {
+			t.Errorf("%s, status: %d, want: %d", path, w.Code, status)
+		}
+	}
+}
+
+func TestGetAudioUpstreamError(t *testing.T) {
+	router := setupAudioTest(t, stubAudioProvider{err: &upstream.Error{Provider: "stub", StatusCode: http.StatusInternalServerError}})
+	if w := adminRequest(router, "user-1", http.MethodGet, "/api/audio/harbor", nil); w.Code != http.StatusBadGateway {
+		t.Errorf("status: %d", w.Code)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"enx-api/repo"
+	"enx-api/translate"
+	"enx-api/utils/logger"
+	"enx-api/youdao"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// entryWordPattern is a word or short phrase an entry can be looked up for
+var entryWordPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z\-'’ ]{0,63}$`)
+
+// fetchEntry looks an entry up upstream, replaced in tests
+var fetchEntry = youdao.QueryEntry
+
+// GetEntry returns the structured dictionary entry of a word: senses grouped by part of speech,
+// UK and US phonetics, example sentences and web phrases. Entries are fetched from the provider
+// once and stored on this node.
+func GetEntry(c *gin.Context) {
+	word := strings.TrimSpace(c.Param("word"))
+	if !entryWordPattern.MatchString(word) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid word"})
+		return
+	}
+
+	entry, err := repo.GetDictionaryEntry(word)
+	if err != nil {
+		logger.Errorf("failed to get dictionary entry, word: %s, error: %v", word, err)
+	}
+	if entry == nil {
+		entry, err = fetchEntry(c.Request.Context(), word)
+		if err != nil {
+			translate.RespondUpstreamError(c, err)
+			return
+		}
+		if entry.Empty() {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Word not found"})
+			return
+		}
+		if err := repo.SaveDictionaryEntry(entry); err != nil {
+			logger.Errorf("failed to save dictionary entry, word: %s, error: %v", word, err)
+		}
+	}
+	entry.Word = word
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": entry})
+}
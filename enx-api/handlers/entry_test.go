@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"enx-api/dictionary"
+	"enx-api/upstream"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupEntryTest(t *testing.T, fetch func(ctx context.Context, word string) (*dictionary.Entry, error)) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	previous := fetchEntry
+	fetchEntry = fetch
+	t.Cleanup(func() { fetchEntry = previous })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/entry/:word", GetEntry)
+	return router
+}
+
+func TestGetEntry(t *testing.T) {
+	calls := 0
+	router := setupEntryTest(t, func(ctx context.Context, word string) (*dictionary.Entry, error) {
+		calls++
+		entry := dictionary.New(word, "test")
+		if word == "harbor" || word == "Harbor" {
+			entry.UKPhonetic, entry.USPhonetic = "ˈhɑːbə", "ˈhɑːrbər"
+			entry.Senses = []dictionary.Sense{{PartOfSpeech: "n.", Meanings: []string{"港口"}}}
+			entry.Examples = []dictionary.Example{{English: "The ship left the harbor.", Chinese: "船离开了港口。"}}
+		}
+		return entry, nil
+	})
+
+	var resp struct {
+		Data dictionary.Entry `json:"data"`
+	}
+	for _, word := range []string{"harbor", "Harbor"} {
+		w := adminRequest(router, "user-1", http.MethodGet, "/api/entry/"+word, nil)
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Data.Word != word || resp.Data.USPhonetic != "ˈhɑːrbər" ||
+			len(resp.Data.Senses) != 1 || len(resp.Data.Examples) != 1 || resp.Data.WebPhrases == nil {
+			t.Errorf("%s, status: %d, body: %s", word, w.Code, w.Body.String())
+		}
+	}
+	// the second lookup is served from the stored entry
+	if calls != 1 {
+		t.Errorf("fetches: %d", calls)
+	}
+
+	if w := adminRequest(router, "user-1", http.MethodGet, "/api/entry/qwzx", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown word, status: %d", w.Code)
+	}
+	if w := adminRequest(router, "user-1", http.MethodGet, "/api/entry/4ever", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid word, status: %d", w.Code)
+	}
+}
+
+func TestGetEntryUpstreamFailure(t *testing.T) {
+	router := setupEntryTest(t, func(ctx context.Context, word string) (*dictionary.Entry, error) {
+		return nil, &upstream.Error{Provider: "test", Err: upstream.ErrCircuitOpen}
+	})
+	if w := adminRequest(router, "user-1", http.MethodGet, "/api/entry/harbor", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status: %d, body: %s", w.Code, w.Body.String())
+	}
+}
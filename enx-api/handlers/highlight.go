@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxMinQueryCount = 1000
+
+// HighlightPolicy decides which words /paragraph-init highlights for the current user
+type HighlightPolicy struct {
+	// MaxRank hides words ranked beyond it in the word frequency list, 0: no limit
+	MaxRank int `json:"max_rank"`
+	// AlwaysTags are tags whose words are highlighted whatever the other rules say
+	AlwaysTags      []string `json:"always_tags"`
+	HideProperNouns bool     `json:"hide_proper_nouns"`
+	// MinQueryCount hides words looked up fewer times, 0 is taken as 1
+	MinQueryCount int   `json:"min_query_count"`
+	UpdatedAt     int64 `json:"updated_at"` // 0 while the default policy is in use
+}
+
+func newHighlightPolicy(policy *repo.HighlightPolicy) HighlightPolicy {
+	return HighlightPolicy{
+		MaxRank:         policy.MaxRank,
+		AlwaysTags:      repo.SplitTags(policy.AlwaysTags),
+		HideProperNouns: policy.HideProperNouns == 1,
+		MinQueryCount:   policy.MinQueryCount,
+		UpdatedAt:       policy.UpdatedAt,
+	}
+}
+
+// GetHighlightPolicy returns the current user's highlight policy
+func GetHighlightPolicy(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	policy, err := repo.GetHighlightPolicy(userID)
+	if err != nil {
+		logger.Errorf("failed to get highlight policy, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to get highlight policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newHighlightPolicy(policy)})
+}
+
+// SaveHighlightPolicy replaces the current user's highlight policy
+func SaveHighlightPolicy(c *gin.Context) {
+	var req HighlightPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+	if req.MaxRank < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid max_rank, use 0 for no limit"})
+		return
+	}
+	if req.MinQueryCount == 0 {
+		req.MinQueryCount = 1
+	}
+	if req.MinQueryCount < 1 || req.MinQueryCount > maxMinQueryCount {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid min_query_count, allowed: 1-" + strconv.Itoa(maxMinQueryCount)})
+		return
+	}
+	if len(req.AlwaysTags) > maxTags {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Too many tags, max " + strconv.Itoa(maxTags)})
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	policy := &repo.HighlightPolicy{
+		UserId:        userID,
+		MaxRank:       req.MaxRank,
+		AlwaysTags:    repo.NormalizeTags(req.AlwaysTags),
+		MinQueryCount: req.MinQueryCount,
+	}
+	if req.HideProperNouns {
+		policy.HideProperNouns = 1
+	}
+	if err := repo.SaveHighlightPolicy(policy); err != nil {
+		logger.Errorf("failed to save highlight policy, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save highlight policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newHighlightPolicy(policy)})
+}
+
+// DeleteHighlightPolicy goes back to the default policy
+func DeleteHighlightPolicy(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	if err := repo.DeleteHighlightPolicy(userID); err != nil {
+		logger.Errorf("failed to delete highlight policy, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to reset highlight policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newHighlightPolicy(repo.DefaultHighlightPolicy(userID))})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/repo"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHighlightPolicy(t *testing.T) {
+	router := setupVocabularyTest(t, "the of and harbor island ocean")
+	group := router.Group("/api", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	group.GET("/my/highlight-policy", GetHighlightPolicy)
+	group.PUT("/my/highlight-policy", SaveHighlightPolicy)
+	group.DELETE("/my/highlight-policy", DeleteHighlightPolicy)
+
+	for _, w := range []struct {
+		english    string
+		count      int
+		acquainted int
+		tags       []string
+	}{
+		{"harbor", 2, 0, nil},
+		{"island", 1, 0, nil},
+		{"ocean", 3, 1, nil},
+		{"quixotic", 1, 0, []string{"IELTS"}},
+		{"Paris", 1, 0, nil},
+	} {
+		word := createTestWord(t, w.english, "")
+		if err := repo.UpsertUserDict("user-1", word.Id, w.count, w.acquainted); err != nil {
+			t.Fatalf("upsert user dict: %v", err)
+		}
+		if w.tags != nil {
+			if _, err := repo.SaveUserWordNote("user-1", word.Id, "", w.tags, ""); err != nil {
+				t.Fatalf("save note: %v", err)
+			}
+		}
+	}
+	paragraph := "The harbor and Paris. Island ocean quixotic 42"
+
+	type decision struct {
+		highlight bool
+		reason    string
+	}
+	check := func(name string, want map[string]decision) {
+		t.Helper()
+		words := enx.QueryCountInText(paragraph, "user-1", "")
+		for raw, d := range want {
+			if got := words[raw]; got.Highlight != d.highlight || got.HighlightReason != d.reason {
+				t.Errorf("%s, %s: highlight %v (%s), want %v (%s)", name, raw, got.Highlight, got.HighlightReason, d.highlight, d.reason)
+			}
+		}
+	}
+
+	// the default policy highlights what was looked up and isn't acquainted
+	check("default", map[string]decision{
+		"The":      {false, enx.HighlightQueryCount},
+		"harbor":   {true, enx.HighlightLearning},
+		"Paris":    {true, enx.HighlightLearning},
+		"Island":   {true, enx.HighlightLearning},
+		"ocean":    {false, enx.HighlightAcquainted},
+		"quixotic": {true, enx.HighlightLearning},
+		"42":       {false, enx.HighlightRaw},
+	})
+
+	body := gin.H{"max_rank": 4, "always_tags": []string{"ielts"}, "hide_proper_nouns": true, "min_query_count": 2}
+	if w := adminRequest(router, "user-1", http.MethodPut, "/api/my/highlight-policy", body); w.Code != http.StatusOK {
+		t.Fatalf("save policy, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	check("custom", map[string]decision{
+		"harbor":   {true, enx.HighlightLearning},
+		"Paris":    {false, enx.HighlightProperNoun},
+		"Island":   {false, enx.HighlightRank}, // capitalized at the start of a sentence only
+		"ocean":    {false, enx.HighlightAcquainted},
+		"quixotic": {true, enx.HighlightTag},
+	})
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/my/highlight-policy", nil)
+	var resp struct {
+		Data HighlightPolicy `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.MaxRank != 4 || len(resp.Data.AlwaysTags) != 1 || !resp.Data.HideProperNouns || resp.Data.UpdatedAt == 0 {
+		t.Errorf("get policy: %s", w.Body.String())
+	}
+
+	// policies are per user
+	if words := enx.QueryCountInText(paragraph, "user-2", ""); words["Paris"].HighlightReason != enx.HighlightQueryCount {
+		t.Errorf("another user: %+v", words["Paris"])
+	}
+
+	if w := adminRequest(router, "user-1", http.MethodDelete, "/api/my/highlight-policy", nil); w.Code != http.StatusOK {
+		t.Fatalf("reset policy, status: %d", w.Code)
+	}
+	check("reset", map[string]decision{"Paris": {true, enx.HighlightLearning}})
+
+	for _, body := range []gin.H{{"max_rank": -1}, {"min_query_count": 1001}, {"always_tags": "ielts"}} {
+		if w := adminRequest(router, "user-1", http.MethodPut, "/api/my/highlight-policy", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListHistory pages through the current user's lookups and acquainted marks, newest first.
+//
+// Query parameters: word, kind (lookup, mark, unmark), source (extension, ui, api), page_host,
+// from and to (date or RFC 3339), cursor and limit.
+func ListHistory(c *gin.Context) {
+	q := repo.HistoryQuery{
+		English:  c.Query("word"),
+		Kind:     c.Query("kind"),
+		Source:   c.Query("source"),
+		PageHost: c.Query("page_host"),
+		Cursor:   c.Query("cursor"),
+		Limit:    defaultPageSize,
+	}
+
+	switch q.Kind {
+	case "", repo.EventLookup, repo.EventMark, repo.EventUnmark:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid kind, allowed: lookup, mark, unmark"})
+		return
+	}
+	switch q.Source {
+	case "", middleware.SourceExtension, middleware.SourceUI, middleware.SourceAPI:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid source, allowed: extension, ui, api"})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid limit, allowed: 1-" + strconv.Itoa(maxPageSize)})
+			return
+		}
+		q.Limit = limit
+	}
+	if !bindDateRange(c, &q.From, &q.To) {
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	entries, next, err := repo.ListHistory(userID, q)
+	if errors.Is(err, repo.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to list history, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": entries, "next_cursor": next})
+}
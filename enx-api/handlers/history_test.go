@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/repo"
+	"enx-api/translate"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type historyResponse struct {
+	Data       []repo.HistoryEntry `json:"data"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+func setupHistoryTest(t *testing.T) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	group.GET("/word/:word", translate.TranslateByWord)
+	group.GET("/history", ListHistory)
+	return router
+}
+
+func getHistory(t *testing.T, router *gin.Engine, params url.Values) historyResponse {
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/history?"+params.Encode(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp historyResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestHistoryRecordsLookups(t *testing.T) {
+	router := setupHistoryTest(t)
+	createTestWord(t, "harbor", "港口")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/word/harbor", nil)
+	req.Header.Set("X-Test-User", "user-1")
+	req.Header.Set("Origin", "chrome-extension://enx")
+	req.Header.Set("X-Page-Host", "News.Example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("translate, status: %d", w.Code)
+	}
+
+	resp := getHistory(t, router, url.Values{})
+	if len(resp.Data) != 1 {
+		t.Fatalf("history: %+v", resp.Data)
+	}
+	entry := resp.Data[0]
+	if entry.English != "harbor" || entry.Chinese != "港口" || entry.Kind != repo.EventLookup ||
+		entry.Source != "extension" || entry.PageHost != "news.example.com" || entry.CreatedAt == 0 {
+		t.Errorf("entry: %+v", entry)
+	}
+
+	// lookups of another user stay private
+	w = adminRequest(router, "user-2", http.MethodGet, "/api/history", nil)
+	var other historyResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &other)
+	if len(other.Data) != 0 {
+		t.Errorf("another user: %s", w.Body.String())
+	}
+}
+
+func TestHistoryFiltersAndPaging(t *testing.T) {
+	router := setupHistoryTest(t)
+	harbor := createTestWord(t, "harbor", "港口")
+	island := createTestWord(t, "island", "岛")
+
+	now := time.Now()
+	for i, e := range []struct {
+		word    *repo.Word
+		kind    string
+		source  string
+		daysAgo int
+	}{
+		{harbor, repo.EventLookup, "extension", 0},
+		{island, repo.EventLookup, "ui", 0},
+		{harbor, repo.EventMark, "ui", 1},
+		{harbor, repo.EventLookup, "api", 5},
+	} {
+		repo.RecordLookupEvent(&repo.LookupEvent{
+			UserId: "user-1", WordId: e.word.Id, Kind: e.kind, Source: e.source,
+			CreatedAt: now.AddDate(0, 0, -e.daysAgo).Add(-time.Duration(i) * time.Second).UnixMilli(),
+		})
+	}
+
+	// events with the same timestamp are paged by id without gaps
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: "user-1", WordId: island.Id, Kind: repo.EventUnmark, CreatedAt: now.UnixMilli()})
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: "user-1", WordId: island.Id, Kind: repo.EventMark, CreatedAt: now.UnixMilli()})
+
+	params := url.Values{"limit": {"2"}}
+	var kinds []string
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("too many pages")
+		}
+		resp := getHistory(t, router, params)
+		for _, entry := range resp.Data {
+			kinds = append(kinds, entry.English+"/"+entry.Kind)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		params.Set("cursor", resp.NextCursor)
+	}
+	if len(kinds) != 6 || kinds[5] != "harbor/lookup" {
+		t.Errorf("paged history: %v", kinds)
+	}
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   int
+	}{
+		{"word", url.Values{"word": {"Harbor"}}, 3},
+		{"kind", url.Values{"kind": {"mark"}}, 2},
+		{"source", url.Values{"source": {"ui"}}, 2},
+		{"from", url.Values{"from": {now.AddDate(0, 0, -2).Format(time.DateOnly)}, "kind": {"lookup"}}, 2},
+	}
+	for _, tt := range tests {
+		if got := getHistory(t, router, tt.params); len(got.Data) != tt.want {
+			t.Errorf("%s: %+v", tt.name, got.Data)
+		}
+	}
+
+	for _, query := range []string{"kind=delete", "source=cli", "limit=0", "cursor=garbage", "to=tomorrow"} {
+		if w := adminRequest(router, "user-1", http.MethodGet, "/api/history?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", query, w.Code)
+		}
+	}
+}
+
+func TestPruneLookupEvents(t *testing.T) {
+	setupHistoryTest(t)
+	word := createTestWord(t, "harbor", "港口")
+	old := time.Now().AddDate(0, 0, -40).UnixMilli()
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: "user-1", WordId: word.Id, Kind: repo.EventLookup, CreatedAt: old})
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: "user-1", WordId: word.Id, Kind: repo.EventLookup})
+
+	deleted, err := repo.PruneLookupEvents(30 * 24 * time.Hour)
+	if err != nil || deleted != 1 {
+		t.Errorf("prune, deleted: %d, error: %v", deleted, err)
+	}
+	entries, _, _ := repo.ListHistory("user-1", repo.HistoryQuery{})
+	if len(entries) != 1 || entries[0].CreatedAt == old {
+		t.Errorf("after prune: %+v", entries)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"enx-api/language"
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserLanguages are the languages of the current user
+type UserLanguages struct {
+	// NativeLanguage is the language lookups are translated into
+	NativeLanguage string `json:"native_language"`
+	// StudyLanguages are the languages the user reads, the first one is the language of
+	// lookups that don't give theirs. Left as they are when missing from a request.
+	StudyLanguages []string `json:"study_languages"`
+	UpdatedAt      int64    `json:"updated_at"` // 0 while the defaults are in use
+}
+
+func newUserLanguages(languages *repo.UserLanguages) UserLanguages {
+	return UserLanguages{NativeLanguage: languages.NativeLanguage, StudyLanguages: languages.Studies(), UpdatedAt: languages.UpdatedAt}
+}
+
+// GetUserLanguages returns the current user's languages along with the supported source and target languages
+func GetUserLanguages(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	languages, err := repo.GetUserLanguages(userID)
+	if err != nil {
+		logger.Errorf("failed to get user languages, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to get languages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    newUserLanguages(languages),
+		"sources": language.Sources(),
+		"targets": language.Targets(),
+	})
+}
+
+// SaveUserLanguages replaces the current user's languages, their study languages are kept when
+// the request has none
+func SaveUserLanguages(c *gin.Context) {
+	var req UserLanguages
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+	native, ok := language.ParseTarget(req.NativeLanguage)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Unsupported native_language: " + req.NativeLanguage})
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	current, err := repo.GetUserLanguages(userID)
+	if err != nil {
+		logger.Errorf("failed to get user languages, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save languages"})
+		return
+	}
+	studies := current.Studies()
+	if req.StudyLanguages != nil {
+		if studies, ok = parseStudyLanguages(req.StudyLanguages); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "study_languages needs one or more supported languages"})
+			return
+		}
+	}
+
+	languages := &repo.UserLanguages{UserId: userID, NativeLanguage: native, StudyLanguages: strings.Join(studies, ",")}
+	if err := repo.SaveUserLanguages(languages); err != nil {
+		logger.Errorf("failed to save user languages, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save languages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newUserLanguages(languages)})
+}
+
+// parseStudyLanguages returns the codes of source languages given in any case without duplicates,
+// false when one isn't supported or there are none
+func parseStudyLanguages(codes []string) ([]string, bool) {
+	var studies []string
+	for _, code := range codes {
+		source, ok := language.ParseSource(code)
+		if !ok {
+			return nil, false
+		}
+		if !slices.Contains(studies, source) {
+			studies = append(studies, source)
+		}
+	}
+	return studies, len(studies) > 0
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/language"
+	"enx-api/repo"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserLanguages(t *testing.T) {
+	router := setupVocabularyTest(t, "the harbor")
+	group := router.Group("/api", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	group.GET("/my/languages", GetUserLanguages)
+	group.PUT("/my/languages", SaveUserLanguages)
+
+	var resp struct {
+		Data    UserLanguages   `json:"data"`
+		Sources []language.Info `json:"sources"`
+		Targets []language.Info `json:"targets"`
+	}
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/my/languages", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Data.NativeLanguage != language.Chinese || resp.Data.UpdatedAt != 0 ||
+		!slices.Equal(resp.Data.StudyLanguages, []string{language.English}) || len(resp.Sources) == 0 || len(resp.Targets) == 0 {
+		t.Fatalf("default, status: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "user-1", http.MethodPut, "/api/my/languages", gin.H{"native_language": "JA"})
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Data.NativeLanguage != language.Japanese || resp.Data.UpdatedAt == 0 {
+		t.Fatalf("save, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	if got := repo.GetNativeLanguage("user-1"); got != language.Japanese {
+		t.Errorf("stored: %s", got)
+	}
+
+	w = adminRequest(router, "user-1", http.MethodPut, "/api/my/languages", gin.H{"native_language": "ja", "study_languages": []string{"de-AT", "EN", "de"}})
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || !slices.Equal(resp.Data.StudyLanguages, []string{language.German, language.English}) {
+		t.Fatalf("save study languages, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	// kept when a request has none, e.g. from clients predating study languages
+	adminRequest(router, "user-1", http.MethodPut, "/api/my/languages", gin.H{"native_language": "ko"})
+	if got := repo.GetStudyLanguages("user-1"); !slices.Equal(got, []string{language.German, language.English}) {
+		t.Errorf("stored study languages: %v", got)
+	}
+	if got := repo.GetSourceLanguage("user-1", ""); got != language.German {
+		t.Errorf("default source language: %s", got)
+	}
+	for _, body := range []gin.H{{"native_language": "xx"}, {"native_language": ""},
+		{"native_language": "ja", "study_languages": []string{}}, {"native_language": "ja", "study_languages": []string{"en", "xx"}}} {
+		if w := adminRequest(router, "user-1", http.MethodPut, "/api/my/languages", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v, status: %d", body, w.Code)
+		}
+	}
+}
+
+func TestGlossesInNativeLanguage(t *testing.T) {
+	setupVocabularyTest(t, "the harbor")
+	harbor := &repo.Word{English: "harbor", Chinese: "港口"}
+	harbor.SetGloss(language.Japanese, "港")
+	if err := repo.CreateWord(harbor); err != nil {
+		t.Fatal(err)
+	}
+	ship := createTestWord(t, "ship", "船")
+	if err := repo.UpsertUserDict("user-ja", ship.Id, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SaveUserWordNote("user-ja", ship.Id, "", nil, "ふね"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUserLanguages(&repo.UserLanguages{UserId: "user-ja", NativeLanguage: language.Japanese}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := repo.GetWordByEnglish("harbor")
+	if stored.Gloss(language.Japanese) != "港" || stored.Gloss(language.Chinese) != "港口" || stored.Gloss(language.Spanish) != "" {
+		t.Errorf("stored glosses: %s", stored.Glosses)
+	}
+
+	words := enx.QueryCountInText("the harbor ship", "user-ja", "")
+	if got := words["harbor"]; got.Language != language.Japanese || got.Gloss != "港" {
+		t.Errorf("harbor: %+v", got)
+	}
+	// the personal translation is in the user's language, it doesn't replace the Chinese one
+	if got := words["ship"]; got.Gloss != "ふね" || got.Chinese != "" {
+		t.Errorf("ship: %+v", got)
+	}
+	if got := enx.QueryCountInText("harbor", "user-zh", "")["harbor"]; got.Language != language.Chinese || got.Gloss != "港口" {
+		t.Errorf("default language: %+v", got)
+	}
+}
+
+func TestWordsOfSourceLanguages(t *testing.T) {
+	setupVocabularyTest(t, "the die harbor")
+	create := func(lang, text, chinese string) *repo.Word {
+		word := &repo.Word{SourceLanguage: lang, English: text, Chinese: chinese}
+		if err := repo.CreateWord(word); err != nil {
+			t.Fatalf("%s %s: %v", lang, text, err)
+		}
+		return word
+	}
+	dieEN := create(language.English, "die", "死")
+	dieDE := create(language.German, "die", "这个")
+	strasse := create(language.German, "Straße", "街道")
+	ueber := create(language.German, "über", "在……上面")
+	irmak := create(language.Turkish, "Irmak", "河")
+	if dieEN.FrequencyRank == 0 || dieDE.FrequencyRank != 0 {
+		t.Errorf("ranks, en: %d, de: %d", dieEN.FrequencyRank, dieDE.FrequencyRank)
+	}
+
+	for _, test := range []struct {
+		lang, text string
+		want       *repo.Word
+	}{
+		{language.English, "Die", dieEN},
+		{language.German, "DIE", dieDE},
+		{language.German, "STRAßE", strasse},
+		// a decomposed umlaut, U with a combining diaeresis, is the same word
+		{language.German, "U\u0308ber", ueber},
+		{language.Turkish, "IRMAK", irmak},
+		{language.French, "die", &repo.Word{}},
+	} {
+		if got := repo.GetWord(test.lang, test.text); got.Id != test.want.Id {
+			t.Errorf("%s %q: %q, want: %q", test.lang, test.text, got.English, test.want.English)
+		}
+	}
+
+	if err := repo.SaveUserLanguages(&repo.UserLanguages{UserId: "user-de", NativeLanguage: language.Chinese, StudyLanguages: "de,en"}); err != nil {
+		t.Fatal(err)
+	}
+	// the paragraph is in German, the first language the user studies, unless the page says otherwise
+	words := enx.QueryCountInText("Die Straße ist über 3 km lang.", "user-de", "")
+	if got := words["Die"]; got.Id != dieDE.Id || got.SourceLanguage != language.German || got.Key != "die" {
+		t.Errorf("Die: %+v", got)
+	}
+	if got := words["Straße"]; got.Id != strasse.Id || got.Gloss != "街道" {
+		t.Errorf("Straße: %+v", got)
+	}
+	if got := words["über"]; got.Id != ueber.Id {
+		t.Errorf("über: %+v", got)
+	}
+	if got := enx.QueryCountInText("Die", "user-de", "en-GB")["Die"]; got.Id != dieEN.Id {
+		t.Errorf("Die in English: %+v", got)
+	}
+	// letters of any script survive, digits and punctuation don't
+	if got := enx.QueryCountInText("garçon!", "user-de", "fr")["garçon"]; got.English != "garçon" || got.SourceLanguage != language.French {
+		t.Errorf("garçon: %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseDate reads a YYYY-MM-DD or RFC 3339 time as Unix milliseconds.
+// A bare date ending a range covers that whole day.
+func parseDate(value string, endOfDay bool) (int64, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UnixMilli(), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Millisecond)
+	}
+	return t.UnixMilli(), nil
+}
+
+// bindDateRange reads the from and to query parameters as Unix milliseconds,
+// it responds with 400 and returns false when either is malformed
+func bindDateRange(c *gin.Context, from, to *int64) bool {
+	for _, bound := range []struct {
+		name     string
+		target   *int64
+		endOfDay bool
+	}{{"from", from, false}, {"to", to, true}} {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		ms, err := parseDate(value, bound.endOfDay)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid " + bound.name + ", use YYYY-MM-DD or RFC 3339"})
+			return false
+		}
+		*bound.target = ms
+	}
+	return true
+}
+
+// ListMyWords pages through the current user's words.
+//
+// Query parameters: sort (recent, count, alpha), acquainted (true, false), tag,
+// min_count, from and to (date or RFC 3339, on the last lookup), prefix, cursor and limit.
+func ListMyWords(c *gin.Context) {
+	q := repo.MyWordsQuery{
+		Sort:   c.DefaultQuery("sort", repo.SortRecent),
+		Tag:    c.Query("tag"),
+		Prefix: c.Query("prefix"),
+		Cursor: c.Query("cursor"),
+		Limit:  defaultPageSize,
+	}
+
+	if value := c.Query("acquainted"); value != "" {
+		acquainted, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid acquainted, allowed: true, false"})
+			return
+		}
+		q.Acquainted = &acquainted
+	}
+	if value := c.Query("min_count"); value != "" {
+		minCount, err := strconv.Atoi(value)
+		if err != nil || minCount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid min_count"})
+			return
+		}
+		q.MinCount = minCount
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid limit, allowed: 1-" + strconv.Itoa(maxPageSize)})
+			return
+		}
+		q.Limit = limit
+	}
+	if !bindDateRange(c, &q.From, &q.To) {
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	words, next, err := repo.ListMyWords(userID, q)
+	if errors.Is(err, repo.ErrInvalidSort) || errors.Is(err, repo.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to list words, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list words"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": words, "next_cursor": next})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupMyWordsTest(t *testing.T) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	fixtures := []struct {
+		english    string
+		count      int
+		acquainted int
+		daysAgo    int
+	}{
+		{"apple", 5, 0, 0},
+		{"apricot", 1, 1, 1},
+		{"banana", 5, 0, 2},
+		{"cherry", 9, 0, 3},
+		{"date", 2, 1, 10},
+		{"a_b", 1, 0, 4},
+	}
+	now := time.Now()
+	for _, f := range fixtures {
+		word := createTestWord(t, f.english, "")
+		if err := repo.UpsertUserDict("user-1", word.Id, f.count, f.acquainted); err != nil {
+			t.Fatalf("upsert user dict: %v", err)
+		}
+		if f.english == "banana" {
+			if _, err := repo.SaveUserWordNote("user-1", word.Id, "", []string{"yellow"}, ""); err != nil {
+				t.Fatalf("save note: %v", err)
+			}
+		}
+		updatedAt := now.AddDate(0, 0, -f.daysAgo).UnixMilli()
+		sqlitex.DB.Model(&repo.UserDict{}).Where("user_id = ? AND word_id = ?", "user-1", word.Id).Update("updated_at", updatedAt)
+	}
+	// another user's words never show up
+	if err := repo.UpsertUserDict("user-2", repo.GetWordByEnglish("banana").Id, 100, 0); err != nil {
+		t.Fatalf("upsert user dict: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/my/words", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) }, ListMyWords)
+	return router
+}
+
+type myWordsResponse struct {
+	Data       []repo.UserWord `json:"data"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// listAll follows next_cursor until the last page and returns the english of every word
+func listAll(t *testing.T, router *gin.Engine, params url.Values) []string {
+	var english []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("too many pages")
+		}
+		w := adminRequest(router, "user-1", http.MethodGet, "/api/my/words?"+params.Encode(), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list words, status: %d, body: %s", w.Code, w.Body.String())
+		}
+		var resp myWordsResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		for _, word := range resp.Data {
+			english = append(english, word.English)
+		}
+		if resp.NextCursor == "" {
+			return english
+		}
+		params.Set("cursor", resp.NextCursor)
+	}
+}
+
+func TestListMyWordsSortAndPaging(t *testing.T) {
+	router := setupMyWordsTest(t)
+
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"recent", "apple,apricot,banana,cherry,a_b,date"},
+		{"alpha", "a_b,apple,apricot,banana,cherry,date"},
+	}
+	for _, tt := range tests {
+		got := listAll(t, router, url.Values{"sort": {tt.sort}, "limit": {"2"}})
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("sort %s: %v", tt.sort, got)
+		}
+	}
+
+	// ties on query count are still paged without gaps or repeats
+	got := listAll(t, router, url.Values{"sort": {"count"}, "limit": {"1"}})
+	if len(got) != 6 || got[0] != "cherry" || (got[5] != "a_b" && got[5] != "apricot") {
+		t.Errorf("sort count: %v", got)
+	}
+	seen := map[string]bool{}
+	for _, english := range got {
+		if seen[english] {
+			t.Errorf("sort count repeated %s: %v", english, got)
+		}
+		seen[english] = true
+	}
+}
+
+func TestListMyWordsFilters(t *testing.T) {
+	router := setupMyWordsTest(t)
+	today := time.Now().Format(time.DateOnly)
+	twoDaysAgo := time.Now().AddDate(0, 0, -2).Format(time.DateOnly)
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   string
+	}{
+		{"acquainted", url.Values{"acquainted": {"true"}, "sort": {"alpha"}}, "apricot,date"},
+		{"tag", url.Values{"tag": {"Yellow"}}, "banana"},
+		{"min count", url.Values{"min_count": {"5"}, "sort": {"alpha"}}, "apple,banana,cherry"},
+		{"date range", url.Values{"from": {twoDaysAgo}, "to": {today}, "sort": {"alpha"}}, "apple,apricot,banana"},
+		{"prefix", url.Values{"prefix": {"AP"}, "sort": {"alpha"}}, "apple,apricot"},
+		{"prefix wildcard is literal", url.Values{"prefix": {"a_"}}, "a_b"},
+	}
+	for _, tt := range tests {
+		got := listAll(t, router, tt.params)
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: %v", tt.name, got)
+		}
+	}
+}
+
+func TestListMyWordsInvalidParams(t *testing.T) {
+	router := setupMyWordsTest(t)
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/my/words?sort=alpha&limit=1", nil)
+	var resp myWordsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	for _, query := range []string{
+		"sort=random",
+		"acquainted=maybe",
+		"min_count=-1",
+		"limit=1000",
+		"from=yesterday",
+		"cursor=garbage",
+		// a cursor only continues the sort it was made for
+		"sort=recent&cursor=" + resp.NextCursor,
+	} {
+		if w := adminRequest(router, "user-1", http.MethodGet, "/api/my/words?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxNoteLength        = 2000
+	maxTranslationLength = 200
+	maxTags              = 20
+)
+
+// SaveWordNoteRequest replaces the user's note, tags and translation of a word
+type SaveWordNoteRequest struct {
+	Note        string   `json:"note"`
+	Tags        []string `json:"tags"`
+	Translation string   `json:"translation"`
+}
+
+// WordNote is the user's annotation of a word next to the shared gloss
+type WordNote struct {
+	WordId      string   `json:"word_id"`
+	English     string   `json:"english"`
+	Chinese     string   `json:"chinese"` // shared translation
+	Note        string   `json:"note"`
+	Tags        []string `json:"tags"`
+	Translation string   `json:"translation"`
+}
+
+// noteWord finds the word in the path, in the language given by from, see repo.GetSourceLanguage.
+// It has to be in the dictionary already.
+func noteWord(c *gin.Context) (*repo.Word, bool) {
+	from := repo.GetSourceLanguage(middleware.GetUserIDFromContext(c), c.Query("from"))
+	word := repo.GetWord(from, strings.TrimSpace(c.Param("word")))
+	if word.Id == "" {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Word not found, look it up first"})
+		return nil, false
+	}
+	return word, true
+}
+
+func newWordNote(word *repo.Word, userDict *repo.UserDict) WordNote {
+	note := WordNote{WordId: word.Id, English: word.English, Chinese: word.Chinese, Tags: []string{}}
+	if userDict != nil {
+		note.Note = userDict.Note
+		note.Tags = repo.SplitTags(userDict.Tags)
+		note.Translation = userDict.Translation
+	}
+	return note
+}
+
+// GetWordNote returns the current user's note, tags and translation of a word
+func GetWordNote(c *gin.Context) {
+	word, ok := noteWord(c)
+	if !ok {
+		return
+	}
+	// no record yet means no annotation
+	userDict, _ := repo.GetUserDict(middleware.GetUserIDFromContext(c), word.Id)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newWordNote(word, userDict)})
+}
+
+// SaveWordNote sets the current user's note, tags and translation of a word
+func SaveWordNote(c *gin.Context) {
+	var req SaveWordNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+	if utf8.RuneCountInString(req.Note) > maxNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Note is too long, max " + strconv.Itoa(maxNoteLength) + " characters"})
+		return
+	}
+	if utf8.RuneCountInString(req.Translation) > maxTranslationLength {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Translation is too long, max " + strconv.Itoa(maxTranslationLength) + " characters"})
+		return
+	}
+	if len(req.Tags) > maxTags {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Too many tags, max " + strconv.Itoa(maxTags)})
+		return
+	}
+
+	word, ok := noteWord(c)
+	if !ok {
+		return
+	}
+	userID := middleware.GetUserIDFromContext(c)
+	userDict, err := repo.SaveUserWordNote(userID, word.Id, req.Note, req.Tags, req.Translation)
+	if err != nil {
+		logger.Errorf("failed to save word note, user id: %s, word: %s, error: %v", userID, word.English, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save note"})
+		return
+	}
+	logger.Infof("saved word note, user id: %s, word: %s", userID, word.English)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": newWordNote(word, userDict)})
+}
+
+// DeleteWordNote clears the current user's note, tags and translation of a word,
+// query count and acquainted state are kept
+func DeleteWordNote(c *gin.Context) {
+	word, ok := noteWord(c)
+	if !ok {
+		return
+	}
+	userID := middleware.GetUserIDFromContext(c)
+	if _, err := repo.GetUserDict(userID, word.Id); err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "Note deleted"})
+		return
+	}
+	if _, err := repo.SaveUserWordNote(userID, word.Id, "", nil, ""); err != nil {
+		logger.Errorf("failed to delete word note, user id: %s, word: %s, error: %v", userID, word.English, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete note"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Note deleted"})
+}
+
+// ExportWords exports the current user's words with their notes as json or csv (format=csv).
+// tag keeps only words carrying that tag, annotated=true only words with a note, tag or translation.
+func ExportWords(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	words, err := repo.ListUserWords(userID, repo.UserWordFilter{
+		Tag:       c.Query("tag"),
+		Annotated: c.Query("annotated") == "true",
+	})
+	if err != nil {
+		logger.Errorf("failed to export words, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to export words"})
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": words, "total": len(words)})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="enx-words.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"english", "chinese", "translation", "pronunciation", "note", "tags", "query_count", "already_acquainted"})
+	for _, word := range words {
+		_ = w.Write([]string{
+			word.English,
+			word.Chinese,
+			word.Translation,
+			word.Pronunciation,
+			word.Note,
+			strings.Join(word.Tags, ","),
+			strconv.Itoa(word.QueryCount),
+			strconv.Itoa(word.AlreadyAcquainted),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		logger.Errorf("failed to write csv export, user id: %s, error: %v", userID, err)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupNoteTest(t *testing.T) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	group.GET("/word/:word/note", GetWordNote)
+	group.PUT("/word/:word/note", SaveWordNote)
+	group.DELETE("/word/:word/note", DeleteWordNote)
+	group.GET("/export", ExportWords)
+	return router
+}
+
+func TestWordNote(t *testing.T) {
+	router := setupNoteTest(t)
+	word := createTestWord(t, "bank", "银行")
+	if err := repo.UpsertUserDict("user-1", word.Id, 2, 0); err != nil {
+		t.Fatalf("upsert user dict: %v", err)
+	}
+
+	if w := adminRequest(router, "user-1", http.MethodPut, "/api/word/unknown/note", SaveWordNoteRequest{Note: "x"}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown word, status: %d", w.Code)
+	}
+	long := SaveWordNoteRequest{Note: strings.Repeat("长", maxNoteLength+1)}
+	if w := adminRequest(router, "user-1", http.MethodPut, "/api/word/bank/note", long); w.Code != http.StatusBadRequest {
+		t.Errorf("long note, status: %d", w.Code)
+	}
+
+	req := SaveWordNoteRequest{Note: "river side", Tags: []string{"Geography", "geography ", ""}, Translation: "河岸"}
+	if w := adminRequest(router, "user-1", http.MethodPut, "/api/word/bank/note", req); w.Code != http.StatusOK {
+		t.Fatalf("save note, status: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/word/bank/note", nil)
+	var resp struct {
+		Data WordNote `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.Note != "river side" || resp.Data.Translation != "河岸" || resp.Data.Chinese != "银行" ||
+		len(resp.Data.Tags) != 1 || resp.Data.Tags[0] != "geography" {
+		t.Errorf("get note: %s", w.Body.String())
+	}
+
+	// another user still sees the shared gloss
+	w = adminRequest(router, "user-2", http.MethodGet, "/api/word/bank/note", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.Note != "" || resp.Data.Translation != "" {
+		t.Errorf("note leaked to another user: %s", w.Body.String())
+	}
+
+	// lookups return the personal translation and keep counting
+	translated := enx.Word{Id: word.Id, Chinese: "银行"}
+	translated.FindQueryCount("user-1")
+	if translated.Chinese != "河岸" || translated.Note != "river side" || translated.LoadCount != 2 {
+		t.Errorf("translate with note: %+v", translated)
+	}
+	counted := enx.QueryCountInText("the bank", "user-1", "")["bank"]
+	if counted.Chinese != "河岸" || len(counted.Tags) != 1 {
+		t.Errorf("paragraph with note: %+v", counted)
+	}
+	other := enx.QueryCountInText("the bank", "user-2", "")["bank"]
+	if other.Chinese != "" || other.Note != "" {
+		t.Errorf("paragraph of another user: %+v", other)
+	}
+
+	if w := adminRequest(router, "user-1", http.MethodDelete, "/api/word/bank/note", nil); w.Code != http.StatusOK {
+		t.Fatalf("delete note, status: %d", w.Code)
+	}
+	userDict, err := repo.GetUserDict("user-1", word.Id)
+	if err != nil || userDict.Note != "" || userDict.Translation != "" || userDict.QueryCount != 2 {
+		t.Errorf("after delete: %+v, error: %v", userDict, err)
+	}
+}
+
+func TestExportWords(t *testing.T) {
+	router := setupNoteTest(t)
+	bank := createTestWord(t, "bank", "银行")
+	river := createTestWord(t, "river", "河")
+	lake := createTestWord(t, "lake", "湖")
+	if err := repo.UpsertUserDict("user-1", lake.Id, 1, 1); err != nil {
+		t.Fatalf("upsert user dict: %v", err)
+	}
+	for _, id := range []string{bank.Id, river.Id} {
+		if _, err := repo.SaveUserWordNote("user-1", id, "", []string{"geo"}, ""); err != nil {
+			t.Fatalf("save note: %v", err)
+		}
+	}
+	if _, err := repo.SaveUserWordNote("user-1", bank.Id, "money", []string{"geo", "finance"}, "河岸"); err != nil {
+		t.Fatalf("save note: %v", err)
+	}
+	if _, err := repo.SaveUserWordNote("user-2", lake.Id, "", []string{"geo"}, ""); err != nil {
+		t.Fatalf("save note: %v", err)
+	}
+
+	var resp struct {
+		Data  []repo.UserWord `json:"data"`
+		Total int             `json:"total"`
+	}
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/export", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 3 {
+		t.Errorf("export all: %s", w.Body.String())
+	}
+
+	w = adminRequest(router, "user-1", http.MethodGet, "/api/export?tag=geo", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 2 || resp.Data[0].English != "bank" || resp.Data[1].English != "river" {
+		t.Errorf("export by tag: %s", w.Body.String())
+	}
+
+	w = adminRequest(router, "user-1", http.MethodGet, "/api/export?tag=fin", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 0 {
+		t.Errorf("tag prefix matched: %s", w.Body.String())
+	}
+
+	w = adminRequest(router, "user-1", http.MethodGet, "/api/export?annotated=true&format=csv", nil)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("content type: %s", w.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(records) != 3 || records[1][0] != "bank" || records[1][2] != "河岸" || records[1][5] != "geo,finance" {
+		t.Errorf("csv export: %v", records)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/utils/logger"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePasswordRequest change password request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ResetPasswordRequest request a password reset mail
+type ResetPasswordRequest struct {
+	Username string `json:"username" binding:"required"` // username or email
+}
+
+// ConfirmResetPasswordRequest set a new password with a reset token
+type ConfirmResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword changes the current user's password, logs out their other sessions and revokes
+// their API tokens. Attempts are throttled like logins, keyed on the user's name.
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	username := enx.GetUserByID(userID).Name
+	// Throttle before verifying the password, Argon2 costs 64MB per attempt
+	if decision := middleware.CheckLogin(c.ClientIP(), username); !decision.Allowed {
+		tooManyPasswordAttempts(c, decision.RetryAfter)
+		return
+	}
+
+	err := enx.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, enx.ErrWrongPassword):
+		if unlockAt := middleware.LoginFailed(username); !unlockAt.IsZero() {
+			tooManyPasswordAttempts(c, time.Until(unlockAt))
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Current password is incorrect"})
+		return
+	case errors.Is(err, enx.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("failed to change password, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to change password"})
+		return
+	}
+
+	if err := middleware.DeleteUserSessions(userID, middleware.GetSessionIDFromContext(c)); err != nil {
+		logger.Errorf("failed to delete other sessions after password change, user id: %s, error: %v", userID, err)
+	}
+	if err := middleware.DeleteUserAPITokens(userID); err != nil {
+		logger.Errorf("failed to delete api tokens after password change, user id: %s, error: %v", userID, err)
+	}
+	middleware.LoginSucceeded(username)
+	logger.Infof("password changed, user id: %s", userID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Password changed"})
+}
+
+// RequestPasswordReset mails a reset link; it always succeeds so accounts can't be enumerated
+func RequestPasswordReset(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	if decision := middleware.CheckPasswordReset(c.ClientIP(), req.Username); !decision.Allowed {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": "Too many reset requests, please try again later"})
+		return
+	}
+
+	if err := enx.RequestPasswordReset(req.Username); err != nil {
+		logger.Errorf("failed to request password reset, username: %s, error: %v", req.Username, err)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "If the account exists, a reset link has been sent"})
+}
+
+// ConfirmPasswordReset sets a new password with a reset token, logs out all sessions and revokes
+// all API tokens
+func ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	userID, err := enx.ResetPassword(req.Token, req.NewPassword)
+	switch {
+	case errors.Is(err, enx.ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid or expired reset token"})
+		return
+	case errors.Is(err, enx.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	case err != nil:
+		logger.Errorf("failed to reset password, error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to reset password"})
+		return
+	}
+
+	if err := middleware.DeleteUserSessions(userID, ""); err != nil {
+		logger.Errorf("failed to delete sessions after password reset, user id: %s, error: %v", userID, err)
+	}
+	if err := middleware.DeleteUserAPITokens(userID); err != nil {
+		logger.Errorf("failed to delete api tokens after password reset, user id: %s, error: %v", userID, err)
+	}
+	middleware.LoginSucceeded(enx.GetUserByID(userID).Name)
+	logger.Infof("password reset, user id: %s", userID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Password has been reset"})
+}
+
+// tooManyPasswordAttempts responds 429 with the time until the next attempt is allowed
+func tooManyPasswordAttempts(c *gin.Context, retryAfter time.Duration) {
+	logger.Warnf("password change throttled, user id: %s, ip: %s, retry after: %s", middleware.GetUserIDFromContext(c), c.ClientIP(), retryAfter)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": "Too many attempts, please try again later"})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/utils/mail"
+	"enx-api/utils/password"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/argon2"
+)
+
+// capturingSender keeps sent mails in memory
+type capturingSender struct {
+	messages []mail.Message
+}
+
+func (s *capturingSender) Send(msg mail.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func setupPasswordTest(t *testing.T) (*gin.Engine, *capturingSender) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	viper.Set("password.reset-ttl", "1h")
+	viper.Set("password.reset-url", "http://enx.test/reset")
+	t.Cleanup(viper.Reset)
+
+	sender := &capturingSender{}
+	mail.SetSender(sender)
+
+	hash, _ := password.HashPassword("password_1")
+	user := &enx.User{Name: "alice", Email: "alice@example.com", Password: hash}
+	if err := user.Create(); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authed := router.Group("/api", func(c *gin.Context) { c.Set("user_id", user.Id) })
+	authed.POST("/password/change", ChangePassword)
+	router.POST("/api/password/reset", RequestPasswordReset)
+	router.POST("/api/password/reset/confirm", ConfirmPasswordReset)
+	return router, sender
+}
+
+func postJSON(router *gin.Engine, path string, body interface{}) int {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func login(name, pw string) bool {
+	return (&enx.User{Name: name, Password: pw}).Login()
+}
+
+func TestChangePassword(t *testing.T) {
+	router, _ := setupPasswordTest(t)
+
+	if code := postJSON(router, "/api/password/change", ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "password_2"}); code != http.StatusUnauthorized {
+		t.Errorf("wrong current password, status: %d", code)
+	}
+	if code := postJSON(router, "/api/password/change", ChangePasswordRequest{CurrentPassword: "password_1", NewPassword: "short"}); code != http.StatusBadRequest {
+		t.Errorf("short new password, status: %d", code)
+	}
+
+	userID := enx.GeUserByName("alice").Id
+	session, _ := middleware.CreateSession(userID, "", "")
+	if _, _, err := middleware.CreateAPIToken(userID, "cli", []string{middleware.ScopeRead}, 0); err != nil {
+		t.Fatalf("create api token: %v", err)
+	}
+	if code := postJSON(router, "/api/password/change", ChangePasswordRequest{CurrentPassword: "password_1", NewPassword: "password_2"}); code != http.StatusOK {
+		t.Fatalf("change password, status: %d", code)
+	}
+	if login("alice", "password_1") || !login("alice", "password_2") {
+		t.Errorf("password not changed")
+	}
+	var count int64
+	sqlitex.DB.Model(&middleware.Session{}).Where("id = ?", session.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("other sessions not logged out")
+	}
+	if tokens, _ := middleware.ListAPITokens(userID); len(tokens) != 0 {
+		t.Errorf("api tokens not revoked: %+v", tokens)
+	}
+}
+
+func TestChangePasswordThrottled(t *testing.T) {
+	router, _ := setupPasswordTest(t)
+	viper.Set("login.lockout-threshold", 2)
+	viper.Set("login.lockout-base", "1m")
+	viper.Set("login.lockout-max", "1h")
+	viper.Set("login.failure-window", "15m")
+	if err := middleware.InitLoginLimiter(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = middleware.InitLoginLimiter() })
+
+	wrong := ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "password_2"}
+	if code := postJSON(router, "/api/password/change", wrong); code != http.StatusUnauthorized {
+		t.Errorf("first wrong password, status: %d", code)
+	}
+	if code := postJSON(router, "/api/password/change", wrong); code != http.StatusTooManyRequests {
+		t.Errorf("wrong password at the threshold, status: %d", code)
+	}
+	// locked like a login, the right password isn't tried either
+	if code := postJSON(router, "/api/password/change", ChangePasswordRequest{CurrentPassword: "password_1", NewPassword: "password_2"}); code != http.StatusTooManyRequests {
+		t.Errorf("locked user, status: %d", code)
+	}
+	if !login("alice", "password_1") {
+		t.Errorf("password changed while locked")
+	}
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	router, sender := setupPasswordTest(t)
+
+	// Unknown accounts look the same as known ones
+	if code := postJSON(router, "/api/password/reset", ResetPasswordRequest{Username: "nobody"}); code != http.StatusOK {
+		t.Errorf("unknown user, status: %d", code)
+	}
+	if len(sender.messages) != 0 {
+		t.Fatalf("mail sent for unknown user")
+	}
+
+	if code := postJSON(router, "/api/password/reset", ResetPasswordRequest{Username: "alice@example.com"}); code != http.StatusOK {
+		t.Fatalf("request reset, status: %d", code)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].To != "alice@example.com" {
+		t.Fatalf("unexpected mails: %+v", sender.messages)
+	}
+	token := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(sender.messages[0].Body)[1]
+
+	userID := enx.GeUserByName("alice").Id
+	if _, _, err := middleware.CreateAPIToken(userID, "cli", []string{middleware.ScopeRead}, 0); err != nil {
+		t.Fatalf("create api token: %v", err)
+	}
+
+	if code := postJSON(router, "/api/password/reset/confirm", ConfirmResetPasswordRequest{Token: "bad", NewPassword: "password_3"}); code != http.StatusBadRequest {
+		t.Errorf("bad token, status: %d", code)
+	}
+	if code := postJSON(router, "/api/password/reset/confirm", ConfirmResetPasswordRequest{Token: token, NewPassword: "password_3"}); code != http.StatusOK {
+		t.Fatalf("confirm reset, status: %d", code)
+	}
+	if !login("alice", "password_3") {
+		t.Errorf("password not reset")
+	}
+	if tokens, _ := middleware.ListAPITokens(userID); len(tokens) != 0 {
+		t.Errorf("api tokens not revoked: %+v", tokens)
+	}
+
+	// Tokens are single use
+	if code := postJSON(router, "/api/password/reset/confirm", ConfirmResetPasswordRequest{Token: token, NewPassword: "password_4"}); code != http.StatusBadRequest {
+		t.Errorf("reused token, status: %d", code)
+	}
+}
+
+func TestLoginRehashesOldParameters(t *testing.T) {
+	setupPasswordTest(t)
+
+	// Hash created with weaker parameters than the current ones
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("password_1"), salt, 1, 8*1024, 1, 32)
+	old := "$argon2id$v=19$m=8192,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(key)
+	sqlitex.DB.Model(&enx.User{}).Where("name = ?", "alice").Update("password", old)
+
+	if !login("alice", "password_1") {
+		t.Fatalf("login with old hash failed")
+	}
+	hash := enx.GeUserByName("alice").Password
+	if hash == old || password.NeedsRehash(hash) {
+		t.Errorf("hash not upgraded: %s", hash)
+	}
+	if !login("alice", "password_1") {
+		t.Errorf("login with upgraded hash failed")
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/utils/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions returns the current user's active sessions
+func ListSessions(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	sessions, err := middleware.ListUserSessions(userID, middleware.GetSessionIDFromContext(c))
+	if err != nil {
+		logger.Errorf("failed to list sessions, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": sessions})
+}
+
+// RevokeSession deletes one of the current user's sessions by its listed id
+func RevokeSession(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	found, err := middleware.RevokeUserSession(userID, c.Param("id"))
+	if err != nil {
+		logger.Errorf("failed to revoke session, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to revoke session"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Session not found"})
+		return
+	}
+	logger.Infof("session revoked, user id: %s, session: %s", userID, c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Session revoked"})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+// GetStats returns the current user's learning statistics.
+//
+// Query parameters: days (1-365, default 30) of daily series up to today,
+// and tz, an IANA time zone such as Asia/Shanghai that days are counted in.
+func GetStats(c *gin.Context) {
+	days := defaultStatsDays
+	if value := c.Query("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid days, allowed: 1-" + strconv.Itoa(maxStatsDays)})
+			return
+		}
+		days = n
+	}
+	loc := time.Local
+	if value := c.Query("tz"); value != "" {
+		l, err := time.LoadLocation(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid tz, use an IANA time zone such as Asia/Shanghai"})
+			return
+		}
+		loc = l
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	stats, err := repo.GetUserStats(userID, days, loc, time.Now())
+	if err != nil {
+		logger.Errorf("failed to get stats, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to get stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": stats})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupStatsTest(t *testing.T) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) int64 { return today.AddDate(0, 0, -daysAgo).UnixMilli() }
+
+	words := map[string]*repo.Word{}
+	for _, f := range []struct {
+		english    string
+		acquainted int
+		daysAgo    int
+	}{
+		{"apple", 0, 0},
+		{"banana", 0, 2},
+		{"cherry", 0, 40},
+		{"elder", 0, 45},
+		{"date", 1, 50},
+	} {
+		word := createTestWord(t, f.english, "")
+		words[f.english] = word
+		if err := repo.UpsertUserDict("user-1", word.Id, 1, f.acquainted); err != nil {
+			t.Fatalf("upsert user dict: %v", err)
+		}
+		sqlitex.DB.Model(&repo.UserDict{}).Where("user_id = ? AND word_id = ?", "user-1", word.Id).
+			Updates(map[string]interface{}{"created_at": at(f.daysAgo), "updated_at": at(f.daysAgo)})
+	}
+
+	// lookups on the last three days and a longer run of four days before
+	for i, daysAgo := range []int{0, 0, 1, 2, 10, 11, 12, 13} {
+		sqlitex.DB.Create(&repo.LookupEvent{Id: fmt.Sprintf("lookup-%d", i), UserId: "user-1",
+			WordId: words["banana"].Id, Kind: repo.EventLookup, CreatedAt: at(daysAgo)})
+	}
+	// date was looked up again after it was marked, so it is not retained
+	sqlitex.DB.Create(&repo.LookupEvent{Id: "date-mark", UserId: "user-1", WordId: words["date"].Id, Kind: repo.EventMark, CreatedAt: at(20)})
+	sqlitex.DB.Create(&repo.LookupEvent{Id: "date-lookup", UserId: "user-1", WordId: words["date"].Id, Kind: repo.EventLookup, CreatedAt: at(11)})
+	sqlitex.DB.Create(&repo.LookupEvent{Id: "other-user", UserId: "user-2", WordId: words["date"].Id, Kind: repo.EventLookup, CreatedAt: at(0)})
+
+	// apple is marked now and has not been looked up since
+	userDict := enx.UserDict{UserId: "user-1", WordId: words["apple"].Id}
+	if err := userDict.Mark(); err != nil {
+		t.Fatalf("mark: %v", err)
+	}
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: "user-1", WordId: words["apple"].Id, Kind: repo.EventMark})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/stats", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) }, GetStats)
+	return router
+}
+
+func TestGetStats(t *testing.T) {
+	router := setupStatsTest(t)
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/stats?days=7&tz=UTC", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get stats, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data repo.UserStats `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	stats := resp.Data
+
+	if len(stats.Days) != 7 || stats.Days[6].Date != time.Now().UTC().Format(time.DateOnly) {
+		t.Fatalf("days: %+v", stats.Days)
+	}
+	today, twoDaysAgo := stats.Days[6], stats.Days[4]
+	if today.NewWords != 1 || today.Lookups != 2 || today.Marked != 1 {
+		t.Errorf("today: %+v", today)
+	}
+	if twoDaysAgo.NewWords != 1 || twoDaysAgo.Lookups != 1 || twoDaysAgo.Marked != 0 {
+		t.Errorf("two days ago: %+v", twoDaysAgo)
+	}
+	if stats.TotalWords != 5 || stats.Acquainted != 2 {
+		t.Errorf("totals: %+v", stats)
+	}
+	if stats.CurrentStreak != 3 || stats.LongestStreak != 4 {
+		t.Errorf("streaks, current: %d, longest: %d", stats.CurrentStreak, stats.LongestStreak)
+	}
+	if math.Abs(stats.RetentionRate-0.5) > 1e-9 {
+		t.Errorf("retention rate: %v", stats.RetentionRate)
+	}
+	// two acquainted words plus half of the two dormant ones
+	if stats.KnownVocabulary != 3 {
+		t.Errorf("known vocabulary: %d", stats.KnownVocabulary)
+	}
+
+	w = adminRequest(router, "user-2", http.MethodGet, "/api/stats", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Days) != defaultStatsDays || resp.Data.TotalWords != 0 || resp.Data.CurrentStreak != 1 {
+		t.Errorf("another user: %+v", resp.Data)
+	}
+}
+
+func TestGetStatsInvalidParams(t *testing.T) {
+	router := setupStatsTest(t)
+	for _, query := range []string{"days=0", "days=366", "days=x", "tz=Mars/Olympus"} {
+		if w := adminRequest(router, "user-1", http.MethodGet, "/api/stats?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/utils/logger"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTokenRequest create API token request
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 never expires
+}
+
+// CreateToken creates a personal access token for the current user.
+// The token is only returned in this response.
+func CreateToken(c *gin.Context) {
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, raw, err := middleware.CreateAPIToken(userID, req.Name, req.Scopes, ttl)
+	if errors.Is(err, middleware.ErrInvalidScope) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid scopes, allowed: read, lookup, mark, export"})
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to create api token, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create token"})
+		return
+	}
+
+	logger.Infof("api token created, user id: %s, token id: %s, scopes: %s", userID, token.ID, token.Scopes)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": token, "token": raw})
+}
+
+// ListTokens returns the current user's API tokens without their secrets
+func ListTokens(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	tokens, err := middleware.ListAPITokens(userID)
+	if err != nil {
+		logger.Errorf("failed to list api tokens, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to list tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tokens})
+}
+
+// RevokeToken deletes one of the current user's API tokens
+func RevokeToken(c *gin.Context) {
+	userID := middleware.GetUserIDFromContext(c)
+	found, err := middleware.RevokeAPIToken(userID, c.Param("id"))
+	if err != nil {
+		logger.Errorf("failed to revoke api token, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to revoke token"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Token not found"})
+		return
+	}
+	logger.Infof("api token revoked, user id: %s, token id: %s", userID, c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Token revoked"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultBandSize = 1000
+	minBandSize     = 100
+	maxBandSize     = 10000
+)
+
+// GetVocabulary estimates the current user's vocabulary size from their acquainted words
+// against bands of the word frequency list.
+//
+// Query parameters: band_size (100-10000, default 1000), the number of ranks in each band.
+func GetVocabulary(c *gin.Context) {
+	bandSize := defaultBandSize
+	if value := c.Query("band_size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minBandSize || n > maxBandSize {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false,
+				"message": "Invalid band_size, allowed: " + strconv.Itoa(minBandSize) + "-" + strconv.Itoa(maxBandSize)})
+			return
+		}
+		bandSize = n
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	estimate, err := repo.EstimateVocabulary(userID, bandSize)
+	if err != nil {
+		logger.Errorf("failed to estimate vocabulary, user id: %s, error: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to estimate vocabulary"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": estimate})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/frequency"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupVocabularyTest(t *testing.T, words string) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	setFrequencyList(t, words)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	group.GET("/vocabulary", GetVocabulary)
+	return router
+}
+
+func setFrequencyList(t *testing.T, words string) {
+	list, err := frequency.Parse(strings.NewReader(strings.ReplaceAll(words, " ", "\n")))
+	if err != nil {
+		t.Fatalf("parse word list: %v", err)
+	}
+	previous := frequency.Current()
+	frequency.SetList(list)
+	t.Cleanup(func() { frequency.SetList(previous) })
+}
+
+func TestFrequencyRankStored(t *testing.T) {
+	setupVocabularyTest(t, "the of and harbor island ocean")
+	word := createTestWord(t, "Harbor", "港口")
+	if stored := repo.GetWordByEnglish("Harbor"); stored.FrequencyRank != 4 {
+		t.Errorf("rank after create: %d", stored.FrequencyRank)
+	}
+
+	word.English = "ocean"
+	if err := repo.UpdateWord(word); err != nil {
+		t.Fatalf("update word: %v", err)
+	}
+	if stored := repo.GetWordByEnglish("ocean"); stored.FrequencyRank != 6 {
+		t.Errorf("rank after rename: %d", stored.FrequencyRank)
+	}
+
+	createTestWord(t, "serendipity", "机缘巧合")
+	setFrequencyList(t, "ocean the serendipity")
+	changed, err := repo.RefreshFrequencyRanks()
+	if err != nil || changed != 2 {
+		t.Errorf("refresh, changed: %d, error: %v", changed, err)
+	}
+	if stored := repo.GetWordByEnglish("serendipity"); stored.FrequencyRank != 3 {
+		t.Errorf("rank after refresh: %d", stored.FrequencyRank)
+	}
+}
+
+func TestParagraphInitReturnsFrequencyRank(t *testing.T) {
+	setupVocabularyTest(t, "the of and harbor island ocean")
+	createTestWord(t, "harbor", "港口")
+
+	words := enx.QueryCountInText("The harbor serendipity", "user-1", "")
+	for raw, want := range map[string]int{"The": 1, "harbor": 4, "serendipity": 0} {
+		if got := words[raw].FrequencyRank; got != want {
+			t.Errorf("rank of %s: %d, want %d", raw, got, want)
+		}
+	}
+}
+
+func TestGetVocabulary(t *testing.T) {
+	router := setupVocabularyTest(t, "the of and harbor island ocean")
+	for english, acquainted := range map[string]int{"harbor": 1, "island": 0, "ocean": 1, "serendipity": 1, "quixotic": 0} {
+		word := createTestWord(t, english, "")
+		if err := repo.UpsertUserDict("user-1", word.Id, 1, acquainted); err != nil {
+			t.Fatalf("upsert user dict: %v", err)
+		}
+	}
+
+	estimate, err := repo.EstimateVocabulary("user-1", 2)
+	if err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	// ranks 1-2 are more frequent than any word the user looked up and count as known,
+	// 3-4 has harbor acquainted, 5-6 has island learning and ocean acquainted
+	if len(estimate.Bands) != 3 || estimate.ListSize != 6 || estimate.UnrankedAcquainted != 1 {
+		t.Fatalf("estimate: %+v", estimate)
+	}
+	if band := estimate.Bands[0]; !band.Inherited || band.KnownRatio != 1 || band.EstimatedKnown != 2 {
+		t.Errorf("first band: %+v", band)
+	}
+	if band := estimate.Bands[2]; band.Acquainted != 1 || band.Learning != 1 || band.EstimatedKnown != 1 {
+		t.Errorf("last band: %+v", band)
+	}
+	if estimate.Estimate != 6 {
+		t.Errorf("estimate: %d", estimate.Estimate)
+	}
+
+	w := adminRequest(router, "user-1", http.MethodGet, "/api/vocabulary", nil)
+	var resp struct {
+		Data repo.VocabularyEstimate `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Data.Bands) != 1 || resp.Data.Bands[0].ToRank != 6 {
+		t.Errorf("vocabulary, status: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	// there is nothing to estimate from for a user who looked nothing up
+	w = adminRequest(router, "user-2", http.MethodGet, "/api/vocabulary", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.Estimate != 0 {
+		t.Errorf("user without words: %s", w.Body.String())
+	}
+
+	for _, query := range []string{"band_size=0", "band_size=99", "band_size=large"} {
+		if w := adminRequest(router, "user-1", http.MethodGet, "/api/vocabulary?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package language lists the languages words are looked up in and translated into, by the codes
+// of the youdao open API, and normalizes words by the rules of their language
+package language
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	Chinese  = "zh-CHS"
+	Japanese = "ja"
+	Korean   = "ko"
+	Spanish  = "es"
+	French   = "fr"
+	German   = "de"
+)
+
+// Default is the native language of users who didn't choose one, what every lookup used before
+const Default = Chinese
+
+// targets names the languages lookups can translate into
+var targets = map[string]string{
+	Chinese:  "Chinese (Simplified)",
+	Japanese: "Japanese",
+	Korean:   "Korean",
+	Spanish:  "Spanish",
+	French:   "French",
+	German:   "German",
+}
+
+// ParseTarget returns the code of a target language given in any case, false when it isn't supported
+func ParseTarget(code string) (string, bool) {
+	for target := range targets {
+		if strings.EqualFold(target, strings.TrimSpace(code)) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// Info is a supported language
+type Info struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Targets returns the supported target languages by code
+func Targets() []Info {
+	return list(targets)
+}
+
+func list(languages map[string]string) []Info {
+	infos := make([]Info, 0, len(languages))
+	for code, name := range languages {
+		infos = append(infos, Info{Code: code, Name: name})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Code < infos[j].Code })
+	return infos
+}
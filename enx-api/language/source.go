@@ -0,0 +1,96 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	textlanguage "golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// source languages besides those words are translated into too
+const (
+	English    = "en"
+	Italian    = "it"
+	Portuguese = "pt"
+	Turkish    = "tr"
+)
+
+// DefaultSource is the language words are in for users who didn't choose the languages they study,
+// the only one before other languages existed
+const DefaultSource = English
+
+// sources names the languages words can be looked up in
+var sources = map[string]string{
+	English:    "English",
+	German:     "German",
+	French:     "French",
+	Spanish:    "Spanish",
+	Italian:    "Italian",
+	Portuguese: "Portuguese",
+	Turkish:    "Turkish",
+}
+
+// ParseSource returns the code of a source language given in any case, with or without a region
+// as in the lang attribute of a page, e.g. de-AT; false when it isn't supported
+func ParseSource(code string) (string, bool) {
+	code = strings.TrimSpace(code)
+	if base, _, found := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-"); found {
+		code = base
+	}
+	for source := range sources {
+		if strings.EqualFold(source, code) {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// Sources returns the supported source languages by code
+func Sources() []Info {
+	return list(sources)
+}
+
+// IsWordRune tells whether a character can be part of a word: a letter of any script, a combining
+// mark, a hyphen, an apostrophe or a space within a phrase
+func IsWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) || r == '-' || r == '\'' || r == '’' || r == ' '
+}
+
+// Clean keeps the characters of a word, see IsWordRune, NFC normalized so that an accent typed as
+// a combining mark is the same word as the precomposed one
+func Clean(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if IsWordRune(r) {
+			return r
+		}
+		return -1
+	}, norm.NFC.String(raw))
+}
+
+// Key is what words are told apart by within a language: NFC normalized and lower case by the
+// rules of the language, e.g. Turkish İstanbul is istanbul and IRMAK is ırmak
+func Key(lang, word string) string {
+	return cases.Lower(parseTag(lang)).String(norm.NFC.String(word))
+}
+
+// Spellings are the forms a word is usually written in: as given, lower case and capitalized
+func Spellings(lang, word string) []string {
+	key := Key(lang, word)
+	first, size := utf8.DecodeRuneInString(key)
+	capitalized := key
+	if first != utf8.RuneError {
+		capitalized = cases.Upper(parseTag(lang)).String(string(first)) + key[size:]
+	}
+	return []string{word, key, capitalized}
+}
+
+func parseTag(lang string) textlanguage.Tag {
+	tag, err := textlanguage.Parse(lang)
+	if err != nil {
+		return textlanguage.Und
+	}
+	return tag
+}
@@ -0,0 +1,55 @@
+package language
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	for code, want := range map[string]string{"de": German, " EN ": English, "de-AT": German, "pt_BR": Portuguese, "zh-CHS": "", "": ""} {
+		if got, ok := ParseSource(code); got != want || ok != (want != "") {
+			t.Errorf("%q: %q, %v", code, got, ok)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	for _, test := range []struct{ lang, word, want string }{
+		{English, "Harbor", "harbor"},
+		{German, "ÜBER", "über"},
+		{German, "STRAßE", "straße"},
+		// decomposed, u and a combining diaeresis
+		{German, "U\u0308ber", "über"},
+		{Turkish, "İstanbul", "istanbul"},
+		{Turkish, "IRMAK", "ırmak"},
+		{English, "IRMAK", "irmak"},
+	} {
+		if got := Key(test.lang, test.word); got != test.want {
+			t.Errorf("%s %q: %q, want: %q", test.lang, test.word, got, test.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	for raw, want := range map[string]string{
+		"Über,":      "Über",
+		"garçon!":    "garçon",
+		"niño's":     "niño's",
+		"U\u0308ber": "Über",
+		"6-year-old": "-year-old",
+		"(l’homme).": "l’homme",
+	} {
+		if got := Clean(raw); got != want {
+			t.Errorf("%q: %q, want: %q", raw, got, want)
+		}
+	}
+}
+
+func TestSpellings(t *testing.T) {
+	if got := Spellings(Turkish, "IRMAK"); !slices.Equal(got, []string{"IRMAK", "ırmak", "Irmak"}) {
+		t.Errorf("turkish: %q", got)
+	}
+	if got := Spellings(German, "über"); !slices.Equal(got, []string{"über", "über", "Über"}) {
+		t.Errorf("german: %q", got)
+	}
+}
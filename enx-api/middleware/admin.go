@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"enx-api/enx"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin allows only users with the admin role, it must run after SessionMiddleware
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := enx.GetUserByID(GetUserIDFromContext(c))
+		if user.Id == "" || !user.IsAdmin() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Admin role required",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
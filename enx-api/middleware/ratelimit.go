@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"enx-api/utils/logger"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// LimiterStoreMemory keeps login limits in process, per enx-api instance
+	LimiterStoreMemory = "memory"
+	// LimiterStoreRedis shares login limits between instances through repo/redisx
+	LimiterStoreRedis = "redis"
+)
+
+// LimiterStore keeps token buckets, failure counters and locks for the login limiter
+type LimiterStore interface {
+	// Take removes a token from the bucket at key, refilled at rate tokens per second up to capacity.
+	// If the bucket is empty it returns false and how long until the next token.
+	Take(key string, capacity int, rate float64) (bool, time.Duration, error)
+	// IncrFailures increments the failure counter at key, which is dropped after ttl without failures
+	IncrFailures(key string, ttl time.Duration) (int, error)
+	// Lock locks key until the given time
+	Lock(key string, until time.Time) error
+	// LockedUntil returns the unlock time of key, zero if it isn't locked
+	LockedUntil(key string) (time.Time, error)
+	// Reset clears the failure counter and lock at key
+	Reset(key string) error
+}
+
+// LoginLimits configures login throttling
+type LoginLimits struct {
+	IPBurst          int           // login.ip-burst
+	IPPerMinute      float64       // login.ip-per-minute
+	UserBurst        int           // login.user-burst
+	UserPerMinute    float64       // login.user-per-minute
+	LockoutThreshold int           // login.lockout-threshold, failures before the first lock
+	LockoutBase      time.Duration // login.lockout-base, doubled for every further failure
+	LockoutMax       time.Duration // login.lockout-max
+	FailureWindow    time.Duration // login.failure-window, failures are forgotten after this long
+}
+
+// LoginDecision is the result of checking a login attempt
+type LoginDecision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	UnlockAt   time.Time // set when the account is locked
+}
+
+// LoginLimiter throttles login attempts per IP and per username with token buckets,
+// and locks usernames out progressively after repeated failures
+type LoginLimiter struct {
+	store  LimiterStore
+	limits LoginLimits
+	now    func() time.Time
+}
+
+// loginLimiter has no limits until InitLoginLimiter reads them from config
+var loginLimiter = NewLoginLimiter(NewMemoryLimiterStore(), LoginLimits{})
+
+func NewLoginLimiter(store LimiterStore, limits LoginLimits) *LoginLimiter {
+	return &LoginLimiter{store: store, limits: limits, now: time.Now}
+}
+
+// InitLoginLimiter selects the limiter store from config (login.store)
+func InitLoginLimiter() error {
+	storeName := viper.GetString("login.store")
+	logger.Infof("init login limiter store: %s", storeName)
+	var store LimiterStore
+	switch storeName {
+	case "", LimiterStoreMemory:
+		store = NewMemoryLimiterStore()
+	case LimiterStoreRedis:
+		store = NewRedisLimiterStore()
+	default:
+		return fmt.Errorf("unknown login limiter store: %s", storeName)
+	}
+	loginLimiter = NewLoginLimiter(store, loginLimitsFromConfig())
+	return nil
+}
+
+func loginLimitsFromConfig() LoginLimits {
+	return LoginLimits{
+		IPBurst:          viper.GetInt("login.ip-burst"),
+		IPPerMinute:      viper.GetFloat64("login.ip-per-minute"),
+		UserBurst:        viper.GetInt("login.user-burst"),
+		UserPerMinute:    viper.GetFloat64("login.user-per-minute"),
+		LockoutThreshold: viper.GetInt("login.lockout-threshold"),
+		LockoutBase:      viper.GetDuration("login.lockout-base"),
+		LockoutMax:       viper.GetDuration("login.lockout-max"),
+		FailureWindow:    viper.GetDuration("login.failure-window"),
+	}
+}
+
+// CheckLogin checks a login attempt before the password is verified
+func CheckLogin(ip, username string) LoginDecision {
+	return loginLimiter.Check(ip, username)
+}
+
+// CheckPasswordReset checks a password reset request, with buckets of its own
+func CheckPasswordReset(ip, username string) LoginDecision {
+	return loginLimiter.CheckReset(ip, username)
+}
+
+// CheckAPIToken checks an api token before its secret is verified
+func CheckAPIToken(ip, tokenID string) LoginDecision {
+	return loginLimiter.CheckAPIToken(ip, tokenID)
+}
+
+// LoginFailed records a failed login, returns the unlock time if the username is now locked
+func LoginFailed(username string) time.Time {
+	return loginLimiter.Failed(username)
+}
+
+// LoginSucceeded clears the failures of username
+func LoginSucceeded(username string) {
+	loginLimiter.Succeeded(username)
+}
+
+// Check consumes a token from the IP and username buckets.
+// Store errors fail open, so a broken Redis doesn't block every login.
+func (l *LoginLimiter) Check(ip, username string) LoginDecision {
+	username = normalizeUsername(username)
+	now := l.now()
+
+	unlockAt, err := l.store.LockedUntil("login:lock:" + username)
+	if err != nil {
+		logger.Errorf("login limiter failed to read lock, username: %s, error: %v", username, err)
+	} else if now.Before(unlockAt) {
+		return LoginDecision{RetryAfter: unlockAt.Sub(now), UnlockAt: unlockAt}
+	}
+
+	return l.take("login:ip:"+ip, "login:user:"+username)
+}
+
+// CheckReset consumes a token from the IP and username buckets of password reset requests,
+// which are kept apart from the login buckets so resets don't lock anyone out of logging in
+func (l *LoginLimiter) CheckReset(ip, username string) LoginDecision {
+	return l.take("reset:ip:"+ip, "reset:user:"+normalizeUsername(username))
+}
+
+// CheckAPIToken consumes a token from the IP and token id buckets of api tokens that aren't verified
+// yet, so guessing a token's secret costs as much as guessing a password. Token ids aren't locked
+// out, which would let anyone knowing an id lock its owner out.
+func (l *LoginLimiter) CheckAPIToken(ip, tokenID string) LoginDecision {
+	return l.take("token:ip:"+ip, "token:id:"+tokenID)
+}
+
+// take consumes a token from the IP bucket at ipKey and the username bucket at userKey
+func (l *LoginLimiter) take(ipKey, userKey string) LoginDecision {
+	buckets := []struct {
+		key       string
+		burst     int
+		perMinute float64
+	}{
+		{ipKey, l.limits.IPBurst, l.limits.IPPerMinute},
+		{userKey, l.limits.UserBurst, l.limits.UserPerMinute},
+	}
+	for _, bucket := range buckets {
+		if bucket.burst <= 0 || bucket.perMinute <= 0 {
+			continue
+		}
+		ok, retryAfter, err := l.store.Take(bucket.key, bucket.burst, bucket.perMinute/60)
+		if err != nil {
+			logger.Errorf("login limiter failed to take token, key: %s, error: %v", bucket.key, err)
+			continue
+		}
+		if !ok {
+			return LoginDecision{RetryAfter: retryAfter}
+		}
+	}
+	return LoginDecision{Allowed: true}
+}
+
+// Failed counts a failure for username and locks it once the threshold is reached.
+// Every failure past the threshold doubles the lock, up to LockoutMax.
+func (l *LoginLimiter) Failed(username string) time.Time {
+	username = normalizeUsername(username)
+	if l.limits.LockoutThreshold <= 0 {
+		return time.Time{}
+	}
+
+	failures, err := l.store.IncrFailures("login:failures:"+username, l.limits.FailureWindow)
+	if err != nil {
+		logger.Errorf("login limiter failed to count failure, username: %s, error: %v", username, err)
+		return time.Time{}
+	}
+	if failures < l.limits.LockoutThreshold {
+		return time.Time{}
+	}
+
+	lockout := l.limits.LockoutBase * time.Duration(math.Pow(2, float64(min(failures-l.limits.LockoutThreshold, 20))))
+	if lockout > l.limits.LockoutMax || lockout <= 0 {
+		lockout = l.limits.LockoutMax
+	}
+	unlockAt := l.now().Add(lockout)
+	if err := l.store.Lock("login:lock:"+username, unlockAt); err != nil {
+		logger.Errorf("login limiter failed to lock, username: %s, error: %v", username, err)
+		return time.Time{}
+	}
+	logger.Warnf("login locked after %d failures, username: %s, unlock at: %s", failures, username, unlockAt.Format(time.RFC3339))
+	return unlockAt
+}
+
+// Succeeded clears failures and lock of username
+func (l *LoginLimiter) Succeeded(username string) {
+	username = normalizeUsername(username)
+	if err := l.store.Reset("login:failures:" + username); err != nil {
+		logger.Errorf("login limiter failed to reset failures, username: %s, error: %v", username, err)
+	}
+	if err := l.store.Reset("login:lock:" + username); err != nil {
+		logger.Errorf("login limiter failed to reset lock, username: %s, error: %v", username, err)
+	}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// MemoryLimiterStore is an in-process LimiterStore
+type MemoryLimiterStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	failures map[string]*failureCounter
+	locks    map[string]time.Time
+	now      func() time.Time
+	lastGC   time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	idle    time.Duration // time to refill completely, the bucket can be dropped after this long
+}
+
+type failureCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// memoryStoreGCInterval is how often stale buckets and counters are dropped
+const memoryStoreGCInterval = time.Minute
+
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{
+		buckets:  map[string]*tokenBucket{},
+		failures: map[string]*failureCounter{},
+		locks:    map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryLimiterStore) Take(key string, capacity int, rate float64) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.gc(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(capacity), updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.idle = time.Duration(float64(capacity) / rate * float64(time.Second))
+	bucket.tokens = math.Min(float64(capacity), bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / rate * float64(time.Second)), nil
+	}
+	bucket.tokens--
+	return true, 0, nil
+}
+
+func (s *MemoryLimiterStore) IncrFailures(key string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	counter, ok := s.failures[key]
+	if !ok || !now.Before(counter.expiresAt) {
+		counter = &failureCounter{}
+		s.failures[key] = counter
+	}
+	counter.count++
+	counter.expiresAt = now.Add(ttl)
+	return counter.count, nil
+}
+
+func (s *MemoryLimiterStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = until
+	return nil
+}
+
+func (s *MemoryLimiterStore) LockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locks[key], nil
+}
+
+func (s *MemoryLimiterStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	delete(s.locks, key)
+	return nil
+}
+
+// gc drops full buckets, expired counters and past locks, so the maps don't grow with every IP seen
+func (s *MemoryLimiterStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < memoryStoreGCInterval {
+		return
+	}
+	s.lastGC = now
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.idle {
+			delete(s.buckets, key)
+		}
+	}
+	for key, counter := range s.failures {
+		if !now.Before(counter.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+	for key, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"enx-api/repo/redisx"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// takeTokenScript refills and takes from a token bucket atomically.
+// KEYS[1] bucket, ARGV capacity, rate (tokens per millisecond), now (Unix milliseconds).
+// Returns {allowed, milliseconds until the next token}.
+var takeTokenScript = redis.NewScript(1, `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, wait}
+`)
+
+// RedisLimiterStore is a LimiterStore shared by all enx-api instances using the same Redis (redis.address)
+type RedisLimiterStore struct{}
+
+func NewRedisLimiterStore() *RedisLimiterStore {
+	return &RedisLimiterStore{}
+}
+
+func (s *RedisLimiterStore) Take(key string, capacity int, rate float64) (bool, time.Duration, error) {
+	conn := redisx.GetConn()
+	defer conn.Close()
+
+	reply, err := redis.Int64s(takeTokenScript.Do(conn, key, capacity, rate/1000, time.Now().UnixMilli()))
+	if err != nil {
+		return false, 0, err
+	}
+	return reply[0] == 1, time.Duration(reply[1]) * time.Millisecond, nil
+}
+
+func (s *RedisLimiterStore) IncrFailures(key string, ttl time.Duration) (int, error) {
+	count, err := redis.Int(redisx.Exec("INCR", key))
+	if err != nil {
+		return 0, err
+	}
+	_, err = redisx.Exec("PEXPIRE", key, ttl.Milliseconds())
+	return count, err
+}
+
+func (s *RedisLimiterStore) Lock(key string, until time.Time) error {
+	ttl := time.Until(until).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := redisx.Exec("SET", key, until.UnixMilli(), "PX", ttl)
+	return err
+}
+
+func (s *RedisLimiterStore) LockedUntil(key string) (time.Time, error) {
+	until, err := redis.Int64(redisx.Exec("GET", key))
+	if err == redis.ErrNil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(until), nil
+}
+
+func (s *RedisLimiterStore) Reset(key string) error {
+	_, err := redisx.Exec("DEL", key)
+	return err
+}
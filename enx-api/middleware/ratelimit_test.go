@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLoginLimiter(limits LoginLimits) (*LoginLimiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := NewMemoryLimiterStore()
+	store.now = clock
+	limiter := NewLoginLimiter(store, limits)
+	limiter.now = clock
+	return limiter, &now
+}
+
+func TestLoginLimiterTokenBuckets(t *testing.T) {
+	limiter, now := newTestLoginLimiter(LoginLimits{IPBurst: 3, IPPerMinute: 60, UserBurst: 2, UserPerMinute: 6})
+
+	// Username bucket runs out first
+	for i := 0; i < 2; i++ {
+		if d := limiter.Check("10.0.0.1", "alice"); !d.Allowed {
+			t.Fatalf("attempt %d rejected", i)
+		}
+	}
+	d := limiter.Check("10.0.0.1", "Alice")
+	if d.Allowed || d.RetryAfter <= 0 || d.RetryAfter > 10*time.Second {
+		t.Fatalf("expected throttled username with retry after up to 10s, got: %+v", d)
+	}
+
+	// Another username from the same IP still has the IP bucket's last token
+	if d := limiter.Check("10.0.0.1", "bob"); d.Allowed {
+		t.Errorf("expected IP bucket to be empty after 3 attempts")
+	}
+
+	// Buckets refill over time
+	*now = now.Add(10 * time.Second)
+	if d := limiter.Check("10.0.0.1", "alice"); !d.Allowed {
+		t.Errorf("expected refilled buckets to allow a login, got: %+v", d)
+	}
+}
+
+func TestLoginLimiterProgressiveLockout(t *testing.T) {
+	limiter, now := newTestLoginLimiter(LoginLimits{
+		LockoutThreshold: 3,
+		LockoutBase:      time.Minute,
+		LockoutMax:       3 * time.Minute,
+		FailureWindow:    time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if unlockAt := limiter.Failed("alice"); !unlockAt.IsZero() {
+			t.Fatalf("locked after %d failures", i+1)
+		}
+	}
+	unlockAt := limiter.Failed("alice")
+	if want := now.Add(time.Minute); !unlockAt.Equal(want) {
+		t.Fatalf("first lock until %v, want %v", unlockAt, want)
+	}
+	d := limiter.Check("10.0.0.1", "alice")
+	if d.Allowed || !d.UnlockAt.Equal(unlockAt) {
+		t.Fatalf("expected locked account, got: %+v", d)
+	}
+
+	// Each failure after a lock doubles it, up to the max
+	*now = now.Add(time.Minute)
+	if d := limiter.Check("10.0.0.1", "alice"); !d.Allowed {
+		t.Fatalf("expected unlock after the lock expired, got: %+v", d)
+	}
+	if unlockAt := limiter.Failed("alice"); !unlockAt.Equal(now.Add(2 * time.Minute)) {
+		t.Errorf("second lock until %v, want 2m", unlockAt.Sub(*now))
+	}
+	if unlockAt := limiter.Failed("alice"); !unlockAt.Equal(now.Add(3 * time.Minute)) {
+		t.Errorf("third lock until %v, want capped 3m", unlockAt.Sub(*now))
+	}
+
+	// A successful login clears the lock and failures
+	limiter.Succeeded("alice")
+	if d := limiter.Check("10.0.0.1", "alice"); !d.Allowed {
+		t.Errorf("expected login allowed after success, got: %+v", d)
+	}
+	if unlockAt := limiter.Failed("alice"); !unlockAt.IsZero() {
+		t.Errorf("failures not reset after success")
+	}
+}
+
+func TestLoginLimiterResetBuckets(t *testing.T) {
+	limiter, _ := newTestLoginLimiter(LoginLimits{IPBurst: 2, IPPerMinute: 1, UserBurst: 2, UserPerMinute: 1})
+
+	for i := 0; i < 2; i++ {
+		if d := limiter.CheckReset("10.0.0.1", "alice"); !d.Allowed {
+			t.Fatalf("reset request %d rejected", i)
+		}
+	}
+	if d := limiter.CheckReset("10.0.0.1", "Alice"); d.Allowed {
+		t.Errorf("expected throttled reset requests")
+	}
+	// reset requests don't use up the login buckets
+	if d := limiter.Check("10.0.0.1", "alice"); !d.Allowed {
+		t.Errorf("expected login allowed after reset requests, got: %+v", d)
+	}
+}
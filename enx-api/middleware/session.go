@@ -1,20 +1,64 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"enx-api/utils/logger"
+	"enx-api/utils/schedule"
 	"enx-api/utils/sqlitex"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/spf13/viper"
 )
 
 type Session struct {
-	ID        string `json:"id" gorm:"primaryKey"`
-	UserID    string `json:"user_id" gorm:"column:user_id"`
-	CreatedAt int64  `json:"created_at" gorm:"column:created_at"` // Unix milliseconds
-	ExpiresAt int64  `json:"expires_at" gorm:"column:expires_at"` // Unix milliseconds
+	ID         string `json:"id" gorm:"primaryKey"`
+	UserID     string `json:"user_id" gorm:"column:user_id"`
+	CreatedAt  int64  `json:"created_at" gorm:"column:created_at"`     // Unix milliseconds
+	ExpiresAt  int64  `json:"expires_at" gorm:"column:expires_at"`     // Unix milliseconds
+	LastSeenAt int64  `json:"last_seen_at" gorm:"column:last_seen_at"` // Unix milliseconds
+	UserAgent  string `json:"user_agent" gorm:"column:user_agent"`
+	IP         string `json:"ip" gorm:"column:ip"`
+}
+
+// SessionInfo is a session as shown to its owner.
+// The session id is a bearer secret, so sessions are identified by a hash of it instead.
+type SessionInfo struct {
+	ID         string `json:"id"`
+	CreatedAt  int64  `json:"created_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	Current    bool   `json:"current"`
+}
+
+// sessionTouchInterval limits how often a session's sliding expiry is written back
+const sessionTouchInterval = time.Minute
+
+// SessionIdleTimeout is how long a session stays valid without activity (session.idle-timeout)
+func SessionIdleTimeout() time.Duration {
+	return viper.GetDuration("session.idle-timeout")
+}
+
+// SessionAbsoluteTimeout is the maximum lifetime of a session regardless of activity (session.absolute-timeout)
+func SessionAbsoluteTimeout() time.Duration {
+	return viper.GetDuration("session.absolute-timeout")
+}
+
+// GetSessionIDFromContext gets the current session id from gin context
+func GetSessionIDFromContext(c *gin.Context) string {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return ""
+	}
+	return sessionID.(string)
 }
 
 // GetUserIDFromContext gets user id from gin context
@@ -38,6 +82,36 @@ func SessionMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Personal access tokens: "Authorization: Bearer enx_..."
+		if raw := bearerToken(c); raw != "" {
+			token, err := authenticateAPIToken(raw, c.ClientIP())
+			var throttled *tokenThrottledError
+			if errors.As(err, &throttled) {
+				logger.Warnf("SessionMiddleware: API token throttled, ip: %s, error: %v", c.ClientIP(), err)
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.retryAfter.Seconds()))))
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"success": false,
+					"message": "Too many attempts, please try again later",
+				})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				logger.Errorf("SessionMiddleware: API token authentication failed, error: %v", err)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"message": "Invalid or expired API token",
+				})
+				c.Abort()
+				return
+			}
+			logger.Infof("SessionMiddleware: API token %s found for user ID: %s", token.ID, token.UserID)
+			c.Set("user_id", token.UserID)
+			c.Set("api_token", token)
+			c.Next()
+			return
+		}
+
 		sessionID := c.GetHeader("X-Session-ID")
 		logger.Infof("SessionMiddleware: X-Session-ID header: '%s'", sessionID)
 
@@ -82,21 +156,51 @@ func SessionMiddleware() gin.HandlerFunc {
 		c.Set("user_id", session.UserID)
 		c.Set("session_id", sessionID)
 
-		// Update session expiration time
-		session.ExpiresAt = time.Now().Add(24 * time.Hour).UnixMilli()
+		// Slide the session expiration time
+		touchSession(&session, time.Now())
 
 		c.Next()
 	}
 }
 
+// touchSession extends the session by the idle timeout, capped at the absolute timeout.
+// Writes are skipped when the session was touched less than sessionTouchInterval ago.
+func touchSession(session *Session, now time.Time) {
+	if now.UnixMilli()-session.LastSeenAt < sessionTouchInterval.Milliseconds() {
+		return
+	}
+	session.LastSeenAt = now.UnixMilli()
+	session.ExpiresAt = sessionExpiresAt(session.CreatedAt, now)
+	err := sqlitex.DB.Model(&Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+		"last_seen_at": session.LastSeenAt,
+		"expires_at":   session.ExpiresAt,
+	}).Error
+	if err != nil {
+		logger.Errorf("failed to update session expiry, user id: %s, error: %v", session.UserID, err)
+	}
+}
+
+// sessionExpiresAt returns the sliding expiry for activity at now, capped at the absolute timeout
+func sessionExpiresAt(createdAt int64, now time.Time) int64 {
+	expiresAt := now.Add(SessionIdleTimeout()).UnixMilli()
+	absoluteExpiresAt := time.UnixMilli(createdAt).Add(SessionAbsoluteTimeout()).UnixMilli()
+	if expiresAt > absoluteExpiresAt {
+		return absoluteExpiresAt
+	}
+	return expiresAt
+}
+
 // CreateSession creates a new session
-func CreateSession(userID string) (*Session, error) {
+func CreateSession(userID, userAgent, ip string) (*Session, error) {
 	now := time.Now()
 	session := &Session{
-		ID:        generateSessionID(),
-		UserID:    userID,
-		CreatedAt: now.UnixMilli(),
-		ExpiresAt: now.Add(24 * time.Hour).UnixMilli(),
+		ID:         generateSessionID(),
+		UserID:     userID,
+		CreatedAt:  now.UnixMilli(),
+		ExpiresAt:  sessionExpiresAt(now.UnixMilli(), now),
+		LastSeenAt: now.UnixMilli(),
+		UserAgent:  userAgent,
+		IP:         ip,
 	}
 	logger.Infof("creating session, user id: %v, session: %+v", userID, session)
 	if err := sqlitex.DB.Create(session).Error; err != nil {
@@ -112,6 +216,75 @@ func DeleteSession(sessionID string) error {
 	return sqlitex.DB.Where("id = ?", sessionID).Delete(&Session{}).Error
 }
 
+// ListUserSessions returns the active sessions of a user, newest first
+func ListUserSessions(userID, currentSessionID string) ([]SessionInfo, error) {
+	var sessions []Session
+	err := sqlitex.DB.Where("user_id = ? AND expires_at > ?", userID, time.Now().UnixMilli()).
+		Order("created_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, SessionInfo{
+			ID:         sessionPublicID(session.ID),
+			CreatedAt:  session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			LastSeenAt: session.LastSeenAt,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return infos, nil
+}
+
+// RevokeUserSession deletes the user's session identified by its public id, false if it doesn't exist
+func RevokeUserSession(userID, publicID string) (bool, error) {
+	var sessions []Session
+	if err := sqlitex.DB.Where("user_id = ?", userID).Find(&sessions).Error; err != nil {
+		return false, err
+	}
+	for _, session := range sessions {
+		if sessionPublicID(session.ID) == publicID {
+			return true, DeleteSession(session.ID)
+		}
+	}
+	return false, nil
+}
+
+// DeleteUserSessions deletes all sessions of a user except keepSessionID, e.g. after a password change
+func DeleteUserSessions(userID, keepSessionID string) error {
+	return sqlitex.DB.Where("user_id = ? AND id <> ?", userID, keepSessionID).Delete(&Session{}).Error
+}
+
+// DeleteExpiredSessions purges sessions past their expiry and returns how many were removed
+func DeleteExpiredSessions() (int64, error) {
+	result := sqlitex.DB.Where("expires_at <= ?", time.Now().UnixMilli()).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+// StartSessionJanitor purges expired sessions, see schedule.Every
+func StartSessionJanitor(interval time.Duration) (stop func(), err error) {
+	return schedule.Every(interval, func() {
+		count, err := DeleteExpiredSessions()
+		if err != nil {
+			logger.Errorf("session janitor failed to delete expired sessions: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Infof("session janitor deleted %d expired sessions", count)
+		}
+	})
+}
+
+// sessionPublicID derives a stable, non-secret identifier from a session id
+func sessionPublicID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
 // generateSessionID generates a unique session ID
 func generateSessionID() string {
 	// Use UUID to generate session ID
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func setupSessionTest(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	viper.Set("session.idle-timeout", "1h")
+	viper.Set("session.absolute-timeout", "3h")
+	t.Cleanup(viper.Reset)
+	gin.SetMode(gin.TestMode)
+}
+
+func doSessionRequest(sessionID string) int {
+	router := gin.New()
+	router.Use(SessionMiddleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Session-ID", sessionID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func loadSession(t *testing.T, id string) Session {
+	var session Session
+	if err := sqlitex.DB.Where("id = ?", id).First(&session).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	return session
+}
+
+func TestSessionSlidingExpiry(t *testing.T) {
+	setupSessionTest(t)
+
+	session, err := CreateSession("user-1", "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	// Pretend the session was last used half an hour ago
+	past := time.Now().Add(-30 * time.Minute).UnixMilli()
+	sqlitex.DB.Model(&Session{}).Where("id = ?", session.ID).
+		Updates(map[string]interface{}{"last_seen_at": past, "expires_at": past + time.Hour.Milliseconds()})
+
+	if code := doSessionRequest(session.ID); code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	}
+	if got := loadSession(t, session.ID); got.ExpiresAt <= past+time.Hour.Milliseconds() {
+		t.Errorf("expiry was not extended, expires at: %d", got.ExpiresAt)
+	}
+}
+
+func TestSessionAbsoluteCap(t *testing.T) {
+	setupSessionTest(t)
+
+	session, err := CreateSession("user-1", "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	// Logged in two and a half hours ago: the idle timeout would go past the 3h cap
+	createdAt := time.Now().Add(-150 * time.Minute).UnixMilli()
+	sqlitex.DB.Model(&Session{}).Where("id = ?", session.ID).
+		Updates(map[string]interface{}{"created_at": createdAt, "last_seen_at": createdAt})
+
+	doSessionRequest(session.ID)
+	if got := loadSession(t, session.ID); got.ExpiresAt != createdAt+(3*time.Hour).Milliseconds() {
+		t.Errorf("expiry not capped, expires at: %d, created at: %d", got.ExpiresAt, createdAt)
+	}
+
+	// Past the cap the session is rejected
+	sqlitex.DB.Model(&Session{}).Where("id = ?", session.ID).Update("expires_at", time.Now().Add(-time.Minute).UnixMilli())
+	if code := doSessionRequest(session.ID); code != http.StatusUnauthorized {
+		t.Errorf("expired session accepted, status: %d", code)
+	}
+}
+
+func TestListAndRevokeUserSessions(t *testing.T) {
+	setupSessionTest(t)
+
+	first, _ := CreateSession("user-1", "agent-a", "10.0.0.1")
+	second, _ := CreateSession("user-1", "agent-b", "10.0.0.2")
+	other, _ := CreateSession("user-2", "agent-c", "10.0.0.3")
+
+	sessions, err := ListUserSessions("user-1", first.ID)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	var current SessionInfo
+	for _, s := range sessions {
+		if s.ID == first.ID || s.ID == second.ID {
+			t.Errorf("session secret exposed in listing")
+		}
+		if s.Current {
+			current = s
+		}
+	}
+	if current.UserAgent != "agent-a" || current.IP != "10.0.0.1" {
+		t.Errorf("unexpected current session: %+v", current)
+	}
+
+	// Another user's session can't be revoked
+	if found, _ := RevokeUserSession("user-1", sessionPublicID(other.ID)); found {
+		t.Errorf("revoked another user's session")
+	}
+	if found, err := RevokeUserSession("user-1", sessionPublicID(second.ID)); !found || err != nil {
+		t.Fatalf("revoke session, found: %v, error: %v", found, err)
+	}
+	if code := doSessionRequest(second.ID); code != http.StatusUnauthorized {
+		t.Errorf("revoked session accepted, status: %d", code)
+	}
+}
+
+func TestDeleteExpiredSessions(t *testing.T) {
+	setupSessionTest(t)
+
+	expired, _ := CreateSession("user-1", "", "")
+	active, _ := CreateSession("user-1", "", "")
+	sqlitex.DB.Model(&Session{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Minute).UnixMilli())
+
+	count, err := DeleteExpiredSessions()
+	if err != nil || count != 1 {
+		t.Fatalf("delete expired sessions, count: %d, error: %v", count, err)
+	}
+	loadSession(t, active.ID)
+}
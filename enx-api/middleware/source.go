@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SourceExtension is a request of the browser extension
+	SourceExtension = "extension"
+	// SourceUI is a request of the web UI
+	SourceUI = "ui"
+	// SourceAPI is a request authenticated with a personal access token
+	SourceAPI = "api"
+)
+
+// maxHostLength is the longest valid DNS name
+const maxHostLength = 253
+
+// GetRequestSource tells where a lookup came from and, when the client sent an X-Page-Host header,
+// the host of the page it was made on. Clients only ever send the host, never the page URL.
+func GetRequestSource(c *gin.Context) (source, pageHost string) {
+	origin := c.GetHeader("Origin")
+	if _, ok := c.Get("api_token"); ok {
+		source = SourceAPI
+	} else if strings.HasPrefix(origin, "chrome-extension:") || strings.HasPrefix(origin, "moz-extension:") {
+		source = SourceExtension
+	} else {
+		source = SourceUI
+	}
+	return source, normalizePageHost(c.GetHeader("X-Page-Host"))
+}
+
+// normalizePageHost lower-cases the host and drops the port, anything that isn't a plain host is dropped
+func normalizePageHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || len(host) > maxHostLength {
+		return ""
+	}
+	for _, r := range host {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune(".-:[]", r)) {
+			return ""
+		}
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetRequestSource(t *testing.T) {
+	tests := []struct {
+		origin   string
+		apiToken bool
+		pageHost string
+		source   string
+		host     string
+	}{
+		{"chrome-extension://abc", false, "Example.com:443", SourceExtension, "example.com"},
+		{"moz-extension://abc", false, "[::1]:8080", SourceExtension, "::1"},
+		{"http://localhost:3000", false, "", SourceUI, ""},
+		{"chrome-extension://abc", true, "example.com", SourceAPI, "example.com"},
+		{"", false, "https://example.com/path", SourceUI, ""},
+		{"", false, "evil.com/<script>", SourceUI, ""},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/translate", nil)
+		c.Request.Header.Set("Origin", tt.origin)
+		c.Request.Header.Set("X-Page-Host", tt.pageHost)
+		if tt.apiToken {
+			c.Set("api_token", &APIToken{})
+		}
+		source, host := GetRequestSource(c)
+		if source != tt.source || host != tt.host {
+			t.Errorf("origin %q, page host %q: got %s %q", tt.origin, tt.pageHost, source, host)
+		}
+	}
+}
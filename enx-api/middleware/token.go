@@ -0,0 +1,280 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"enx-api/utils/logger"
+	"enx-api/utils/password"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API token scopes
+const (
+	ScopeRead   = "read"   // read counts and user data
+	ScopeLookup = "lookup" // translate and search words
+	ScopeMark   = "mark"   // mark words as acquainted and annotate them
+	ScopeExport = "export" // export user data
+)
+
+// AllScopes lists every scope a token can be granted
+var AllScopes = []string{ScopeRead, ScopeLookup, ScopeMark, ScopeExport}
+
+const (
+	apiTokenPrefix = "enx"
+	// verifiedTokenTTL is how long a verified token skips the Argon2 check
+	verifiedTokenTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid api token")
+	ErrInvalidScope = errors.New("invalid api token scope")
+)
+
+// tokenThrottledError is returned for a token verified too often, see CheckAPIToken
+type tokenThrottledError struct {
+	retryAfter time.Duration
+}
+
+func (e *tokenThrottledError) Error() string {
+	return fmt.Sprintf("too many api token attempts, retry after %s", e.retryAfter)
+}
+
+// APIToken is a long-lived personal access token; only a hash of its secret is stored
+type APIToken struct {
+	ID         string `json:"id" gorm:"primaryKey"`
+	UserID     string `json:"-" gorm:"column:user_id"`
+	Name       string `json:"name" gorm:"column:name"`
+	TokenHash  string `json:"-" gorm:"column:token_hash"`
+	Scopes     string `json:"scopes" gorm:"column:scopes"`             // comma separated
+	CreatedAt  int64  `json:"created_at" gorm:"column:created_at"`     // Unix milliseconds
+	LastUsedAt int64  `json:"last_used_at" gorm:"column:last_used_at"` // Unix milliseconds
+	ExpiresAt  int64  `json:"expires_at" gorm:"column:expires_at"`     // Unix milliseconds, 0 never expires
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type verifiedToken struct {
+	token     APIToken
+	expiresAt time.Time
+}
+
+// verifiedTokens caches recently verified tokens by the sha256 of the raw token,
+// so Argon2 runs once per token every verifiedTokenTTL instead of on every request
+var (
+	verifiedTokens   = map[string]verifiedToken{}
+	verifiedTokensMu sync.Mutex
+)
+
+// CreateAPIToken creates a token for the user and returns it with the raw token, which is shown only once
+func CreateAPIToken(userID, name string, scopes []string, ttl time.Duration) (*APIToken, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !isValidScope(scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := password.HashPassword(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	token := &APIToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hash,
+		Scopes:    strings.Join(scopes, ","),
+		CreatedAt: now.UnixMilli(),
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl).UnixMilli()
+	}
+	if err := sqlitex.DB.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, apiTokenPrefix + "_" + id + "_" + secret, nil
+}
+
+// ListAPITokens returns the user's tokens, newest first
+func ListAPITokens(userID string) ([]APIToken, error) {
+	var tokens []APIToken
+	err := sqlitex.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken deletes the user's token, false if it doesn't exist
+func RevokeAPIToken(userID, tokenID string) (bool, error) {
+	result := sqlitex.DB.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&APIToken{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	verifiedTokensMu.Lock()
+	for key, verified := range verifiedTokens {
+		if verified.token.ID == tokenID {
+			delete(verifiedTokens, key)
+		}
+	}
+	verifiedTokensMu.Unlock()
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteUserAPITokens revokes all tokens of a user, e.g. when the account is disabled
+func DeleteUserAPITokens(userID string) error {
+	if err := sqlitex.DB.Where("user_id = ?", userID).Delete(&APIToken{}).Error; err != nil {
+		return err
+	}
+
+	verifiedTokensMu.Lock()
+	for key, verified := range verifiedTokens {
+		if verified.token.UserID == userID {
+			delete(verifiedTokens, key)
+		}
+	}
+	verifiedTokensMu.Unlock()
+	return nil
+}
+
+// authenticateAPIToken verifies a raw token from the Authorization header of a request from ip
+func authenticateAPIToken(raw, ip string) (*APIToken, error) {
+	now := time.Now()
+	sum := sha256.Sum256([]byte(raw))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	verifiedTokensMu.Lock()
+	verified, ok := verifiedTokens[cacheKey]
+	verifiedTokensMu.Unlock()
+	if ok && now.Before(verified.expiresAt) && !verified.token.isExpired(now) {
+		return &verified.token, nil
+	}
+
+	parts := strings.Split(raw, "_")
+	if len(parts) != 3 || parts[0] != apiTokenPrefix {
+		return nil, ErrInvalidToken
+	}
+
+	if decision := CheckAPIToken(ip, parts[1]); !decision.Allowed {
+		return nil, &tokenThrottledError{retryAfter: decision.RetryAfter}
+	}
+	var token APIToken
+	if err := sqlitex.DB.Where("id = ?", parts[1]).First(&token).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+	if token.isExpired(now) {
+		return nil, ErrInvalidToken
+	}
+	match, err := password.VerifyPassword(parts[2], token.TokenHash)
+	if err != nil || !match {
+		return nil, ErrInvalidToken
+	}
+
+	token.LastUsedAt = now.UnixMilli()
+	if err := sqlitex.DB.Model(&APIToken{}).Where("id = ?", token.ID).Update("last_used_at", token.LastUsedAt).Error; err != nil {
+		logger.Errorf("failed to update api token last used time, token id: %s, error: %v", token.ID, err)
+	}
+
+	verifiedTokensMu.Lock()
+	// drop the expired entries, so only tokens verified within verifiedTokenTTL are kept
+	for key, verified := range verifiedTokens {
+		if !now.Before(verified.expiresAt) {
+			delete(verifiedTokens, key)
+		}
+	}
+	verifiedTokens[cacheKey] = verifiedToken{token: token, expiresAt: now.Add(verifiedTokenTTL)}
+	verifiedTokensMu.Unlock()
+	return &token, nil
+}
+
+func (t *APIToken) isExpired(now time.Time) bool {
+	return t.ExpiresAt > 0 && now.UnixMilli() >= t.ExpiresAt
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// RequireScope allows requests authenticated by a session, or by an api token granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := c.Get("api_token")
+		if !ok || token.(*APIToken).HasScope(scope) {
+			c.Next()
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "API token is missing scope: " + scope,
+		})
+		c.Abort()
+	}
+}
+
+// RequireSession rejects requests authenticated by an api token, e.g. for managing tokens and sessions
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_token"); ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "This API requires a login session",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
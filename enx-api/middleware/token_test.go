@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func doTokenRequest(method, path, authorization string) int {
+	router := gin.New()
+	router.Use(SessionMiddleware())
+	router.GET("/translate", RequireScope(ScopeLookup), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/mark", RequireScope(ScopeMark), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/tokens", RequireSession(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	setupSessionTest(t)
+
+	token, raw, err := CreateAPIToken("user-1", "chrome", []string{ScopeRead, ScopeLookup}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if token.TokenHash == "" || token.TokenHash == raw {
+		t.Errorf("token secret not hashed")
+	}
+
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw); code != http.StatusOK {
+		t.Errorf("lookup with lookup scope, status: %d", code)
+	}
+	if code := doTokenRequest(http.MethodPost, "/mark", "Bearer "+raw); code != http.StatusForbidden {
+		t.Errorf("mark without mark scope, status: %d", code)
+	}
+	if code := doTokenRequest(http.MethodGet, "/tokens", "Bearer "+raw); code != http.StatusForbidden {
+		t.Errorf("token management with a token, status: %d", code)
+	}
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw+"x"); code != http.StatusUnauthorized {
+		t.Errorf("wrong secret accepted, status: %d", code)
+	}
+}
+
+func TestAPITokenRevokeAndExpiry(t *testing.T) {
+	setupSessionTest(t)
+
+	token, raw, _ := CreateAPIToken("user-1", "script", []string{ScopeLookup}, 0)
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw); code != http.StatusOK {
+		t.Fatalf("valid token rejected, status: %d", code)
+	}
+
+	// Revocation also drops the verified token from the cache
+	if found, _ := RevokeAPIToken("user-2", token.ID); found {
+		t.Errorf("revoked another user's token")
+	}
+	if found, err := RevokeAPIToken("user-1", token.ID); !found || err != nil {
+		t.Fatalf("revoke token, found: %v, error: %v", found, err)
+	}
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw); code != http.StatusUnauthorized {
+		t.Errorf("revoked token accepted, status: %d", code)
+	}
+
+	_, expired, _ := CreateAPIToken("user-1", "short", []string{ScopeLookup}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+expired); code != http.StatusUnauthorized {
+		t.Errorf("expired token accepted, status: %d", code)
+	}
+
+	if _, _, err := CreateAPIToken("user-1", "bad", []string{"admin"}, 0); err != ErrInvalidScope {
+		t.Errorf("expected invalid scope error, got: %v", err)
+	}
+}
+
+func TestAPITokenThrottled(t *testing.T) {
+	setupSessionTest(t)
+	previous := loginLimiter
+	loginLimiter = NewLoginLimiter(NewMemoryLimiterStore(), LoginLimits{IPBurst: 10, IPPerMinute: 1, UserBurst: 2, UserPerMinute: 1})
+	t.Cleanup(func() { loginLimiter = previous })
+
+	token, raw, _ := CreateAPIToken("user-1", "script", []string{ScopeLookup}, 0)
+	for i := 0; i < 2; i++ {
+		if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw+"x"); code != http.StatusUnauthorized {
+			t.Fatalf("wrong secret %d, status: %d", i, code)
+		}
+	}
+	// the secret isn't verified any more for the token id, not even the right one
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw); code != http.StatusTooManyRequests {
+		t.Errorf("guessing not throttled, status: %d", code)
+	}
+	// nor for ids guessed from the same IP
+	for i := 0; i < 10; i++ {
+		doTokenRequest(http.MethodGet, "/translate", "Bearer enx_"+token.ID+fmt.Sprint(i)+"_x")
+	}
+	_, other, _ := CreateAPIToken("user-1", "other", []string{ScopeLookup}, 0)
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+other); code != http.StatusTooManyRequests {
+		t.Errorf("guessing ids not throttled, status: %d", code)
+	}
+}
+
+func TestVerifiedTokensEvicted(t *testing.T) {
+	setupSessionTest(t)
+
+	verifiedTokensMu.Lock()
+	verifiedTokens["stale"] = verifiedToken{token: APIToken{ID: "gone"}, expiresAt: time.Now().Add(-time.Second)}
+	verifiedTokensMu.Unlock()
+
+	_, raw, _ := CreateAPIToken("user-1", "script", []string{ScopeLookup}, 0)
+	if code := doTokenRequest(http.MethodGet, "/translate", "Bearer "+raw); code != http.StatusOK {
+		t.Fatalf("valid token rejected, status: %d", code)
+	}
+	verifiedTokensMu.Lock()
+	_, stale := verifiedTokens["stale"]
+	verifiedTokensMu.Unlock()
+	if stale {
+		t.Error("expired verified token kept")
+	}
+}
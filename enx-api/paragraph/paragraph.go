@@ -11,6 +11,8 @@ import (
 // their 6-year-old to
 func ParagraphInit(c *gin.Context) {
 	paragraph := c.Query("paragraph")
+	// the lang of the page, if any
+	from := c.Query("from")
 	userId := middleware.GetUserIDFromContext(c)
 	if userId == "" {
 		logger.Errorf("no valid user id found in session")
@@ -22,7 +24,7 @@ func ParagraphInit(c *gin.Context) {
 	}
 
 	logger.Debugf("words count, paragraph: %s, user_id: %s", paragraph, userId)
-	out := enx.QueryCountInText(paragraph, userId)
+	out := enx.QueryCountInText(paragraph, userId, from)
 	c.JSON(200, gin.H{
 		"data": out,
 	})
@@ -15,7 +15,7 @@ func TestParagraph0(t *testing.T) {
 	paragraph := "their 6-year-old to"
 	utils.ViperInit()
 	sqlitex.Init()
-	out := enx.QueryCountInText(paragraph, 1)
+	out := enx.QueryCountInText(paragraph, "1", "")
 	fmt.Printf("out: %+v\n", out)
 	// check if key "6-year-old" exist
 	if _, ok := out["6-year-old"]; !ok {
@@ -31,7 +31,7 @@ func TestParagraphEndingChar(t *testing.T) {
 	paragraph := "Good morning."
 	utils.ViperInit()
 	sqlitex.Init()
-	out := enx.QueryCountInText(paragraph, 1)
+	out := enx.QueryCountInText(paragraph, "1", "")
 	fmt.Printf("out: %+v\n", out)
 	for key, word := range out {
 		fmt.Printf("key: %s, word: %+v\n", key, word)
@@ -46,7 +46,7 @@ func TestParagraphBarcket(t *testing.T) {
 	paragraph := "scientists. (Assassins wove through traffic to attach “sticky bombs” to their car doors.) The"
 	utils.ViperInit()
 	sqlitex.Init()
-	out := enx.QueryCountInText(paragraph, 1)
+	out := enx.QueryCountInText(paragraph, "1", "")
 	fmt.Printf("out: %+v\n", out)
 	for key, word := range out {
 		fmt.Printf("key: %s, word: %+v\n", key, word)
@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrMergeSameWord = errors.New("cannot merge a word into itself")
+
+// WordUsage is a word with its lookups summed over all users
+type WordUsage struct {
+	Id         string `json:"id"`
+	English    string `json:"english"`
+	Chinese    string `json:"chinese"`
+	QueryCount int64  `json:"query_count"`
+	Users      int64  `json:"users"`
+}
+
+// LookupStats is an overview of dictionary usage
+type LookupStats struct {
+	Users        int64       `json:"users"`
+	ActiveUsers  int64       `json:"active_users"` // looked up a word in the last 7 days
+	Words        int64       `json:"words"`
+	DeletedWords int64       `json:"deleted_words"`
+	UserDicts    int64       `json:"user_dicts"`
+	Lookups      int64       `json:"lookups"`
+	TopWords     []WordUsage `json:"top_words"`
+}
+
+// GetWordByID returns a word including soft-deleted ones
+func GetWordByID(id string) (*Word, error) {
+	word := &Word{}
+	if err := sqlitex.DB.Where("id = ?", id).First(word).Error; err != nil {
+		return nil, err
+	}
+	return word, nil
+}
+
+// SearchWords finds words whose english or chinese contains q, newest first
+func SearchWords(q string, includeDeleted bool, offset, limit int) ([]Word, int64, error) {
+	query := sqlitex.DB.Model(&Word{})
+	if q != "" {
+		query = query.Where("english LIKE ? OR chinese LIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	if !includeDeleted {
+		query = query.Where("deleted_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var words []Word
+	err := query.Order("updated_at DESC").Offset(offset).Limit(limit).Find(&words).Error
+	return words, total, err
+}
+
+// MergeWords moves every user's lookups and lookup history of source onto target and soft-deletes
+// source, in one transaction of the store. Query counts are added up; a word stays acquainted only
+// if the user knew both. Notes and translations of the target are kept over those of the source.
+func MergeWords(sourceId, targetId string) error {
+	if sourceId == targetId {
+		return ErrMergeSameWord
+	}
+	if _, err := GetWordByID(sourceId); err != nil {
+		return err
+	}
+	if _, err := GetWordByID(targetId); err != nil {
+		return err
+	}
+
+	var sourceDicts []UserDict
+	if err := sqlitex.DB.Where("word_id = ?", sourceId).Find(&sourceDicts).Error; err != nil {
+		return err
+	}
+	merged := make([]UserDict, 0, len(sourceDicts))
+	for _, source := range sourceDicts {
+		userDict := UserDict{
+			UserId:            source.UserId,
+			WordId:            targetId,
+			QueryCount:        source.QueryCount,
+			AlreadyAcquainted: source.AlreadyAcquainted,
+			Note:              source.Note,
+			Tags:              source.Tags,
+			Translation:       source.Translation,
+		}
+		target, err := store.GetUserDict(source.UserId, targetId)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			userDict.QueryCount += target.QueryCount
+			userDict.AlreadyAcquainted = min(source.AlreadyAcquainted, target.AlreadyAcquainted)
+			userDict.CreatedAt = target.CreatedAt
+			// The target's own annotations win, tags of both are kept
+			if target.Note != "" {
+				userDict.Note = target.Note
+			}
+			if target.Translation != "" {
+				userDict.Translation = target.Translation
+			}
+			userDict.Tags = NormalizeTags(append(SplitTags(target.Tags), SplitTags(source.Tags)...))
+		}
+		merged = append(merged, userDict)
+	}
+	return store.MergeWords(sourceId, targetId, merged)
+}
+
+// GetLookupStats summarizes users, words and lookups, with the top most looked up words
+func GetLookupStats(top int) (*LookupStats, error) {
+	stats := &LookupStats{}
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour).UnixMilli()
+
+	counts := []*gorm.DB{
+		sqlitex.DB.Table("users").Count(&stats.Users),
+		sqlitex.DB.Model(&UserDict{}).Where("updated_at > ?", weekAgo).Distinct("user_id").Count(&stats.ActiveUsers),
+		sqlitex.DB.Model(&Word{}).Where("deleted_at IS NULL").Count(&stats.Words),
+		sqlitex.DB.Model(&Word{}).Where("deleted_at IS NOT NULL").Count(&stats.DeletedWords),
+		sqlitex.DB.Model(&UserDict{}).Count(&stats.UserDicts),
+		sqlitex.DB.Model(&UserDict{}).Select("COALESCE(SUM(query_count), 0)").Scan(&stats.Lookups),
+	}
+	for _, count := range counts {
+		if count.Error != nil {
+			return nil, count.Error
+		}
+	}
+
+	err := sqlitex.DB.Table("user_dicts").
+		Select("words.id, words.english, COALESCE(words.chinese, '') AS chinese, SUM(user_dicts.query_count) AS query_count, COUNT(*) AS users").
+		Joins("JOIN words ON words.id = user_dicts.word_id").
+		Where("words.deleted_at IS NULL").
+		Group("words.id").
+		Order("query_count DESC").
+		Limit(top).
+		Scan(&stats.TopWords).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AudioClip indexes the pronunciation audio of a word in the blob directory, local to this node
+type AudioClip struct {
+	Word        string `gorm:"column:word;primaryKey"` // lower-cased
+	Accent      string `gorm:"column:accent;primaryKey"`
+	Hash        string `gorm:"column:hash"` // sha256 of the audio, hex, names the blob
+	ContentType string `gorm:"column:content_type"`
+	Size        int64  `gorm:"column:size"`
+	Source      string `gorm:"column:source"`
+	CreatedAt   int64  `gorm:"column:created_at"`  // Unix milliseconds
+	AccessedAt  int64  `gorm:"column:accessed_at"` // Unix milliseconds
+}
+
+func (AudioClip) TableName() string {
+	return "audio_clips"
+}
+
+// GetAudioClip returns the clip of a word in an accent, or nil if there is none
+func GetAudioClip(word, accent string) (*AudioClip, error) {
+	clip := &AudioClip{}
+	err := sqlitex.DB.Where("word = ? AND accent = ?", word, accent).First(clip).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// SaveAudioClip inserts or replaces a clip
+func SaveAudioClip(clip *AudioClip) error {
+	return sqlitex.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(clip).Error
+}
+
+// TouchAudioClip records that a clip was played
+func TouchAudioClip(word, accent string) error {
+	return sqlitex.DB.Model(&AudioClip{}).Where("word = ? AND accent = ?", word, accent).
+		UpdateColumn("accessed_at", time.Now().UnixMilli()).Error
+}
+
+// DeleteAudioClip removes a clip and tells whether other clips still use its blob
+func DeleteAudioClip(clip *AudioClip) (blobInUse bool, err error) {
+	if err := sqlitex.DB.Where("word = ? AND accent = ?", clip.Word, clip.Accent).Delete(&AudioClip{}).Error; err != nil {
+		return false, err
+	}
+	var count int64
+	err = sqlitex.DB.Model(&AudioClip{}).Where("hash = ?", clip.Hash).Count(&count).Error
+	return count > 0, err
+}
+
+// AudioBlobsSize is the size of all blobs, each counted once however many clips share it
+func AudioBlobsSize() (int64, error) {
+	var size int64
+	err := sqlitex.DB.Raw("SELECT COALESCE(SUM(size), 0) FROM (SELECT DISTINCT hash, size FROM audio_clips)").Scan(&size).Error
+	return size, err
+}
+
+// LeastRecentlyPlayedAudioClips returns up to limit clips, least recently played first
+func LeastRecentlyPlayedAudioClips(limit int) ([]AudioClip, error) {
+	var clips []AudioClip
+	err := sqlitex.DB.Order("accessed_at, word, accent").Limit(limit).Find(&clips).Error
+	return clips, err
+}
@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"context"
+	pb "enx-api/proto"
+	"enx-api/utils/logger"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const dataServiceTimeout = 5 * time.Second
+
+// ErrOtherDatabase is a DataService writing to another database file than the one enx-api reads
+var ErrOtherDatabase = errors.New("data service writes to another database file, point enx-api and enx-sync at the same one")
+
+// DataServiceStore writes words and user_dicts through enx-sync's DataService,
+// so enx-sync is the only process writing to the database file.
+type DataServiceStore struct {
+	conn   *grpc.ClientConn
+	client pb.DataServiceClient
+}
+
+// NewDataServiceStore creates a store backed by the DataService at address, e.g. localhost:50051
+func NewDataServiceStore(address string, opts ...grpc.DialOption) (*DataServiceStore, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("data service client created, address: %s", address)
+	return &DataServiceStore{conn: conn, client: pb.NewDataServiceClient(conn)}, nil
+}
+
+func (s *DataServiceStore) Close() error {
+	return s.conn.Close()
+}
+
+// CheckDatabase makes sure the DataService writes to the database file enx-api reads words from:
+// a word it lists has to be in the local file, which has no words either when it lists none
+func (s *DataServiceStore) CheckDatabase() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.ListWords(ctx, &pb.ListWordsRequest{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("failed to list words: %w", err)
+	}
+	query := sqlitex.DB.Model(&Word{}).Where("deleted_at IS NULL")
+	if len(resp.Words) > 0 {
+		query = query.Where("id = ?", resp.Words[0].Id)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if (count > 0) != (len(resp.Words) > 0) {
+		return ErrOtherDatabase
+	}
+	return nil
+}
+
+func (s *DataServiceStore) CreateWord(word *Word) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.CreateWord(ctx, &pb.CreateWordRequest{
+		English:        word.English,
+		Chinese:        word.Chinese,
+		Pronunciation:  word.Pronunciation,
+		Glosses:        word.GlossMap(),
+		SourceLanguage: word.SourceLanguage,
+	})
+	if err != nil {
+		logger.Errorf("data service create word failed, english: %s, error: %v", word.English, err)
+		return err
+	}
+	word.Id = resp.Word.Id
+	word.CreatedAt = resp.Word.CreatedAt
+	word.UpdatedAt = resp.Word.UpdatedAt
+	// words are read from the local file, one missing there would be created again on every lookup
+	var count int64
+	if err := sqlitex.DB.Model(&Word{}).Where("id = ?", word.Id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("word %s: %w", word.Id, ErrOtherDatabase)
+	}
+	return s.SetFrequencyRanks(map[string]int{word.Id: word.FrequencyRank})
+}
+
+func (s *DataServiceStore) UpdateWord(word *Word) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.UpdateWord(ctx, &pb.UpdateWordRequest{Word: &pb.Word{
+		Id:            word.Id,
+		English:       word.English,
+		Chinese:       word.Chinese,
+		Pronunciation: word.Pronunciation,
+		Glosses:       word.GlossMap(),
+	}})
+	if err != nil {
+		logger.Errorf("data service update word failed, id: %s, error: %v", word.Id, err)
+		return err
+	}
+	word.UpdatedAt = resp.Word.UpdatedAt
+	return s.SetFrequencyRanks(map[string]int{word.Id: word.FrequencyRank})
+}
+
+func (s *DataServiceStore) SetFrequencyRanks(ranks map[string]int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	req := &pb.SetFrequencyRanksRequest{Ranks: make(map[string]int32, len(ranks))}
+	for id, rank := range ranks {
+		req.Ranks[id] = int32(rank)
+	}
+	if _, err := s.client.SetFrequencyRanks(ctx, req); err != nil {
+		logger.Errorf("data service set frequency ranks failed, words: %d, error: %v", len(ranks), err)
+		return err
+	}
+	return nil
+}
+
+func (s *DataServiceStore) DeleteWord(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	if _, err := s.client.DeleteWord(ctx, &pb.DeleteWordRequest{Id: id}); err != nil {
+		logger.Errorf("data service delete word failed, id: %s, error: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+func (s *DataServiceStore) MergeWords(sourceId, targetId string, merged []UserDict) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	req := &pb.MergeWordsRequest{SourceId: sourceId, TargetId: targetId}
+	for _, userDict := range merged {
+		req.UserDicts = append(req.UserDicts, &pb.UserDict{
+			UserId:            userDict.UserId,
+			WordId:            userDict.WordId,
+			QueryCount:        int32(userDict.QueryCount),
+			AlreadyAcquainted: int32(userDict.AlreadyAcquainted),
+			Note:              userDict.Note,
+			Tags:              userDict.Tags,
+			Translation:       userDict.Translation,
+			CreatedAt:         userDict.CreatedAt,
+		})
+	}
+	if _, err := s.client.MergeWords(ctx, req); err != nil {
+		logger.Errorf("data service merge words failed, source: %s, target: %s, error: %v", sourceId, targetId, err)
+		return err
+	}
+	return nil
+}
+
+func (s *DataServiceStore) GetUserDict(userId, wordId string) (*UserDict, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.GetUserDict(ctx, &pb.GetUserDictRequest{UserId: userId, WordId: wordId})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &UserDict{
+		UserId:            resp.UserDict.UserId,
+		WordId:            resp.UserDict.WordId,
+		QueryCount:        int(resp.UserDict.QueryCount),
+		AlreadyAcquainted: int(resp.UserDict.AlreadyAcquainted),
+		Note:              resp.UserDict.Note,
+		Tags:              resp.UserDict.Tags,
+		Translation:       resp.UserDict.Translation,
+		CreatedAt:         resp.UserDict.CreatedAt,
+		UpdatedAt:         resp.UserDict.UpdatedAt,
+	}, nil
+}
+
+func (s *DataServiceStore) UpsertUserDict(userDict *UserDict) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.UpsertUserDict(ctx, &pb.UpsertUserDictRequest{
+		UserDict: &pb.UserDict{
+			UserId:            userDict.UserId,
+			WordId:            userDict.WordId,
+			QueryCount:        int32(userDict.QueryCount),
+			AlreadyAcquainted: int32(userDict.AlreadyAcquainted),
+			Note:              userDict.Note,
+			Tags:              userDict.Tags,
+			Translation:       userDict.Translation,
+			UpdatedAt:         time.Now().UnixMilli(),
+		},
+	})
+	if err != nil {
+		logger.Errorf("data service upsert user dict failed, user_id: %s, word_id: %s, error: %v",
+			userDict.UserId, userDict.WordId, err)
+		return err
+	}
+	userDict.CreatedAt = resp.UserDict.CreatedAt
+	userDict.UpdatedAt = resp.UserDict.UpdatedAt
+	return nil
+}
@@ -0,0 +1,259 @@
+package repo
+
+import (
+	"context"
+	"enx-api/frequency"
+	pb "enx-api/proto"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeDataService keeps user_dicts and frequency ranks in memory and writes words to the local
+// database, or to one of its own when separate is set. Reads of user_dicts fail with err when it is set.
+type fakeDataService struct {
+	pb.UnimplementedDataServiceServer
+	userDicts map[string]*pb.UserDict
+	ranks     map[string]int32
+	separate  bool
+	err       error
+}
+
+func (f *fakeDataService) CreateWord(ctx context.Context, req *pb.CreateWordRequest) (*pb.CreateWordResponse, error) {
+	now := time.Now().UnixMilli()
+	word := &pb.Word{
+		Id:        "word-" + req.English,
+		English:   req.English,
+		Chinese:   req.Chinese,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !f.separate {
+		sqlitex.DB.Create(&Word{Id: word.Id, SourceLanguage: "en", English: word.English, CreatedAt: now, UpdatedAt: now})
+	}
+	return &pb.CreateWordResponse{Word: word}, nil
+}
+
+func (f *fakeDataService) SetFrequencyRanks(ctx context.Context, req *pb.SetFrequencyRanksRequest) (*pb.SetFrequencyRanksResponse, error) {
+	if f.ranks == nil {
+		f.ranks = map[string]int32{}
+	}
+	for id, rank := range req.Ranks {
+		f.ranks[id] = rank
+	}
+	return &pb.SetFrequencyRanksResponse{Changed: int64(len(req.Ranks))}, nil
+}
+
+func (f *fakeDataService) ListWords(ctx context.Context, req *pb.ListWordsRequest) (*pb.ListWordsResponse, error) {
+	if f.separate {
+		return &pb.ListWordsResponse{Words: []*pb.Word{{Id: "word-elsewhere", English: "elsewhere"}}, Total: 1}, nil
+	}
+	var words []Word
+	sqlitex.DB.Where("deleted_at IS NULL").Limit(int(req.Limit)).Find(&words)
+	resp := &pb.ListWordsResponse{Total: int32(len(words))}
+	for _, word := range words {
+		resp.Words = append(resp.Words, &pb.Word{Id: word.Id, English: word.English})
+	}
+	return resp, nil
+}
+
+func (f *fakeDataService) GetUserDict(ctx context.Context, req *pb.GetUserDictRequest) (*pb.GetUserDictResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	ud, ok := f.userDicts[req.UserId+"/"+req.WordId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user_dict not found")
+	}
+	return &pb.GetUserDictResponse{UserDict: ud}, nil
+}
+
+func (f *fakeDataService) UpsertUserDict(ctx context.Context, req *pb.UpsertUserDictRequest) (*pb.UpsertUserDictResponse, error) {
+	f.userDicts[req.UserDict.UserId+"/"+req.UserDict.WordId] = req.UserDict
+	return &pb.UpsertUserDictResponse{UserDict: req.UserDict}, nil
+}
+
+func (f *fakeDataService) MergeWords(ctx context.Context, req *pb.MergeWordsRequest) (*pb.MergeWordsResponse, error) {
+	for key, ud := range f.userDicts {
+		if ud.WordId == req.SourceId {
+			delete(f.userDicts, key)
+		}
+	}
+	for _, ud := range req.UserDicts {
+		f.userDicts[ud.UserId+"/"+ud.WordId] = ud
+	}
+	return &pb.MergeWordsResponse{SourceId: req.SourceId, TargetId: req.TargetId}, nil
+}
+
+func newTestDataServiceStore(t *testing.T) *DataServiceStore {
+	return newFakeDataServiceStore(t, &fakeDataService{userDicts: map[string]*pb.UserDict{}})
+}
+
+func newFakeDataServiceStore(t *testing.T, fake *fakeDataService) *DataServiceStore {
+	// words are read from the local database with either backend
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterDataServiceServer(server, fake)
+	go server.Serve(lis)
+
+	s, err := NewDataServiceStore("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create data service store: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		server.Stop()
+	})
+	return s
+}
+
+func TestDataServiceStoreCreateWord(t *testing.T) {
+	s := newTestDataServiceStore(t)
+
+	word := &Word{English: "morning", Chinese: "早晨"}
+	if err := s.CreateWord(word); err != nil {
+		t.Fatalf("create word: %v", err)
+	}
+	if word.Id != "word-morning" || word.CreatedAt == 0 {
+		t.Errorf("word not filled from response: %+v", word)
+	}
+	if err := s.CheckDatabase(); err != nil {
+		t.Errorf("check database: %v", err)
+	}
+}
+
+func TestDataServiceStoreFrequencyRanks(t *testing.T) {
+	fake := &fakeDataService{userDicts: map[string]*pb.UserDict{}}
+	s := newFakeDataServiceStore(t, fake)
+	SetStore(s)
+	defer SetStore(&sqliteStore{})
+	list, err := frequency.Parse(strings.NewReader("the\nmorning"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := frequency.Current()
+	frequency.SetList(list)
+	t.Cleanup(func() { frequency.SetList(previous) })
+
+	if err := CreateWord(&Word{English: "morning"}); err != nil {
+		t.Fatalf("create word: %v", err)
+	}
+	if fake.ranks["word-morning"] != 2 {
+		t.Errorf("rank of a new word: %v", fake.ranks)
+	}
+	fake.ranks = nil
+	if changed, err := RefreshFrequencyRanks(); err != nil || changed != 1 || fake.ranks["word-morning"] != 2 {
+		t.Errorf("refresh, changed: %d, ranks: %v, error: %v", changed, fake.ranks, err)
+	}
+	// enx-sync owns the words table, enx-api doesn't write the rank itself
+	var rank int
+	sqlitex.DB.Model(&Word{}).Where("id = ?", "word-morning").Select("frequency_rank").Scan(&rank)
+	if rank != 0 {
+		t.Errorf("rank written to the local database: %d", rank)
+	}
+}
+
+func TestDataServiceStoreOtherDatabase(t *testing.T) {
+	s := newFakeDataServiceStore(t, &fakeDataService{userDicts: map[string]*pb.UserDict{}, separate: true})
+
+	if err := s.CheckDatabase(); !errors.Is(err, ErrOtherDatabase) {
+		t.Errorf("check database: %v", err)
+	}
+	if err := s.CreateWord(&Word{English: "morning"}); !errors.Is(err, ErrOtherDatabase) {
+		t.Errorf("create word: %v", err)
+	}
+
+}
+
+func TestDataServiceStoreUnavailable(t *testing.T) {
+	fake := &fakeDataService{userDicts: map[string]*pb.UserDict{}}
+	s := newFakeDataServiceStore(t, fake)
+	SetStore(s)
+	defer SetStore(&sqliteStore{})
+
+	if _, err := s.GetUserDict("user-1", "word-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing user dict: %v", err)
+	}
+	if err := UpsertUserDict("user-1", "word-1", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	fake.err = status.Error(codes.Unavailable, "data service down")
+	if _, _, err := GetUserWordQueryCount("word-1", "user-1"); status.Code(err) != codes.Unavailable {
+		t.Errorf("query count: %v", err)
+	}
+	// the count isn't reset while the record can't be read
+	if err := UpsertUserDict("user-1", "word-1", 1, 0); err == nil {
+		t.Error("upsert without the existing record")
+	}
+	fake.err = nil
+	if queryCount, _, _ := GetUserWordQueryCount("word-1", "user-1"); queryCount != 3 {
+		t.Errorf("query count after the outage: %d", queryCount)
+	}
+}
+
+func TestDataServiceStoreUserDict(t *testing.T) {
+	s := newTestDataServiceStore(t)
+
+	if _, err := s.GetUserDict("user-1", "word-1"); err == nil {
+		t.Errorf("expected not found error")
+	}
+
+	SetStore(s)
+	defer SetStore(&sqliteStore{})
+
+	if err := UpsertUserDict("user-1", "word-1", 3, 1); err != nil {
+		t.Fatalf("upsert user dict: %v", err)
+	}
+	queryCount, acquainted, err := GetUserWordQueryCount("word-1", "user-1")
+	if err != nil || queryCount != 3 || acquainted != 1 {
+		t.Errorf("unexpected user dict, query count: %d, acquainted: %d", queryCount, acquainted)
+	}
+
+	// notes survive count updates and counts survive note updates
+	if _, err := SaveUserWordNote("user-1", "word-1", "a note", []string{"Work", " work", "travel"}, "早上"); err != nil {
+		t.Fatalf("save note: %v", err)
+	}
+	if err := UpsertUserDict("user-1", "word-1", 4, 0); err != nil {
+		t.Fatalf("upsert user dict: %v", err)
+	}
+	userDict, err := GetUserDict("user-1", "word-1")
+	if err != nil {
+		t.Fatalf("get user dict: %v", err)
+	}
+	if userDict.QueryCount != 4 || userDict.Note != "a note" || userDict.Tags != "work,travel" || userDict.Translation != "早上" {
+		t.Errorf("unexpected user dict: %+v", userDict)
+	}
+}
+
+func TestDataServiceStoreMergeWords(t *testing.T) {
+	fake := &fakeDataService{userDicts: map[string]*pb.UserDict{
+		"user-1/word-a": {UserId: "user-1", WordId: "word-a", QueryCount: 2},
+	}}
+	s := newFakeDataServiceStore(t, fake)
+
+	merged := []UserDict{{UserId: "user-1", WordId: "word-b", QueryCount: 5, Note: "a note"}}
+	if err := s.MergeWords("word-a", "word-b", merged); err != nil {
+		t.Fatalf("merge words: %v", err)
+	}
+	if len(fake.userDicts) != 1 {
+		t.Fatalf("unexpected user dicts: %v", fake.userDicts)
+	}
+	if ud := fake.userDicts["user-1/word-b"]; ud == nil || ud.QueryCount != 5 || ud.Note != "a note" {
+		t.Errorf("merged user dict: %+v", ud)
+	}
+}
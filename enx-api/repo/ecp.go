@@ -1,33 +1,79 @@
 package repo
 
 import (
+	"encoding/json"
+	"enx-api/language"
 	"enx-api/utils/logger"
 	"enx-api/utils/sqlitex"
+	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Word struct {
-	Id             string    `gorm:"column:id;primaryKey"` // UUID
+	Id             string    `gorm:"column:id;primaryKey"`   // UUID
+	SourceLanguage string    `gorm:"column:source_language"` // the language of English, see language.Sources
 	English        string    `gorm:"column:english"`
 	LoadCount      int       `gorm:"column:load_count;default:0"`
 	Chinese        string    `gorm:"column:chinese"`
 	Pronunciation  string    `gorm:"column:pronunciation"`
-	CreatedAt      int64     `gorm:"column:created_at"` // Unix milliseconds
-	UpdatedAt      int64     `gorm:"column:updated_at"` // Unix milliseconds
-	DeletedAt      *int64    `gorm:"column:deleted_at"` // NULL or Unix milliseconds
-	CreateDatetime time.Time `gorm:"-"`                 // For compatibility
-	UpdateDatetime time.Time `gorm:"-"`                 // For compatibility
+	CreatedAt      int64     `gorm:"column:created_at"`     // Unix milliseconds
+	UpdatedAt      int64     `gorm:"column:updated_at"`     // Unix milliseconds
+	DeletedAt      *int64    `gorm:"column:deleted_at"`     // NULL or Unix milliseconds
+	FrequencyRank  int       `gorm:"column:frequency_rank"` // 0 = not in the word frequency list
+	Glosses        string    `gorm:"column:glosses"`        // JSON object, target language -> translation, see Gloss
+	CreateDatetime time.Time `gorm:"-"`                     // For compatibility
+	UpdateDatetime time.Time `gorm:"-"`                     // For compatibility
 }
 
 func (Word) TableName() string {
 	return "words"
 }
 
+// GlossMap decodes Glosses, the zh-CHS gloss of words predating glosses is their Chinese
+func (w *Word) GlossMap() map[string]string {
+	glosses := map[string]string{}
+	if w.Glosses != "" {
+		if err := json.Unmarshal([]byte(w.Glosses), &glosses); err != nil {
+			logger.Errorf("invalid glosses of word %s: %v", w.Id, err)
+		}
+	}
+	if _, ok := glosses[language.Chinese]; !ok && w.Chinese != "" {
+		glosses[language.Chinese] = w.Chinese
+	}
+	return glosses
+}
+
+// Gloss returns the translation in a target language, empty when the word wasn't translated into it
+func (w *Word) Gloss(lang string) string {
+	return w.GlossMap()[lang]
+}
+
+// SetGloss sets the translation in a target language, an empty one removes it.
+// The zh-CHS one is mirrored in Chinese.
+func (w *Word) SetGloss(lang, gloss string) {
+	glosses := w.GlossMap()
+	if gloss == "" {
+		delete(glosses, lang)
+	} else {
+		glosses[lang] = gloss
+	}
+	if lang == language.Chinese {
+		w.Chinese = gloss
+	}
+	data, _ := json.Marshal(glosses)
+	w.Glosses = string(data)
+}
+
 type UserDict struct {
 	UserId            string    `gorm:"column:user_id;primaryKey"`
 	WordId            string    `gorm:"column:word_id;primaryKey"`
 	QueryCount        int       `gorm:"column:query_count;default:0"`
 	AlreadyAcquainted int       `gorm:"column:already_acquainted;default:0"`
+	Note              string    `gorm:"column:note"`
+	Tags              string    `gorm:"column:tags"`        // comma-separated, see NormalizeTags
+	Translation       string    `gorm:"column:translation"` // preferred over words.chinese for this user
 	CreatedAt         int64     `gorm:"column:created_at"`
 	UpdatedAt         int64     `gorm:"column:updated_at"`
 	UpdateTime        time.Time `gorm:"-"` // For compatibility
@@ -37,18 +83,19 @@ func (UserDict) TableName() string {
 	return "user_dicts"
 }
 
-type YoudaoQueryHistory struct {
-	English string
-	Result  string
-	Exist   int
-}
-
 // GetWordByEnglish get word id by english
 func GetWordByEnglish(english string) *Word {
+	return GetWord(language.English, english)
+}
+
+// GetWord finds a word of a source language by its key, see language.Key, so that case and the
+// way accents are typed don't matter
+func GetWord(lang, text string) *Word {
+	lang = sourceLanguage(lang)
 	word := &Word{}
-	err := sqlitex.DB.Where("LOWER(english) = LOWER(?) AND deleted_at IS NULL", english).First(word).Error
+	err := whereWordKey(sqlitex.DB, lang, text).First(word).Error
 	if err != nil {
-		logger.Debugf("word not found: %s, error: %v", english, err)
+		logger.Debugf("word not found: %s, language: %s, error: %v", text, lang, err)
 		return &Word{} // Return empty word for compatibility
 	}
 
@@ -64,9 +111,24 @@ func GetWordByEnglish(english string) *Word {
 	return word
 }
 
-func GetWordByEnglishCaseSensitive(english string) *Word {
+// whereWordKey filters the words of a language with the key of text. Only the usual spellings and
+// the ASCII case variants of text are passed to word_key, a Go function, rather than every word.
+func whereWordKey(db *gorm.DB, lang, text string) *gorm.DB {
+	return db.Where("source_language = ? AND (LOWER(english) = LOWER(?) OR english IN ?) AND word_key(source_language, english) = ? AND deleted_at IS NULL",
+		lang, text, language.Spellings(lang, text), language.Key(lang, text))
+}
+
+// sourceLanguage is the language of words looked up without one, the only one before others existed
+func sourceLanguage(lang string) string {
+	if lang == "" {
+		return language.DefaultSource
+	}
+	return lang
+}
+
+func GetWordByEnglishCaseSensitive(lang, english string) *Word {
 	word := &Word{}
-	err := sqlitex.DB.Where("english = ? AND deleted_at IS NULL", english).First(word).Error
+	err := sqlitex.DB.Where("source_language = ? AND english = ? AND deleted_at IS NULL", sourceLanguage(lang), english).First(word).Error
 	if err != nil {
 		logger.Debugf("word not found (case sensitive): %s, error: %v", english, err)
 		return &Word{}
@@ -83,48 +145,45 @@ func GetWordByEnglishCaseSensitive(english string) *Word {
 	return word
 }
 
-// GetUserWordQueryCount get user word query count via GORM
-func GetUserWordQueryCount(wordId, userId string) (int, int) {
-	userDict := &UserDict{}
-	err := sqlitex.DB.Where("user_id = ? AND word_id = ?", userId, wordId).First(userDict).Error
+// GetUserWordQueryCount get user word query count from the storage backend, both are 0 when the
+// user has no record of the word. A failed read is returned, it doesn't count as no record.
+func GetUserWordQueryCount(wordId, userId string) (queryCount, alreadyAcquainted int, err error) {
+	userDict, err := store.GetUserDict(userId, wordId)
+	if errors.Is(err, ErrNotFound) {
+		logger.Debugf("user dict not found: user_id=%s, word_id=%s", userId, wordId)
+		return 0, 0, nil
+	}
 	if err != nil {
-		logger.Debugf("user dict not found: user_id=%s, word_id=%s, error: %v", userId, wordId, err)
-		return 0, 0
+		return 0, 0, err
 	}
 
-	return userDict.QueryCount, userDict.AlreadyAcquainted
+	return userDict.QueryCount, userDict.AlreadyAcquainted, nil
 }
 
-// UpsertUserDict creates or updates user dictionary entry via GORM
+// UpsertUserDict creates or updates user dictionary entry via the storage backend.
+// The user's note, tags and translation of the word are kept.
 func UpsertUserDict(userId, wordId string, queryCount, alreadyAcquainted int) error {
-	now := time.Now().UnixMilli()
 	userDict := &UserDict{
 		UserId:            userId,
 		WordId:            wordId,
 		QueryCount:        queryCount,
 		AlreadyAcquainted: alreadyAcquainted,
-		UpdatedAt:         now,
 	}
-
-	// Check if record exists
-	var existing UserDict
-	err := sqlitex.DB.Where("user_id = ? AND word_id = ?", userId, wordId).First(&existing).Error
-	if err != nil {
-		// Record doesn't exist, create it
-		userDict.CreatedAt = now
-		return sqlitex.DB.Create(userDict).Error
+	existing, err := store.GetUserDict(userId, wordId)
+	switch {
+	case err == nil:
+		userDict.Note = existing.Note
+		userDict.Tags = existing.Tags
+		userDict.Translation = existing.Translation
+	case !errors.Is(err, ErrNotFound):
+		// the annotations would be lost
+		return err
 	}
-
-	// Record exists, update it
-	return sqlitex.DB.Model(&UserDict{}).Where("user_id = ? AND word_id = ?", userId, wordId).Updates(map[string]interface{}{
-		"query_count":        queryCount,
-		"already_acquainted": alreadyAcquainted,
-		"updated_at":         now,
-	}).Error
+	return store.UpsertUserDict(userDict)
 }
 
-func Translate(key string, userId string) Word {
-	word := GetWordByEnglish(key)
+func Translate(lang, key string, userId string) Word {
+	word := GetWord(lang, key)
 	if word.Id != "" {
 		logger.Debugf("find word via GORM, id: %s, english: %s, user_id: %s", word.Id, key, userId)
 		return *word
@@ -134,41 +193,15 @@ func Translate(key string, userId string) Word {
 	return Word{} // Return empty word
 }
 
-// IsYouDaoRecordExist check if youdao dict response exist in db
-func IsYouDaoRecordExist(words string) (bool, bool, *YoudaoQueryHistory) {
-	cacheHit := false
-	youdaoCanHandleThisWord := false
-	yd := YoudaoQueryHistory{}
-	sqlitex.DB.Where("english=?", words).Find(&yd)
-
-	if yd.English != "" {
-		cacheHit = true
-	}
-	if cacheHit && yd.Exist == 1 {
-		youdaoCanHandleThisWord = true
-	}
-	logger.Debugf("check if youdao record exist, english: %s, cache hit: %v, youdao has this word: %v", words, cacheHit, youdaoCanHandleThisWord)
-	return cacheHit, youdaoCanHandleThisWord, &yd
-}
-
-// SaveYouDaoDictResponse save youdao dict response to db
-func SaveYouDaoDictResponse(word, response string, exist int) {
-	yqh := YoudaoQueryHistory{}
-	yqh.English = word
-	yqh.Result = response
-	yqh.Exist = exist
-	sqlitex.DB.Create(&yqh)
-}
-
-func CountByEnglish(english string) int {
+func CountByEnglish(lang, english string) int {
 	var count int64
-	sqlitex.DB.Model(&Word{}).Where("LOWER(english) = LOWER(?) AND deleted_at IS NULL", english).Count(&count)
+	whereWordKey(sqlitex.DB.Model(&Word{}), sourceLanguage(lang), english).Count(&count)
 	logger.Debugf("count by english via GORM, word: %s, count: %d", english, count)
 	return int(count)
 }
 
 func DeleteDuplicateWord(english string, excludeId string) {
 	// This function is no longer needed with UUID-based P2P system
-	// Duplicates are prevented by unique constraint on english field
+	// Duplicates are prevented by the unique index on source_language and english
 	logger.Debugf("DeleteDuplicateWord called but skipped (P2P system prevents duplicates), english: %s, excluding id: %s", english, excludeId)
 }
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"encoding/json"
+	"enx-api/dictionary"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DictionaryEntry is a structured entry stored as JSON, local to this node
+type DictionaryEntry struct {
+	Key       string `gorm:"column:key;primaryKey"` // lower-cased word
+	Source    string `gorm:"column:source"`
+	Entry     string `gorm:"column:entry"`
+	UpdatedAt int64  `gorm:"column:updated_at"` // Unix milliseconds
+}
+
+func (DictionaryEntry) TableName() string {
+	return "dictionary_entries"
+}
+
+func entryKey(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// GetDictionaryEntry returns the stored entry of a word, case-insensitive, or nil if there is none
+func GetDictionaryEntry(word string) (*dictionary.Entry, error) {
+	stored := &DictionaryEntry{}
+	err := sqlitex.DB.Where("key = ?", entryKey(word)).First(stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := dictionary.New(word, stored.Source)
+	if err := json.Unmarshal([]byte(stored.Entry), entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SaveDictionaryEntry stores the entry of a word, replacing the one stored before
+func SaveDictionaryEntry(entry *dictionary.Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return sqlitex.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&DictionaryEntry{
+		Key:       entryKey(entry.Word),
+		Source:    entry.Source,
+		Entry:     string(value),
+		UpdatedAt: time.Now().UnixMilli(),
+	}).Error
+}
+
+// DeleteDictionaryEntry removes the stored entry of a word, it is fetched again on the next request
+func DeleteDictionaryEntry(word string) error {
+	return sqlitex.DB.Where("key = ?", entryKey(word)).Delete(&DictionaryEntry{}).Error
+}
@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	pb "enx-api/proto"
+	"enx-api/utils/logger"
+	"enx-api/utils/schedule"
+	"enx-api/utils/sqlitex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// EventLookup is a word looked up by a user
+	EventLookup = "lookup"
+	// EventMark is a word marked as acquainted
+	EventMark = "mark"
+	// EventUnmark is a word marked as not acquainted again
+	EventUnmark = "unmark"
+)
+
+// LookupEvent is one entry of the append-only per-user lookup and mark log
+type LookupEvent struct {
+	Seq       int64  `gorm:"column:seq;primaryKey;autoIncrement"`
+	Id        string `gorm:"column:id"` // UUID, the same on every node
+	UserId    string `gorm:"column:user_id"`
+	WordId    string `gorm:"column:word_id"`
+	Kind      string `gorm:"column:kind"`       // EventLookup, EventMark or EventUnmark
+	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
+	Source    string `gorm:"column:source"`     // extension, ui or api
+	PageHost  string `gorm:"column:page_host"`  // host of the page the word was looked up on
+}
+
+func (LookupEvent) TableName() string {
+	return "lookup_events"
+}
+
+// RecordLookupEvent appends an event to the log. Failures are logged and not returned,
+// a missing event only skews history and statistics and must not fail the lookup itself.
+func RecordLookupEvent(event *LookupEvent) {
+	if event.UserId == "" || event.WordId == "" {
+		return
+	}
+	if err := store.AppendLookupEvent(event); err != nil {
+		logger.Errorf("failed to record lookup event, user id: %s, word id: %s, kind: %s, error: %v",
+			event.UserId, event.WordId, event.Kind, err)
+	}
+}
+
+// PruneLookupEvents deletes events older than retention and returns how many were removed.
+// Pruning is local to this node, peers keep their events until their own retention passes.
+func PruneLookupEvents(retention time.Duration) (int64, error) {
+	return store.PruneLookupEvents(time.Now().Add(-retention).UnixMilli())
+}
+
+// StartHistoryJanitor prunes events older than retention, see schedule.Every
+func StartHistoryJanitor(retention, interval time.Duration) (stop func(), err error) {
+	return schedule.Every(interval, func() {
+		count, err := PruneLookupEvents(retention)
+		if err != nil {
+			logger.Errorf("history janitor failed to prune lookup events: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Infof("history janitor pruned %d lookup events", count)
+		}
+	})
+}
+
+func (s *sqliteStore) AppendLookupEvent(event *LookupEvent) error {
+	if event.Id == "" {
+		event.Id = uuid.NewString()
+	}
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().UnixMilli()
+	}
+	return sqlitex.DB.Create(event).Error
+}
+
+func (s *sqliteStore) PruneLookupEvents(before int64) (int64, error) {
+	result := sqlitex.DB.Where("created_at < ?", before).Delete(&LookupEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *DataServiceStore) AppendLookupEvent(event *LookupEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.AppendLookupEvent(ctx, &pb.AppendLookupEventRequest{Event: &pb.LookupEvent{
+		Id:        event.Id,
+		UserId:    event.UserId,
+		WordId:    event.WordId,
+		Kind:      event.Kind,
+		CreatedAt: event.CreatedAt,
+		Source:    event.Source,
+		PageHost:  event.PageHost,
+	}})
+	if err != nil {
+		return err
+	}
+	event.Id = resp.Event.Id
+	event.CreatedAt = resp.Event.CreatedAt
+	event.Seq = resp.Event.Seq
+	return nil
+}
+
+func (s *DataServiceStore) PruneLookupEvents(before int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataServiceTimeout)
+	defer cancel()
+
+	resp, err := s.client.PruneLookupEvents(ctx, &pb.PruneLookupEventsRequest{Before: before})
+	if err != nil {
+		logger.Errorf("data service prune lookup events failed, before: %d, error: %v", before, err)
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
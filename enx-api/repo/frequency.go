@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/utils/logger"
+	"enx-api/utils/schedule"
+	"enx-api/utils/sqlitex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// words.frequency_rank is derived from the local word frequency list and not replicated by enx-sync,
+// since peers may rank with a different list. It is written through the store like the rest of the
+// word, by enx-sync with the data-service backend, and never moves updated_at.
+
+const frequencyBatchSize = 500
+
+// frequencyRank ranks a word against the current word list, which is an English one
+func frequencyRank(word *Word) int {
+	if sourceLanguage(word.SourceLanguage) != language.English {
+		return 0
+	}
+	return frequency.Rank(word.English)
+}
+
+// RefreshFrequencyRanks re-ranks all words against the current word list, e.g. after frequency.file
+// changed or words arrived from peers, and returns how many ranks changed
+func RefreshFrequencyRanks() (int, error) {
+	list := frequency.Current()
+	changed := 0
+	var words []Word
+	err := sqlitex.DB.Select("id", "source_language", "english", "frequency_rank").
+		FindInBatches(&words, frequencyBatchSize, func(tx *gorm.DB, batch int) error {
+			ranks := map[string]int{}
+			for _, word := range words {
+				rank := 0
+				if sourceLanguage(word.SourceLanguage) == language.English {
+					rank = list.Rank(word.English)
+				}
+				if rank != word.FrequencyRank {
+					ranks[word.Id] = rank
+				}
+			}
+			if len(ranks) == 0 {
+				return nil
+			}
+			if err := store.SetFrequencyRanks(ranks); err != nil {
+				return err
+			}
+			changed += len(ranks)
+			return nil
+		}).Error
+	return changed, err
+}
+
+// StartFrequencyRefresher re-ranks words right away and then every interval, see schedule.Now
+func StartFrequencyRefresher(interval time.Duration) (stop func(), err error) {
+	return schedule.Now(interval, func() {
+		count, err := RefreshFrequencyRanks()
+		if err != nil {
+			logger.Errorf("failed to refresh word frequency ranks: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Infof("refreshed frequency rank of %d words", count)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HighlightPolicy decides which words of a paragraph are highlighted for a user.
+// It is kept on this node only, like sessions.
+type HighlightPolicy struct {
+	UserId string `gorm:"column:user_id;primaryKey"`
+	// MaxRank hides words ranked beyond it in the word frequency list, 0: no limit
+	MaxRank int `gorm:"column:max_rank"`
+	// AlwaysTags are comma-separated tags whose words are always highlighted, see NormalizeTags
+	AlwaysTags string `gorm:"column:always_tags"`
+	// 0: false, 1: true
+	HideProperNouns int   `gorm:"column:hide_proper_nouns"`
+	MinQueryCount   int   `gorm:"column:min_query_count"`
+	UpdatedAt       int64 `gorm:"column:updated_at"` // Unix milliseconds
+}
+
+func (HighlightPolicy) TableName() string {
+	return "highlight_policies"
+}
+
+// DefaultHighlightPolicy highlights every word looked up at least once and not acquainted yet,
+// which is what clients did before policies existed
+func DefaultHighlightPolicy(userId string) *HighlightPolicy {
+	return &HighlightPolicy{UserId: userId, MinQueryCount: 1}
+}
+
+// GetHighlightPolicy returns the user's policy, or the default one if they never saved one
+func GetHighlightPolicy(userId string) (*HighlightPolicy, error) {
+	policy := &HighlightPolicy{}
+	err := sqlitex.DB.Where("user_id = ?", userId).First(policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultHighlightPolicy(userId), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SaveHighlightPolicy replaces the user's policy, AlwaysTags have to be normalized already
+func SaveHighlightPolicy(policy *HighlightPolicy) error {
+	policy.UpdatedAt = time.Now().UnixMilli()
+	return sqlitex.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(policy).Error
+}
+
+// DeleteHighlightPolicy resets the user's policy to the default one
+func DeleteHighlightPolicy(userId string) error {
+	return sqlitex.DB.Where("user_id = ?", userId).Delete(&HighlightPolicy{}).Error
+}
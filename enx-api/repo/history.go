@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"enx-api/utils/sqlitex"
+)
+
+// HistoryEntry is a lookup event with the word it refers to
+type HistoryEntry struct {
+	Id        string `json:"id"`
+	WordId    string `json:"word_id"`
+	English   string `json:"english"` // empty while the word hasn't been synced to this node yet
+	Chinese   string `json:"chinese"`
+	Kind      string `json:"kind"`
+	Source    string `json:"source"`
+	PageHost  string `json:"page_host"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// HistoryQuery filters and pages a user's lookup history, zero values don't filter
+type HistoryQuery struct {
+	// English only keeps events of this word, case-insensitive
+	English  string
+	Kind     string
+	Source   string
+	PageHost string
+	// From and To bound created_at (Unix milliseconds), both inclusive
+	From int64
+	To   int64
+	// Cursor is the next_cursor of the previous page, empty for the first page
+	Cursor string
+	Limit  int
+}
+
+// historyCursor is the position after the last event of a page, newest first
+type historyCursor struct {
+	CreatedAt int64  `json:"c"`
+	Id        string `json:"i"`
+}
+
+func (c *historyCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeHistoryCursor(s string) (*historyCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &historyCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ListHistory returns a page of a user's lookup events, newest first, and the cursor of the
+// next page, which is empty on the last page
+func ListHistory(userId string, q HistoryQuery) ([]HistoryEntry, string, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	query := sqlitex.DB.Table("lookup_events").
+		Select("lookup_events.id, lookup_events.word_id, COALESCE(words.english, '') AS english, "+
+			"COALESCE(words.chinese, '') AS chinese, lookup_events.kind, lookup_events.source, "+
+			"lookup_events.page_host, lookup_events.created_at").
+		Joins("LEFT JOIN words ON words.id = lookup_events.word_id").
+		Where("lookup_events.user_id = ?", userId)
+	if q.English != "" {
+		query = query.Where("LOWER(words.english) = LOWER(?)", q.English)
+	}
+	if q.Kind != "" {
+		query = query.Where("lookup_events.kind = ?", q.Kind)
+	}
+	if q.Source != "" {
+		query = query.Where("lookup_events.source = ?", q.Source)
+	}
+	if q.PageHost != "" {
+		query = query.Where("lookup_events.page_host = ?", q.PageHost)
+	}
+	if q.From > 0 {
+		query = query.Where("lookup_events.created_at >= ?", q.From)
+	}
+	if q.To > 0 {
+		query = query.Where("lookup_events.created_at <= ?", q.To)
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeHistoryCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("lookup_events.created_at < ? OR (lookup_events.created_at = ? AND lookup_events.id < ?)",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.Id)
+	}
+
+	// one extra row tells whether there is a next page
+	var entries []HistoryEntry
+	if err := query.Order("lookup_events.created_at DESC, lookup_events.id DESC").
+		Limit(q.Limit + 1).Scan(&entries).Error; err != nil {
+		return nil, "", err
+	}
+	if len(entries) <= q.Limit {
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		return entries, "", nil
+	}
+
+	entries = entries[:q.Limit]
+	last := entries[len(entries)-1]
+	next := &historyCursor{CreatedAt: last.CreatedAt, Id: last.Id}
+	return entries, next.encode(), nil
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"enx-api/language"
+	"enx-api/utils/logger"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserLanguages are the languages of a user, kept on this node only like highlight policies
+type UserLanguages struct {
+	UserId string `gorm:"column:user_id;primaryKey"`
+	// NativeLanguage is the target language lookups are translated into, see language.Targets
+	NativeLanguage string `gorm:"column:native_language"`
+	// StudyLanguages are the source languages the user reads, comma-separated, see language.Sources.
+	// Words are in the first one unless a lookup says otherwise.
+	StudyLanguages string `gorm:"column:study_languages"`
+	UpdatedAt      int64  `gorm:"column:updated_at"` // Unix milliseconds
+}
+
+func (UserLanguages) TableName() string {
+	return "user_languages"
+}
+
+// GetUserLanguages returns the user's languages, the defaults if they never saved any
+func GetUserLanguages(userId string) (*UserLanguages, error) {
+	languages := &UserLanguages{}
+	err := sqlitex.DB.Where("user_id = ?", userId).First(languages).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &UserLanguages{UserId: userId, NativeLanguage: language.Default, StudyLanguages: language.DefaultSource}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// GetNativeLanguage returns the language the user's lookups are translated into,
+// the default one when it can't be read
+func GetNativeLanguage(userId string) string {
+	languages, err := GetUserLanguages(userId)
+	if err != nil {
+		logger.Errorf("failed to get user languages, using the default, user id: %s, error: %v", userId, err)
+		return language.Default
+	}
+	return languages.NativeLanguage
+}
+
+// Studies splits StudyLanguages, the default source language when there are none
+func (l *UserLanguages) Studies() []string {
+	var studies []string
+	for _, lang := range strings.Split(l.StudyLanguages, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			studies = append(studies, lang)
+		}
+	}
+	if len(studies) == 0 {
+		return []string{language.DefaultSource}
+	}
+	return studies
+}
+
+// GetStudyLanguages returns the source languages the user reads, never empty,
+// the default one when they can't be read
+func GetStudyLanguages(userId string) []string {
+	languages, err := GetUserLanguages(userId)
+	if err != nil {
+		logger.Errorf("failed to get user languages, using the default, user id: %s, error: %v", userId, err)
+		return []string{language.DefaultSource}
+	}
+	return languages.Studies()
+}
+
+// GetSourceLanguage returns the language the words of a lookup are in: the requested one when it is
+// supported, e.g. the lang of the page the word is on, the first language the user studies otherwise
+func GetSourceLanguage(userId, requested string) string {
+	if lang, ok := language.ParseSource(requested); ok {
+		return lang
+	}
+	return GetStudyLanguages(userId)[0]
+}
+
+// SaveUserLanguages replaces the user's languages, which have to be valid codes already
+func SaveUserLanguages(languages *UserLanguages) error {
+	languages.UpdatedAt = time.Now().UnixMilli()
+	return sqlitex.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(languages).Error
+}
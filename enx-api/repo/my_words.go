@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	// SortRecent lists the most recently looked up words first
+	SortRecent = "recent"
+	// SortCount lists the most looked up words first
+	SortCount = "count"
+	// SortAlpha lists words alphabetically
+	SortAlpha = "alpha"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort, allowed: recent, count, alpha")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// MyWordsQuery filters, sorts and pages a user's words, zero values don't filter
+type MyWordsQuery struct {
+	Sort       string
+	Acquainted *bool
+	Tag        string
+	MinCount   int
+	// From and To bound updated_at (Unix milliseconds), both inclusive
+	From   int64
+	To     int64
+	Prefix string
+	// Cursor is the next_cursor of the previous page, empty for the first page
+	Cursor string
+	Limit  int
+}
+
+// wordsCursor is the position after the last row of a page, in the sort order it was made for
+type wordsCursor struct {
+	Sort   string `json:"s"`
+	Number int64  `json:"n,omitempty"` // updated_at or query_count
+	Text   string `json:"t,omitempty"` // english
+	WordId string `json:"w"`
+}
+
+func (c *wordsCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeWordsCursor(s, sort string) (*wordsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &wordsCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.Sort != sort || c.WordId == "" {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ListMyWords returns a page of a user's words and the cursor of the next page,
+// which is empty on the last page
+func ListMyWords(userId string, q MyWordsQuery) ([]UserWord, string, error) {
+	if q.Sort == "" {
+		q.Sort = SortRecent
+	}
+	if q.Sort != SortRecent && q.Sort != SortCount && q.Sort != SortAlpha {
+		return nil, "", ErrInvalidSort
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	query := userWordsQuery(userId)
+	if q.Acquainted != nil {
+		acquainted := 0
+		if *q.Acquainted {
+			acquainted = 1
+		}
+		query = query.Where("user_dicts.already_acquainted = ?", acquainted)
+	}
+	if tag := NormalizeTags([]string{q.Tag}); tag != "" {
+		query = query.Where("instr(',' || user_dicts.tags || ',', ?) > 0", ","+tag+",")
+	}
+	if q.MinCount > 0 {
+		query = query.Where("user_dicts.query_count >= ?", q.MinCount)
+	}
+	if q.From > 0 {
+		query = query.Where("user_dicts.updated_at >= ?", q.From)
+	}
+	if q.To > 0 {
+		query = query.Where("user_dicts.updated_at <= ?", q.To)
+	}
+	if q.Prefix != "" {
+		query = query.Where(`words.english LIKE ? ESCAPE '\'`, escapeLike(q.Prefix)+"%")
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeWordsCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+		switch q.Sort {
+		case SortRecent:
+			query = query.Where("user_dicts.updated_at < ? OR (user_dicts.updated_at = ? AND user_dicts.word_id < ?)",
+				cursor.Number, cursor.Number, cursor.WordId)
+		case SortCount:
+			query = query.Where("user_dicts.query_count < ? OR (user_dicts.query_count = ? AND user_dicts.word_id < ?)",
+				cursor.Number, cursor.Number, cursor.WordId)
+		case SortAlpha:
+			query = query.Where("words.english > ? OR (words.english = ? AND user_dicts.word_id > ?)",
+				cursor.Text, cursor.Text, cursor.WordId)
+		}
+	}
+
+	switch q.Sort {
+	case SortRecent:
+		query = query.Order("user_dicts.updated_at DESC, user_dicts.word_id DESC")
+	case SortCount:
+		query = query.Order("user_dicts.query_count DESC, user_dicts.word_id DESC")
+	case SortAlpha:
+		query = query.Order("words.english ASC, user_dicts.word_id ASC")
+	}
+
+	// one extra row tells whether there is a next page
+	var rows []userWordRow
+	if err := query.Limit(q.Limit + 1).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	if len(rows) <= q.Limit {
+		return toUserWords(rows), "", nil
+	}
+
+	rows = rows[:q.Limit]
+	last := rows[len(rows)-1]
+	next := &wordsCursor{Sort: q.Sort, WordId: last.WordId}
+	switch q.Sort {
+	case SortRecent:
+		next.Number = last.UpdatedAt
+	case SortCount:
+		next.Number = int64(last.QueryCount)
+	case SortAlpha:
+		next.Text = last.English
+	}
+	return toUserWords(rows), next.encode(), nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// UserWord is a word looked up, marked or annotated by a user, as exported
+type UserWord struct {
+	WordId            string   `json:"word_id"`
+	English           string   `json:"english"`
+	Chinese           string   `json:"chinese"`
+	Pronunciation     string   `json:"pronunciation"`
+	QueryCount        int      `json:"query_count"`
+	AlreadyAcquainted int      `json:"already_acquainted"`
+	Note              string   `json:"note"`
+	Tags              []string `json:"tags"`
+	Translation       string   `json:"translation"`
+	UpdatedAt         int64    `json:"updated_at"`
+}
+
+// userWordRow is UserWord as scanned, with tags still comma-separated
+type userWordRow struct {
+	WordId            string
+	English           string
+	Chinese           string
+	Pronunciation     string
+	QueryCount        int
+	AlreadyAcquainted int
+	Note              string
+	Tags              string
+	Translation       string
+	UpdatedAt         int64
+}
+
+// UserWordFilter narrows ListUserWords, zero values don't filter
+type UserWordFilter struct {
+	// Tag only keeps words carrying this tag
+	Tag string
+	// Annotated only keeps words with a note, tag or translation
+	Annotated bool
+}
+
+// GetUserDict returns the user_dicts record of a user and word from the storage backend
+func GetUserDict(userId, wordId string) (*UserDict, error) {
+	return store.GetUserDict(userId, wordId)
+}
+
+// SplitTags turns the stored comma-separated tags into a list
+func SplitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
+
+// NormalizeTags lower-cases and trims tags, drops empty and duplicate ones,
+// and joins them with commas for storage. Commas inside a tag become dashes.
+func NormalizeTags(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, ",", "-")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return strings.Join(normalized, ",")
+}
+
+// SaveUserWordNote sets the note, tags and translation a user keeps for a word.
+// Query count and acquainted state are left as they are; empty values clear the annotation.
+func SaveUserWordNote(userId, wordId, note string, tags []string, translation string) (*UserDict, error) {
+	userDict, err := store.GetUserDict(userId, wordId)
+	if errors.Is(err, ErrNotFound) {
+		userDict = &UserDict{UserId: userId, WordId: wordId}
+	} else if err != nil {
+		return nil, err
+	}
+	userDict.Note = strings.TrimSpace(note)
+	userDict.Tags = NormalizeTags(tags)
+	userDict.Translation = strings.TrimSpace(translation)
+	if err := store.UpsertUserDict(userDict); err != nil {
+		return nil, err
+	}
+	return userDict, nil
+}
+
+// ListUserWords returns the words in a user's dictionary ordered by english
+func ListUserWords(userId string, filter UserWordFilter) ([]UserWord, error) {
+	query := userWordsQuery(userId)
+	if tag := NormalizeTags([]string{filter.Tag}); tag != "" {
+		query = query.Where("instr(',' || user_dicts.tags || ',', ?) > 0", ","+tag+",")
+	}
+	if filter.Annotated {
+		query = query.Where("user_dicts.note != '' OR user_dicts.tags != '' OR user_dicts.translation != ''")
+	}
+
+	var rows []userWordRow
+	if err := query.Order("words.english").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return toUserWords(rows), nil
+}
+
+// userWordsQuery selects a user's user_dicts joined with their words as userWordRow
+func userWordsQuery(userId string) *gorm.DB {
+	return sqlitex.DB.Table("user_dicts").
+		Select("words.id AS word_id, words.english, words.chinese, words.pronunciation, "+
+			"user_dicts.query_count, user_dicts.already_acquainted, user_dicts.note, user_dicts.tags, "+
+			"user_dicts.translation, user_dicts.updated_at").
+		Joins("JOIN words ON words.id = user_dicts.word_id").
+		Where("user_dicts.user_id = ? AND words.deleted_at IS NULL", userId)
+}
+
+func toUserWords(rows []userWordRow) []UserWord {
+	words := make([]UserWord, len(rows))
+	for i, row := range rows {
+		words[i] = UserWord{
+			WordId:            row.WordId,
+			English:           row.English,
+			Chinese:           row.Chinese,
+			Pronunciation:     row.Pronunciation,
+			QueryCount:        row.QueryCount,
+			AlreadyAcquainted: row.AlreadyAcquainted,
+			Note:              row.Note,
+			Tags:              SplitTags(row.Tags),
+			Translation:       row.Translation,
+			UpdatedAt:         row.UpdatedAt,
+		}
+	}
+	return words
+}
@@ -16,6 +16,6 @@ func Test0(t *testing.T) {
 	// Translate("foo")
 	utils.ViperInit()
 	sqlitex.Init()
-	cacheHit, youdaoCanHandleThisWorld, _ := IsYouDaoRecordExist("foo")
-	fmt.Println(cacheHit, youdaoCanHandleThisWorld)
+	entry, err := GetCachedTranslation("youdao-api", "foo")
+	fmt.Println(entry, err)
 }
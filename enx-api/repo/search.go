@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"enx-api/language"
+	"enx-api/utils/sqlitex"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// reverseScripts are the scripts of queries searched among meanings rather than words
+var reverseScripts = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul}
+
+// IsReverseSearch tells whether a query is a meaning to search words by, e.g. 港口 for harbor
+func IsReverseSearch(query string) bool {
+	return strings.IndexFunc(query, func(r rune) bool { return unicode.IsOneOf(reverseScripts, r) }) >= 0
+}
+
+// SearchWordList finds words of a source language starting with query, or, for a query in Chinese
+// or another script of glosses, whose meaning starts with it. Searching words_fts, accents and case
+// don't matter. An exact match comes first, then words ranked by frequency, then by relevance.
+func SearchWordList(lang, query string, limit int) ([]string, error) {
+	query = strings.TrimSpace(language.Clean(query))
+	if query == "" {
+		return []string{}, nil
+	}
+	columns := "english"
+	if IsReverseSearch(query) {
+		columns = "chinese glosses"
+	}
+	// a phrase whose last word is a prefix, quoted so that the query, cleaned of quotes, isn't FTS5 syntax
+	match := "{" + columns + "} : \"" + query + "\"*"
+
+	words := []string{}
+	err := sqlitex.DB.Raw(`SELECT w.english FROM words_fts JOIN words w ON w.rowid = words_fts.rowid
+		WHERE words_fts MATCH ? AND w.source_language = ? AND w.deleted_at IS NULL
+		ORDER BY w.english = ? COLLATE NOCASE DESC, w.frequency_rank = 0, w.frequency_rank,
+			bm25(words_fts, 10.0, 5.0, 1.0), length(w.english), w.english
+		LIMIT ?`, match, sourceLanguage(lang), query, limit).Scan(&words).Error
+	return words, err
+}
+
+// translatedWordsBatchSize is how many words TranslatedWords reads at once
+const translatedWordsBatchSize = 1000
+
+// TranslatedWords calls fn with batches of the words, not phrases, that have a translation
+func TranslatedWords(fn func(words []Word) error) error {
+	var words []Word
+	return sqlitex.DB.Select("id", "source_language", "english", "frequency_rank", "load_count").
+		Where("deleted_at IS NULL AND english NOT LIKE '% %' AND (COALESCE(chinese, '') != '' OR glosses NOT IN ('', '{}'))").
+		FindInBatches(&words, translatedWordsBatchSize, func(tx *gorm.DB, batch int) error {
+			return fn(words)
+		}).Error
+}
@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/utils/sqlitex"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupSearchTest(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	list, err := frequency.Parse(strings.NewReader("the\nhave\nharbor\nhats\nhat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := frequency.Current()
+	frequency.SetList(list)
+	t.Cleanup(func() { frequency.SetList(previous) })
+
+	words := []*Word{
+		{English: "harbor", Chinese: "n. 港口；避风港"},
+		{English: "harbinger", Chinese: "n. 先驱；预兆"},
+		{English: "hat", Chinese: "n. 帽子"},
+		{English: "hats", Chinese: "n. 帽子（hat 的复数）"},
+		{English: "have", Chinese: "v. 有"},
+		{English: "port", Chinese: "n. 港口；端口", Glosses: `{"ja":"ポート"}`},
+		{English: "haven", Chinese: "n. 避难所"},
+		{English: "ice cream", Chinese: "冰淇淋"},
+		{English: "Über", SourceLanguage: language.German, Chinese: "在……之上"},
+		{English: "Hafen", SourceLanguage: language.German, Chinese: "港口"},
+	}
+	for _, word := range words {
+		if err := CreateWord(word); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchWordList(t *testing.T) {
+	setupSearchTest(t)
+
+	tests := []struct {
+		lang, query string
+		want        []string
+	}{
+		// ranked words first, in the order of the list
+		{language.English, "ha", []string{"have", "harbor", "hats", "hat", "haven", "harbinger"}},
+		{language.English, "HARB", []string{"harbor", "harbinger"}},
+		// an exact match first
+		{language.English, "hat", []string{"hat", "hats"}},
+		{language.English, "ice c", []string{"ice cream"}},
+		// the start of a meaning, by the rank of words
+		{language.English, "港", []string{"harbor", "port"}},
+		{language.English, "避", []string{"harbor", "haven"}},
+		{language.English, "ポー", []string{"port"}},
+		// accents and case don't matter, only words of the language are listed
+		{language.German, "uber", []string{"Über"}},
+		{language.German, "港口", []string{"Hafen"}},
+		{language.English, "uber", []string{}},
+		// not FTS5 syntax
+		{language.English, `"harb"*`, []string{"harbor", "harbinger"}},
+		{language.English, "hat OR have", []string{}},
+		{language.English, "!?", []string{}},
+	}
+	for _, test := range tests {
+		got, err := SearchWordList(test.lang, test.query, 20)
+		if err != nil {
+			t.Errorf("%s %q: %v", test.lang, test.query, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: %v, want %v", test.lang, test.query, got, test.want)
+		}
+	}
+}
+
+func TestSearchWordListFollowsWords(t *testing.T) {
+	setupSearchTest(t)
+
+	harbor := GetWord(language.English, "harbor")
+	harbor.English, harbor.Chinese = "harbour", "n. 海港"
+	if err := UpdateWord(harbor); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteWord(GetWord(language.English, "hat").Id); err != nil {
+		t.Fatal(err)
+	}
+	for query, want := range map[string][]string{
+		"harb": {"harbour", "harbinger"},
+		"海":    {"harbour"},
+		"港":    {"port"},
+		"hat":  {"hats"},
+	} {
+		got, err := SearchWordList(language.English, query, 20)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: %v, %v, want %v", query, got, err, want)
+		}
+	}
+
+	// removed for good, e.g. by enx-sync
+	sqlitex.DB.Exec("DELETE FROM words WHERE english = 'have'")
+	if got, _ := SearchWordList(language.English, "hav", 20); !reflect.DeepEqual(got, []string{"haven"}) {
+		t.Errorf("deleted word: %v", got)
+	}
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"math"
+	"time"
+)
+
+// dormantAfter is how long a word that is not acquainted has to go without a lookup
+// before the known vocabulary estimate counts it as possibly known
+const dormantAfter = 30 * 24 * time.Hour
+
+// DailyStats is one day of a user's activity
+type DailyStats struct {
+	Date     string `json:"date"`      // YYYY-MM-DD in the requested time zone
+	NewWords int    `json:"new_words"` // words looked up for the first time
+	Lookups  int    `json:"lookups"`
+	Marked   int    `json:"marked"` // words marked as acquainted
+}
+
+// UserStats is a user's learning progress
+type UserStats struct {
+	Days       []DailyStats `json:"days"` // oldest first, one entry per day
+	TotalWords int          `json:"total_words"`
+	Acquainted int          `json:"acquainted"`
+	// RetentionRate is the share of words marked as acquainted that were not looked up again afterwards
+	RetentionRate float64 `json:"retention_rate"`
+	// CurrentStreak counts the days with a lookup up to today, or yesterday when there is none today yet
+	CurrentStreak int `json:"current_streak"`
+	LongestStreak int `json:"longest_streak"`
+	// KnownVocabulary estimates the words the user knows: the acquainted ones plus
+	// the share of dormant words (not looked up for 30 days) expected to be retained
+	KnownVocabulary int `json:"known_vocabulary"`
+}
+
+// GetUserStats returns the statistics of the last days days up to now, bucketed by day in loc
+func GetUserStats(userId string, days int, loc *time.Location, now time.Time) (*UserStats, error) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	start := today.AddDate(0, 0, -(days - 1))
+
+	stats := &UserStats{Days: make([]DailyStats, days)}
+	index := map[string]int{}
+	for i := range stats.Days {
+		date := start.AddDate(0, 0, i).Format(time.DateOnly)
+		stats.Days[i].Date = date
+		index[date] = i
+	}
+	day := func(ms int64) (int, bool) {
+		i, ok := index[time.UnixMilli(ms).In(loc).Format(time.DateOnly)]
+		return i, ok
+	}
+
+	var created []int64
+	if err := sqlitex.DB.Model(&UserDict{}).Where("user_id = ? AND created_at >= ?", userId, start.UnixMilli()).
+		Pluck("created_at", &created).Error; err != nil {
+		return nil, err
+	}
+	for _, ms := range created {
+		if i, ok := day(ms); ok {
+			stats.Days[i].NewWords++
+		}
+	}
+
+	var events []LookupEvent
+	if err := sqlitex.DB.Select("kind", "created_at").
+		Where("user_id = ? AND created_at >= ? AND kind IN ?", userId, start.UnixMilli(), []string{EventLookup, EventMark}).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		i, ok := day(event.CreatedAt)
+		if !ok {
+			continue
+		}
+		if event.Kind == EventLookup {
+			stats.Days[i].Lookups++
+		} else {
+			stats.Days[i].Marked++
+		}
+	}
+
+	var counts struct {
+		Total      int
+		Acquainted int
+		Dormant    int
+	}
+	if err := sqlitex.DB.Model(&UserDict{}).Where("user_id = ?", userId).
+		Select("COUNT(*) AS total, COALESCE(SUM(already_acquainted = 1), 0) AS acquainted, "+
+			"COALESCE(SUM(already_acquainted = 0 AND updated_at < ?), 0) AS dormant", now.Add(-dormantAfter).UnixMilli()).
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	stats.TotalWords = counts.Total
+	stats.Acquainted = counts.Acquainted
+
+	// a marked word is retained when there is no lookup after its last mark
+	var retention struct {
+		Marked   int
+		Retained int
+	}
+	if err := sqlitex.DB.Raw(`SELECT COUNT(*) AS marked, COALESCE(SUM(NOT EXISTS (
+			SELECT 1 FROM lookup_events l
+			WHERE l.user_id = m.user_id AND l.word_id = m.word_id AND l.kind = ? AND l.created_at > m.created_at
+		)), 0) AS retained
+		FROM (SELECT user_id, word_id, MAX(created_at) AS created_at FROM lookup_events
+			WHERE user_id = ? AND kind = ? GROUP BY user_id, word_id) m`, EventLookup, userId, EventMark).
+		Scan(&retention).Error; err != nil {
+		return nil, err
+	}
+	if retention.Marked > 0 {
+		stats.RetentionRate = float64(retention.Retained) / float64(retention.Marked)
+	}
+	stats.KnownVocabulary = counts.Acquainted + int(math.Round(stats.RetentionRate*float64(counts.Dormant)))
+
+	current, longest, err := lookupStreaks(userId, loc, today)
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak = current
+	stats.LongestStreak = longest
+	return stats, nil
+}
+
+// lookupStreaks walks the whole lookup history, newest first, counting consecutive days with a lookup
+func lookupStreaks(userId string, loc *time.Location, today time.Time) (current, longest int, err error) {
+	rows, err := sqlitex.DB.Model(&LookupEvent{}).Select("created_at").
+		Where("user_id = ? AND kind = ?", userId, EventLookup).Order("created_at DESC").Rows()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var last time.Time // the day of the previous row
+	streak := 0
+	currentOpen := true
+	for rows.Next() {
+		var ms int64
+		if err := rows.Scan(&ms); err != nil {
+			return 0, 0, err
+		}
+		t := time.UnixMilli(ms).In(loc)
+		d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if !last.IsZero() && d.Equal(last) {
+			continue
+		}
+		if last.IsZero() {
+			streak = 1
+			// the current streak survives a day without lookups so far
+			currentOpen = !d.Before(today.AddDate(0, 0, -1))
+		} else if d.Equal(last.AddDate(0, 0, -1)) {
+			streak++
+		} else {
+			if currentOpen {
+				current = streak
+				currentOpen = false
+			}
+			longest = max(longest, streak)
+			streak = 1
+		}
+		last = d
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if currentOpen {
+		current = streak
+	}
+	return current, max(longest, streak), nil
+}
@@ -0,0 +1,202 @@
+package repo
+
+import (
+	"enx-api/language"
+	"enx-api/utils/logger"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	// BackendSQLite writes words and user_dicts directly to the local SQLite file
+	BackendSQLite = "sqlite"
+	// BackendDataService writes words and user_dicts through enx-sync's gRPC DataService
+	BackendDataService = "data-service"
+)
+
+// Store is the write path for words, user_dicts and lookup_events.
+// Reads of the shared words table still go through sqlitex.DB, so every backend has to write
+// to the database file enx-api opened, see DataServiceStore.CheckDatabase.
+type Store interface {
+	CreateWord(word *Word) error
+	UpdateWord(word *Word) error
+	DeleteWord(id string) error
+	// SetFrequencyRanks stores words.frequency_rank by word id, without moving updated_at
+	SetFrequencyRanks(ranks map[string]int) error
+	// GetUserDict returns ErrNotFound when the user has no record of the word
+	GetUserDict(userId, wordId string) (*UserDict, error)
+	UpsertUserDict(userDict *UserDict) error
+	// MergeWords replaces the user dicts of word sourceId with merged, the rows of targetId after the
+	// merge, moves its lookup events to targetId and soft-deletes it, all or nothing
+	MergeWords(sourceId, targetId string, merged []UserDict) error
+	// AppendLookupEvent adds an event to the log, filling in the id and created_at when empty
+	AppendLookupEvent(event *LookupEvent) error
+	// PruneLookupEvents deletes events created before the cutoff (Unix milliseconds)
+	PruneLookupEvents(before int64) (int64, error)
+}
+
+// ErrNotFound is returned by a Store for a record that doesn't exist, other errors are failures
+var ErrNotFound = errors.New("record not found")
+
+var store Store = &sqliteStore{}
+
+// InitStore selects the storage backend from config (storage.backend)
+func InitStore() error {
+	backend := viper.GetString("storage.backend")
+	logger.Infof("init storage backend: %s", backend)
+	switch backend {
+	case "", BackendSQLite:
+		store = &sqliteStore{}
+	case BackendDataService:
+		address := viper.GetString("data-service.address")
+		dataService, err := NewDataServiceStore(address)
+		if err != nil {
+			return fmt.Errorf("failed to connect data service %s: %w", address, err)
+		}
+		if err := dataService.CheckDatabase(); err != nil {
+			dataService.Close()
+			return fmt.Errorf("data service %s: %w", address, err)
+		}
+		store = dataService
+	default:
+		return fmt.Errorf("unknown storage backend: %s", backend)
+	}
+	return nil
+}
+
+// SetStore replaces the storage backend, e.g. with a test double
+func SetStore(s Store) {
+	store = s
+}
+
+// CreateWord saves a new word and fills in the id and timestamps assigned by the backend
+func CreateWord(word *Word) error {
+	word.SourceLanguage = sourceLanguage(word.SourceLanguage)
+	word.FrequencyRank = frequencyRank(word)
+	word.SetGloss(language.Chinese, word.Chinese)
+	return store.CreateWord(word)
+}
+
+// UpdateWord saves english, chinese, pronunciation and glosses of a word and moves its updated_at.
+// Chinese wins over a different zh-CHS gloss, as callers predating glosses only set Chinese.
+func UpdateWord(word *Word) error {
+	word.FrequencyRank = frequencyRank(word)
+	word.SetGloss(language.Chinese, word.Chinese)
+	return store.UpdateWord(word)
+}
+
+// DeleteWord soft-deletes a word, the deletion reaches peers through enx-sync
+func DeleteWord(id string) error {
+	return store.DeleteWord(id)
+}
+
+type sqliteStore struct{}
+
+func (s *sqliteStore) CreateWord(word *Word) error {
+	if word.Id == "" {
+		word.Id = uuid.NewString()
+	}
+	now := time.Now().UnixMilli()
+	word.CreatedAt = now
+	word.UpdatedAt = now
+	return sqlitex.DB.Create(word).Error
+}
+
+func (s *sqliteStore) UpdateWord(word *Word) error {
+	word.UpdatedAt = time.Now().UnixMilli()
+	return sqlitex.DB.Model(&Word{}).Where("id = ?", word.Id).Updates(map[string]interface{}{
+		"english":        word.English,
+		"chinese":        word.Chinese,
+		"pronunciation":  word.Pronunciation,
+		"frequency_rank": word.FrequencyRank,
+		"glosses":        word.Glosses,
+		"updated_at":     word.UpdatedAt,
+	}).Error
+}
+
+func (s *sqliteStore) DeleteWord(id string) error {
+	now := time.Now().UnixMilli()
+	return sqlitex.DB.Model(&Word{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at": now,
+		"updated_at": now,
+	}).Error
+}
+
+func (s *sqliteStore) SetFrequencyRanks(ranks map[string]int) error {
+	return sqlitex.DB.Transaction(func(tx *gorm.DB) error {
+		for id, rank := range ranks {
+			if err := tx.Model(&Word{}).Where("id = ?", id).UpdateColumn("frequency_rank", rank).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) GetUserDict(userId, wordId string) (*UserDict, error) {
+	userDict := &UserDict{}
+	err := sqlitex.DB.Where("user_id = ? AND word_id = ?", userId, wordId).First(userDict).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return userDict, nil
+}
+
+func (s *sqliteStore) UpsertUserDict(userDict *UserDict) error {
+	return upsertUserDict(sqlitex.DB, userDict)
+}
+
+func upsertUserDict(db *gorm.DB, userDict *UserDict) error {
+	now := time.Now().UnixMilli()
+	userDict.UpdatedAt = now
+
+	// Check if record exists
+	var existing UserDict
+	err := db.Where("user_id = ? AND word_id = ?", userDict.UserId, userDict.WordId).First(&existing).Error
+	if err != nil {
+		// Record doesn't exist, create it
+		userDict.CreatedAt = now
+		return db.Create(userDict).Error
+	}
+
+	// Record exists, update it
+	userDict.CreatedAt = existing.CreatedAt
+	return db.Model(&UserDict{}).Where("user_id = ? AND word_id = ?", userDict.UserId, userDict.WordId).Updates(map[string]interface{}{
+		"query_count":        userDict.QueryCount,
+		"already_acquainted": userDict.AlreadyAcquainted,
+		"note":               userDict.Note,
+		"tags":               userDict.Tags,
+		"translation":        userDict.Translation,
+		"updated_at":         now,
+	}).Error
+}
+
+func (s *sqliteStore) MergeWords(sourceId, targetId string, merged []UserDict) error {
+	return sqlitex.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range merged {
+			if err := upsertUserDict(tx, &merged[i]); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("word_id = ?", sourceId).Delete(&UserDict{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&LookupEvent{}).Where("word_id = ?", sourceId).Update("word_id", targetId).Error; err != nil {
+			return err
+		}
+		now := time.Now().UnixMilli()
+		return tx.Model(&Word{}).Where("id = ?", sourceId).Updates(map[string]interface{}{
+			"deleted_at": now,
+			"updated_at": now,
+		}).Error
+	})
+}
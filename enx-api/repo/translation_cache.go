@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"enx-api/utils/sqlitex"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CachedTranslation is an upstream dictionary response kept until ExpiresAt.
+// It is local to this node and never replicated.
+type CachedTranslation struct {
+	Provider  string `gorm:"column:provider;primaryKey"`
+	Key       string `gorm:"column:key;primaryKey"` // lower-cased query
+	Value     string `gorm:"column:value"`
+	Found     int    `gorm:"column:found"`      // 0: the provider had no result, 1: Value holds it
+	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
+	ExpiresAt int64  `gorm:"column:expires_at"` // Unix milliseconds
+}
+
+func (CachedTranslation) TableName() string {
+	return "translation_cache"
+}
+
+// GetCachedTranslation returns the cached response of a provider for a key, expired or not,
+// or nil if there is none
+func GetCachedTranslation(provider, key string) (*CachedTranslation, error) {
+	entry := &CachedTranslation{}
+	err := sqlitex.DB.Where("provider = ? AND key = ?", provider, key).First(entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SaveCachedTranslation inserts or replaces a cached response
+func SaveCachedTranslation(entry *CachedTranslation) error {
+	return sqlitex.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(entry).Error
+}
+
+// DeleteCachedTranslations removes the cached responses for a key, of one provider or of all
+// of them when provider is empty, and returns how many were removed
+func DeleteCachedTranslations(provider, key string) (int64, error) {
+	query := sqlitex.DB.Where("key = ?", key)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	result := query.Delete(&CachedTranslation{})
+	return result.RowsAffected, result.Error
+}
+
+// PruneCachedTranslations removes responses that expired before the given time (Unix milliseconds)
+func PruneCachedTranslations(before int64) (int64, error) {
+	result := sqlitex.DB.Where("expires_at < ?", before).Delete(&CachedTranslation{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"enx-api/frequency"
+	"enx-api/utils/sqlitex"
+	"math"
+)
+
+// VocabularyBand is the user's knowledge of one band of the word frequency list
+type VocabularyBand struct {
+	FromRank int `json:"from_rank"`
+	ToRank   int `json:"to_rank"`
+	// Acquainted and Learning count the user's words in the band marked as acquainted or still looked up
+	Acquainted int `json:"acquainted"`
+	Learning   int `json:"learning"`
+	// KnownRatio is Acquainted / (Acquainted + Learning), or inherited when the user has no words in the band
+	KnownRatio     float64 `json:"known_ratio"`
+	Inherited      bool    `json:"inherited"`
+	EstimatedKnown int     `json:"estimated_known"`
+}
+
+// VocabularyEstimate is an estimate of how many words a user knows
+type VocabularyEstimate struct {
+	Estimate int              `json:"estimate"`
+	Bands    []VocabularyBand `json:"bands"`
+	ListSize int              `json:"list_size"`
+	// UnrankedAcquainted are acquainted words outside the list, added to the estimate as they are
+	UnrankedAcquainted int `json:"unranked_acquainted"`
+}
+
+// EstimateVocabulary estimates a user's vocabulary size from their words against bands of the
+// word frequency list. Each band contributes its size times the share of the user's words in it that
+// are acquainted. Bands without any of the user's words take the ratio of the nearest more frequent band,
+// and bands more frequent than all of them count as known, since the user never needed to look those up.
+// Without any ranked words only the acquainted unranked words are counted.
+func EstimateVocabulary(userId string, bandSize int) (*VocabularyEstimate, error) {
+	listSize := frequency.Current().Size()
+	estimate := &VocabularyEstimate{ListSize: listSize}
+
+	var rows []struct {
+		Band       int
+		Acquainted int
+		Learning   int
+	}
+	if err := sqlitex.DB.Table("user_dicts").
+		Select("(words.frequency_rank - 1) / ? AS band, "+
+			"SUM(user_dicts.already_acquainted = 1) AS acquainted, "+
+			"SUM(user_dicts.already_acquainted = 0) AS learning", bandSize).
+		Joins("JOIN words ON words.id = user_dicts.word_id AND words.deleted_at IS NULL").
+		Where("user_dicts.user_id = ? AND words.frequency_rank > 0 AND words.frequency_rank <= ?", userId, listSize).
+		Group("band").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if err := sqlitex.DB.Table("user_dicts").
+		Joins("JOIN words ON words.id = user_dicts.word_id AND words.deleted_at IS NULL").
+		Where("user_dicts.user_id = ? AND user_dicts.already_acquainted = 1 AND words.frequency_rank = 0", userId).
+		Select("COUNT(*)").Scan(&estimate.UnrankedAcquainted).Error; err != nil {
+		return nil, err
+	}
+
+	bandCount := (listSize + bandSize - 1) / bandSize
+	estimate.Bands = make([]VocabularyBand, bandCount)
+	for i := range estimate.Bands {
+		estimate.Bands[i].FromRank = i*bandSize + 1
+		estimate.Bands[i].ToRank = min((i+1)*bandSize, listSize)
+	}
+	for _, row := range rows {
+		if row.Band >= 0 && row.Band < bandCount {
+			estimate.Bands[row.Band].Acquainted = row.Acquainted
+			estimate.Bands[row.Band].Learning = row.Learning
+		}
+	}
+
+	// a user without ranked words gives nothing to estimate from
+	ratio := 0.0
+	if len(rows) > 0 {
+		ratio = 1
+	}
+	estimate.Estimate = estimate.UnrankedAcquainted
+	for i := range estimate.Bands {
+		band := &estimate.Bands[i]
+		if sampled := band.Acquainted + band.Learning; sampled > 0 {
+			ratio = float64(band.Acquainted) / float64(sampled)
+		} else {
+			band.Inherited = true
+		}
+		band.KnownRatio = ratio
+		band.EstimatedKnown = int(math.Round(ratio * float64(band.ToRank-band.FromRank+1)))
+		estimate.Estimate += band.EstimatedKnown
+	}
+	return estimate, nil
+}
@@ -6,6 +6,8 @@ CREATE TABLE users (
   `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `update_time` datetime DEFAULT NULL,
   `last_login_time` datetime DEFAULT NULL,
+  `role` varchar(16) NOT NULL DEFAULT 'user', -- user | admin
+  `disabled` boolean NOT NULL DEFAULT 0,
   UNIQUE (`name`),
   UNIQUE (`email`)
 );
@@ -25,8 +27,9 @@ CREATE TABLE IF NOT EXISTS words (
     -- Primary key: UUID v4 for P2P compatibility
     id TEXT PRIMARY KEY,
     
-    -- Word content
-    english TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    -- Word content. english is the word in its source language, which isn't English for every word.
+    source_language TEXT NOT NULL DEFAULT 'en',
+    english TEXT NOT NULL COLLATE NOCASE,
     chinese TEXT,
     pronunciation TEXT,
     
@@ -36,7 +39,15 @@ CREATE TABLE IF NOT EXISTS words (
     deleted_at INTEGER,  -- Soft delete: NULL = active, timestamp = deleted
     
     -- Query statistics
-    load_count INTEGER DEFAULT 0
+    load_count INTEGER DEFAULT 0,
+
+    -- Rank in the local word frequency list (frequency.file), 0 = not ranked.
+    -- Derived on each node and not replicated, peers may use a different list.
+    frequency_rank INTEGER NOT NULL DEFAULT 0,
+
+    -- Translations by target language code as a JSON object, e.g. {"zh-CHS": "港口", "ja": "港"}.
+    -- Replicated with the word; the zh-CHS one is mirrored in chinese for old clients and peers.
+    glosses TEXT NOT NULL DEFAULT '{}'
 );
 
 -- Index for soft delete queries (only active records)
@@ -52,6 +63,40 @@ ON words(updated_at);
 CREATE INDEX IF NOT EXISTS idx_words_english 
 ON words(english COLLATE NOCASE);
 
+-- A word is unique within its source language, e.g. English and German "die" are different words.
+-- Lookups compare word_key(source_language, english), a function registered by enx-api: NFC
+-- normalized and lower case by the rules of the language, as NOCASE folds ASCII letters only.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_words_source_language_english
+ON words(source_language, english);
+
+-- Full-text search of words by english prefix, or by the start of a meaning for reverse search.
+-- Indexes the english, chinese and glosses of words by rowid, kept up to date by the triggers below.
+-- Accents and case are folded, e.g. uber matches Über. enx-sync needs FTS5 to write words,
+-- build it with -tags sqlite_fts5.
+CREATE VIRTUAL TABLE IF NOT EXISTS words_fts USING fts5(
+    english, chinese, glosses,
+    content='words',
+    tokenize='unicode61 remove_diacritics 2',
+    prefix='1 2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS words_fts_insert AFTER INSERT ON words BEGIN
+    INSERT INTO words_fts(rowid, english, chinese, glosses)
+    VALUES (new.rowid, new.english, new.chinese, new.glosses);
+END;
+
+CREATE TRIGGER IF NOT EXISTS words_fts_delete AFTER DELETE ON words BEGIN
+    INSERT INTO words_fts(words_fts, rowid, english, chinese, glosses)
+    VALUES ('delete', old.rowid, old.english, old.chinese, old.glosses);
+END;
+
+CREATE TRIGGER IF NOT EXISTS words_fts_update AFTER UPDATE OF english, chinese, glosses ON words BEGIN
+    INSERT INTO words_fts(words_fts, rowid, english, chinese, glosses)
+    VALUES ('delete', old.rowid, old.english, old.chinese, old.glosses);
+    INSERT INTO words_fts(rowid, english, chinese, glosses)
+    VALUES (new.rowid, new.english, new.chinese, new.glosses);
+END;
+
 -- User Dictionary Table
 -- Stores user-specific word data (query count, familiarity)
 -- Supports P2P sync with UUID foreign keys
@@ -68,6 +113,12 @@ CREATE TABLE IF NOT EXISTS user_dicts (
     -- Familiarity flag: 0 = learning, 1 = already acquainted
     already_acquainted INTEGER DEFAULT 0,
     
+    -- Personal annotations: free text note, comma-separated tags and
+    -- a translation shown instead of words.chinese
+    note TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '',
+    translation TEXT NOT NULL DEFAULT '',
+    
     -- Timestamps (Unix milliseconds for P2P sync)
     created_at INTEGER NOT NULL,
     updated_at INTEGER NOT NULL,
@@ -88,19 +139,158 @@ ON user_dicts(word_id);
 CREATE INDEX IF NOT EXISTS idx_user_dicts_updated_at 
 ON user_dicts(updated_at);
 
-create table youdao
-(
-    english TEXT          not null,
-    result  TEXT          not null,
-    exist   INTEGER default 0 not null
+-- Indexes for listing a user's words by recency and by query count
+CREATE INDEX IF NOT EXISTS idx_user_dicts_user_updated_at
+ON user_dicts(user_id, updated_at);
+
+CREATE INDEX IF NOT EXISTS idx_user_dicts_user_query_count
+ON user_dicts(user_id, query_count);
+
+-- Translation Cache Table
+-- Responses of upstream dictionaries, kept on this node only and dropped once expired.
+-- Lookups the provider had no result for are cached too (found = 0), with a shorter TTL.
+CREATE TABLE IF NOT EXISTS translation_cache (
+    -- upstream the value came from, e.g. youdao-api or youdao-web
+    provider TEXT NOT NULL,
+    -- lower-cased query
+    key TEXT NOT NULL,
+    value TEXT NOT NULL DEFAULT '',
+    -- 0: false, 1: true
+    found INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (provider, key)
+);
+CREATE INDEX IF NOT EXISTS idx_translation_cache_expires_at ON translation_cache(expires_at);
+
+-- Dictionary Entries Table
+-- Structured entries (senses by part of speech, UK/US phonetics, examples, web phrases) as JSON,
+-- kept on this node only. words.chinese and words.pronunciation stay the flat form for old clients.
+CREATE TABLE IF NOT EXISTS dictionary_entries (
+    -- lower-cased word
+    key TEXT PRIMARY KEY,
+    -- provider the entry came from, e.g. youdao-web
+    source TEXT NOT NULL DEFAULT '',
+    entry TEXT NOT NULL,
+    updated_at INTEGER NOT NULL
 );
 
 CREATE TABLE sessions (
     id TEXT PRIMARY KEY,
     user_id INTEGER NOT NULL,
     created_at DATETIME NOT NULL,
-    expires_at DATETIME NOT NULL
+    expires_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL DEFAULT 0,
+    user_agent TEXT,
+    ip TEXT
 );
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+-- API Tokens Table
+-- Long-lived personal access tokens, only an Argon2 hash of the secret is stored
+CREATE TABLE api_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT,
+    token_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    last_used_at INTEGER NOT NULL DEFAULT 0,
+    expires_at INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+
+-- Password Resets Table
+-- Single-use reset tokens, only a sha256 of the token is stored
+CREATE TABLE password_resets (
+    token_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    used_at INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+
+-- User Identities Table
+-- Links external identity provider subjects (OIDC) to users
+CREATE TABLE user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    email TEXT,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- Lookup Events Table
+-- Append-only log of lookups and acquainted marks, replicated by enx-sync.
+-- seq is the local append order peers pull by, id is the same on every node.
+CREATE TABLE IF NOT EXISTS lookup_events (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    word_id TEXT NOT NULL,
+    -- lookup, mark or unmark
+    kind TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    -- extension, ui or api, empty when unknown
+    source TEXT NOT NULL DEFAULT '',
+    -- host of the page the word was looked up on, never the full URL
+    page_host TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_lookup_events_user_created_at ON lookup_events(user_id, created_at);
+
+-- Highlight Policies Table
+-- Per-user rules deciding which words /paragraph-init highlights, kept on this node only
+CREATE TABLE IF NOT EXISTS highlight_policies (
+    user_id TEXT PRIMARY KEY,
+    -- hide words ranked beyond max_rank in the word frequency list, 0: no limit
+    max_rank INTEGER NOT NULL DEFAULT 0,
+    -- comma-separated tags whose words are always highlighted
+    always_tags TEXT NOT NULL DEFAULT '',
+    -- 0: false, 1: true
+    hide_proper_nouns INTEGER NOT NULL DEFAULT 0,
+    -- hide words looked up fewer times
+    min_query_count INTEGER NOT NULL DEFAULT 1,
+    updated_at INTEGER NOT NULL
+);
+
+-- User Languages Table
+-- The language each user's lookups are translated into and the languages they study,
+-- kept on this node only
+CREATE TABLE IF NOT EXISTS user_languages (
+    user_id TEXT PRIMARY KEY,
+    -- target language code, e.g. zh-CHS, ja, es
+    native_language TEXT NOT NULL DEFAULT 'zh-CHS',
+    -- comma-separated source language codes, e.g. en,de; words are in the first one unless
+    -- a lookup says otherwise
+    study_languages TEXT NOT NULL DEFAULT 'en',
+    updated_at INTEGER NOT NULL
+);
+
+-- Audio Clips Table
+-- Pronunciation audio of words, kept on this node only. The audio itself is a file named by
+-- its sha256 in the audio.dir blob directory, clips with the same audio share the file.
+CREATE TABLE IF NOT EXISTS audio_clips (
+    -- lower-cased word
+    word TEXT NOT NULL,
+    -- us or uk
+    accent TEXT NOT NULL,
+    -- sha256 of the audio, hex
+    hash TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    -- provider or text-to-speech engine the audio came from
+    source TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    -- least recently played clips are evicted first once audio.max-total-size is exceeded
+    accessed_at INTEGER NOT NULL,
+    PRIMARY KEY (word, accent)
+);
+CREATE INDEX IF NOT EXISTS idx_audio_clips_hash ON audio_clips(hash);
+CREATE INDEX IF NOT EXISTS idx_audio_clips_accessed_at ON audio_clips(accessed_at);
 
 -- Sync State Table
 -- Tracks last sync timestamp for each peer to avoid re-syncing unchanged data
@@ -112,5 +302,8 @@ CREATE TABLE IF NOT EXISTS sync_state (
     last_sync_time INTEGER NOT NULL,
     
     -- Last sync attempt timestamp
-    updated_at INTEGER NOT NULL
+    updated_at INTEGER NOT NULL,
+    
+    -- Last lookup_events.seq of the peer applied locally
+    events_cursor INTEGER NOT NULL DEFAULT 0
 );
@@ -0,0 +1,267 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/utils/logger"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// stateCookie binds the authorization request to the browser that started it
+	stateCookie = "oidc_state"
+	// flowTTL is how long a user has to finish logging in at the identity provider
+	flowTTL = 10 * time.Minute
+)
+
+// Config of the OIDC identity provider (oidc.*)
+type Config struct {
+	Issuer            string
+	ClientID          string
+	ClientSecret      string
+	RedirectURL       string   // enx-api callback, e.g. https://enx.example.com/api/oidc/callback
+	Scopes            []string // openid is always requested
+	PostLoginRedirect string   // web UI page receiving #session_id=...
+	LinkByEmail       bool     // link to an existing user with the same verified email
+}
+
+// pendingFlow is an authorization request waiting for its callback
+type pendingFlow struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// Client runs the authorization code flow with PKCE against one identity provider
+type Client struct {
+	config Config
+
+	mu       sync.Mutex
+	provider *oidc.Provider // discovered on first use
+	flows    map[string]pendingFlow
+}
+
+var client *Client
+
+// Init configures OIDC login from config, it stays disabled unless oidc.enabled is set
+func Init() {
+	if !viper.GetBool("oidc.enabled") {
+		return
+	}
+	client = NewClient(Config{
+		Issuer:            viper.GetString("oidc.issuer"),
+		ClientID:          viper.GetString("oidc.client-id"),
+		ClientSecret:      viper.GetString("oidc.client-secret"),
+		RedirectURL:       viper.GetString("oidc.redirect-url"),
+		Scopes:            strings.Fields(viper.GetString("oidc.scopes")),
+		PostLoginRedirect: viper.GetString("oidc.post-login-redirect"),
+		LinkByEmail:       viper.GetBool("oidc.link-by-email"),
+	})
+	logger.Infof("oidc login enabled, issuer: %s", client.config.Issuer)
+}
+
+// SetClient replaces the OIDC client, nil disables OIDC login
+func SetClient(c *Client) {
+	client = c
+}
+
+func NewClient(config Config) *Client {
+	return &Client{config: config, flows: map[string]pendingFlow{}}
+}
+
+// Login redirects the browser to the identity provider
+func Login(c *gin.Context) {
+	if client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "OIDC login is not enabled"})
+		return
+	}
+	client.Login(c)
+}
+
+// Callback finishes the login started by Login and issues a session
+func Callback(c *gin.Context) {
+	if client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "OIDC login is not enabled"})
+		return
+	}
+	client.Callback(c)
+}
+
+func (cl *Client) Login(c *gin.Context) {
+	oauthConfig, _, err := cl.oauthConfig()
+	if err != nil {
+		logger.Errorf("oidc provider discovery failed, issuer: %s, error: %v", cl.config.Issuer, err)
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "Identity provider unavailable"})
+		return
+	}
+
+	state, nonce := randomString(), randomString()
+	verifier := oauth2.GenerateVerifier()
+	cl.mu.Lock()
+	cl.gcFlows(time.Now())
+	cl.flows[state] = pendingFlow{verifier: verifier, nonce: nonce, expiresAt: time.Now().Add(flowTTL)}
+	cl.mu.Unlock()
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, state, int(flowTTL.Seconds()), "/", "", false, true)
+	c.Redirect(http.StatusFound, oauthConfig.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce)))
+}
+
+func (cl *Client) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		logger.Errorf("oidc login rejected by identity provider, error: %s, description: %s", errCode, c.Query("error_description"))
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Login rejected by identity provider"})
+		return
+	}
+
+	state := c.Query("state")
+	cookie, err := c.Cookie(stateCookie)
+	if state == "" || err != nil || cookie != state {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid login state"})
+		return
+	}
+	c.SetCookie(stateCookie, "", -1, "/", "", false, true)
+
+	cl.mu.Lock()
+	flow, ok := cl.flows[state]
+	delete(cl.flows, state)
+	cl.mu.Unlock()
+	if !ok || time.Now().After(flow.expiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Login expired, please try again"})
+		return
+	}
+
+	claims, err := cl.exchange(c.Request.Context(), c.Query("code"), flow)
+	if err != nil {
+		logger.Errorf("oidc code exchange failed, error: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Login failed"})
+		return
+	}
+
+	user, err := enx.LoginWithIdentity(enx.ExternalIdentity{
+		Issuer:            cl.config.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+	}, cl.config.LinkByEmail)
+	if errors.Is(err, enx.ErrUserDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "Account is disabled"})
+		return
+	}
+	if errors.Is(err, enx.ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Email already registered, please log in with password"})
+		return
+	}
+	if err != nil {
+		logger.Errorf("oidc user login failed, subject: %s, error: %v", claims.Subject, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Login failed"})
+		return
+	}
+
+	session, err := middleware.CreateSession(user.Id, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		logger.Errorf("failed to create session for user %s: %v", user.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create session"})
+		return
+	}
+	c.SetCookie("session_id", session.ID, int(middleware.SessionAbsoluteTimeout().Seconds()), "/", "", false, true)
+	logger.Infof("oidc login success, user: %s, subject: %s", user.Name, claims.Subject)
+
+	if cl.config.PostLoginRedirect == "" {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "Login successful", "user": user, "session_id": session.ID})
+		return
+	}
+	// The fragment isn't sent to servers, the web UI reads the session id from it
+	c.Redirect(http.StatusFound, cl.config.PostLoginRedirect+"#session_id="+url.QueryEscape(session.ID))
+}
+
+type idTokenClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// exchange redeems the code with the PKCE verifier and verifies the ID token
+func (cl *Client) exchange(ctx context.Context, code string, flow pendingFlow) (*idTokenClaims, error) {
+	oauthConfig, provider, err := cl.oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(flow.verifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: cl.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != flow.nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims idTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (cl *Client) oauthConfig() (*oauth2.Config, *oidc.Provider, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.provider == nil {
+		// The provider keeps fetching signing keys with this context, so it can't be the request's
+		provider, err := oidc.NewProvider(context.Background(), cl.config.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		cl.provider = provider
+	}
+
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range cl.config.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     cl.config.ClientID,
+		ClientSecret: cl.config.ClientSecret,
+		RedirectURL:  cl.config.RedirectURL,
+		Endpoint:     cl.provider.Endpoint(),
+		Scopes:       scopes,
+	}, cl.provider, nil
+}
+
+func (cl *Client) gcFlows(now time.Time) {
+	for state, flow := range cl.flows {
+		if now.After(flow.expiresAt) {
+			delete(cl.flows, state)
+		}
+	}
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
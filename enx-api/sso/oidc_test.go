@@ -0,0 +1,231 @@
+package sso
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"enx-api/enx"
+	"enx-api/middleware"
+	"enx-api/utils/sqlitex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// fakeIdP is an in-process identity provider: discovery and keys from oidctest,
+// plus an authorization endpoint that logs in subject immediately and a token endpoint checking PKCE
+type fakeIdP struct {
+	*oidctest.Server
+	url     string
+	key     *rsa.PrivateKey
+	subject string
+	email   string
+	// the email isn't verified by the IdP
+	unverified bool
+
+	mu    sync.Mutex
+	codes map[string]url.Values // code -> authorization request
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	idp := &fakeIdP{
+		Server: &oidctest.Server{PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "test-key", Algorithm: oidc.RS256}}},
+		key:    key,
+		codes:  map[string]url.Values{},
+	}
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.url = srv.URL
+	idp.SetIssuer(srv.URL)
+	return idp
+}
+
+func (f *fakeIdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/auth":
+		query := r.URL.Query()
+		code := randomString()
+		f.mu.Lock()
+		f.codes[code] = query
+		f.mu.Unlock()
+		http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+query.Get("state"), http.StatusFound)
+	case "/token":
+		_ = r.ParseForm()
+		f.mu.Lock()
+		auth, ok := f.codes[r.Form.Get("code")]
+		delete(f.codes, r.Form.Get("code"))
+		f.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || auth.Get("code_challenge_method") != "S256" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != auth.Get("code_challenge") {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		claims := fmt.Sprintf(`{"iss":%q,"aud":%q,"sub":%q,"email":%q,"email_verified":%t,"preferred_username":"alice","nonce":%q,"exp":%d}`,
+			f.url, auth.Get("client_id"), f.subject, f.email, !f.unverified, auth.Get("nonce"), time.Now().Add(time.Hour).Unix())
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     oidctest.SignIDToken(f.key, "test-key", oidc.RS256, claims),
+		})
+	default:
+		f.Server.ServeHTTP(w, r)
+	}
+}
+
+func setupOIDCTest(t *testing.T) (*fakeIdP, *gin.Engine) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	viper.Set("session.idle-timeout", "1h")
+	viper.Set("session.absolute-timeout", "24h")
+	t.Cleanup(viper.Reset)
+
+	idp := newFakeIdP(t)
+	SetClient(NewClient(Config{
+		Issuer:      idp.url,
+		ClientID:    "enx",
+		RedirectURL: "http://enx.test/api/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	}))
+	t.Cleanup(func() { SetClient(nil) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/oidc/login", Login)
+	router.GET("/api/oidc/callback", Callback)
+	return idp, router
+}
+
+// loginThroughIdP follows the browser redirects of a full login and returns the callback response
+func loginThroughIdP(t *testing.T, idp *fakeIdP, router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	authURL := w.Header().Get("Location")
+	if !strings.HasPrefix(authURL, idp.url+"/auth") || !strings.Contains(authURL, "code_challenge=") {
+		t.Fatalf("unexpected authorization url: %s", authURL)
+	}
+	stateCookie := w.Result().Cookies()[0]
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/oidc/callback?"+callback.RawQuery, nil)
+	req.AddCookie(stateCookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCLoginCreatesAndLinksUser(t *testing.T) {
+	idp, router := setupOIDCTest(t)
+	idp.subject, idp.email = "subject-1", "alice@example.com"
+
+	w := loginThroughIdP(t, idp, router)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		SessionID string   `json:"session_id"`
+		User      enx.User `json:"user"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.User.Name != "alice" || resp.SessionID == "" {
+		t.Fatalf("unexpected login response: %s", w.Body.String())
+	}
+	var session middleware.Session
+	if err := sqlitex.DB.Where("id = ? AND user_id = ?", resp.SessionID, resp.User.Id).First(&session).Error; err != nil {
+		t.Errorf("session not created: %v", err)
+	}
+
+	// The second login finds the linked user instead of creating another one
+	w = loginThroughIdP(t, idp, router)
+	var again struct {
+		User enx.User `json:"user"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &again)
+	if again.User.Id != resp.User.Id {
+		t.Errorf("second login got another user: %s", w.Body.String())
+	}
+
+	// Another subject with a taken name gets a suffixed name and no email conflict without an email
+	idp.subject, idp.email = "subject-2", ""
+	w = loginThroughIdP(t, idp, router)
+	var other struct {
+		User enx.User `json:"user"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &other)
+	if w.Code != http.StatusOK || other.User.Name != "alice2" {
+		t.Errorf("unexpected second user, status: %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	_, router := setupOIDCTest(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/oidc/callback?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: stateCookie, Value: "other"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("forged state, status: %d", w.Code)
+	}
+}
+
+func TestOIDCLoginRejectsTakenEmail(t *testing.T) {
+	idp, router := setupOIDCTest(t)
+	existing := &enx.User{Name: "bob", Email: "bob@example.com"}
+	if err := existing.Create(); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	idp.subject, idp.email = "subject-3", "bob@example.com"
+
+	if w := loginThroughIdP(t, idp, router); w.Code != http.StatusConflict {
+		t.Errorf("taken email without link-by-email, status: %d", w.Code)
+	}
+}
+
+func TestOIDCLoginIgnoresUnverifiedEmail(t *testing.T) {
+	idp, router := setupOIDCTest(t)
+	idp.subject, idp.email, idp.unverified = "subject-4", "carol@example.com", true
+
+	w := loginThroughIdP(t, idp, router)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback, status: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		User enx.User `json:"user"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if user := enx.GetUserByID(resp.User.Id); user.Id == "" || user.Email != "" {
+		t.Errorf("unverified email stored: %+v", user)
+	}
+}
@@ -0,0 +1,265 @@
+// Package suggest offers "did you mean" spellings for words nobody has a translation of. The
+// translated words of the dictionary and the words of the frequency list are indexed with SymSpell:
+// every variant of a word with up to max distance characters deleted points back to the word, so a
+// lookup only compares the word asked for with the words sharing one of its variants.
+package suggest
+
+import (
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/utils/logger"
+	"enx-api/utils/schedule"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Limit is how many suggestions a lookup returns
+const Limit = 5
+
+// prefixLength bounds the variants of long words, only their start is indexed
+const prefixLength = 7
+
+type term struct {
+	word  string // as written, e.g. Über
+	rank  int    // in the word frequency list, 0: not ranked
+	count int    // how often the word was looked up
+}
+
+// Index suggests words of each source language, safe for concurrent use
+type Index struct {
+	maxDistance int
+	mu          sync.RWMutex
+	terms       map[string]map[string]*term    // language -> key -> term
+	deletes     map[string]map[string][]string // language -> variant -> keys
+}
+
+// New creates an empty index suggesting words within maxDistance edits
+func New(maxDistance int) *Index {
+	return &Index{maxDistance: maxDistance, terms: map[string]map[string]*term{}, deletes: map[string]map[string][]string{}}
+}
+
+var (
+	mu      sync.RWMutex
+	current = New(2)
+)
+
+// Init builds the index from the dictionary and the word frequency list, with suggest.max-distance
+func Init() error {
+	index, err := Build(viper.GetInt("suggest.max-distance"))
+	if err != nil {
+		return err
+	}
+	SetIndex(index)
+	return nil
+}
+
+// Build indexes the words of the dictionary with a translation, a typo nobody has one of isn't
+// suggested, and the words of the frequency list in use, which are English
+func Build(maxDistance int) (*Index, error) {
+	index := New(maxDistance)
+	for rank, word := range frequency.Current().Words() {
+		index.Add(language.English, word, rank+1, 0)
+	}
+	err := repo.TranslatedWords(func(words []repo.Word) error {
+		for _, word := range words {
+			index.Add(word.SourceLanguage, word.English, word.FrequencyRank, word.LoadCount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("spelling suggestions, max distance: %d, words: %d", maxDistance, index.Size())
+	return index, nil
+}
+
+// SetIndex replaces the index in use, e.g. with one of a test
+func SetIndex(index *Index) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = index
+}
+
+// Current returns the index in use
+func Current() *Index {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Suggest returns up to Limit spellings of a word of a language from the index in use, see Index.Suggest
+func Suggest(lang, word string) []string {
+	return Current().Suggest(lang, word, Limit)
+}
+
+// StartRefresher rebuilds the index now and then, so that words translated on peers are suggested
+// too, see schedule.Every
+func StartRefresher(interval time.Duration) (stop func(), err error) {
+	return schedule.Every(interval, func() {
+		index, err := Build(Current().maxDistance)
+		if err != nil {
+			logger.Errorf("failed to rebuild spelling suggestions: %v", err)
+			return
+		}
+		SetIndex(index)
+	})
+}
+
+// Add indexes a word of a language. A word added again keeps the best rank and the larger count,
+// and the spelling of the dictionary over the lower case one of the frequency list.
+func (x *Index) Add(lang, word string, rank, count int) {
+	key := language.Key(lang, word)
+	if key == "" {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	terms := x.terms[lang]
+	if terms == nil {
+		terms = map[string]*term{}
+		x.terms[lang] = terms
+		x.deletes[lang] = map[string][]string{}
+	}
+	if t, ok := terms[key]; ok {
+		if rank > 0 && (t.rank == 0 || rank < t.rank) {
+			t.rank = rank
+		}
+		if count > t.count {
+			t.count = count
+		}
+		if word != key {
+			t.word = word
+		}
+		return
+	}
+	terms[key] = &term{word: word, rank: rank, count: count}
+	for variant := range x.variants(key) {
+		x.deletes[lang][variant] = append(x.deletes[lang][variant], key)
+	}
+}
+
+// Size is the number of indexed words
+func (x *Index) Size() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	size := 0
+	for _, terms := range x.terms {
+		size += len(terms)
+	}
+	return size
+}
+
+type candidate struct {
+	*term
+	distance int
+}
+
+// Suggest returns up to limit words of a language within the max distance of word, other than
+// the word itself: the closest first, then ranked words by rank, then the most looked up
+func (x *Index) Suggest(lang, word string, limit int) []string {
+	key := language.Key(lang, word)
+	suggestions := []string{}
+	if key == "" {
+		return suggestions
+	}
+	input := []rune(key)
+	x.mu.RLock()
+	var candidates []candidate
+	seen := map[string]bool{key: true}
+	for variant := range x.variants(key) {
+		for _, other := range x.deletes[lang][variant] {
+			if seen[other] {
+				continue
+			}
+			seen[other] = true
+			if distance := editDistance(input, []rune(other), x.maxDistance); distance <= x.maxDistance {
+				candidates = append(candidates, candidate{x.terms[lang][other], distance})
+			}
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.distance != b.distance:
+			return a.distance < b.distance
+		case (a.rank == 0) != (b.rank == 0):
+			return a.rank != 0
+		case a.rank != b.rank:
+			return a.rank < b.rank
+		case a.count != b.count:
+			return a.count > b.count
+		}
+		return a.word < b.word
+	})
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		suggestions = append(suggestions, candidates[i].word)
+	}
+	return suggestions
+}
+
+// variants returns the start of key with up to max distance characters deleted, the start itself included
+func (x *Index) variants(key string) map[string]bool {
+	runes := []rune(key)
+	if len(runes) > prefixLength {
+		runes = runes[:prefixLength]
+	}
+	variants := map[string]bool{string(runes): true}
+	level := [][]rune{runes}
+	for distance := 0; distance < x.maxDistance; distance++ {
+		var next [][]rune
+		for _, variant := range level {
+			if len(variant) <= 1 {
+				continue
+			}
+			for i := range variant {
+				deleted := append(append([]rune{}, variant[:i]...), variant[i+1:]...)
+				if !variants[string(deleted)] {
+					variants[string(deleted)] = true
+					next = append(next, deleted)
+				}
+			}
+		}
+		level = next
+	}
+	return variants
+}
+
+// editDistance is the optimal string alignment distance of a and b: insertions, deletions,
+// substitutions and transpositions of adjacent characters. Beyond limit it returns limit+1.
+func editDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		least := rows[i][0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+			least = min(least, rows[i][j])
+		}
+		if least > limit {
+			return limit + 1
+		}
+	}
+	return rows[len(a)][len(b)]
+}
@@ -0,0 +1,118 @@
+package suggest
+
+import (
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/utils/sqlitex"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"harbor", "harbor", 0},
+		{"harbr", "harbor", 1},
+		{"hrabor", "harbor", 1},
+		{"harbour", "harbor", 1},
+		{"hrbr", "harbor", 2},
+		{"strase", "straße", 1},
+		{"ship", "harbor", 3},
+		{"", "ab", 2},
+	}
+	for _, test := range tests {
+		if got := editDistance([]rune(test.a), []rune(test.b), 2); got != test.want {
+			t.Errorf("%s, %s: %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	index := New(2)
+	for rank, word := range []string{"the", "have", "hat", "harbor", "heat"} {
+		index.Add(language.English, word, rank+1, 0)
+	}
+	index.Add(language.English, "harbour", 0, 3)
+	index.Add(language.English, "harbinger", 0, 1)
+	index.Add(language.English, "hart", 0, 7)
+	index.Add(language.English, "Paris", 0, 0)
+	index.Add(language.German, "Straße", 0, 0)
+	index.Add(language.German, "hat", 0, 0)
+
+	tests := []struct {
+		lang, word string
+		want       []string
+	}{
+		// the closest first, then by rank, then the most looked up
+		{language.English, "harbr", []string{"harbor", "hart", "harbour"}},
+		{language.English, "hrabor", []string{"harbor", "harbour"}},
+		{language.English, "hav", []string{"have", "hat", "heat"}},
+		{language.English, "harbingr", []string{"harbinger"}},
+		// the word itself isn't a suggestion
+		{language.English, "harbor", []string{"harbour"}},
+		{language.English, "PARIS", []string{}},
+		{language.English, "pari", []string{"Paris", "hart"}},
+		// only words of the language
+		{language.German, "strase", []string{"Straße"}},
+		{language.German, "hut", []string{"hat"}},
+		{language.French, "hat", []string{}},
+		{language.English, "qwzx", []string{}},
+		{language.English, "", []string{}},
+	}
+	for _, test := range tests {
+		if got := index.Suggest(test.lang, test.word, 3); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: %v, want %v", test.lang, test.word, got, test.want)
+		}
+	}
+
+	// the dictionary's spelling, rank and count are merged into the word of the list
+	index.Add(language.English, "Heat", 0, 9)
+	if got := index.Suggest(language.English, "heet", 1); !reflect.DeepEqual(got, []string{"Heat"}) {
+		t.Errorf("merged word: %v", got)
+	}
+	if index.Size() != 11 {
+		t.Errorf("size: %d", index.Size())
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	list, err := frequency.Parse(strings.NewReader("the\nharbor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := frequency.Current()
+	frequency.SetList(list)
+	t.Cleanup(func() { frequency.SetList(previous) })
+
+	for _, word := range []*repo.Word{
+		{English: "harbour", Chinese: "港口"},
+		{English: "harbr"},
+		{English: "harbor master", Chinese: "港务长"},
+		{English: "Hafen", SourceLanguage: language.German, Glosses: `{"ja":"港"}`},
+	} {
+		if err := repo.CreateWord(word); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := Build(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the untranslated typo and the phrase aren't indexed
+	if got := index.Suggest(language.English, "harbir", 5); !reflect.DeepEqual(got, []string{"harbor", "harbour"}) {
+		t.Errorf("english: %v", got)
+	}
+	if got := index.Suggest(language.German, "hafn", 5); !reflect.DeepEqual(got, []string{"Hafen"}) {
+		t.Errorf("german: %v", got)
+	}
+	if index.Size() != 4 {
+		t.Errorf("size: %d", index.Size())
+	}
+}
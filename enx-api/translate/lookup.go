@@ -0,0 +1,251 @@
+package translate
+
+import (
+	"context"
+	"enx-api/enx"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/suggest"
+	"enx-api/utils/logger"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// GlossFunc looks words of a source language up upstream in a target language, see QueryGloss
+type GlossFunc func(ctx context.Context, words, from, to string) (gloss, pronunciation string, err error)
+
+// ErrSaveWord is returned by lookups that couldn't store a new word, nothing is counted then
+var ErrSaveWord = errors.New("failed to save word")
+
+// ErrSaveMark is returned by Mark when the toggled flag couldn't be stored
+var ErrSaveMark = errors.New("failed to save mark")
+
+// Query is a word or phrase a user looks up
+type Query struct {
+	UserId string
+	// as asked for, e.g. `Harbor,`
+	Raw string
+	// source language asked for, the user's default one when empty, see repo.GetSourceLanguage
+	From string
+	// where the lookup comes from, kept in the lookup history, see middleware.GetRequestSource
+	Source   string
+	PageHost string
+}
+
+// Lookup looks words up for users, the same way for every route. A lookup runs through
+// normalize → local → cache → provider → persist → record: the word is cleaned, found in the
+// dictionary or else asked of the provider through the translation cache, a new word is stored,
+// and the lookup is counted in the user's stats and history.
+type Lookup struct {
+	gloss GlossFunc
+}
+
+// NewLookup creates a lookup service asking gloss for the words missing from the dictionary,
+// gloss goes through the translation cache like QueryGloss does
+func NewLookup(gloss GlossFunc) *Lookup {
+	return &Lookup{gloss: gloss}
+}
+
+var (
+	lookupMu sync.RWMutex
+	lookup   = NewLookup(QueryGloss)
+)
+
+// SetLookup replaces the lookup service in use, e.g. with one of a test
+func SetLookup(l *Lookup) {
+	lookupMu.Lock()
+	defer lookupMu.Unlock()
+	lookup = l
+}
+
+// CurrentLookup returns the lookup service in use
+func CurrentLookup() *Lookup {
+	lookupMu.RLock()
+	defer lookupMu.RUnlock()
+	return lookup
+}
+
+// Translate looks a word or phrase up for a user. A phrase is only translated, a word is stored
+// when it is new and the lookup counted for the user. Errors are upstream failures or ErrSaveWord.
+func (l *Lookup) Translate(ctx context.Context, q Query) (*enx.Word, error) {
+	word, lang := l.normalize(q)
+	if isPhrase(q.Raw) {
+		// do not save sentence into DB
+		if err := l.fetch(ctx, word, lang); err != nil {
+			return nil, err
+		}
+		return word, nil
+	}
+	created, err := l.resolve(ctx, word, q.UserId, lang)
+	if err != nil {
+		return nil, err
+	}
+	l.count(word, q.UserId, created)
+	l.record(q, word.Id, repo.EventLookup)
+	word.FindQueryCount(q.UserId)
+	suggestSpellings(word)
+	return word, nil
+}
+
+// Mark toggles whether a user knows a word, a word not in the dictionary yet is looked up and
+// stored first. Errors are upstream failures, ErrSaveWord or ErrSaveMark.
+func (l *Lookup) Mark(ctx context.Context, q Query) (*enx.Word, error) {
+	word, lang := l.normalize(q)
+	if _, err := l.resolve(ctx, word, q.UserId, lang); err != nil {
+		return nil, err
+	}
+	ud := enx.UserDict{UserId: q.UserId, WordId: word.Id}
+	if err := ud.Mark(); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrSaveMark, word.English, err)
+	}
+	// every toggle is kept in the lookup history
+	kind := repo.EventMark
+	if ud.AlreadyAcquainted == 0 {
+		kind = repo.EventUnmark
+	}
+	l.record(q, word.Id, kind)
+	word.LoadCount = ud.QueryCount
+	word.AlreadyAcquainted = ud.AlreadyAcquainted
+	return word, nil
+}
+
+// Define translates a search key of a source language into the user's native language without
+// storing or counting anything: from the dictionary, or else the provider. A meaning isn't
+// translated back, see repo.IsReverseSearch.
+func (l *Lookup) Define(ctx context.Context, userId, from, key string) (*enx.Dictionary, error) {
+	lang := repo.GetNativeLanguage(userId)
+	dict := enx.FindOne(from, lang, key)
+	if dict.Gloss != "" || repo.IsReverseSearch(key) {
+		return dict, nil
+	}
+	return l.define(ctx, from, lang, key)
+}
+
+// DefineUpstream translates a search key like Define, with the provider only
+func (l *Lookup) DefineUpstream(ctx context.Context, userId, from, key string) (*enx.Dictionary, error) {
+	return l.define(ctx, from, repo.GetNativeLanguage(userId), key)
+}
+
+func (l *Lookup) define(ctx context.Context, from, lang, key string) (*enx.Dictionary, error) {
+	gloss, pronunciation, err := l.gloss(ctx, key, from, lang)
+	if err != nil {
+		return nil, err
+	}
+	dict := &enx.Dictionary{English: key, Pronunciation: pronunciation, Gloss: gloss, Language: lang}
+	if lang == language.Chinese {
+		dict.Chinese = gloss
+	}
+	return dict, nil
+}
+
+// isPhrase tells a phrase, which is translated as it is, from a word
+func isPhrase(raw string) bool {
+	return strings.Contains(raw, " ")
+}
+
+// normalize makes the word of a query in its source language, with the user's native language
+// as the language of its gloss
+func (l *Lookup) normalize(q Query) (*enx.Word, string) {
+	lang := repo.GetNativeLanguage(q.UserId)
+	word := &enx.Word{SourceLanguage: repo.GetSourceLanguage(q.UserId, q.From)}
+	if isPhrase(q.Raw) {
+		word.English = q.Raw
+		word.Key = language.Key(word.SourceLanguage, q.Raw)
+	} else {
+		word.SetEnglish(q.Raw)
+	}
+	word.UseLanguage(lang)
+	logger.Debugf("lookup word: %s, key: %s, user_id: %s", q.Raw, word.Key, q.UserId)
+	return word, lang
+}
+
+// resolve finds a word in the dictionary, or else fetches and stores it, created tells which
+func (l *Lookup) resolve(ctx context.Context, word *enx.Word, userId, lang string) (created bool, err error) {
+	if l.local(word, userId, lang) {
+		logger.Infof("word exist in local dict: %v", word.English)
+		l.complete(ctx, word, lang)
+		return false, nil
+	}
+	if err := l.fetch(ctx, word, lang); err != nil {
+		return false, err
+	}
+	if err := l.persist(word); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// local finds the word in the dictionary, with its translation into lang
+func (l *Lookup) local(word *enx.Word, userId, lang string) bool {
+	word.Translate(userId)
+	word.UseLanguage(lang)
+	return word.Id != ""
+}
+
+// fetch asks the provider for the translation of a word into lang, through the translation cache
+func (l *Lookup) fetch(ctx context.Context, word *enx.Word, lang string) error {
+	logger.Debugf("find from provider: %s", word.English)
+	gloss, pronunciation, err := l.gloss(ctx, word.English, word.SourceLanguage, lang)
+	if err != nil {
+		return err
+	}
+	word.SetGloss(lang, gloss)
+	word.Pronunciation = pronunciation
+	return nil
+}
+
+// complete fetches and stores the translation of a word in the dictionary that wasn't translated
+// into lang yet. Failures are logged only, the lookup goes on with what is known.
+func (l *Lookup) complete(ctx context.Context, word *enx.Word, lang string) {
+	if word.Gloss != "" {
+		return
+	}
+	gloss, _, err := l.gloss(ctx, word.English, word.SourceLanguage, lang)
+	if err != nil || gloss == "" {
+		logger.Infof("no %s gloss of word: %s, error: %v", lang, word.English, err)
+		return
+	}
+	if err := word.SaveGloss(lang, gloss); err != nil {
+		logger.Errorf("failed to save %s gloss of word: %s, error: %v", lang, word.English, err)
+	}
+}
+
+// persist stores a new word, from now on it is suggested if it has a translation
+func (l *Lookup) persist(word *enx.Word) error {
+	if err := word.Save(); err != nil {
+		logger.Errorf("failed to save word: %s, error: %v", word.English, err)
+		return fmt.Errorf("%w %s: %w", ErrSaveWord, word.English, err)
+	}
+	if word.Gloss != "" {
+		suggest.Current().Add(word.SourceLanguage, word.English, word.FrequencyRank, 1)
+	}
+	return nil
+}
+
+// count counts a lookup of a word in the user's stats, a word looked up again isn't known after all
+func (l *Lookup) count(word *enx.Word, userId string, created bool) {
+	userDict := enx.UserDict{UserId: userId, WordId: word.Id}
+	switch {
+	case created:
+		userDict.QueryCount = 1
+		userDict.Save()
+	case userDict.IsExist():
+		userDict.QueryCount = userDict.QueryCount + 1
+		if userDict.AlreadyAcquainted == 1 {
+			logger.Infof("word was marked as acquainted, resetting to unacquainted: %s", word.English)
+			userDict.AlreadyAcquainted = 0
+		}
+		userDict.UpdateQueryCount()
+	default:
+		// the count of the word from before there were user dicts
+		userDict.QueryCount = max(word.LoadCount, 0) + 1
+		userDict.Save()
+	}
+}
+
+// record keeps a lookup of a word in the user's history
+func (l *Lookup) record(q Query, wordId, kind string) {
+	repo.RecordLookupEvent(&repo.LookupEvent{UserId: q.UserId, WordId: wordId, Kind: kind, Source: q.Source, PageHost: q.PageHost})
+}
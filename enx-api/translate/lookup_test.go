@@ -0,0 +1,255 @@
+package translate
+
+import (
+	"context"
+	"enx-api/enx"
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/suggest"
+	"enx-api/upstream"
+	"enx-api/utils/sqlitex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stubGlosses translates the words it knows, keyed by source language, word and target language,
+// fails for "outage" and counts the calls
+type stubGlosses struct {
+	glosses map[string]string
+	calls   []string
+}
+
+func (s *stubGlosses) gloss(ctx context.Context, words, from, to string) (string, string, error) {
+	s.calls = append(s.calls, from+" "+words+" "+to)
+	if words == "outage" {
+		return "", "", &upstream.Error{Provider: "stub", StatusCode: http.StatusTooManyRequests}
+	}
+	return s.glosses[from+" "+words+" "+to], "/" + strings.ToLower(words) + "/", nil
+}
+
+// setupLookupTest opens an in-memory database with harbor, which user-1 looked up before,
+// user-2 is a Japanese reader of German
+func setupLookupTest(t *testing.T) (*Lookup, *stubGlosses) {
+	t.Setenv("DB_PATH", sqlitex.Memory)
+	sqlitex.Init()
+	previousIndex, previousList := suggest.Current(), frequency.Current()
+	t.Cleanup(func() {
+		suggest.SetIndex(previousIndex)
+		frequency.SetList(previousList)
+	})
+	list, err := frequency.Parse(strings.NewReader("the\nharbor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frequency.SetList(list)
+	suggest.SetIndex(suggest.New(2))
+
+	harbor := &repo.Word{English: "harbor", Chinese: "n. 港口", LoadCount: 4}
+	if err := repo.CreateWord(harbor); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertUserDict("user-1", harbor.Id, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUserLanguages(&repo.UserLanguages{UserId: "user-2", NativeLanguage: language.Japanese, StudyLanguages: "de,en"}); err != nil {
+		t.Fatal(err)
+	}
+	stub := &stubGlosses{glosses: map[string]string{
+		"en harbour zh-CHS":   "n. 港口（英式）",
+		"en ice cream zh-CHS": "冰淇淋",
+		"en harbor ja":        "港",
+		"de Hafen ja":         "港",
+	}}
+	return NewLookup(stub.gloss), stub
+}
+
+func lookupEvents(userId string) []string {
+	var kinds []string
+	sqlitex.DB.Raw("SELECT kind FROM lookup_events WHERE user_id = ? ORDER BY seq", userId).Scan(&kinds)
+	return kinds
+}
+
+func TestLookupTranslate(t *testing.T) {
+	lookup, stub := setupLookupTest(t)
+
+	// run in order on the same database
+	tests := []struct {
+		name       string
+		query      Query
+		english    string
+		gloss      string
+		count      int
+		acquainted int
+		calls      []string // of the provider
+		stored     bool
+		err        bool
+	}{
+		{name: "known word looked up again isn't known after all",
+			query: Query{UserId: "user-1", Raw: "Harbor,"}, english: "Harbor", gloss: "n. 港口", count: 3, stored: true},
+		{name: "legacy count of another user",
+			query: Query{UserId: "user-3", Raw: "harbor"}, english: "harbor", gloss: "n. 港口", count: 5, stored: true},
+		{name: "new word stored",
+			query: Query{UserId: "user-1", Raw: "harbour."}, english: "harbour", gloss: "n. 港口（英式）", count: 1,
+			calls: []string{"en harbour zh-CHS"}, stored: true},
+		{name: "stored word from the dictionary",
+			query: Query{UserId: "user-1", Raw: "harbour"}, english: "harbour", gloss: "n. 港口（英式）", count: 2, stored: true},
+		{name: "phrase not stored",
+			query: Query{UserId: "user-1", Raw: "ice cream"}, english: "ice cream", gloss: "冰淇淋",
+			calls: []string{"en ice cream zh-CHS"}},
+		{name: "missing translation completed",
+			query: Query{UserId: "user-2", Raw: "harbor", From: "en"}, english: "harbor", gloss: "港", count: 5,
+			calls: []string{"en harbor ja"}, stored: true},
+		{name: "default study language",
+			query: Query{UserId: "user-2", Raw: "Hafen"}, english: "Hafen", gloss: "港", count: 1,
+			calls: []string{"de Hafen ja"}, stored: true},
+		{name: "upstream failure",
+			query: Query{UserId: "user-1", Raw: "outage"}, calls: []string{"en outage zh-CHS"}, err: true},
+	}
+	for _, test := range tests {
+		stub.calls = nil
+		word, err := lookup.Translate(context.Background(), test.query)
+		if test.err {
+			if upstream.HTTPStatus(err) != http.StatusServiceUnavailable {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		} else if word.English != test.english || word.Gloss != test.gloss || word.LoadCount != test.count ||
+			word.AlreadyAcquainted != test.acquainted {
+			t.Errorf("%s: %+v", test.name, word)
+		}
+		if !reflect.DeepEqual(stub.calls, test.calls) {
+			t.Errorf("%s, provider calls: %v, want %v", test.name, stub.calls, test.calls)
+		}
+		from := repo.GetSourceLanguage(test.query.UserId, test.query.From)
+		if stored := repo.GetWord(from, strings.Trim(test.query.Raw, ",.")).Id != ""; stored != test.stored {
+			t.Errorf("%s, stored: %v", test.name, stored)
+		}
+	}
+
+	if got := repo.GetWord(language.English, "harbor").Gloss(language.Japanese); got != "港" {
+		t.Errorf("completed gloss: %q", got)
+	}
+	// phrases aren't counted
+	if got := lookupEvents("user-1"); !reflect.DeepEqual(got, []string{repo.EventLookup, repo.EventLookup, repo.EventLookup}) {
+		t.Errorf("events: %v", got)
+	}
+	// a new word with a translation is suggested right away
+	if got := suggest.Suggest(language.English, "harbou"); !reflect.DeepEqual(got, []string{"harbour"}) {
+		t.Errorf("suggestions: %v", got)
+	}
+}
+
+func TestLookupMark(t *testing.T) {
+	lookup, stub := setupLookupTest(t)
+
+	tests := []struct {
+		name       string
+		query      Query
+		count      int
+		acquainted int
+		calls      []string
+		err        bool
+	}{
+		{name: "known word", query: Query{UserId: "user-1", Raw: "harbor"}, count: 2},
+		{name: "unknown again", query: Query{UserId: "user-1", Raw: "harbor"}, count: 2, acquainted: 1},
+		{name: "never looked up", query: Query{UserId: "user-3", Raw: "harbor"}, acquainted: 1},
+		{name: "new word stored first", query: Query{UserId: "user-1", Raw: "harbour"}, acquainted: 1,
+			calls: []string{"en harbour zh-CHS"}},
+		{name: "upstream failure", query: Query{UserId: "user-1", Raw: "outage"}, calls: []string{"en outage zh-CHS"}, err: true},
+	}
+	for _, test := range tests {
+		stub.calls = nil
+		word, err := lookup.Mark(context.Background(), test.query)
+		if test.err != (err != nil) {
+			t.Errorf("%s: %v", test.name, err)
+		} else if err == nil && (word.Id == "" || word.LoadCount != test.count || word.AlreadyAcquainted != test.acquainted) {
+			t.Errorf("%s: %+v", test.name, word)
+		}
+		if !reflect.DeepEqual(stub.calls, test.calls) {
+			t.Errorf("%s, provider calls: %v, want %v", test.name, stub.calls, test.calls)
+		}
+	}
+	want := []string{repo.EventUnmark, repo.EventMark, repo.EventMark}
+	if got := lookupEvents("user-1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("events: %v, want %v", got, want)
+	}
+}
+
+func TestLookupSaveFailure(t *testing.T) {
+	lookup, _ := setupLookupTest(t)
+	if err := sqlitex.DB.Exec("CREATE TRIGGER fail_words BEFORE INSERT ON words BEGIN SELECT RAISE(ABORT, 'disk full'); END").Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlitex.DB.Exec("DROP TRIGGER fail_words") })
+
+	for name, lookupFunc := range map[string]func(context.Context, Query) (*enx.Word, error){"translate": lookup.Translate, "mark": lookup.Mark} {
+		if _, err := lookupFunc(context.Background(), Query{UserId: "user-1", Raw: "harbour"}); !errors.Is(err, ErrSaveWord) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	// nothing counted for a word without an id
+	var count int64
+	sqlitex.DB.Raw("SELECT COUNT(*) FROM user_dicts WHERE word_id = ''").Scan(&count)
+	if count != 0 || len(lookupEvents("user-1")) != 0 {
+		t.Errorf("lookup counted, user dicts: %d, events: %v", count, lookupEvents("user-1"))
+	}
+
+	// a mark that isn't stored isn't reported as flipped
+	if err := sqlitex.DB.Exec("CREATE TRIGGER fail_user_dicts BEFORE UPDATE ON user_dicts BEGIN SELECT RAISE(ABORT, 'disk full'); END").Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlitex.DB.Exec("DROP TRIGGER fail_user_dicts") })
+	if word, err := lookup.Mark(context.Background(), Query{UserId: "user-1", Raw: "harbor"}); !errors.Is(err, ErrSaveMark) || word != nil {
+		t.Errorf("mark: %+v, %v", word, err)
+	}
+	ud := enx.UserDict{UserId: "user-1", WordId: repo.GetWord(language.English, "harbor").Id}
+	if !ud.IsExist() || ud.AlreadyAcquainted != 1 || len(lookupEvents("user-1")) != 0 {
+		t.Errorf("mark stored, user dict: %+v, events: %v", ud, lookupEvents("user-1"))
+	}
+}
+
+func TestLookupDefine(t *testing.T) {
+	lookup, stub := setupLookupTest(t)
+
+	tests := []struct {
+		userId, key string
+		upstream    bool
+		want        enx.Dictionary
+		calls       []string
+	}{
+		{"user-1", "harbor", false, enx.Dictionary{English: "harbor", Chinese: "n. 港口", Gloss: "n. 港口", Language: language.Chinese}, nil},
+		{"user-1", "harbour", false, enx.Dictionary{English: "harbour", Chinese: "n. 港口（英式）", Gloss: "n. 港口（英式）", Pronunciation: "/harbour/",
+			Language: language.Chinese}, []string{"en harbour zh-CHS"}},
+		{"user-1", "harbor", true, enx.Dictionary{English: "harbor", Pronunciation: "/harbor/", Language: language.Chinese}, []string{"en harbor zh-CHS"}},
+		// a meaning isn't translated back
+		{"user-1", "港口", false, enx.Dictionary{Language: language.Chinese}, nil},
+		{"user-2", "harbor", false, enx.Dictionary{English: "harbor", Gloss: "港", Pronunciation: "/harbor/", Language: language.Japanese},
+			[]string{"en harbor ja"}},
+	}
+	for _, test := range tests {
+		stub.calls = nil
+		define := lookup.Define
+		if test.upstream {
+			define = lookup.DefineUpstream
+		}
+		dict, err := define(context.Background(), test.userId, language.English, test.key)
+		if err != nil {
+			t.Errorf("%s %q: %v", test.userId, test.key, err)
+			continue
+		}
+		dict.CreateTime = ""
+		if !reflect.DeepEqual(*dict, test.want) || !reflect.DeepEqual(stub.calls, test.calls) {
+			t.Errorf("%s %q: %+v, provider calls: %v", test.userId, test.key, dict, stub.calls)
+		}
+	}
+	// nothing stored or counted
+	if repo.GetWord(language.English, "harbour").Id != "" || len(lookupEvents("user-1")) != 0 {
+		t.Error("search stored the word")
+	}
+}
@@ -0,0 +1,132 @@
+package translate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Sentence is a segment of a text, Paragraph counts from 0
+type Sentence struct {
+	Text      string `json:"text"`
+	Paragraph int    `json:"paragraph"`
+}
+
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// titles precede a name, a period after them never ends a sentence
+var titles = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true, "sr": true, "jr": true,
+	"gen": true, "col": true, "capt": true, "rev": true, "hon": true, "gov": true, "sen": true, "rep": true,
+}
+
+// abbreviations end a sentence only when a capitalized word follows, and some of them not even then
+var abbreviations = map[string]bool{
+	"e.g": false, "i.e": false, "vs": false, "cf": false, "approx": false, "no": false, "fig": false,
+	"etc": true, "inc": true, "ltd": true, "co": true, "corp": true, "u.s": true, "u.k": true,
+	"a.m": true, "p.m": true, "jan": true, "feb": true, "aug": true, "sept": true, "oct": true, "nov": true, "dec": true,
+}
+
+// Segment splits a text into sentences. Blank lines separate paragraphs, other whitespace is collapsed.
+// A sentence ends at . ! ? … or their CJK forms, along with closing quotes and brackets, unless
+// the period belongs to a title, an abbreviation, an initial or a number.
+func Segment(text string) []Sentence {
+	var sentences []Sentence
+	paragraph := 0
+	for _, block := range paragraphBreak.Split(text, -1) {
+		block = strings.Join(strings.Fields(block), " ")
+		if block == "" {
+			continue
+		}
+		for _, s := range splitSentences(block) {
+			sentences = append(sentences, Sentence{Text: s, Paragraph: paragraph})
+		}
+		paragraph++
+	}
+	return sentences
+}
+
+func splitSentences(block string) []string {
+	runes := []rune(block)
+	var sentences []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !isTerminator(runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && (isTerminator(runes[end]) || isCloser(runes[end])) {
+			end++
+		}
+		// CJK terminators need no space after them, the others do
+		cjk := runes[i] == '。' || runes[i] == '！' || runes[i] == '？'
+		if end < len(runes) && runes[end] != ' ' && !cjk {
+			i = end - 1
+			continue
+		}
+		// a lower-case word goes on with the sentence, as in "Stop!" he said
+		following := next(runes, end)
+		if unicode.IsLower(following) {
+			i = end - 1
+			continue
+		}
+		if runes[i] == '.' && end == i+1 && !endsAtPeriod(runes[start:i], following) {
+			i = end - 1
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start, i = end, end-1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+func isTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+func isCloser(r rune) bool {
+	switch r {
+	case '"', '\'', '”', '’', ')', ']', '）', '」', '』':
+		return true
+	}
+	return false
+}
+
+// next is the first letter or digit from i on, 0 at the end of the text
+func next(runes []rune, i int) rune {
+	for ; i < len(runes); i++ {
+		if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+// endsAtPeriod tells whether a single period after before ends the sentence, given the next letter
+func endsAtPeriod(before []rune, following rune) bool {
+	if following == 0 {
+		return true
+	}
+	word := string(before)
+	if i := strings.LastIndexAny(word, " (\"'“‘"); i >= 0 {
+		word = word[i+1:]
+	}
+	word = strings.ToLower(word)
+	if titles[word] {
+		return false
+	}
+	if ends, ok := abbreviations[word]; ok {
+		return ends
+	}
+	// an initial such as J. in J. K. Rowling
+	return !(len([]rune(word)) == 1 && unicode.IsLetter([]rune(word)[0]))
+}
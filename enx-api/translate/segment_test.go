@@ -0,0 +1,41 @@
+package translate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The ship left. It was late!  Was it?", []string{"The ship left.", "It was late!", "Was it?"}},
+		{"Mr. Smith met Dr. Jones at 3.30 p.m. yesterday.", []string{"Mr. Smith met Dr. Jones at 3.30 p.m. yesterday."}},
+		{"Buy fruit, e.g. Apples. Then go home.", []string{"Buy fruit, e.g. Apples.", "Then go home."}},
+		{"He works at Acme Inc. The firm is small.", []string{"He works at Acme Inc.", "The firm is small."}},
+		{"J. K. Rowling wrote it.", []string{"J. K. Rowling wrote it."}},
+		{`"Stop!" he said. "Why?" She left…`, []string{`"Stop!" he said.`, `"Why?"`, "She left…"}},
+		{"Wait... what? Fine.", []string{"Wait... what?", "Fine."}},
+		{"Visit example.com today", []string{"Visit example.com today"}},
+		{"他来了。我们走吧！", []string{"他来了。", "我们走吧！"}},
+		{" \n ", nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, s := range Segment(test.text) {
+			got = append(got, s.Text)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: %q, want: %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSegmentParagraphs(t *testing.T) {
+	got := Segment("First one. Second\none.\n\n  \nThird one.")
+	want := []Sentence{{"First one.", 0}, {"Second one.", 0}, {"Third one.", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%+v, want: %+v", got, want)
+	}
+}
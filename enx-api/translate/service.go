@@ -1,100 +1,88 @@
 package translate
 
 import (
+	"context"
 	"enx-api/enx"
+	"enx-api/language"
 	"enx-api/middleware"
+	"enx-api/suggest"
+	"enx-api/upstream"
 	"enx-api/utils/logger"
 	"enx-api/youdao"
+	"errors"
 
-	"strings"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-// search db by english, return chinese and pronunciation
-func Translate(c *gin.Context) {
-	sessionId := c.GetHeader("X-Session-ID")
-	logger.Debugf("session id: %s", sessionId)
-
-	raw := c.Query("word")
-	userId := middleware.GetUserIDFromContext(c)
-	if userId == "" {
-		logger.Errorf("no valid user id found in session")
-		c.JSON(401, gin.H{
-			"success": false,
-			"message": "Invalid session",
-		})
+// RespondLookupError answers a failed lookup with 500 if the word or mark couldn't be stored, like
+// RespondUpstreamError otherwise
+func RespondLookupError(c *gin.Context, err error) {
+	if errors.Is(err, ErrSaveWord) {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save word"})
 		return
 	}
-
-	logger.Debugf("translate word: %s, user_id: %s", raw, userId)
-
-	// do not save sentence into DB
-	if strings.Contains(raw, " ") {
-		logger.Debugf("find from youdao: %s", raw)
-		epc := youdao.Query(raw)
-		word := enx.Word{}
-		word.English = epc.English
-		word.Key = strings.ToLower(epc.English)
-		word.Chinese = epc.Chinese
-		word.Pronunciation = epc.Pronunciation
-		logger.Debugf("translate result: %+v", word)
-		c.JSON(200, word)
+	if errors.Is(err, ErrSaveMark) {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to save mark"})
 		return
 	}
+	RespondUpstreamError(c, err)
+}
 
-	word := enx.Word{}
-	word.SetEnglish(raw)
-	word.Translate(userId)
-
-	if word.Id == "" {
-		logger.Debugf("find from youdao: %s", raw)
-		epc := youdao.Query(word.English)
-		word.English = epc.English
-		word.Key = strings.ToLower(epc.English)
-		word.Chinese = epc.Chinese
-		word.Pronunciation = epc.Pronunciation
-		word.Save()
+// RespondUpstreamError answers a failed dictionary provider call with 503 while the provider
+// is unavailable and 502 otherwise
+func RespondUpstreamError(c *gin.Context, err error) {
+	status := upstream.HTTPStatus(err)
+	message := "Dictionary provider failed"
+	if status == http.StatusServiceUnavailable {
+		message = "Dictionary provider is unavailable, try again later"
+	}
+	c.JSON(status, gin.H{"success": false, "message": message})
+}
 
-		userDict := enx.UserDict{}
-		userDict.UserId = userId
-		userDict.WordId = word.Id
-		userDict.AlreadyAcquainted = word.AlreadyAcquainted
-		userDict.QueryCount = 1
-		userDict.Save()
-	} else {
-		logger.Infof("word exist in local dict: %v", raw)
-		userDict := enx.UserDict{}
-		userDict.UserId = userId
-		userDict.WordId = word.Id
-		if userDict.IsExist() {
-			userDict.QueryCount = userDict.QueryCount + 1
-			// If the word was marked as acquainted, reset to unacquainted when user queries again
-			if userDict.AlreadyAcquainted == 1 {
-				logger.Infof("word was marked as acquainted, resetting to unacquainted: %s", raw)
-				userDict.AlreadyAcquainted = 0
-			}
-			userDict.UpdateQueryCount()
-		} else {
-			if word.LoadCount >= 0 {
-				userDict.QueryCount = word.LoadCount + 1
-			} else {
-				userDict.QueryCount = 1
-			}
-			userDict.Save()
+// QueryGloss looks words of a source language up upstream in a target language: on the youdao
+// dictionary pages for English to Chinese, with the youdao open API for the other languages.
+// The gloss is empty when there is none.
+func QueryGloss(ctx context.Context, words, from, to string) (gloss, pronunciation string, err error) {
+	if from == language.English && to == language.Chinese {
+		epc, err := youdao.Query(ctx, words)
+		if err != nil {
+			return "", "", err
 		}
+		return epc.Chinese, epc.Pronunciation, nil
 	}
-	word.FindQueryCount(userId)
-	logger.Debugf("translate result: %+v", word)
-	c.JSON(200, word)
+	resp, err := youdao.Translate(ctx, words, from, to)
+	if err != nil || resp == nil {
+		return "", "", err
+	}
+	gloss, pronunciation = resp.Gloss()
+	return gloss, pronunciation, nil
+}
+
+// suggestSpellings suggests other spellings of a word neither the dictionary nor the provider
+// has a translation of, e.g. one with a typo
+func suggestSpellings(word *enx.Word) {
+	if word.Gloss == "" {
+		word.Suggestions = suggest.Suggest(word.SourceLanguage, word.English)
+	}
+}
+
+// Translate handles /translate?word=, the gloss in the user's native language and pronunciation
+// of a word. from is the language of the word, see repo.GetSourceLanguage.
+func Translate(c *gin.Context) {
+	translateWord(c, c.Query("word"))
 }
 
-// TranslateByWord handles /api/word/:word endpoint - same functionality as Translate but gets word from URL path
+// TranslateByWord handles /word/:word, Translate with the word in the URL path
 func TranslateByWord(c *gin.Context) {
+	translateWord(c, c.Param("word"))
+}
+
+func translateWord(c *gin.Context, raw string) {
 	sessionId := c.GetHeader("X-Session-ID")
 	logger.Debugf("session id: %s", sessionId)
 
-	raw := c.Param("word")
 	userId := middleware.GetUserIDFromContext(c)
 	if userId == "" {
 		logger.Errorf("no valid user id found in session")
@@ -106,63 +94,12 @@ func TranslateByWord(c *gin.Context) {
 	}
 
 	logger.Debugf("translate word: %s, user_id: %s", raw, userId)
-
-	// do not save sentence into DB
-	if strings.Contains(raw, " ") {
-		logger.Debugf("find from youdao: %s", raw)
-		epc := youdao.Query(raw)
-		word := enx.Word{}
-		word.English = epc.English
-		word.Key = strings.ToLower(epc.English)
-		word.Chinese = epc.Chinese
-		word.Pronunciation = epc.Pronunciation
-		logger.Debugf("translate result: %+v", word)
-		c.JSON(200, word)
+	source, pageHost := middleware.GetRequestSource(c)
+	word, err := CurrentLookup().Translate(c.Request.Context(), Query{UserId: userId, Raw: raw, From: c.Query("from"), Source: source, PageHost: pageHost})
+	if err != nil {
+		RespondLookupError(c, err)
 		return
 	}
-
-	word := enx.Word{}
-	word.SetEnglish(raw)
-	word.Translate(userId)
-
-	if word.Id == "" {
-		logger.Debugf("find from youdao: %s", raw)
-		epc := youdao.Query(word.English)
-		word.English = epc.English
-		word.Key = strings.ToLower(epc.English)
-		word.Chinese = epc.Chinese
-		word.Pronunciation = epc.Pronunciation
-		word.Save()
-
-		userDict := enx.UserDict{}
-		userDict.UserId = userId
-		userDict.WordId = word.Id
-		userDict.AlreadyAcquainted = word.AlreadyAcquainted
-		userDict.QueryCount = 1
-		userDict.Save()
-	} else {
-		logger.Infof("word exist in local dict: %v", raw)
-		userDict := enx.UserDict{}
-		userDict.UserId = userId
-		userDict.WordId = word.Id
-		if userDict.IsExist() {
-			userDict.QueryCount = userDict.QueryCount + 1
-			// If the word was marked as acquainted, reset to unacquainted when user queries again
-			if userDict.AlreadyAcquainted == 1 {
-				logger.Infof("word was marked as acquainted, resetting to unacquainted: %s", raw)
-				userDict.AlreadyAcquainted = 0
-			}
-			userDict.UpdateQueryCount()
-		} else {
-			if word.LoadCount >= 0 {
-				userDict.QueryCount = word.LoadCount + 1
-			} else {
-				userDict.QueryCount = 1
-			}
-			userDict.Save()
-		}
-	}
-	word.FindQueryCount(userId)
 	logger.Debugf("translate result: %+v", word)
 	c.JSON(200, word)
 }
@@ -0,0 +1,78 @@
+package translate
+
+import (
+	"encoding/json"
+	"enx-api/cache"
+	"enx-api/enx"
+	"enx-api/frequency"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/suggest"
+	"enx-api/utils/sqlitex"
+	"enx-api/youdao"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSuggestions(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	previousCache, previousIndex, previousList := cache.Current(), suggest.Current(), frequency.Current()
+	t.Cleanup(func() {
+		cache.SetCache(previousCache)
+		suggest.SetIndex(previousIndex)
+		frequency.SetList(previousList)
+	})
+	list, err := frequency.Parse(strings.NewReader("the\nlabor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frequency.SetList(list)
+	// youdao has no entry of the typos
+	cache.SetCache(cache.New(time.Hour, time.Hour, 100))
+	for _, typo := range []string{"harbr", "hrabor"} {
+		if _, err := cache.Get(youdao.CacheProviderWeb, typo, func() (string, bool, error) { return "", false, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateWord(&repo.Word{English: "harbor", Chinese: "港口"}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := suggest.Build(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suggest.SetIndex(index)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-1") })
+	router.GET("/api/translate", Translate)
+	router.GET("/api/word/:word", TranslateByWord)
+
+	for path, want := range map[string][]string{
+		"/api/translate?word=harbr":  {"harbor"},
+		"/api/word/hrabor":           {"harbor", "labor"},
+		"/api/translate?word=harbor": nil,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var word enx.Word
+		_ = json.Unmarshal(w.Body.Bytes(), &word)
+		if w.Code != http.StatusOK || !reflect.DeepEqual(word.Suggestions, want) {
+			t.Errorf("%s, status: %d, body: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	// the typos looked up are stored without a translation, they aren't suggested
+	if got := suggest.Suggest(language.English, "harbo"); !reflect.DeepEqual(got, []string{"harbor"}) {
+		t.Errorf("suggestions after lookups: %v", got)
+	}
+}
@@ -0,0 +1,133 @@
+package translate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"enx-api/cache"
+	"enx-api/language"
+	"enx-api/middleware"
+	"enx-api/repo"
+	"enx-api/upstream"
+	"enx-api/youdao"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxTextLength is in characters
+	maxTextLength = 5000
+	maxSentences  = 200
+	// sentenceConcurrency bounds the provider calls of one request
+	sentenceConcurrency = 4
+)
+
+// TextTranslator is a provider able to translate whole sentences
+type TextTranslator interface {
+	// Name identifies the provider in the translation cache
+	Name() string
+	// TranslateText translates from a source into a target language, see language.Sources and
+	// language.Targets. found is false when the provider has no translation, errors are upstream failures.
+	TranslateText(ctx context.Context, text, from, to string) (translation string, found bool, err error)
+}
+
+// textTranslator is a variable so tests can replace the provider
+var textTranslator TextTranslator = youdao.TextTranslator{}
+
+// TranslatedSentence aligns a sentence of the text with its translation, empty when the provider has none
+type TranslatedSentence struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Paragraph int    `json:"paragraph"`
+}
+
+// sentenceKey is the translation cache key of a sentence, its hash as sentences can be long
+func sentenceKey(sentence string) string {
+	sum := sha256.Sum256([]byte(sentence))
+	return hex.EncodeToString(sum[:])
+}
+
+// TranslateSentences translates each sentence from a source into a target language through the
+// translation cache, with a few provider calls at a time
+func TranslateSentences(ctx context.Context, translator TextTranslator, sentences []Sentence, from, to string) ([]TranslatedSentence, error) {
+	result := make([]TranslatedSentence, len(sentences))
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(sentenceConcurrency)
+	for i, sentence := range sentences {
+		group.Go(func() error {
+			entry, err := cache.Get(cache.LanguageProvider(translator.Name(), from, to), sentenceKey(sentence.Text), func() (string, bool, error) {
+				// other texts with the sentence wait for this call, it mustn't end with this one
+				ctx, cancel := upstream.Detach(ctx)
+				defer cancel()
+				return translator.TranslateText(ctx, sentence.Text, from, to)
+			})
+			if err != nil {
+				return err
+			}
+			result[i] = TranslatedSentence{Source: sentence.Text, Target: entry.Value, Paragraph: sentence.Paragraph}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TranslateText handles POST /translate/text with {"text": "...", "from": "de", "to": "ja"}: the text
+// is segmented into sentences, translated one by one and returned as aligned source/target pairs.
+// from defaults to the first language the user studies and to to their native language.
+// Sentences aren't saved as words nor counted as lookups.
+func TranslateText(c *gin.Context) {
+	var req struct {
+		Text string `json:"text"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request parameters"})
+		return
+	}
+	userID := middleware.GetUserIDFromContext(c)
+	from := repo.GetStudyLanguages(userID)[0]
+	if req.From != "" {
+		var ok bool
+		if from, ok = language.ParseSource(req.From); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Unsupported source language: " + req.From})
+			return
+		}
+	}
+	to := repo.GetNativeLanguage(userID)
+	if req.To != "" {
+		var ok bool
+		if to, ok = language.ParseTarget(req.To); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Unsupported target language: " + req.To})
+			return
+		}
+	}
+	if utf8.RuneCountInString(req.Text) > maxTextLength {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Text too long, max " + strconv.Itoa(maxTextLength) + " characters"})
+		return
+	}
+	sentences := Segment(req.Text)
+	if len(sentences) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Text is empty"})
+		return
+	}
+	if len(sentences) > maxSentences {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Too many sentences, max " + strconv.Itoa(maxSentences)})
+		return
+	}
+
+	translated, err := TranslateSentences(c.Request.Context(), textTranslator, sentences, from, to)
+	if err != nil {
+		RespondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"sentences": translated, "source_language": from, "language": to,
+		"provider": textTranslator.Name()}})
+}
@@ -0,0 +1,185 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"enx-api/cache"
+	"enx-api/language"
+	"enx-api/repo"
+	"enx-api/upstream"
+	"enx-api/utils/sqlitex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubTranslator upper-cases sentences and tags them with the source language unless it is English
+// and the target language unless it is zh-CHS, has no translation for ones starting with "Xyz"
+// and fails with err when it is set
+type stubTranslator struct {
+	mu    sync.Mutex
+	calls map[string]int
+	err   error
+}
+
+func (s *stubTranslator) Name() string {
+	return "stub-text"
+}
+
+func (s *stubTranslator) TranslateText(ctx context.Context, text, from, to string) (string, bool, error) {
+	s.mu.Lock()
+	s.calls[text]++
+	s.mu.Unlock()
+	if s.err != nil {
+		return "", false, s.err
+	}
+	if strings.HasPrefix(text, "Xyz") {
+		return "", false, nil
+	}
+	tag := ""
+	if from != language.English {
+		tag = from + ">"
+	}
+	if to != language.Chinese {
+		tag += to + ":"
+	}
+	return tag + strings.ToUpper(text), true, nil
+}
+
+func setupTextTest(t *testing.T, translator *stubTranslator) *gin.Engine {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	sqlitex.Init()
+	previousCache := cache.Current()
+	cache.SetCache(cache.New(time.Hour, time.Minute, 100))
+	previous := textTranslator
+	textTranslator = translator
+	t.Cleanup(func() {
+		cache.SetCache(previousCache)
+		textTranslator = previous
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/api/translate/text", TranslateText)
+	return router
+}
+
+func postText(router *gin.Engine, text string) *httptest.ResponseRecorder {
+	return postTextAs(router, "user-1", gin.H{"text": text})
+}
+
+func postTextAs(router *gin.Engine, userID string, payload gin.H) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/translate/text", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTranslateText(t *testing.T) {
+	translator := &stubTranslator{calls: map[string]int{}}
+	router := setupTextTest(t, translator)
+
+	w := postText(router, "The ship left. Xyz abc.\n\nThe ship left.")
+	var resp struct {
+		Data struct {
+			Sentences []TranslatedSentence `json:"sentences"`
+			Provider  string               `json:"provider"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []TranslatedSentence{
+		{Source: "The ship left.", Target: "THE SHIP LEFT.", Paragraph: 0},
+		{Source: "Xyz abc.", Target: "", Paragraph: 0},
+		{Source: "The ship left.", Target: "THE SHIP LEFT.", Paragraph: 1},
+	}
+	if w.Code != http.StatusOK || resp.Data.Provider != "stub-text" || len(resp.Data.Sentences) != len(want) {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body.String())
+	}
+	for i := range want {
+		if resp.Data.Sentences[i] != want[i] {
+			t.Errorf("sentence %d: %+v, want: %+v", i, resp.Data.Sentences[i], want[i])
+		}
+	}
+
+	// cached per sentence, misses included
+	postText(router, "Xyz abc. The ship left.")
+	if translator.calls["The ship left."] != 1 || translator.calls["Xyz abc."] != 1 {
+		t.Errorf("calls: %v", translator.calls)
+	}
+}
+
+func TestTranslateTextInvalid(t *testing.T) {
+	router := setupTextTest(t, &stubTranslator{calls: map[string]int{}})
+	for name, text := range map[string]string{
+		"empty":          " \n ",
+		"too long":       strings.Repeat("a", maxTextLength+1),
+		"many sentences": strings.Repeat("Go. ", maxSentences+1),
+	} {
+		if w := postText(router, text); w.Code != http.StatusBadRequest {
+			t.Errorf("%s, status: %d", name, w.Code)
+		}
+	}
+}
+
+func TestTranslateTextUpstreamError(t *testing.T) {
+	router := setupTextTest(t, &stubTranslator{calls: map[string]int{}, err: &upstream.Error{Provider: "stub-text", StatusCode: http.StatusTooManyRequests}})
+	if w := postText(router, "The ship left."); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status: %d", w.Code)
+	}
+}
+
+func TestTranslateTextLanguage(t *testing.T) {
+	translator := &stubTranslator{calls: map[string]int{}}
+	router := setupTextTest(t, translator)
+	if err := repo.SaveUserLanguages(&repo.UserLanguages{UserId: "user-ja", NativeLanguage: language.Japanese}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUserLanguages(&repo.UserLanguages{UserId: "user-de", NativeLanguage: language.Chinese, StudyLanguages: "de,en"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Data struct {
+			Sentences []TranslatedSentence `json:"sentences"`
+			Language  string               `json:"language"`
+		} `json:"data"`
+	}
+	for _, test := range []struct {
+		userID  string
+		payload gin.H
+		want    string
+	}{
+		{"user-ja", gin.H{"text": "Go."}, "ja:GO."},
+		{"user-ja", gin.H{"text": "Go.", "to": "ES"}, "es:GO."},
+		{"user-1", gin.H{"text": "Go."}, "GO."},
+		{"user-ja", gin.H{"text": "Go.", "from": "FR"}, "fr>ja:GO."},
+		{"user-de", gin.H{"text": "Geh."}, "de>GEH."},
+	} {
+		w := postTextAs(router, test.userID, test.payload)
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || len(resp.Data.Sentences) != 1 || resp.Data.Sentences[0].Target != test.want {
+			t.Errorf("%s %v, status: %d, body: %s", test.userID, test.payload, w.Code, w.Body.String())
+		}
+	}
+	// each language is cached on its own
+	if translator.calls["Go."] != 4 {
+		t.Errorf("calls: %v", translator.calls)
+	}
+	for _, payload := range []gin.H{{"text": "Go.", "to": "xx"}, {"text": "Go.", "from": "zh-CHS"}} {
+		if w := postTextAs(router, "user-1", payload); w.Code != http.StatusBadRequest {
+			t.Errorf("unsupported language %v, status: %d", payload, w.Code)
+		}
+	}
+}
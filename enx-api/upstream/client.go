@@ -0,0 +1,240 @@
+// Package upstream is the HTTP client dictionary providers are called with. Calls time out,
+// are retried with backoff when the provider fails or throttles, and stop for a while once a
+// provider keeps failing. Failures are returned as typed errors for handlers to answer 502 or 503.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrCircuitOpen is returned without calling a provider that failed too often recently
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Error is a failed call to a provider
+type Error struct {
+	Provider string
+	// StatusCode of the provider's last response, 0 when none was received
+	StatusCode int
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("upstream %s: status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("upstream %s: %v", e.Provider, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus is the status a handler answers a failed upstream call with: 503 when the provider
+// is unavailable for now, i.e. the breaker is open or the provider throttles, 502 otherwise
+func HTTPStatus(err error) int {
+	var upstreamErr *Error
+	if errors.Is(err, ErrCircuitOpen) || errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusTooManyRequests {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// Config tunes a Client
+type Config struct {
+	// Timeout of a single attempt
+	Timeout time.Duration
+	// MaxRetries after the first attempt on network errors, 5xx and 429
+	MaxRetries int
+	// Backoff before the first retry, doubled for every further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BreakerThreshold consecutive failed calls open the breaker for BreakerCooldown, 0 disables it
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// ConfigFromViper reads upstream.*
+func ConfigFromViper() Config {
+	return Config{
+		Timeout:          viper.GetDuration("upstream.timeout"),
+		MaxRetries:       viper.GetInt("upstream.max-retries"),
+		Backoff:          viper.GetDuration("upstream.backoff"),
+		MaxBackoff:       viper.GetDuration("upstream.max-backoff"),
+		BreakerThreshold: viper.GetInt("upstream.breaker-threshold"),
+		BreakerCooldown:  viper.GetDuration("upstream.breaker-cooldown"),
+	}
+}
+
+// CallTimeout bounds a whole call: every attempt timing out, with the longest backoff before each retry
+func (c Config) CallTimeout() time.Duration {
+	return time.Duration(c.MaxRetries+1)*c.Timeout + time.Duration(c.MaxRetries)*c.MaxBackoff
+}
+
+// Detach returns the context of a call made on behalf of several requests, e.g. by a singleflight
+// loader: it keeps the values of ctx but isn't canceled with it, only once upstream.* CallTimeout passed
+func Detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	if timeout := ConfigFromViper().CallTimeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Client calls one provider
+type Client struct {
+	provider string
+	config   Config
+	http     *http.Client
+	breaker  *breaker
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient creates a client for a provider, e.g. youdao-api
+func NewClient(provider string, config Config) *Client {
+	return &Client{
+		provider: provider,
+		config:   config,
+		http:     &http.Client{Timeout: config.Timeout},
+		breaker:  newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		sleep:    sleep,
+	}
+}
+
+var (
+	mu      sync.Mutex
+	clients = map[string]*Client{}
+)
+
+// For returns the shared client of a provider, configured from upstream.*
+func For(provider string) *Client {
+	mu.Lock()
+	defer mu.Unlock()
+	client, ok := clients[provider]
+	if !ok {
+		client = NewClient(provider, ConfigFromViper())
+		clients[provider] = client
+	}
+	return client
+}
+
+// Get fetches a URL, see Do
+func (c *Client) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// PostForm posts form values to a URL, see Do
+func (c *Client) PostForm(ctx context.Context, rawURL string, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+// Do sends a request and returns the first 2xx response, whose body the caller closes.
+// Any other outcome is an *Error, or ErrCircuitOpen when the provider isn't called at all.
+// The request body has to be replayable, as it is for bodies made by http.NewRequest.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if !c.breaker.allow() {
+		return nil, &Error{Provider: c.provider, Err: ErrCircuitOpen}
+	}
+
+	var lastErr *Error
+	for attempt := 0; ; attempt++ {
+		resp, retryAfter, err := c.attempt(req)
+		if err == nil {
+			c.breaker.success()
+			return resp, nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			// the provider answered, it just rejected this request
+			c.breaker.success()
+			return nil, err
+		}
+		if attempt >= c.config.MaxRetries {
+			break
+		}
+		if err := c.sleep(req.Context(), c.backoff(attempt, retryAfter)); err != nil {
+			lastErr = &Error{Provider: c.provider, Err: err}
+			break
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				lastErr = &Error{Provider: c.provider, Err: err}
+				break
+			}
+			req.Body = body
+		}
+	}
+	c.breaker.failure()
+	return nil, lastErr
+}
+
+// attempt sends the request once, retryAfter is the provider's Retry-After if it sent one
+func (c *Client) attempt(req *http.Request) (resp *http.Response, retryAfter time.Duration, err *Error) {
+	resp, doErr := c.http.Do(req)
+	if doErr != nil {
+		return nil, 0, &Error{Provider: c.provider, Err: doErr}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, 0, nil
+	}
+	// drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, retryAfter, &Error{Provider: c.provider, StatusCode: resp.StatusCode, Err: errors.New(resp.Status)}
+}
+
+// retryable tells whether the call failed because of the provider rather than the request
+func retryable(err *Error) bool {
+	return err.StatusCode == 0 || err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// backoff doubles from Backoff up to MaxBackoff with jitter, the provider's Retry-After wins
+// when it is longer but is capped by MaxBackoff too
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	d := c.config.Backoff << attempt
+	if d <= 0 || d > c.config.MaxBackoff {
+		d = c.config.MaxBackoff
+	}
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+	}
+	return min(max(d, retryAfter), c.config.MaxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
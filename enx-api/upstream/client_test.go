@@ -0,0 +1,150 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// testServer answers with the given statuses in turn, the last one repeatedly
+func testServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		status := statuses[min(n, len(statuses))-1]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(r.FormValue("q")))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestClient(config Config) (*Client, *[]time.Duration) {
+	client := NewClient("test", config)
+	var sleeps []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return client, &sleeps
+}
+
+func TestClientRetries(t *testing.T) {
+	server, calls := testServer(t, http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK)
+	client, sleeps := newTestClient(Config{Timeout: time.Second, MaxRetries: 2, Backoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second})
+
+	resp, err := client.PostForm(context.Background(), server.URL, url.Values{"q": {"harbor"}})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if *calls != 3 || len(*sleeps) != 2 {
+		t.Errorf("calls: %d, sleeps: %v", *calls, *sleeps)
+	}
+	// backoff with jitter, then the provider's Retry-After
+	if d := (*sleeps)[0]; d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("first backoff: %v", d)
+	}
+	if d := (*sleeps)[1]; d != time.Second {
+		t.Errorf("retry after: %v", d)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantCalls  int32
+		wantStatus int
+	}{
+		{"rejected request is not retried", []int{http.StatusNotFound}, 1, http.StatusBadGateway},
+		{"server error after retries", []int{http.StatusInternalServerError}, 3, http.StatusBadGateway},
+		{"throttled after retries", []int{http.StatusTooManyRequests}, 3, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		server, calls := testServer(t, tt.statuses...)
+		client, _ := newTestClient(Config{Timeout: time.Second, MaxRetries: 2})
+		_, err := client.Get(context.Background(), server.URL)
+		var upstreamErr *Error
+		if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != tt.statuses[0] || upstreamErr.Provider != "test" {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if *calls != tt.wantCalls || HTTPStatus(err) != tt.wantStatus {
+			t.Errorf("%s, calls: %d, status: %d", tt.name, *calls, HTTPStatus(err))
+		}
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, _ := newTestClient(Config{Timeout: 50 * time.Millisecond})
+	_, err := client.Get(context.Background(), server.URL)
+	var upstreamErr *Error
+	if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != 0 || HTTPStatus(err) != http.StatusBadGateway {
+		t.Errorf("timeout: %v", err)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	viper.Set("upstream.timeout", "1s")
+	viper.Set("upstream.max-retries", 2)
+	viper.Set("upstream.max-backoff", "2s")
+	t.Cleanup(viper.Reset)
+
+	type key struct{}
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), key{}, "request"))
+	ctx, cancel := Detach(parent)
+	defer cancel()
+	cancelParent()
+
+	deadline, ok := ctx.Deadline()
+	if ctx.Err() != nil || ctx.Value(key{}) != "request" || !ok || time.Until(deadline) > 7*time.Second || time.Until(deadline) < 6*time.Second {
+		t.Errorf("detached context, err: %v, value: %v, deadline in %v", ctx.Err(), ctx.Value(key{}), time.Until(deadline))
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	server, calls := testServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	client, _ := newTestClient(Config{Timeout: time.Second, BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), server.URL); err == nil {
+			t.Fatalf("call %d succeeded", i)
+		}
+	}
+	_, err := client.Get(context.Background(), server.URL)
+	if !errors.Is(err, ErrCircuitOpen) || HTTPStatus(err) != http.StatusServiceUnavailable || *calls != 2 {
+		t.Errorf("open breaker: %v, calls: %d", err, *calls)
+	}
+
+	// after the cooldown a trial call goes out and closes the breaker again
+	now = now.Add(time.Minute)
+	resp, err := client.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	resp.Body.Close()
+	if resp, err := client.Get(context.Background(), server.URL); err != nil || *calls != 4 {
+		t.Errorf("closed breaker: %v, calls: %d", err, *calls)
+	} else {
+		resp.Body.Close()
+	}
+}
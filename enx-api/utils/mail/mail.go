@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"enx-api/utils/logger"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// SenderFile writes mails to files in mail.file-dir, for development
+	SenderFile = "file"
+	// SenderSMTP sends mails through mail.smtp-address
+	SenderSMTP = "smtp"
+)
+
+// Message is a plain text mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers mails
+type Sender interface {
+	Send(msg Message) error
+}
+
+var sender Sender = &FileSender{Dir: os.TempDir()}
+
+// Init selects the mail sender from config (mail.sender)
+func Init() error {
+	name := viper.GetString("mail.sender")
+	logger.Infof("init mail sender: %s", name)
+	switch name {
+	case "", SenderFile:
+		sender = &FileSender{Dir: viper.GetString("mail.file-dir")}
+	case SenderSMTP:
+		sender = &SMTPSender{
+			Address:  viper.GetString("mail.smtp-address"),
+			Username: viper.GetString("mail.smtp-username"),
+			Password: viper.GetString("mail.smtp-password"),
+			From:     viper.GetString("mail.from"),
+		}
+	default:
+		return fmt.Errorf("unknown mail sender: %s", name)
+	}
+	return nil
+}
+
+// SetSender replaces the mail sender, e.g. with a test double
+func SetSender(s Sender) {
+	sender = s
+}
+
+// Send delivers a mail with the configured sender
+func Send(msg Message) error {
+	return sender.Send(msg)
+}
+
+// FileSender writes every mail to its own file instead of sending it
+type FileSender struct {
+	Dir string
+}
+
+func (s *FileSender) Send(msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	path := filepath.Join(s.Dir, name)
+	if err := os.WriteFile(path, []byte(format("enx@localhost", msg)), 0600); err != nil {
+		return err
+	}
+	logger.Infof("mail written to file: %s", path)
+	return nil
+}
+
+// SMTPSender sends mails with PLAIN auth, STARTTLS is used when the server offers it
+type SMTPSender struct {
+	Address  string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	if err := smtp.SendMail(s.Address, auth, s.From, []string{msg.To}, []byte(format(s.From, msg))); err != nil {
+		logger.Errorf("failed to send mail, to: %s, error: %v", msg.To, err)
+		return err
+	}
+	logger.Infof("mail sent, to: %s, subject: %s", msg.To, msg.Subject)
+	return nil
+}
+
+func format(from string, msg Message) string {
+	// Header values must not break out of their line
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	b.WriteString("From: " + header.Replace(from) + "\r\n")
+	b.WriteString("To: " + header.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + header.Replace(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.String()
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == 0 {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -1,8 +0,0 @@
-package mysql
-
-type Config struct {
-	DatabaseName string
-	Address      string
-	Username     string
-	Password     string
-}
@@ -2,14 +2,19 @@ package password
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
 // user migration: cmd/migrate/main.go
+//
+// Raising these parameters is safe: existing hashes keep the parameters they were created with,
+// and NeedsRehash tells Login to upgrade them on the next successful login.
 const (
 	// Argon2 parameters
 	memory      = 64 * 1024 // 64MB
@@ -19,6 +24,18 @@ const (
 	keyLength   = 32
 )
 
+// MinLength is the minimum length of a new password
+const MinLength = 8
+
+var ErrInvalidHash = errors.New("invalid hash format")
+
+// params are the Argon2 parameters encoded in a hash
+type params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
 // HashPassword hashes a password using Argon2
 func HashPassword(password string) (string, error) {
 	// Generate random salt
@@ -35,40 +52,62 @@ func HashPassword(password string) (string, error) {
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
 	// Return formatted string: $argon2id$v=19$m=65536,t=3,p=2$[salt]$[hash]
-	return "$argon2id$v=19$m=65536,t=3,p=2$" + b64Salt + "$" + b64Hash, nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, memory, iterations, parallelism, b64Salt, b64Hash), nil
 }
 
 // VerifyPassword checks if the provided password matches the stored hash
 func VerifyPassword(password, encodedHash string) (bool, error) {
+	p, salt, hash, err := decodeHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	// Calculate hash of the provided password using the parameters of the stored hash
+	computedHash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(hash)))
+
+	// Compare computed hash with stored hash
+	return subtle.ConstantTimeCompare(computedHash, hash) == 1, nil
+}
+
+// NeedsRehash reports whether a hash was created with parameters other than the current ones
+func NeedsRehash(encodedHash string) bool {
+	p, salt, hash, err := decodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return p.memory != memory || p.iterations != iterations || p.parallelism != parallelism ||
+		len(salt) != saltLength || len(hash) != keyLength
+}
+
+// decodeHash parses $argon2id$v=19$m=65536,t=3,p=2$[salt]$[hash]
+func decodeHash(encodedHash string) (params, []byte, []byte, error) {
+	var p params
+
 	// Parse the encoded hash string
 	parts := strings.Split(encodedHash, "$")
-	if len(parts) != 6 {
-		return false, errors.New("invalid hash format")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
 	}
 
 	// Decode salt
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, err
+		return p, nil, nil, err
 	}
 
 	// Decode hash
 	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, err
-	}
-
-	// Calculate hash of the provided password using the same parameters
-	computedHash := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, keyLength)
-
-	// Compare computed hash with stored hash
-	if len(computedHash) != len(hash) {
-		return false, nil
-	}
-	for i := 0; i < len(hash); i++ {
-		if computedHash[i] != hash[i] {
-			return false, nil
-		}
+		return p, nil, nil, err
 	}
-	return true, nil
+	return p, salt, hash, nil
 }
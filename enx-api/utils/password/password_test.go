@@ -0,0 +1,45 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashAndVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("password_1")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if match, err := VerifyPassword("password_1", hash); err != nil || !match {
+		t.Errorf("password not verified, match: %v, error: %v", match, err)
+	}
+	if match, _ := VerifyPassword("password_2", hash); match {
+		t.Errorf("wrong password verified")
+	}
+	if NeedsRehash(hash) {
+		t.Errorf("fresh hash needs rehash")
+	}
+}
+
+func TestVerifyPasswordWithOlderParameters(t *testing.T) {
+	// A hash created before the parameters were raised
+	salt := make([]byte, saltLength)
+	_, _ = rand.Read(salt)
+	key := argon2.IDKey([]byte("password_1"), salt, 1, 32*1024, 1, keyLength)
+	old := fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s", 32*1024, 1, 1,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	if match, err := VerifyPassword("password_1", old); err != nil || !match {
+		t.Errorf("old hash not verified, match: %v, error: %v", match, err)
+	}
+	if !NeedsRehash(old) {
+		t.Errorf("old hash doesn't need rehash")
+	}
+	if _, err := VerifyPassword("password_1", "plaintext"); err == nil {
+		t.Errorf("expected invalid hash error")
+	}
+}
@@ -0,0 +1,41 @@
+// Package schedule runs the background jobs of enx-api, e.g. janitors purging expired rows.
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Every calls fn every interval in a goroutine of its own until stop is called. The interval has
+// to be positive, a missing or unparsable setting is read as 0 by viper.
+func Every(interval time.Duration, fn func()) (stop func(), err error) {
+	return start(interval, fn, false)
+}
+
+// Now calls fn right away, then every interval like Every
+func Now(interval time.Duration, fn func()) (stop func(), err error) {
+	return start(interval, fn, true)
+}
+
+func start(interval time.Duration, fn func(), now bool) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %v", interval)
+	}
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		if now {
+			fn()
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
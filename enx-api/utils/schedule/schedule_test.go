@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	var calls int32
+	stop, err := Every(time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	stopped := atomic.LoadInt32(&calls)
+	if stopped == 0 {
+		t.Error("fn not called")
+	}
+	time.Sleep(10 * time.Millisecond)
+	// a call running when stop was called may still finish
+	if after := atomic.LoadInt32(&calls); after > stopped+1 {
+		t.Errorf("fn called after stop, calls: %d, then %d", stopped, after)
+	}
+}
+
+func TestNow(t *testing.T) {
+	called := make(chan struct{}, 1)
+	stop, err := Now(time.Hour, func() { called <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Error("fn not called right away")
+	}
+}
+
+func TestInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if stop, err := Every(interval, func() {}); err == nil || stop != nil {
+			t.Errorf("interval %v accepted", interval)
+		}
+	}
+}
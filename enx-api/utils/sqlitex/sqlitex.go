@@ -1,20 +1,28 @@
 package sqlitex
 
 import (
+	"database/sql/driver"
+	"enx-api/language"
 	zapLog "enx-api/utils/logger"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"time"
 
-	"github.com/glebarez/sqlite"
+	sqlite "github.com/glebarez/go-sqlite"
+	gormsqlite "github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// Memory as DB_PATH opens an in-memory database, e.g. for tests, which lives until Init is called again
+const Memory = ":memory:"
+
 // Define models for AutoMigrate
 // These are minimal struct definitions for table creation
 type User struct {
@@ -25,17 +33,23 @@ type User struct {
 	CreatedAt     time.Time `gorm:"column:created_at"`
 	UpdatedAt     time.Time `gorm:"column:updated_at"`
 	LastLoginTime time.Time `gorm:"column:last_login_time"`
+	Role          string    `gorm:"column:role;not null;default:user"`
+	Disabled      bool      `gorm:"column:disabled;not null;default:false"`
 }
 
 type Word struct {
-	Id            string  `gorm:"column:id;primaryKey"`
-	English       string  `gorm:"column:english;unique;not null"`
-	Chinese       *string `gorm:"column:chinese"`
-	Pronunciation *string `gorm:"column:pronunciation"`
-	CreatedAt     int64   `gorm:"column:created_at;not null"`
-	UpdatedAt     int64   `gorm:"column:updated_at;not null"`
-	DeletedAt     *int64  `gorm:"column:deleted_at;index:idx_words_deleted_at"`
-	LoadCount     int     `gorm:"column:load_count;default:0"`
+	Id string `gorm:"column:id;primaryKey"`
+	// a word is unique within its source language, e.g. English and German "die" are different words
+	SourceLanguage string  `gorm:"column:source_language;not null;default:'en';uniqueIndex:idx_words_source_language_english,priority:1"`
+	English        string  `gorm:"column:english;not null;uniqueIndex:idx_words_source_language_english,priority:2"`
+	Chinese        *string `gorm:"column:chinese"`
+	Pronunciation  *string `gorm:"column:pronunciation"`
+	CreatedAt      int64   `gorm:"column:created_at;not null"`
+	UpdatedAt      int64   `gorm:"column:updated_at;not null"`
+	DeletedAt      *int64  `gorm:"column:deleted_at;index:idx_words_deleted_at"`
+	LoadCount      int     `gorm:"column:load_count;default:0"`
+	FrequencyRank  int     `gorm:"column:frequency_rank;not null;default:0"`
+	Glosses        string  `gorm:"column:glosses;not null;default:'{}'"` // JSON, target language -> translation
 }
 
 func (Word) TableName() string {
@@ -43,12 +57,15 @@ func (Word) TableName() string {
 }
 
 type UserDict struct {
-	UserId            string `gorm:"column:user_id;primaryKey"`
+	UserId            string `gorm:"column:user_id;primaryKey;index:idx_user_dicts_user_updated_at,priority:1;index:idx_user_dicts_user_query_count,priority:1"`
 	WordId            string `gorm:"column:word_id;primaryKey"`
-	QueryCount        int    `gorm:"column:query_count;default:0"`
+	QueryCount        int    `gorm:"column:query_count;default:0;index:idx_user_dicts_user_query_count,priority:2"`
 	AlreadyAcquainted int    `gorm:"column:already_acquainted;default:0"`
+	Note              string `gorm:"column:note;not null;default:''"`
+	Tags              string `gorm:"column:tags;not null;default:''"`
+	Translation       string `gorm:"column:translation;not null;default:''"`
 	CreatedAt         int64  `gorm:"column:created_at"`
-	UpdatedAt         int64  `gorm:"column:updated_at"`
+	UpdatedAt         int64  `gorm:"column:updated_at;index:idx_user_dicts_user_updated_at,priority:2"`
 }
 
 func (UserDict) TableName() string {
@@ -56,34 +73,245 @@ func (UserDict) TableName() string {
 }
 
 type Session struct {
-	ID        string `gorm:"column:id;primaryKey"`
-	UserID    string `gorm:"column:user_id"`
-	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
-	ExpiresAt int64  `gorm:"column:expires_at"` // Unix milliseconds
+	ID         string `gorm:"column:id;primaryKey"`
+	UserID     string `gorm:"column:user_id;index:idx_sessions_user_id"`
+	CreatedAt  int64  `gorm:"column:created_at"`                               // Unix milliseconds
+	ExpiresAt  int64  `gorm:"column:expires_at;index:idx_sessions_expires_at"` // Unix milliseconds
+	LastSeenAt int64  `gorm:"column:last_seen_at;not null;default:0"`          // Unix milliseconds
+	UserAgent  string `gorm:"column:user_agent"`
+	IP         string `gorm:"column:ip"`
 }
 
 func (Session) TableName() string {
 	return "sessions"
 }
 
+type APIToken struct {
+	ID         string `gorm:"column:id;primaryKey"`
+	UserID     string `gorm:"column:user_id;index:idx_api_tokens_user_id"`
+	Name       string `gorm:"column:name"`
+	TokenHash  string `gorm:"column:token_hash;not null"`
+	Scopes     string `gorm:"column:scopes;not null"`                 // comma separated
+	CreatedAt  int64  `gorm:"column:created_at"`                      // Unix milliseconds
+	LastUsedAt int64  `gorm:"column:last_used_at;not null;default:0"` // Unix milliseconds
+	ExpiresAt  int64  `gorm:"column:expires_at;not null;default:0"`   // Unix milliseconds, 0 never expires
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+type PasswordReset struct {
+	TokenHash string `gorm:"column:token_hash;primaryKey"` // sha256 of the reset token
+	UserID    string `gorm:"column:user_id;index:idx_password_resets_user_id"`
+	CreatedAt int64  `gorm:"column:created_at"`                 // Unix milliseconds
+	ExpiresAt int64  `gorm:"column:expires_at"`                 // Unix milliseconds
+	UsedAt    int64  `gorm:"column:used_at;not null;default:0"` // Unix milliseconds
+}
+
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
+
+type UserIdentity struct {
+	Issuer    string `gorm:"column:issuer;primaryKey"`
+	Subject   string `gorm:"column:subject;primaryKey"`
+	UserID    string `gorm:"column:user_id;not null;index:idx_user_identities_user_id"`
+	Email     string `gorm:"column:email"`
+	CreatedAt int64  `gorm:"column:created_at"` // Unix milliseconds
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+type LookupEvent struct {
+	Seq       int64  `gorm:"column:seq;primaryKey;autoIncrement"`
+	ID        string `gorm:"column:id;not null;unique"`
+	UserID    string `gorm:"column:user_id;not null;index:idx_lookup_events_user_created_at,priority:1"`
+	WordID    string `gorm:"column:word_id;not null"`
+	Kind      string `gorm:"column:kind;not null"`
+	CreatedAt int64  `gorm:"column:created_at;not null;index:idx_lookup_events_user_created_at,priority:2"` // Unix milliseconds
+	Source    string `gorm:"column:source;not null;default:''"`
+	PageHost  string `gorm:"column:page_host;not null;default:''"`
+}
+
+func (LookupEvent) TableName() string {
+	return "lookup_events"
+}
+
+type HighlightPolicy struct {
+	UserID          string `gorm:"column:user_id;primaryKey"`
+	MaxRank         int    `gorm:"column:max_rank;not null;default:0"`
+	AlwaysTags      string `gorm:"column:always_tags;not null;default:''"`
+	HideProperNouns int    `gorm:"column:hide_proper_nouns;not null;default:0"`
+	MinQueryCount   int    `gorm:"column:min_query_count;not null;default:1"`
+	UpdatedAt       int64  `gorm:"column:updated_at;not null"` // Unix milliseconds
+}
+
+func (HighlightPolicy) TableName() string {
+	return "highlight_policies"
+}
+
+type UserLanguages struct {
+	UserID         string `gorm:"column:user_id;primaryKey"`
+	NativeLanguage string `gorm:"column:native_language;not null;default:'zh-CHS'"`
+	StudyLanguages string `gorm:"column:study_languages;not null;default:'en'"` // comma-separated, the first one is the default
+	UpdatedAt      int64  `gorm:"column:updated_at;not null"`                   // Unix milliseconds
+}
+
+func (UserLanguages) TableName() string {
+	return "user_languages"
+}
+
 type SyncState struct {
 	PeerAddr     string `gorm:"column:peer_addr;primaryKey"`
 	LastSyncTime int64  `gorm:"column:last_sync_time;not null"` // Unix milliseconds
 	UpdatedAt    int64  `gorm:"column:updated_at;not null"`     // Unix milliseconds
+	EventsCursor int64  `gorm:"column:events_cursor;not null;default:0"`
 }
 
 func (SyncState) TableName() string {
 	return "sync_state"
 }
 
-type Youdao struct {
-	English string `gorm:"column:english;not null"`
-	Result  string `gorm:"column:result;not null"`
-	Exist   int    `gorm:"column:exist;not null;default:0"`
+type TranslationCache struct {
+	Provider  string `gorm:"column:provider;primaryKey"`
+	Key       string `gorm:"column:key;primaryKey"`
+	Value     string `gorm:"column:value;not null;default:''"`
+	Found     int    `gorm:"column:found;not null;default:0"`
+	CreatedAt int64  `gorm:"column:created_at;not null"`                                        // Unix milliseconds
+	ExpiresAt int64  `gorm:"column:expires_at;not null;index:idx_translation_cache_expires_at"` // Unix milliseconds
+}
+
+func (TranslationCache) TableName() string {
+	return "translation_cache"
+}
+
+type DictionaryEntry struct {
+	Key       string `gorm:"column:key;primaryKey"`
+	Source    string `gorm:"column:source;not null;default:''"`
+	Entry     string `gorm:"column:entry;not null"`
+	UpdatedAt int64  `gorm:"column:updated_at;not null"` // Unix milliseconds
+}
+
+func (DictionaryEntry) TableName() string {
+	return "dictionary_entries"
+}
+
+type AudioClip struct {
+	Word        string `gorm:"column:word;primaryKey"`
+	Accent      string `gorm:"column:accent;primaryKey"`
+	Hash        string `gorm:"column:hash;not null;index:idx_audio_clips_hash"`
+	ContentType string `gorm:"column:content_type;not null"`
+	Size        int64  `gorm:"column:size;not null"`
+	Source      string `gorm:"column:source;not null;default:''"`
+	CreatedAt   int64  `gorm:"column:created_at;not null"`                                    // Unix milliseconds
+	AccessedAt  int64  `gorm:"column:accessed_at;not null;index:idx_audio_clips_accessed_at"` // Unix milliseconds
+}
+
+func (AudioClip) TableName() string {
+	return "audio_clips"
+}
+
+func init() {
+	// word_key(language, word) is the key words are compared by, see language.Key.
+	// SQLite's LOWER and NOCASE fold ASCII letters only.
+	sqlite.MustRegisterDeterministicScalarFunction("word_key", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		lang, _ := args[0].(string)
+		word, _ := args[1].(string)
+		return language.Key(lang, word), nil
+	})
+}
+
+// englishUnique matches the UNIQUE of english in the words table as created by schema.sql or
+// enx-sync, from before words were unique within their source language only
+var englishUnique = regexp.MustCompile("(?i)([`\"]?english[`\"]?\\s+TEXT\\s+NOT\\s+NULL)\\s+UNIQUE")
+
+var wordsTableName = regexp.MustCompile("(?i)^CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?words[`\"]?")
+
+var ifNotExists = regexp.MustCompile("(?i)^(CREATE\\s+(?:UNIQUE\\s+)?(?:INDEX|TRIGGER))\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?")
+
+// wordsIndexes returns the statements creating the indexes and triggers of the words table
+func wordsIndexes(db *gorm.DB) ([]string, error) {
+	var statements []string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE tbl_name = 'words' AND type IN ('index', 'trigger') AND sql IS NOT NULL").Scan(&statements).Error
+	return statements, err
+}
+
+// migrateWordsUnique rebuilds the words table without the UNIQUE of english, leaving
+// idx_words_source_language_english to keep words unique. SQLite can't drop a constraint in place.
+// Rebuilding the table, here or by AutoMigrate, drops indexes and triggers gorm doesn't know of,
+// so those in indexes, as saved before AutoMigrate, are created again. It tells whether the table
+// was rebuilt either way.
+func migrateWordsUnique(db *gorm.DB, indexes []string) (bool, error) {
+	var ddl string
+	if err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'words'").Scan(&ddl).Error; err != nil {
+		return false, err
+	}
+	current, err := wordsIndexes(db)
+	if err != nil {
+		return false, err
+	}
+	// AutoMigrate rebuilt the table when it dropped any
+	rebuilt := slices.ContainsFunc(indexes, func(index string) bool { return !slices.Contains(current, index) })
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var statements []string
+		if englishUnique.MatchString(ddl) {
+			rebuilt = true
+			ddl = wordsTableName.ReplaceAllString(englishUnique.ReplaceAllString(ddl, "$1"), "CREATE TABLE words__temp")
+			statements = []string{ddl, "INSERT INTO words__temp SELECT * FROM words", "DROP TABLE words",
+				"ALTER TABLE words__temp RENAME TO words"}
+		}
+		for _, index := range append(current, indexes...) {
+			statements = append(statements, ifNotExists.ReplaceAllString(index, "$1 IF NOT EXISTS "))
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rebuilt, err
+}
+
+// wordsSearch creates words_fts, the full-text index of the english and glosses of words kept up to
+// date by triggers, see schema.sql. Accents and case are folded, e.g. uber matches Über.
+var wordsSearch = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS words_fts USING fts5(english, chinese, glosses, content='words',
+		tokenize='unicode61 remove_diacritics 2', prefix='1 2 3')`,
+	`CREATE TRIGGER IF NOT EXISTS words_fts_insert AFTER INSERT ON words BEGIN
+		INSERT INTO words_fts(rowid, english, chinese, glosses) VALUES (new.rowid, new.english, new.chinese, new.glosses);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS words_fts_delete AFTER DELETE ON words BEGIN
+		INSERT INTO words_fts(words_fts, rowid, english, chinese, glosses) VALUES ('delete', old.rowid, old.english, old.chinese, old.glosses);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS words_fts_update AFTER UPDATE OF english, chinese, glosses ON words BEGIN
+		INSERT INTO words_fts(words_fts, rowid, english, chinese, glosses) VALUES ('delete', old.rowid, old.english, old.chinese, old.glosses);
+		INSERT INTO words_fts(rowid, english, chinese, glosses) VALUES (new.rowid, new.english, new.chinese, new.glosses);
+	END`,
 }
 
-func (Youdao) TableName() string {
-	return "youdao"
+// migrateWordsSearch creates words_fts and indexes the words. The index refers to words by rowid,
+// which a rebuild of the words table doesn't keep, so then it is indexed again.
+func migrateWordsSearch(db *gorm.DB, rebuilt bool) error {
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'words_fts'").Scan(&count).Error; err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range wordsSearch {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if count > 0 && !rebuilt {
+			return nil
+		}
+		zapLog.Info("indexing words for search...")
+		return tx.Exec("INSERT INTO words_fts(words_fts) VALUES ('rebuild')").Error
+	})
 }
 
 func Init() {
@@ -110,28 +338,53 @@ func Init() {
 
 	// Ensure database directory exists
 	dbDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		zapLog.Errorf("failed to create database directory %s: %v", dbDir, err)
-		return
+	if dbPath != Memory {
+		if err := os.MkdirAll(dbDir, 0755); err != nil {
+			zapLog.Errorf("failed to create database directory %s: %v", dbDir, err)
+			return
+		}
 	}
 
 	var err error
 	zapLog.Infof("opening db: %s", dbPath)
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	DB, err = gorm.Open(gormsqlite.Open(dbPath), &gorm.Config{
 		Logger: newLogger,
 	})
 	if err != nil {
 		zapLog.Errorf("failed to init db: %s, error: %v", dbPath, err)
 		return
 	}
+	if dbPath == Memory {
+		// every connection opens a database of its own, one connection keeps the same one
+		sqlDB, err := DB.DB()
+		if err != nil {
+			zapLog.Errorf("failed to init db: %s, error: %v", dbPath, err)
+			return
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	// Auto-migrate database schema
 	zapLog.Info("running database auto-migration...")
-	err = DB.AutoMigrate(&User{}, &Word{}, &UserDict{}, &Session{}, &SyncState{}, &Youdao{})
+	indexes, err := wordsIndexes(DB)
+	if err != nil {
+		zapLog.Errorf("failed to read the indexes of words: %v", err)
+		return
+	}
+	err = DB.AutoMigrate(&User{}, &Word{}, &UserDict{}, &Session{}, &APIToken{}, &PasswordReset{}, &UserIdentity{}, &LookupEvent{}, &HighlightPolicy{}, &UserLanguages{}, &SyncState{}, &TranslationCache{}, &DictionaryEntry{}, &AudioClip{})
 	if err != nil {
 		zapLog.Errorf("failed to auto-migrate database: %v", err)
 		return
 	}
+	rebuilt, err := migrateWordsUnique(DB, indexes)
+	if err != nil {
+		zapLog.Errorf("failed to migrate the unique key of words: %v", err)
+		return
+	}
+	if err := migrateWordsSearch(DB, rebuilt); err != nil {
+		zapLog.Errorf("failed to migrate the search index of words: %v", err)
+		return
+	}
 	zapLog.Info("database auto-migration completed successfully")
 }
 
@@ -0,0 +1,107 @@
+package sqlitex
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// words as created by schema.sql before words were unique within their source language only
+const legacyWords = `
+CREATE TABLE IF NOT EXISTS words (
+    id TEXT PRIMARY KEY,
+    english TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    chinese TEXT,
+    pronunciation TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    deleted_at INTEGER,
+    load_count INTEGER DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_words_updated_at ON words(updated_at);
+INSERT INTO words (id, english, chinese, created_at, updated_at) VALUES ('word-1', 'die', '死', 1, 1);
+`
+
+func TestMigrateWordsUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enx.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(legacyWords); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	t.Setenv("DB_PATH", path)
+	Init()
+	insert := "INSERT INTO words (id, source_language, english, created_at, updated_at) VALUES (?, ?, ?, 2, 2)"
+	if err := DB.Exec(insert, "word-2", "de", "die").Error; err != nil {
+		t.Errorf("german word: %v", err)
+	}
+	if err := DB.Exec(insert, "word-3", "en", "die").Error; err == nil {
+		t.Error("english word twice")
+	}
+
+	var word struct {
+		SourceLanguage string
+		Chinese        string
+	}
+	DB.Raw("SELECT source_language, chinese FROM words WHERE id = 'word-1'").Scan(&word)
+	if word.SourceLanguage != "en" || word.Chinese != "死" {
+		t.Errorf("migrated word: %+v", word)
+	}
+	var indexes int64
+	DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_words_updated_at'").Scan(&indexes)
+	if indexes != 1 {
+		t.Error("index of the words table dropped")
+	}
+
+	var key string
+	DB.Raw("SELECT word_key('tr', 'IRMAK')").Scan(&key)
+	if key != "ırmak" {
+		t.Errorf("word_key: %q", key)
+	}
+}
+
+func TestMigrateWordsSearch(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "enx.db"))
+	Init()
+	insert := "INSERT INTO words (id, english, chinese, created_at, updated_at) VALUES (?, ?, ?, 1, 1)"
+	for _, word := range [][]string{{"word-1", "harbor", "港口"}, {"word-2", "hat", "帽子"}, {"word-3", "port", "港口"}} {
+		DB.Exec(insert, word[0], word[1], word[2])
+	}
+	DB.Exec("DELETE FROM words WHERE id = 'word-2'")
+	search := func(query string) []string {
+		var ids []string
+		DB.Raw("SELECT w.id FROM words_fts JOIN words w ON w.rowid = words_fts.rowid WHERE words_fts MATCH ? ORDER BY w.id", query).Scan(&ids)
+		return ids
+	}
+	if ids := search("港口"); len(ids) != 2 {
+		t.Fatalf("indexed: %v", ids)
+	}
+
+	// rebuilt by an older version with other rowids and a glosses column AutoMigrate alters,
+	// which rebuilds the table once more
+	var ddl string
+	DB.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'words'").Scan(&ddl)
+	indexes, _ := wordsIndexes(DB)
+	ddl = wordsTableName.ReplaceAllString(strings.Replace(ddl, "`glosses` text NOT NULL DEFAULT \"{}\"", "`glosses` text", 1), "CREATE TABLE words__temp")
+	for _, statement := range append([]string{ddl, "INSERT INTO words__temp SELECT * FROM words ORDER BY rowid DESC",
+		"DROP TABLE words", "ALTER TABLE words__temp RENAME TO words"}, indexes...) {
+		if err := DB.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	Init()
+	if ids := search("港口"); len(ids) != 2 || ids[0] != "word-1" || ids[1] != "word-3" {
+		t.Errorf("after migration: %v", ids)
+	}
+	DB.Exec(insert, "word-4", "harbour", "港口")
+	if ids := search("harb*"); len(ids) != 2 {
+		t.Errorf("triggers after migration: %v", ids)
+	}
+}
@@ -17,18 +17,83 @@ func ViperInit() {
 	// Set defaults so the app works without any config file
 	viper.SetDefault("enx.port", 8091)
 	viper.SetDefault("enx.dev-mode", false)
+	viper.SetDefault("enx.trusted-proxies", []string{})
 	viper.SetDefault("youdao.url", "https://openapi.youdao.com/api")
+	viper.SetDefault("storage.backend", "sqlite")
+	viper.SetDefault("data-service.address", "localhost:50051")
+	viper.SetDefault("session.idle-timeout", "24h")
+	viper.SetDefault("session.absolute-timeout", "720h")
+	viper.SetDefault("session.cleanup-interval", "1h")
+	viper.SetDefault("login.store", "memory")
+	viper.SetDefault("login.ip-burst", 20)
+	viper.SetDefault("login.ip-per-minute", 10)
+	viper.SetDefault("login.user-burst", 5)
+	viper.SetDefault("login.user-per-minute", 5)
+	viper.SetDefault("login.lockout-threshold", 5)
+	viper.SetDefault("login.lockout-base", "1m")
+	viper.SetDefault("login.lockout-max", "1h")
+	viper.SetDefault("login.failure-window", "15m")
+	viper.SetDefault("password.reset-ttl", "1h")
+	viper.SetDefault("password.reset-url", "http://localhost:3000/reset-password")
+	viper.SetDefault("mail.sender", "file")
+	viper.SetDefault("mail.file-dir", "/tmp/enx-mail")
+	viper.SetDefault("mail.from", "enx@localhost")
+	viper.SetDefault("oidc.enabled", false)
+	viper.SetDefault("oidc.scopes", "openid profile email")
+	viper.SetDefault("oidc.link-by-email", false)
+	viper.SetDefault("history.retention-days", 0)
+	viper.SetDefault("history.cleanup-interval", "24h")
+	viper.SetDefault("frequency.file", "")
+	viper.SetDefault("frequency.refresh-interval", "1h")
+	viper.SetDefault("suggest.max-distance", 2)
+	viper.SetDefault("suggest.refresh-interval", "1h")
+	viper.SetDefault("translation-cache.positive-ttl", "720h")
+	viper.SetDefault("translation-cache.negative-ttl", "24h")
+	viper.SetDefault("translation-cache.memory-size", 10000)
+	viper.SetDefault("translation-cache.cleanup-interval", "24h")
+	viper.SetDefault("upstream.timeout", "5s")
+	viper.SetDefault("upstream.max-retries", 2)
+	viper.SetDefault("upstream.backoff", "200ms")
+	viper.SetDefault("upstream.max-backoff", "2s")
+	viper.SetDefault("upstream.breaker-threshold", 5)
+	viper.SetDefault("upstream.breaker-cooldown", "30s")
+	viper.SetDefault("audio.provider", "youdao")
+	viper.SetDefault("audio.dir", "/tmp/enx-audio")
+	viper.SetDefault("audio.max-file-size", 1<<20)
+	viper.SetDefault("audio.max-total-size", 512<<20)
+	viper.SetDefault("audio.tts", "")
+	viper.SetDefault("audio.tts-command", "espeak-ng")
+	viper.SetDefault("audio.tts-args", []string{"-v", "{voice}", "--stdout", "{word}"})
 
 	// Bind each config key to an explicit environment variable
 	_ = viper.BindEnv("enx.port", "ENX_PORT")
 	_ = viper.BindEnv("enx.dev-mode", "ENX_DEV_MODE")
-	_ = viper.BindEnv("mysql.address", "MYSQL_ADDRESS")
-	_ = viper.BindEnv("mysql.user", "MYSQL_USER")
-	_ = viper.BindEnv("mysql.password", "MYSQL_PASSWORD")
+	_ = viper.BindEnv("enx.trusted-proxies", "ENX_TRUSTED_PROXIES")
 	_ = viper.BindEnv("redis.address", "REDIS_ADDRESS")
 	_ = viper.BindEnv("youdao.url", "YOUDAO_URL")
 	_ = viper.BindEnv("youdao.app-key", "YOUDAO_APP_KEY")
 	_ = viper.BindEnv("youdao.app-secret", "YOUDAO_APP_SECRET")
+	_ = viper.BindEnv("storage.backend", "STORAGE_BACKEND")
+	_ = viper.BindEnv("data-service.address", "DATA_SERVICE_ADDRESS")
+	_ = viper.BindEnv("session.idle-timeout", "SESSION_IDLE_TIMEOUT")
+	_ = viper.BindEnv("session.absolute-timeout", "SESSION_ABSOLUTE_TIMEOUT")
+	_ = viper.BindEnv("login.store", "LOGIN_LIMITER_STORE")
+	_ = viper.BindEnv("password.reset-url", "PASSWORD_RESET_URL")
+	_ = viper.BindEnv("mail.sender", "MAIL_SENDER")
+	_ = viper.BindEnv("mail.file-dir", "MAIL_FILE_DIR")
+	_ = viper.BindEnv("mail.from", "MAIL_FROM")
+	_ = viper.BindEnv("mail.smtp-address", "MAIL_SMTP_ADDRESS")
+	_ = viper.BindEnv("mail.smtp-username", "MAIL_SMTP_USERNAME")
+	_ = viper.BindEnv("mail.smtp-password", "MAIL_SMTP_PASSWORD")
+	_ = viper.BindEnv("oidc.enabled", "OIDC_ENABLED")
+	_ = viper.BindEnv("oidc.issuer", "OIDC_ISSUER")
+	_ = viper.BindEnv("oidc.client-id", "OIDC_CLIENT_ID")
+	_ = viper.BindEnv("oidc.client-secret", "OIDC_CLIENT_SECRET")
+	_ = viper.BindEnv("oidc.redirect-url", "OIDC_REDIRECT_URL")
+	_ = viper.BindEnv("oidc.post-login-redirect", "OIDC_POST_LOGIN_REDIRECT")
+	_ = viper.BindEnv("history.retention-days", "HISTORY_RETENTION_DAYS")
+	_ = viper.BindEnv("frequency.file", "FREQUENCY_FILE")
+	_ = viper.BindEnv("audio.dir", "AUDIO_DIR")
 
 	// Also support automatic env var lookup (e.g. ENX_PORT for enx.port)
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
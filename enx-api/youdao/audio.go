@@ -0,0 +1,50 @@
+package youdao
+
+import (
+	"context"
+	"enx-api/audio"
+	"enx-api/upstream"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProviderAudio is the youdao dictionary voice service
+const ProviderAudio = "youdao-audio"
+
+// AudioProvider fetches pronunciation audio from the youdao dictionary
+type AudioProvider struct{}
+
+func (AudioProvider) Name() string {
+	return ProviderAudio
+}
+
+func (AudioProvider) Fetch(ctx context.Context, word, accent string) ([]byte, string, error) {
+	// type 1 is the British voice, 2 the American one
+	voice := "2"
+	if accent == audio.AccentUK {
+		voice = "1"
+	}
+	rawURL := "https://dict.youdao.com/dictvoice?" + url.Values{"audio": {word}, "type": {voice}}.Encode()
+	resp, err := upstream.For(ProviderAudio).Get(ctx, rawURL)
+	var upstreamErr *upstream.Error
+	if errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusNotFound {
+		return nil, "", audio.ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, audio.ReadLimit))
+	if err != nil {
+		return nil, "", &upstream.Error{Provider: ProviderAudio, Err: err}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if len(data) == 0 || !strings.HasPrefix(contentType, "audio/") {
+		return nil, "", audio.ErrNotFound
+	}
+	return data, contentType, nil
+}
@@ -0,0 +1,141 @@
+package youdao
+
+import (
+	"context"
+	"encoding/json"
+	"enx-api/cache"
+	"enx-api/dictionary"
+	"enx-api/upstream"
+	"enx-api/utils/logger"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tidwall/gjson"
+)
+
+// QueryEntry looks a word up on the youdao dictionary website. The entry is empty when
+// youdao has no result, failed calls return an upstream error.
+func QueryEntry(ctx context.Context, words string) (*dictionary.Entry, error) {
+	cached, err := cache.Get(CacheProviderWeb, words, func() (string, bool, error) {
+		// callers looking the same words up wait for this call, it mustn't end with the first one
+		ctx, cancel := upstream.Detach(ctx)
+		defer cancel()
+		entry, err := queryPage(ctx, words)
+		if err != nil {
+			return "", false, err
+		}
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return "", false, err
+		}
+		return string(value), !entry.Empty(), nil
+	})
+	if err != nil {
+		logger.Errorf("youdao query failed, words: %s, error: %v", words, err)
+		return nil, err
+	}
+
+	entry := dictionary.New(words, ProviderWeb)
+	if cached.Found {
+		if err := json.Unmarshal([]byte(cached.Value), entry); err != nil {
+			logger.Errorf("failed to read cached youdao entry, words: %s, error: %v", words, err)
+		}
+	}
+	// the cache is case-insensitive, keep the spelling asked for
+	entry.Word = words
+	return entry, nil
+}
+
+func queryPage(ctx context.Context, words string) (*dictionary.Entry, error) {
+	baseUrl, _ := url.Parse("https://dict.youdao.com/")
+	baseUrl.Path = fmt.Sprintf("w/eng/%s", words)
+	params := url.Values{}
+	params.Add("#keyfrom", "dict2.index")
+	baseUrl.RawQuery = params.Encode()
+
+	logger.Infof("url: %v", baseUrl.String())
+	resp, err := upstream.For(ProviderWeb).Get(ctx, baseUrl.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Load the HTML document
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, &upstream.Error{Provider: ProviderWeb, Err: err}
+	}
+	return parsePage(doc, words), nil
+}
+
+// parsePage reads the entry off a dictionary page
+func parsePage(doc *goquery.Document, words string) *dictionary.Entry {
+	entry := dictionary.New(words, ProviderWeb)
+
+	doc.Find("#phrsListTab .pronounce").Each(func(_ int, s *goquery.Selection) {
+		phonetic := dictionary.TrimPhonetic(s.Find(".phonetic").Text())
+		switch label := strings.TrimSpace(s.Text()); {
+		case strings.HasPrefix(label, "英"):
+			entry.UKPhonetic = phonetic
+		case strings.HasPrefix(label, "美"):
+			entry.USPhonetic = phonetic
+		}
+	})
+	// some words have a single phonetic without a label
+	if entry.UKPhonetic == "" && entry.USPhonetic == "" {
+		phonetic := dictionary.TrimPhonetic(doc.Find("#phrsListTab .phonetic").First().Text())
+		entry.UKPhonetic, entry.USPhonetic = phonetic, phonetic
+	}
+
+	doc.Find("#phrsListTab .trans-container ul li").Each(func(_ int, s *goquery.Selection) {
+		if sense := dictionary.ParseSense(s.Text()); len(sense.Meanings) > 0 {
+			entry.Senses = append(entry.Senses, sense)
+		}
+	})
+
+	doc.Find("#bilingual ul li").Each(func(_ int, s *goquery.Selection) {
+		paragraphs := s.Find("p")
+		example := dictionary.Example{
+			English: collapseSpaces(paragraphs.Eq(0).Text()),
+			Chinese: collapseSpaces(paragraphs.Eq(1).Text()),
+		}
+		if example.English != "" {
+			entry.Examples = append(entry.Examples, example)
+		}
+	})
+
+	doc.Find("#webPhrase .wordGroup").Each(func(_ int, s *goquery.Selection) {
+		title := collapseSpaces(s.Find(".contentTitle").Text())
+		meanings := strings.TrimPrefix(collapseSpaces(s.Text()), title)
+		if title != "" {
+			entry.WebPhrases = append(entry.WebPhrases, dictionary.Phrase{Phrase: title, Meanings: dictionary.SplitMeanings(meanings)})
+		}
+	})
+	return entry
+}
+
+// parseAPIEntry reads the entry off an open API response
+func parseAPIEntry(jsonBody, words string) *dictionary.Entry {
+	entry := dictionary.New(words, ProviderAPI)
+	entry.UKPhonetic = dictionary.TrimPhonetic(gjson.Get(jsonBody, "basic.uk-phonetic").String())
+	entry.USPhonetic = dictionary.TrimPhonetic(gjson.Get(jsonBody, "basic.us-phonetic").String())
+	for _, explain := range gjson.Get(jsonBody, "basic.explains").Array() {
+		if sense := dictionary.ParseSense(explain.String()); len(sense.Meanings) > 0 {
+			entry.Senses = append(entry.Senses, sense)
+		}
+	}
+	for _, web := range gjson.Get(jsonBody, "web").Array() {
+		phrase := dictionary.Phrase{Phrase: web.Get("key").String(), Meanings: []string{}}
+		for _, value := range web.Get("value").Array() {
+			phrase.Meanings = append(phrase.Meanings, value.String())
+		}
+		entry.WebPhrases = append(entry.WebPhrases, phrase)
+	}
+	return entry
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,82 @@
+package youdao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const harborPage = `<html><body>
+<div id="phrsListTab" class="trans-wrapper clearfix">
+  <h2 class="wordbook-js">
+    <span class="keyword">harbor</span>
+    <div class="baav">
+      <span class="pronounce">英<span class="phonetic">[ˈhɑːbə]</span></span>
+      <span class="pronounce">美<span class="phonetic">[ˈhɑːrbər]</span></span>
+    </div>
+  </h2>
+  <div class="trans-container">
+    <ul>
+      <li>n. 海港；避风港</li>
+      <li>vt. 庇护；怀有</li>
+    </ul>
+  </div>
+</div>
+<div id="webPhrase">
+  <p class="wordGroup"><span class="contentTitle"><a href="#">Pearl Harbor</a></span>
+    珍珠港；珍珠港事件</p>
+</div>
+<div id="bilingual">
+  <ul class="ol">
+    <li>
+      <p><span>The</span> <span>ship</span> <span>left</span> <span>the</span> <span>harbor</span>.</p>
+      <p><span>船离开了港口。</span></p>
+      <p class="example-via"><a>dict</a></p>
+    </li>
+  </ul>
+</div>
+</body></html>`
+
+func TestParsePage(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(harborPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := parsePage(doc, "harbor")
+	if entry.UKPhonetic != "ˈhɑːbə" || entry.USPhonetic != "ˈhɑːrbər" {
+		t.Errorf("phonetics: %q, %q", entry.UKPhonetic, entry.USPhonetic)
+	}
+	if len(entry.Senses) != 2 || entry.Senses[1].PartOfSpeech != "vt." || entry.Senses[1].Meanings[1] != "怀有" {
+		t.Errorf("senses: %+v", entry.Senses)
+	}
+	if len(entry.Examples) != 1 || entry.Examples[0].English != "The ship left the harbor." || entry.Examples[0].Chinese != "船离开了港口。" {
+		t.Errorf("examples: %+v", entry.Examples)
+	}
+	if len(entry.WebPhrases) != 1 || entry.WebPhrases[0].Phrase != "Pearl Harbor" || len(entry.WebPhrases[0].Meanings) != 2 {
+		t.Errorf("web phrases: %+v", entry.WebPhrases)
+	}
+
+	// a page without a result
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(`<html><body><div id="results"></div></body></html>`))
+	if entry := parsePage(doc, "qwzx"); !entry.Empty() || entry.Senses == nil {
+		t.Errorf("empty page: %+v", entry)
+	}
+}
+
+func TestParseAPIEntry(t *testing.T) {
+	body := `{"errorCode": "0", "query": "harbor",
+		"basic": {"uk-phonetic": "ˈhɑːbə", "us-phonetic": "ˈhɑːrbər", "explains": ["n. 海港；避风港", "vt. 庇护"]},
+		"web": [{"key": "Pearl Harbor", "value": ["珍珠港", "珍珠港事件"]}]}`
+	response := parseResponse(body)
+	entry := response.Entry
+	if entry.Word != "harbor" || entry.Source != ProviderAPI || entry.USPhonetic != "ˈhɑːrbər" {
+		t.Errorf("entry: %+v", entry)
+	}
+	if len(entry.Senses) != 2 || entry.Senses[0].Meanings[1] != "避风港" {
+		t.Errorf("senses: %+v", entry.Senses)
+	}
+	if len(entry.WebPhrases) != 1 || entry.WebPhrases[0].Meanings[1] != "珍珠港事件" {
+		t.Errorf("web phrases: %+v", entry.WebPhrases)
+	}
+}
@@ -1,56 +1,128 @@
 package youdao
 
 import (
+	"context"
 	"crypto/sha256"
-	"enx-api/repo"
+	"enx-api/cache"
+	"enx-api/dictionary"
+	"enx-api/upstream"
 	"enx-api/utils/logger"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
 	"github.com/tidwall/gjson"
 	"io"
-	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// ProviderAPI is the translation cache provider of the youdao open API
+const ProviderAPI = "youdao-api"
+
 type Response struct {
 	ReturnPhrase  string
 	Query         string
 	BasicExplains string
 	Phonetic      string
+	// Translation is the machine translation of the query, set when the dictionary has no entry too
+	Translation string
+	// Entry is the whole response, structured
+	Entry *dictionary.Entry
+}
+
+// Gloss flattens the dictionary entry of the response, falling back to the machine translation
+func (r *Response) Gloss() (gloss, pronunciation string) {
+	gloss, pronunciation = r.Entry.Flatten()
+	if gloss == "" {
+		gloss = r.Translation
+	}
+	return gloss, pronunciation
+}
+
+// Translate looks words of a source language up with the youdao open API in a target language,
+// see language.Sources and language.Targets. The response is nil when youdao has no result,
+// failed calls return an upstream error.
+func Translate(ctx context.Context, words, from, to string) (*Response, error) {
+	entry, err := cache.Get(cache.LanguageProvider(ProviderAPI, from, to), words, func() (string, bool, error) {
+		// callers looking the same words up wait for this call, it mustn't end with the first one
+		ctx, cancel := upstream.Detach(ctx)
+		defer cancel()
+		return callAPI(ctx, words, from, to)
+	})
+	if err != nil {
+		logger.Errorf("youdao api failed, words: %s, error: %v", words, err)
+		return nil, err
+	}
+	if !entry.Found {
+		return nil, nil
+	}
+	return parseResponse(entry.Value), nil
+}
+
+// callAPI returns the response body, found is false when youdao answered without a translation
+func callAPI(ctx context.Context, words, from, to string) (string, bool, error) {
+	body, err := post(ctx, words, from, to)
+	if err != nil {
+		return "", false, err
+	}
+	found := gjson.Get(body, "basic").Exists() || len(gjson.Get(body, "translation").Array()) > 0
+	return body, found, nil
 }
 
-func Translate(words string) *Response {
-	// check if words already exist
-	cacheHit, youdaoCanHandleThisWorld, youdaoCache := repo.IsYouDaoRecordExist(words)
-	if cacheHit && !youdaoCanHandleThisWorld {
-		return nil
-	} else if cacheHit && youdaoCanHandleThisWorld {
-		result := youdaoCache.Result
-		return parseResponse(result)
+// ProviderAPIText is the translation cache provider of sentences translated with the youdao open API
+const ProviderAPIText = "youdao-api-text"
+
+// TextTranslator translates sentences with the youdao open API, which Query, a dictionary
+// page scraper, can't do
+type TextTranslator struct{}
+
+func (TextTranslator) Name() string {
+	return ProviderAPIText
+}
+
+// TranslateText returns the translation of a sentence from a source into a target language,
+// found is false when youdao has none
+func (TextTranslator) TranslateText(ctx context.Context, text, from, to string) (string, bool, error) {
+	body, err := post(ctx, text, from, to)
+	if err != nil {
+		return "", false, err
+	}
+	var translation strings.Builder
+	for _, part := range gjson.Get(body, "translation").Array() {
+		translation.WriteString(part.String())
 	}
+	return translation.String(), translation.Len() > 0, nil
+}
 
-	size := len(words)
-	if size > 20 {
-		words = words[0:10] + strconv.Itoa(size) + words[size-10:size]
+// signInput is what the v3 signature is computed over: long queries are cut to their first
+// and last 10 characters around their length in characters
+func signInput(q string) string {
+	runes := []rune(q)
+	if size := len(runes); size > 20 {
+		return string(runes[:10]) + strconv.Itoa(size) + string(runes[size-10:])
 	}
+	return q
+}
 
+// post sends words to the open API to be translated from a source into a target language and
+// returns the response body
+func post(ctx context.Context, words, from, to string) (string, error) {
 	appKey := viper.GetString("youdao.app-key")
 	salt := uuid.New().String()
 	currentSecond := time.Now().Unix()
 	currentSecondStr := strconv.FormatInt(currentSecond, 10)
 	appSecret := viper.GetString("youdao.app-secret")
 
-	signStr := appKey + words + salt + currentSecondStr + appSecret
+	signStr := appKey + signInput(words) + salt + currentSecondStr + appSecret
 	sum := sha256.Sum256([]byte(signStr))
 	sign := fmt.Sprintf("%x", sum)
 
 	logger.Infof("call youdao api, words: %v", words)
-	response, err := http.PostForm(viper.GetString("youdao.url"), url.Values{
-		"from":     {"en"},
-		"to":       {"zh-CHS"},
+	response, err := upstream.For(ProviderAPI).PostForm(ctx, viper.GetString("youdao.url"), url.Values{
+		"from":     {from},
+		"to":       {to},
 		"signType": {"v3"},
 		"curtime":  {currentSecondStr},
 		"appKey":   {appKey},
@@ -58,35 +130,24 @@ func Translate(words string) *Response {
 		"salt":     {salt},
 		"sign":     {sign},
 	})
-
 	if err != nil {
-		//handle postform error
+		return "", err
 	}
-
 	defer response.Body.Close()
-	logger.Infof("you dao api response status code: %v", response.StatusCode)
-	if response.StatusCode != 200 {
-		logger.Infof("status code error: %d %s", response.StatusCode, response.Status)
-		repo.SaveYouDaoDictResponse(words, "", 0)
-		return nil
-	}
 
 	body, err := io.ReadAll(response.Body)
-	logger.Infof("read response body: %v, err: %v", body, err)
 	if err != nil {
-		//handle read response error
+		return "", &upstream.Error{Provider: ProviderAPI, Err: err}
 	}
 	jsonStringBody := string(body)
 	logger.Infof("read response body: %v", jsonStringBody)
 
+	// a non-zero error code is a rejected request, e.g. a bad signature or an exhausted quota
 	errorCode := gjson.Get(jsonStringBody, "errorCode").String()
-	logger.Infof("error code: %v", errorCode)
 	if errorCode != "0" {
-		repo.SaveYouDaoDictResponse(words, "", 0)
-		return nil
+		return "", &upstream.Error{Provider: ProviderAPI, Err: fmt.Errorf("youdao error code: %s", errorCode)}
 	}
-	repo.SaveYouDaoDictResponse(words, jsonStringBody, 1)
-	return parseResponse(jsonStringBody)
+	return jsonStringBody, nil
 }
 
 func parseResponse(jsonBody string) *Response {
@@ -95,5 +156,9 @@ func parseResponse(jsonBody string) *Response {
 	youdaoResponse.Query = gjson.Get(jsonBody, "query").String()
 	youdaoResponse.BasicExplains = gjson.Get(jsonBody, "basic.explains").String()
 	youdaoResponse.Phonetic = gjson.Get(jsonBody, "basic.us-phonetic").String()
+	for _, part := range gjson.Get(jsonBody, "translation").Array() {
+		youdaoResponse.Translation += part.String()
+	}
+	youdaoResponse.Entry = parseAPIEntry(jsonBody, youdaoResponse.Query)
 	return &youdaoResponse
 }
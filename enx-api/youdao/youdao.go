@@ -1,49 +1,26 @@
 package youdao
 
 import (
+	"context"
 	"enx-api/enx"
-	"enx-api/utils/logger"
-	"fmt"
-	"github.com/PuerkitoBio/goquery"
-	"log"
-	"net/http"
-	"net/url"
 )
 
-func Query(words string) *enx.Dictionary {
-	baseUrl, _ := url.Parse("https://dict.youdao.com/")
-	baseUrl.Path = fmt.Sprintf("w/eng/%s", words)
-	params := url.Values{}
-	params.Add("#keyfrom", "dict2.index")
-	baseUrl.RawQuery = params.Encode()
+// ProviderWeb is the translation cache provider of the youdao dictionary web pages
+const ProviderWeb = "youdao-web"
 
-	logger.Infof("url: %v", baseUrl.String())
-	resp, err := http.Get(baseUrl.String())
-	if err != nil {
-		fmt.Println(err)
-		return nil
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		log.Fatalf("status code error: %d %s", resp.StatusCode, resp.Status)
-	}
+// CacheProviderWeb is the translation cache provider the entries of the web pages are kept under,
+// it changes with the format of the cached value. Rows of ProviderWeb hold the flat enx.Dictionary
+// of older versions and are left to expire.
+const CacheProviderWeb = ProviderWeb + ":v2"
 
-	// Load the HTML document
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// Query looks words up on the youdao dictionary website and flattens the entry. The dictionary
+// has an empty Chinese when youdao has no entry, failed calls return an upstream error.
+func Query(ctx context.Context, words string) (*enx.Dictionary, error) {
+	entry, err := QueryEntry(ctx, words)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	p := doc.Find("#phrsListTab .phonetic")
-	logger.Infof("pronounce: %v", p.Text())
-
-	// Find the review items
-	// .trans-container
-	s := doc.Find("#phrsListTab .trans-container ul")
-	logger.Infof("chinese: %v", s.Text())
-	epc := &enx.Dictionary{}
-	epc.English = words
-	epc.Pronunciation = p.Text()
-	epc.Chinese = s.Text()
-	return epc
+	epc := &enx.Dictionary{English: words}
+	epc.Chinese, epc.Pronunciation = entry.Flatten()
+	return epc, nil
 }
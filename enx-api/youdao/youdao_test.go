@@ -4,6 +4,8 @@
 package youdao
 
 import (
+	"context"
+	"enx-api/language"
 	"enx-api/utils"
 	"enx-api/utils/logger"
 	"enx-api/utils/sqlitex"
@@ -18,12 +20,12 @@ func init() {
 }
 func TestQuery0(t *testing.T) {
 
-	Query("decentralized")
+	Query(context.Background(), "decentralized")
 
 }
 func TestQuery1(t *testing.T) {
 
-	Query("a little")
+	Query(context.Background(), "a little")
 }
 
 func TestQuery2(t *testing.T) {
@@ -32,6 +34,6 @@ func TestQuery2(t *testing.T) {
 
 	devMode := viper.GetBool("enx.dev-mode")
 	fmt.Println("devMode:", devMode)
-	r := Translate("test")
-	fmt.Printf("r: %+v", r)
+	r, err := Translate(context.Background(), "test", language.English, language.Chinese)
+	fmt.Printf("r: %+v, err: %v", r, err)
 }
@@ -193,21 +193,26 @@ chrome.action.onClicked.addListener(async tab => {
 })
 
 // Handle messages from content scripts and popup
-chrome.runtime.onMessage.addListener((request, _sender, sendResponse) => {
+chrome.runtime.onMessage.addListener((request, sender, sendResponse) => {
   console.log('Background received message:', request)
+  const pageHeaders = pageHostHeaders(sender.tab?.url)
 
   // Handle async responses
   const handleAsync = async () => {
     try {
       switch (request.type || request.action) {
         case 'getOneWord':
-          return await handleGetOneWord(request.word)
+          return await handleGetOneWord(request.word, pageHeaders, request.from)
 
         case 'getWords':
-          return await handleGetWords(request.paragraph)
+          return await handleGetWords(request.paragraph, request.from)
 
         case 'markAcquainted':
-          return await handleMarkAcquainted(request.word, request.userId)
+          return await handleMarkAcquainted(
+            request.word,
+            request.userId,
+            pageHeaders
+          )
 
         case 'login':
           return await handleLogin(request.username, request.password)
@@ -243,15 +248,41 @@ chrome.runtime.onMessage.addListener((request, _sender, sendResponse) => {
   return true // Keep the message channel open for async response
 })
 
+// Only the host of the page a word is looked up on is sent, for the lookup history
+const pageHostHeaders = (url?: string): Record<string, string> => {
+  if (!url) {
+    return {}
+  }
+  try {
+    const { protocol, host } = new URL(url)
+    return protocol === 'http:' || protocol === 'https:'
+      ? { 'X-Page-Host': host }
+      : {}
+  } catch {
+    return {}
+  }
+}
+
+// The source language query parameter, none for pages without a lang attribute
+const fromParam = (from?: string) =>
+  from ? `&from=${encodeURIComponent(from)}` : ''
+
 // Handle get one word translation
-const handleGetOneWord = async (word: string) => {
+const handleGetOneWord = async (
+  word: string,
+  headers: Record<string, string> = {},
+  from?: string
+) => {
   if (!word || word.trim() === '') {
     return { success: false, error: 'No word provided' }
   }
 
   console.log('Handling getOneWord request for:', word)
   const encodedWord = encodeURIComponent(word.trim())
-  const response = await makeApiRequest(`/api/translate?word=${encodedWord}`)
+  const response = await makeApiRequest(
+    `/api/translate?word=${encodedWord}${fromParam(from)}`,
+    { headers }
+  )
 
   console.log('API response for word translation:', response)
 
@@ -304,14 +335,14 @@ const handleGetOneWord = async (word: string) => {
 }
 
 // Handle get multiple words
-const handleGetWords = async (paragraph: string) => {
+const handleGetWords = async (paragraph: string, from?: string) => {
   if (!paragraph || paragraph.trim() === '') {
     return { success: false, error: 'No paragraph provided' }
   }
 
   const encodedParagraph = encodeURIComponent(paragraph)
   const response = await makeApiRequest(
-    `/api/paragraph-init?paragraph=${encodedParagraph}`
+    `/api/paragraph-init?paragraph=${encodedParagraph}${fromParam(from)}`
   )
 
   console.log('paragraph-init API response:', response)
@@ -338,7 +369,11 @@ const handleGetWords = async (paragraph: string) => {
 }
 
 // Handle mark word as acquainted
-const handleMarkAcquainted = async (word: string, userId: number) => {
+const handleMarkAcquainted = async (
+  word: string,
+  userId: number,
+  headers: Record<string, string> = {}
+) => {
   if (!word) {
     console.error('handleMarkAcquainted: Missing word', { word })
     return { success: false, error: 'Missing word' }
@@ -376,6 +411,7 @@ const handleMarkAcquainted = async (word: string, userId: number) => {
 
   const response = await makeApiRequest('/api/mark', {
     method: 'POST',
+    headers,
     body: JSON.stringify({
       English: word.trim(),
     }),
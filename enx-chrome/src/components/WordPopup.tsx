@@ -111,13 +111,22 @@ export default function WordPopup({
             </div>
           )}
 
-          {/* Chinese translation */}
-          {currentWord.Chinese && (
+          {/* Translation into the user's native language */}
+          {(currentWord.Gloss || currentWord.Chinese) && (
             <div>
-              <p className="text-gray-800">{currentWord.Chinese}</p>
+              <p className="text-gray-800">{currentWord.Gloss || currentWord.Chinese}</p>
             </div>
           )}
 
+          {/* Spellings the word may be when it has no translation */}
+          {!(currentWord.Gloss || currentWord.Chinese) &&
+            currentWord.suggestions &&
+            currentWord.suggestions.length > 0 && (
+              <div className="text-gray-600 text-sm">
+                Did you mean: {currentWord.suggestions.join(', ')}?
+              </div>
+            )}
+
           {/* Acquainted status */}
           {currentWord.AlreadyAcquainted === 1 && (
             <div className="text-green-600 text-sm font-medium">
@@ -12,10 +12,16 @@ let wordCache: Record<string, WordData> = {}
 let isProcessing = false
 let popupEventCleanup: (() => void) | null = null
 
+// The language of the page as its lang attribute tells, e.g. de-AT; the server falls back to the
+// user's study languages without it
+const pageLanguage = (): string => document.documentElement.lang || ''
+
 // Word processing utilities (inline to avoid import issues)
 class ContentWordProcessor {
   static readonly WORD_PATTERNS = {
-    contractedWord: /\b[a-zA-Z][a-zA-Z'''-]*[a-zA-Z]\b|\b[a-zA-Z]\b/g,
+    // Letters of any script, \b only knows ASCII ones
+    contractedWord: /[\p{L}\p{M}][\p{L}\p{M}'’-]*[\p{L}\p{M}]|[\p{L}\p{M}]/gu,
+    letter: /\p{L}/u,
     htmlTag: /<[^>]*>/g,
     htmlEntity: /&[a-zA-Z0-9#]+;/g,
   }
@@ -36,15 +42,20 @@ class ContentWordProcessor {
           word.length > 0 &&
           word.length <= 50 &&
           !/^\d+$/.test(word) &&
-          /[a-zA-Z]/.test(word)
+          this.WORD_PATTERNS.letter.test(word)
         )
       })
-      .map(word => word.toLowerCase())
+      .map(word => word.normalize('NFC').toLocaleLowerCase(pageLanguage() || undefined))
   }
 
   static getColorCode(wordData: WordData): string {
-    // If word is already acquainted, known word type, or not in database, don't highlight
-    if (
+    // The server decides with the user's highlight policy, older servers don't send
+    // Highlight: then skip acquainted, known word type, or not in database
+    if (wordData.Highlight !== undefined) {
+      if (!wordData.Highlight) {
+        return '#FFFFFF'
+      }
+    } else if (
       wordData.AlreadyAcquainted === 1 ||
       wordData.WordType === 1 ||
       wordData.LoadCount === 0
@@ -91,8 +102,8 @@ class ContentWordProcessor {
       .map(word => ({
         word,
         regex: new RegExp(
-          `\\b${word.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')}\\b`,
-          'gi'
+          `(?<![\\p{L}\\p{M}])${word.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')}(?![\\p{L}\\p{M}])`,
+          'giu'
         ),
         colorCode: this.getColorCode(wordDict[word]),
         wordData: wordDict[word],
@@ -131,7 +142,7 @@ class ContentWordProcessor {
 
         // Only accept nodes with meaningful text content
         const text = node.textContent?.trim() || ''
-        return text.length > 0 && /[a-zA-Z]/.test(text)
+        return text.length > 0 && ContentWordProcessor.WORD_PATTERNS.letter.test(text)
           ? NodeFilter.FILTER_ACCEPT
           : NodeFilter.FILTER_REJECT
       },
@@ -410,6 +421,7 @@ const showWordPopup = async (word: string, event: MouseEvent) => {
     const response = await sendToBackground({
       type: 'getOneWord',
       word: word.trim(),
+      from: pageLanguage(),
     })
 
     console.log('Translation response:', response)
@@ -434,7 +446,8 @@ const showWordPopup = async (word: string, event: MouseEvent) => {
           <button class="enx-close-btn" style="background: none; border: none; font-size: 20px; cursor: pointer; color: #999; padding: 0; margin: 0; line-height: 1; width: 20px; height: 20px;">×</button>
         </div>
         <div class="enx-popup-content">
-          ${wordData.Chinese ? `<div style="margin-bottom: 12px; color: #333;">${wordData.Chinese}</div>` : ''}
+          ${wordData.Gloss || wordData.Chinese ? `<div style="margin-bottom: 12px; color: #333;">${wordData.Gloss || wordData.Chinese}</div>` : ''}
+          ${!(wordData.Gloss || wordData.Chinese) && wordData.suggestions?.length ? `<div style="margin-bottom: 12px; font-size: 12px; color: #666;">Did you mean: ${wordData.suggestions.join(', ')}?</div>` : ''}
           ${wordData.LoadCount !== undefined ? `<div style="margin-bottom: 12px; font-size: 12px; color: #888;">Query Count: ${wordData.LoadCount}</div>` : ''}
           ${wordData.AlreadyAcquainted === 1 ? `<div style="color: #4CAF50; font-size: 12px; margin-bottom: 12px;">✓ Already acquainted</div>` : ''}
           <div style="padding-top: 12px; border-top: 1px solid #eee;">
@@ -695,6 +708,7 @@ const processArticleContent = async (): Promise<boolean> => {
         const response = await sendToBackground({
           type: 'getWords',
           paragraph,
+          from: pageLanguage(),
         })
 
         console.log(`📨 Response for chunk ${processedChunks + 1}:`, {
@@ -87,7 +87,16 @@ export class WordProcessor {
    * Generate color code based on word familiarity
    */
   static getColorCode(wordData: WordData): string {
-    if (wordData.AlreadyAcquainted === 1 || wordData.WordType === 1) {
+    if (wordData.Highlight === false) {
+      console.log(
+        `Word "${wordData.Key}" is not highlighted: ${wordData.HighlightReason}`
+      )
+      return this.COLOR_CONFIG.acquaintedColor
+    }
+    if (
+      wordData.Highlight === undefined &&
+      (wordData.AlreadyAcquainted === 1 || wordData.WordType === 1)
+    ) {
       // add console log for debugging
       console.log(
         `Word "${wordData.Key}" is already acquainted or a special type.`
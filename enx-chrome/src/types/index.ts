@@ -8,6 +8,14 @@ export interface WordData {
   LoadCount: number
   AlreadyAcquainted: number
   WordType: number
+  // decided by the user's highlight policy on the server, missing from older servers
+  Highlight?: boolean
+  HighlightReason?: string
+  // translation into the user's native language, missing from older servers
+  Gloss?: string
+  Language?: string
+  // "did you mean" spellings when there is no translation
+  suggestions?: string[]
 }
 
 export interface User {
@@ -55,6 +63,8 @@ export interface ContentMessage {
   word?: string
   words?: string
   paragraph?: string
+  // source language of the page, see pageLanguage
+  from?: string
   userId?: number
   username?: string
   password?: string
@@ -30,6 +30,7 @@ func main() {
 	// Command line flags
 	dbPath := flag.String("db", defaultDBPath, "Database file path")
 	configPath := flag.String("config", defaultConfigPath, "Config file path")
+	bootstrapFrom := flag.String("bootstrap-from", "", "Peer address (host:port) to seed an empty database from")
 	flag.Parse()
 
 	log.Println("🚀 Starting ENX Data Service...")
@@ -42,6 +43,13 @@ func main() {
 	}
 	log.Printf("✅ Loaded configuration (node: %s)", cfg.Node.ID)
 
+	// Seed a brand-new node from a peer snapshot instead of replaying every row
+	if *bootstrapFrom != "" {
+		if err := bootstrapDatabase(*dbPath, *bootstrapFrom, cfg.Node.SyncToken); err != nil {
+			log.Fatalf("❌ Failed to bootstrap from %s: %v", *bootstrapFrom, err)
+		}
+	}
+
 	// Initialize Repository
 	repo, err := repository.NewWordRepository(*dbPath)
 	if err != nil {
@@ -52,6 +60,10 @@ func main() {
 
 	// Initialize Sync Coordinator
 	coordinator := sync.NewCoordinator(repo, cfg.Node.ID)
+	coordinator.SetSyncToken(cfg.Node.SyncToken)
+	for _, peer := range cfg.Peers {
+		coordinator.SetCompression(peer.Addr, peer.Compression)
+	}
 	log.Printf("✅ Sync coordinator initialized")
 
 	// Start gRPC Server
@@ -63,6 +75,7 @@ func main() {
 
 	grpcServer := grpc.NewServer()
 	wordService := service.NewWordService(repo)
+	wordService.SetSyncToken(cfg.Node.SyncToken)
 	pb.RegisterDataServiceServer(grpcServer, wordService)
 
 	log.Printf("✅ gRPC server listening at %v", lis.Addr())
@@ -111,6 +124,7 @@ func main() {
 				// Add default port if not specified
 				if !strings.Contains(peerAddr, ":") {
 					peerAddr = fmt.Sprintf("%s:50051", peerAddr)
+					coordinator.SetCompression(peerAddr, peer.Compression)
 				}
 
 				// Check if peer is reachable first
@@ -141,6 +155,37 @@ func main() {
 	}
 }
 
+// bootstrapDatabase copies a peer's snapshot into dbPath unless the local database already has replicated data
+func bootstrapDatabase(dbPath, peerAddr, syncToken string) error {
+	if !strings.Contains(peerAddr, ":") {
+		peerAddr = fmt.Sprintf("%s:50051", peerAddr)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		repo, err := repository.NewWordRepository(dbPath)
+		if err != nil {
+			return err
+		}
+		empty, err := repo.IsEmpty()
+		repo.Close()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			log.Printf("⚠️  Database %s already has data, skipping bootstrap", dbPath)
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	return sync.Bootstrap(ctx, peerAddr, dbPath, syncToken)
+}
+
 func loadConfig(path string) (*config.Config, error) {
 	// Try to find config file in multiple locations
 	locations := []string{
@@ -18,11 +18,16 @@ type NodeConfig struct {
 	ID       string `yaml:"id"`
 	GRPCPort int    `yaml:"grpc_port"`
 	HTTPPort int    `yaml:"http_port"`
+	// SyncToken is the secret shared by the nodes, a peer presents it to download a snapshot.
+	// Snapshots are refused while it is empty.
+	SyncToken string `yaml:"sync_token"`
 }
 
 type PeerConfig struct {
 	Addr string `yaml:"addr"`
 	Name string `yaml:"name"`
+	// Compression of sync streams pulled from this peer: zstd (default), gzip or none
+	Compression string `yaml:"compression"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -68,6 +73,9 @@ func LoadConfig(path string) (*Config, error) {
 	if httpPort := viper.GetInt("HTTP_PORT"); httpPort > 0 {
 		config.Node.HTTPPort = httpPort
 	}
+	if syncToken := viper.GetString("SYNC_TOKEN"); syncToken != "" {
+		config.Node.SyncToken = syncToken
+	}
 
 	// Parse PEERS from environment variable
 	if peersStr := viper.GetString("PEERS"); peersStr != "" {
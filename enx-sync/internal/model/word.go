@@ -4,13 +4,46 @@ package model
 // Aligned with migrated database schema (UUID + Unix timestamps)
 type Word struct {
 	ID            string  `json:"id"`            // UUID v4 primary key
-	English       string  `json:"english"`       // English word (unique)
+	English       string  `json:"english"`       // Word in SourceLanguage (unique within the language)
 	Chinese       *string `json:"chinese"`       // Chinese translation (nullable)
 	Pronunciation *string `json:"pronunciation"` // Pronunciation guide (nullable)
 	CreatedAt     int64   `json:"created_at"`    // Unix timestamp in milliseconds
 	LoadCount     int     `json:"load_count"`    // Usage counter
 	UpdatedAt     int64   `json:"updated_at"`    // Unix timestamp in milliseconds (required for sync)
 	DeletedAt     *int64  `json:"deleted_at"`    // Soft delete timestamp (NULL = not deleted)
+	// Translations by target language code, the zh-CHS one mirrors Chinese
+	Glosses map[string]string `json:"glosses"`
+	// Language of English, e.g. en, de, fr
+	SourceLanguage string `json:"source_language"`
+}
+
+// DefaultSourceLanguage is the language of words written by versions predating source languages
+const DefaultSourceLanguage = "en"
+
+// ChineseLanguage is the target language whose gloss is mirrored in Word.Chinese
+const ChineseLanguage = "zh-CHS"
+
+// SetGloss sets the translation of a word in a target language, the zh-CHS one becomes Chinese too
+func (w *Word) SetGloss(language, gloss string) {
+	if w.Glosses == nil {
+		w.Glosses = map[string]string{}
+	}
+	w.Glosses[language] = gloss
+	if language == ChineseLanguage {
+		w.Chinese = &gloss
+	}
+}
+
+// NormalizeGlosses fills in the zh-CHS gloss from Chinese, or Chinese from the zh-CHS gloss,
+// for words written by versions predating glosses
+func (w *Word) NormalizeGlosses() {
+	if w.Chinese != nil && *w.Chinese != "" {
+		if _, ok := w.Glosses[ChineseLanguage]; !ok {
+			w.SetGloss(ChineseLanguage, *w.Chinese)
+		}
+	} else if gloss := w.Glosses[ChineseLanguage]; gloss != "" {
+		w.Chinese = &gloss
+	}
 }
 
 // UserDict represents user-specific word data (query count, familiarity)
@@ -19,6 +52,58 @@ type UserDict struct {
 	WordId            string `json:"word_id"`            // Word UUID (foreign key to words.id)
 	QueryCount        int    `json:"query_count"`        // Number of times user queried this word
 	AlreadyAcquainted int    `json:"already_acquainted"` // 0 = learning, 1 = already knows
+	Note              string `json:"note"`               // Personal note
+	Tags              string `json:"tags"`               // Comma-separated personal tags
+	Translation       string `json:"translation"`        // Personal translation, preferred over words.chinese
 	CreatedAt         int64  `json:"created_at"`         // Unix timestamp in milliseconds
 	UpdatedAt         int64  `json:"updated_at"`         // Unix timestamp in milliseconds
 }
+
+// Lookup event kinds
+const (
+	EventLookup = "lookup" // the user looked the word up
+	EventMark   = "mark"   // the user marked the word as acquainted
+	EventUnmark = "unmark" // the user marked the word as not acquainted again
+)
+
+// LookupEvent is one entry of the append-only per-user event log.
+// Events are never updated; peers replicate them by the sender's Seq.
+type LookupEvent struct {
+	Seq       int64  `json:"seq"`        // Local append position (AUTOINCREMENT), differs between nodes
+	ID        string `json:"id"`         // UUID, the same on every node
+	UserId    string `json:"user_id"`    // User UUID
+	WordId    string `json:"word_id"`    // Word UUID (foreign key to words.id)
+	Kind      string `json:"kind"`       // EventLookup, EventMark or EventUnmark
+	CreatedAt int64  `json:"created_at"` // Unix timestamp in milliseconds
+	Source    string `json:"source"`     // "extension", "ui" or "api", empty when unknown
+	PageHost  string `json:"page_host"`  // Host of the page the word was looked up on, empty when unknown
+}
+
+// SyncCheckpoint is the resume point of an interrupted sync transfer from a peer
+type SyncCheckpoint struct {
+	PeerAddr        string `json:"peer_addr"`         // Peer the transfer is pulled from
+	Stream          string `json:"stream"`            // "words" or "user_dicts"
+	CursorUpdatedAt int64  `json:"cursor_updated_at"` // updated_at of the last applied row
+	CursorID        string `json:"cursor_id"`         // words.id or user_dicts.user_id of the last applied row
+	CursorSubID     string `json:"cursor_sub_id"`     // user_dicts.word_id of the last applied row (empty for words)
+	UpdatedAt       int64  `json:"updated_at"`        // Unix timestamp in milliseconds
+}
+
+// Snapshot describes a consistent copy of the database shipped to bootstrap a new node
+type Snapshot struct {
+	Size            int64  `json:"size"`              // Size of the database file in bytes
+	SHA256          string `json:"sha256"`            // Hex SHA-256 of the database file
+	WordsCursor     int64  `json:"words_cursor"`      // Max words.updated_at contained in the copy
+	UserDictsCursor int64  `json:"user_dicts_cursor"` // Max user_dicts.updated_at contained in the copy
+	EventsCursor    int64  `json:"events_cursor"`     // Max lookup_events.seq contained in the copy
+	CreatedAt       int64  `json:"created_at"`        // Unix timestamp in milliseconds
+}
+
+// Cursor returns the timestamp incremental sync can safely continue from.
+// An empty table (cursor 0) doesn't hold the other one back.
+func (s *Snapshot) Cursor() int64 {
+	if s.WordsCursor == 0 || s.UserDictsCursor == 0 {
+		return max(s.WordsCursor, s.UserDictsCursor)
+	}
+	return min(s.WordsCursor, s.UserDictsCursor)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"enx-sync/internal/model"
+)
+
+// AppendLookupEvent adds an event to the log and fills in its local seq.
+// An event already in the log (same id) is left alone and reported as not inserted.
+func (r *WordRepository) AppendLookupEvent(event *model.LookupEvent) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO lookup_events (id, user_id, word_id, kind, created_at, source, page_host)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, event.ID, event.UserId, event.WordId, event.Kind, event.CreatedAt, event.Source, event.PageHost)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+	event.Seq, err = result.LastInsertId()
+	return true, err
+}
+
+// FindLookupEventsAfterBatch retrieves events with seq > afterSeq in seq order, in batches.
+// Callback function receives each batch and should return true to continue, false to stop
+func (r *WordRepository) FindLookupEventsAfterBatch(afterSeq int64, batchSize int, callback func([]*model.LookupEvent) (bool, error)) error {
+	for {
+		rows, err := r.db.Query(`
+			SELECT seq, id, user_id, word_id, kind, created_at, source, page_host
+			FROM lookup_events WHERE seq > ?
+			ORDER BY seq ASC
+			LIMIT ?
+		`, afterSeq, batchSize)
+		if err != nil {
+			return err
+		}
+
+		var batch []*model.LookupEvent
+		for rows.Next() {
+			event := &model.LookupEvent{}
+			if err := rows.Scan(&event.Seq, &event.ID, &event.UserId, &event.WordId, &event.Kind, &event.CreatedAt, &event.Source, &event.PageHost); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, event)
+		}
+		rows.Close()
+
+		if len(batch) == 0 {
+			break
+		}
+		shouldContinue, err := callback(batch)
+		if err != nil {
+			return err
+		}
+		if !shouldContinue || len(batch) < batchSize {
+			break
+		}
+		afterSeq = batch[len(batch)-1].Seq
+	}
+	return nil
+}
+
+// PruneLookupEvents deletes events created before the cutoff (Unix milliseconds) and returns how many were removed.
+// The seq of the remaining events is unchanged, so peers pulling by seq are not affected.
+func (r *WordRepository) PruneLookupEvents(before int64) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM lookup_events WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune lookup events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetEventsCursor retrieves the last seq of a peer's lookup_events applied locally
+func (r *WordRepository) GetEventsCursor(peerAddr string) (int64, error) {
+	var cursor int64
+	err := r.db.QueryRow(`SELECT events_cursor FROM sync_state WHERE peer_addr = ?`, peerAddr).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get events cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// UpdateEventsCursor records the last seq of a peer's lookup_events applied locally
+func (r *WordRepository) UpdateEventsCursor(peerAddr string, seq, now int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO sync_state (peer_addr, last_sync_time, updated_at, events_cursor)
+		VALUES (?, 0, ?, ?)
+		ON CONFLICT(peer_addr) DO UPDATE SET
+			events_cursor = excluded.events_cursor,
+			updated_at = excluded.updated_at
+	`, peerAddr, now, seq)
+	if err != nil {
+		return fmt.Errorf("failed to update events cursor: %w", err)
+	}
+	return nil
+}
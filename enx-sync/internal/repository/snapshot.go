@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"enx-sync/internal/model"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// snapshotTables are the replicated tables a snapshot keeps. Every other table of the shared file is
+// dropped from the copy: users, sessions, API tokens and password resets never leave a node, and
+// the caches of enx-api are filled again on the new one.
+var snapshotTables = map[string]bool{
+	"words":            true,
+	"user_dicts":       true,
+	"lookup_events":    true,
+	"sync_state":       true,
+	"sync_checkpoints": true,
+}
+
+// Snapshot writes a consistent copy of the replicated tables to destPath using SQLite's online
+// backup API and returns the change cursors the copy corresponds to.
+func (r *WordRepository) Snapshot(ctx context.Context, destPath string) (*model.Snapshot, error) {
+	destDB, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer destDB.Close()
+
+	srcConn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connection: %w", err)
+	}
+	defer srcConn.Close()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot connection: %w", err)
+	}
+	defer destConn.Close()
+
+	err = destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			dest, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", destDriverConn)
+			}
+			src, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcDriverConn)
+			}
+
+			backup, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+
+			// Copy all pages in one step so the copy reflects a single point in time.
+			// Step reports not done while the source is busy/locked, retry until it completes.
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err := stripSnapshot(ctx, destDB); err != nil {
+		return nil, err
+	}
+
+	// Read the cursors from the copy itself, so they match its content exactly
+	snapshot := &model.Snapshot{CreatedAt: time.Now().UnixMilli()}
+	err = destDB.QueryRowContext(ctx, `SELECT COALESCE(MAX(updated_at), 0) FROM words`).Scan(&snapshot.WordsCursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read words cursor: %w", err)
+	}
+	err = destDB.QueryRowContext(ctx, `SELECT COALESCE(MAX(updated_at), 0) FROM user_dicts`).Scan(&snapshot.UserDictsCursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user_dicts cursor: %w", err)
+	}
+	err = destDB.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM lookup_events`).Scan(&snapshot.EventsCursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lookup_events cursor: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// stripSnapshot drops the tables that aren't replicated from a copy, and vacuums it so that none
+// of their rows stay behind in free pages. The search index of words is kept, it is derived from them.
+func stripSnapshot(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshot tables: %w", err)
+	}
+	var drop []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list snapshot tables: %w", err)
+		}
+		if !snapshotTables[name] && !strings.HasPrefix(name, "sqlite_") && !strings.HasPrefix(name, "words_fts") {
+			drop = append(drop, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list snapshot tables: %w", err)
+	}
+
+	for _, name := range drop {
+		if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS "`+name+`"`); err != nil {
+			return fmt.Errorf("failed to drop %s from snapshot: %w", name, err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum snapshot: %w", err)
+	}
+	return nil
+}
+
+// IsEmpty reports whether the replicated tables have no words, user_dicts and lookup_events yet.
+// The other tables, e.g. the users of enx-api, aren't counted, a snapshot leaves them alone.
+func (r *WordRepository) IsEmpty() (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT (SELECT COUNT(*) FROM words) + (SELECT COUNT(*) FROM user_dicts) +
+		(SELECT COUNT(*) FROM lookup_events)`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return count == 0, nil
+}
+
+// RestoreSnapshot copies the replicated tables of the snapshot file at snapshotPath into the
+// database in one transaction, replacing their rows. The database file stays in place, so enx-api
+// can keep it open, and its other tables are left alone. Columns only one side has are skipped.
+func (r *WordRepository) RestoreSnapshot(ctx context.Context, snapshotPath string) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS snapshot`, snapshotPath); err != nil {
+		return fmt.Errorf("failed to attach snapshot: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `DETACH DATABASE snapshot`)
+
+	tables := make([]string, 0, len(snapshotTables))
+	for name := range snapshotTables {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range tables {
+		columns, err := sharedColumns(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM main."`+name+`"`); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+		list := `"` + strings.Join(columns, `", "`) + `"`
+		if _, err := tx.ExecContext(ctx, `INSERT INTO main."`+name+`" (`+list+`) SELECT `+list+` FROM snapshot."`+name+`"`); err != nil {
+			return fmt.Errorf("failed to copy %s from snapshot: %w", name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return nil
+}
+
+// sharedColumns returns the columns a table has both in the database and in the attached snapshot,
+// none when either lacks the table
+func sharedColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	snapshotColumns := map[string]bool{}
+	var columns []string
+	for _, schema := range []string{"snapshot", "main"} {
+		rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?, ?)`, table, schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+			}
+			if schema == "snapshot" {
+				snapshotColumns[name] = true
+			} else if snapshotColumns[name] {
+				columns = append(columns, name)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+	}
+	return columns, nil
+}
+
+// ResetSyncState removes sync progress copied from another node (sync_state and sync_checkpoints)
+func (r *WordRepository) ResetSyncState() error {
+	if _, err := r.db.Exec(`DELETE FROM sync_state`); err != nil {
+		return fmt.Errorf("failed to reset sync_state: %w", err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM sync_checkpoints`); err != nil {
+		return fmt.Errorf("failed to reset sync_checkpoints: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"enx-sync/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	empty, err := repo.IsEmpty()
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	now := time.Now().UnixMilli()
+	require.NoError(t, repo.Create(&model.Word{ID: uuid.New().String(), English: "a", CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, repo.Create(&model.Word{ID: uuid.New().String(), English: "b", CreatedAt: now, UpdatedAt: now + 10}))
+
+	// tables of enx-api that aren't replicated
+	_, err = repo.db.Exec(`CREATE TABLE sessions (id TEXT PRIMARY KEY, user_id TEXT);
+		INSERT INTO sessions VALUES ('secret-session-id', 'user-1')`)
+	require.NoError(t, err)
+
+	destPath := "/tmp/test_snapshot_" + uuid.New().String() + ".db"
+	defer os.Remove(destPath)
+
+	snapshot, err := repo.Snapshot(context.Background(), destPath)
+	require.NoError(t, err)
+	assert.Equal(t, now+10, snapshot.WordsCursor)
+	assert.Equal(t, int64(0), snapshot.UserDictsCursor)
+	assert.Equal(t, now+10, snapshot.Cursor())
+
+	copied, err := NewWordRepository(destPath)
+	require.NoError(t, err)
+	defer copied.Close()
+
+	words, err := copied.FindAll()
+	require.NoError(t, err)
+	assert.Len(t, words, 2)
+
+	empty, err = copied.IsEmpty()
+	require.NoError(t, err)
+	assert.False(t, empty)
+
+	var tables int
+	require.NoError(t, copied.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'sessions'`).Scan(&tables))
+	assert.Equal(t, 0, tables)
+	content, err := os.ReadFile(destPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret-session-id")
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"regexp"
 
 	"enx-sync/internal/model"
 
@@ -22,18 +24,42 @@ func NewWordRepository(dbPath string) (*WordRepository, error) {
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS words (
 			id TEXT PRIMARY KEY,
-			english TEXT NOT NULL UNIQUE,
+			english TEXT NOT NULL,
 			chinese TEXT,
 			pronunciation TEXT,
 			created_at INTEGER,
 			load_count INTEGER NOT NULL DEFAULT 0,
 			updated_at INTEGER NOT NULL,
-			deleted_at INTEGER
+			deleted_at INTEGER,
+			glosses TEXT NOT NULL DEFAULT '{}',
+			source_language TEXT NOT NULL DEFAULT 'en'
 		)
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
+	// Translations by target language as a JSON object, added after words.chinese
+	if err := addColumnIfMissing(db, "words", "glosses", "TEXT NOT NULL DEFAULT '{}'"); err != nil {
+		return nil, fmt.Errorf("failed to migrate words: %w", err)
+	}
+	// Words are unique within their source language, added after words were all English
+	if err := addColumnIfMissing(db, "words", "source_language", "TEXT NOT NULL DEFAULT 'en'"); err != nil {
+		return nil, fmt.Errorf("failed to migrate words: %w", err)
+	}
+	// Rank in enx-api's word frequency list, local to the node: not replicated and never moves updated_at
+	if err := addColumnIfMissing(db, "words", "frequency_rank", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, fmt.Errorf("failed to migrate words: %w", err)
+	}
+	if err := migrateWordsUnique(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate words: %w", err)
+	}
+	if err := checkWordsSearch(db); err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_words_source_language_english ON words(source_language, english)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create words index: %w", err)
+	}
 
 	// Create sync_state table
 	_, err = db.Exec(`
@@ -46,6 +72,26 @@ func NewWordRepository(dbPath string) (*WordRepository, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sync_state table: %w", err)
 	}
+	// Pull cursor of the peer's lookup_events, kept across syncs since the log is append-only
+	if err := addColumnIfMissing(db, "sync_state", "events_cursor", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, fmt.Errorf("failed to migrate sync_state: %w", err)
+	}
+
+	// Create sync_checkpoints table (resume point of an interrupted transfer)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS sync_checkpoints (
+			peer_addr TEXT NOT NULL,
+			stream TEXT NOT NULL,
+			cursor_updated_at INTEGER NOT NULL,
+			cursor_id TEXT NOT NULL,
+			cursor_sub_id TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (peer_addr, stream)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync_checkpoints table: %w", err)
+	}
 
 	// Create user_dicts table
 	_, err = db.Exec(`
@@ -54,6 +100,9 @@ func NewWordRepository(dbPath string) (*WordRepository, error) {
 			word_id TEXT NOT NULL,
 			query_count INTEGER DEFAULT 0,
 			already_acquainted INTEGER DEFAULT 0,
+			note TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '',
+			translation TEXT NOT NULL DEFAULT '',
 			created_at INTEGER NOT NULL,
 			updated_at INTEGER NOT NULL,
 			PRIMARY KEY (user_id, word_id)
@@ -63,75 +112,175 @@ func NewWordRepository(dbPath string) (*WordRepository, error) {
 		return nil, fmt.Errorf("failed to create user_dicts table: %w", err)
 	}
 
-	// Create indexes for user_dicts
+	// Add the per-user annotation columns to user_dicts created by older versions
+	for _, column := range []string{"note", "tags", "translation"} {
+		if err := addColumnIfMissing(db, "user_dicts", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, fmt.Errorf("failed to migrate user_dicts: %w", err)
+		}
+	}
+
+	// Create indexes for user_dicts, the per-user ones back enx-api's word listing
 	_, err = db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_user_dicts_updated_at ON user_dicts(updated_at)
+		CREATE INDEX IF NOT EXISTS idx_user_dicts_updated_at ON user_dicts(updated_at);
+		CREATE INDEX IF NOT EXISTS idx_user_dicts_user_updated_at ON user_dicts(user_id, updated_at);
+		CREATE INDEX IF NOT EXISTS idx_user_dicts_user_query_count ON user_dicts(user_id, query_count);
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create index on user_dicts: %w", err)
 	}
 
-	return &WordRepository{db: db}, nil
-}
+	// Create lookup_events table (append-only, seq is the local append order peers pull by)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS lookup_events (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			id TEXT NOT NULL UNIQUE,
+			user_id TEXT NOT NULL,
+			word_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			source TEXT NOT NULL DEFAULT '',
+			page_host TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_lookup_events_user_created_at ON lookup_events(user_id, created_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lookup_events table: %w", err)
+	}
+	for _, column := range []string{"source", "page_host"} {
+		if err := addColumnIfMissing(db, "lookup_events", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, fmt.Errorf("failed to migrate lookup_events: %w", err)
+		}
+	}
 
-func (r *WordRepository) Close() error {
-	return r.db.Close()
+	return &WordRepository{db: db}, nil
 }
 
-func (r *WordRepository) Create(word *model.Word) error {
-	chinese := sql.NullString{}
-	if word.Chinese != nil {
-		chinese = sql.NullString{String: *word.Chinese, Valid: true}
+// addColumnIfMissing adds column to table unless an earlier run already did
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	pronunciation := sql.NullString{}
-	if word.Pronunciation != nil {
-		pronunciation = sql.NullString{String: *word.Pronunciation, Valid: true}
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
 
-	_, err := r.db.Exec(`
-		INSERT INTO words (id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, word.ID, word.English, chinese, pronunciation, word.CreatedAt, word.LoadCount, word.UpdatedAt, word.DeletedAt)
-
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
-func (r *WordRepository) Update(word *model.Word) error {
-	chinese := sql.NullString{}
-	if word.Chinese != nil {
-		chinese = sql.NullString{String: *word.Chinese, Valid: true}
+// englishUnique matches the UNIQUE of english in the words table as created before words were
+// unique within their source language only
+var englishUnique = regexp.MustCompile("(?i)([`\"]?english[`\"]?\\s+TEXT\\s+NOT\\s+NULL)\\s+UNIQUE")
+
+var wordsTableName = regexp.MustCompile("(?i)^CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?words[`\"]?")
+
+// migrateWordsUnique rebuilds the words table without the UNIQUE of english, as SQLite can't
+// drop a constraint in place. Other columns, e.g. those enx-api adds to a shared file, are kept.
+func migrateWordsUnique(db *sql.DB) error {
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'words'`).Scan(&ddl); err != nil {
+		return err
+	}
+	if !englishUnique.MatchString(ddl) {
+		return nil
+	}
+	rows, err := db.Query(`SELECT sql FROM sqlite_master WHERE tbl_name = 'words' AND type IN ('index', 'trigger') AND sql IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	var others []string
+	for rows.Next() {
+		var other string
+		if err := rows.Scan(&other); err != nil {
+			rows.Close()
+			return err
+		}
+		others = append(others, other)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	pronunciation := sql.NullString{}
-	if word.Pronunciation != nil {
-		pronunciation = sql.NullString{String: *word.Pronunciation, Valid: true}
+	ddl = wordsTableName.ReplaceAllString(englishUnique.ReplaceAllString(ddl, "$1"), "CREATE TABLE words__temp")
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := append([]string{ddl, "INSERT INTO words__temp SELECT * FROM words", "DROP TABLE words",
+		"ALTER TABLE words__temp RENAME TO words"}, others...)
+	if indexed, err := hasTable(db, "words_fts"); err != nil {
+		return err
+	} else if indexed {
+		// enx-api's search index refers to words by rowid, which the new table doesn't keep
+		statements = append(statements, "INSERT INTO words_fts(words_fts) VALUES ('rebuild')")
 	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
-	_, err := r.db.Exec(`
-		UPDATE words 
-		SET english = ?, chinese = ?, pronunciation = ?, load_count = ?, updated_at = ?, deleted_at = ?
-		WHERE id = ?
-	`, word.English, chinese, pronunciation, word.LoadCount, word.UpdatedAt, word.DeletedAt, word.ID)
+// checkWordsSearch fails when the file has enx-api's search index of words, maintained by triggers
+// on words, but SQLite was built without FTS5, as then no word could be written
+func checkWordsSearch(db *sql.DB) error {
+	indexed, err := hasTable(db, "words_fts")
+	if err != nil || !indexed {
+		return err
+	}
+	var fts5 bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return err
+	}
+	if !fts5 {
+		return fmt.Errorf("words are indexed with FTS5 for search, build with -tags sqlite_fts5")
+	}
+	return nil
+}
 
-	return err
+func hasTable(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	return count > 0, err
 }
 
-func (r *WordRepository) SoftDelete(id string, deletedAt int64) error {
-	_, err := r.db.Exec(`UPDATE words SET deleted_at = ? WHERE id = ?`, deletedAt, id)
-	return err
+func (r *WordRepository) Close() error {
+	return r.db.Close()
 }
 
-func (r *WordRepository) FindByID(id string) (*model.Word, error) {
+// wordColumns is the column list scanned by scanWord
+const wordColumns = "id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at, glosses, source_language"
+
+func scanWord(row rowScanner) (*model.Word, error) {
 	word := &model.Word{}
 	var chinese, pronunciation sql.NullString
 	var deletedAt sql.NullInt64
+	var glosses string
 
-	err := r.db.QueryRow(`
-		SELECT id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at
-		FROM words WHERE id = ?
-	`, id).Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt)
-
+	err := row.Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt, &glosses, &word.SourceLanguage)
 	if err != nil {
 		return nil, err
 	}
@@ -145,13 +294,109 @@ func (r *WordRepository) FindByID(id string) (*model.Word, error) {
 	if deletedAt.Valid {
 		word.DeletedAt = &deletedAt.Int64
 	}
+	if glosses == "" {
+		glosses = "{}"
+	}
+	if err := json.Unmarshal([]byte(glosses), &word.Glosses); err != nil {
+		return nil, fmt.Errorf("invalid glosses of word %s: %w", word.ID, err)
+	}
+	word.NormalizeGlosses()
 
 	return word, nil
 }
 
+// wordValues returns the nullable columns and the glosses of a word as written to the database
+func wordValues(word *model.Word) (chinese, pronunciation sql.NullString, glosses string, err error) {
+	word.NormalizeGlosses()
+	if word.SourceLanguage == "" {
+		word.SourceLanguage = model.DefaultSourceLanguage
+	}
+	if word.Chinese != nil {
+		chinese = sql.NullString{String: *word.Chinese, Valid: true}
+	}
+	if word.Pronunciation != nil {
+		pronunciation = sql.NullString{String: *word.Pronunciation, Valid: true}
+	}
+	glosses = "{}"
+	if len(word.Glosses) > 0 {
+		data, err := json.Marshal(word.Glosses)
+		if err != nil {
+			return chinese, pronunciation, "", err
+		}
+		glosses = string(data)
+	}
+	return chinese, pronunciation, glosses, nil
+}
+
+func (r *WordRepository) Create(word *model.Word) error {
+	chinese, pronunciation, glosses, err := wordValues(word)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO words (id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at, glosses, source_language)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, word.ID, word.English, chinese, pronunciation, word.CreatedAt, word.LoadCount, word.UpdatedAt, word.DeletedAt, glosses, word.SourceLanguage)
+
+	return err
+}
+
+func (r *WordRepository) Update(word *model.Word) error {
+	chinese, pronunciation, glosses, err := wordValues(word)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE words 
+		SET english = ?, chinese = ?, pronunciation = ?, load_count = ?, updated_at = ?, deleted_at = ?, glosses = ?
+		WHERE id = ?
+	`, word.English, chinese, pronunciation, word.LoadCount, word.UpdatedAt, word.DeletedAt, glosses, word.ID)
+
+	return err
+}
+
+// SoftDelete marks a word deleted; updated_at moves too so the deletion is synced to peers
+func (r *WordRepository) SoftDelete(id string, deletedAt int64) error {
+	_, err := r.db.Exec(`UPDATE words SET deleted_at = ?, updated_at = ? WHERE id = ?`, deletedAt, deletedAt, id)
+	return err
+}
+
+// SetFrequencyRanks stores the frequency ranks of words by id in one transaction, without moving
+// updated_at, and returns how many changed
+func (r *WordRepository) SetFrequencyRanks(ranks map[string]int) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var changed int64
+	for id, rank := range ranks {
+		result, err := tx.Exec(`UPDATE words SET frequency_rank = ? WHERE id = ? AND frequency_rank != ?`, rank, id, rank)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		changed += n
+	}
+	return changed, tx.Commit()
+}
+
+func (r *WordRepository) FindByID(id string) (*model.Word, error) {
+	return scanWord(r.db.QueryRow(`
+		SELECT `+wordColumns+`
+		FROM words WHERE id = ?
+	`, id))
+}
+
 func (r *WordRepository) FindAll() ([]*model.Word, error) {
 	rows, err := r.db.Query(`
-		SELECT id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at
+		SELECT ` + wordColumns + `
 		FROM words WHERE deleted_at IS NULL
 		ORDER BY english
 	`)
@@ -162,25 +407,10 @@ func (r *WordRepository) FindAll() ([]*model.Word, error) {
 
 	var words []*model.Word
 	for rows.Next() {
-		word := &model.Word{}
-		var chinese, pronunciation sql.NullString
-		var deletedAt sql.NullInt64
-
-		err := rows.Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt)
+		word, err := scanWord(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if chinese.Valid {
-			word.Chinese = &chinese.String
-		}
-		if pronunciation.Valid {
-			word.Pronunciation = &pronunciation.String
-		}
-		if deletedAt.Valid {
-			word.DeletedAt = &deletedAt.Int64
-		}
-
 		words = append(words, word)
 	}
 
@@ -222,36 +452,63 @@ func (r *WordRepository) UpdateLastSyncTime(peerAddr string, timestamp int64) er
 	return nil
 }
 
-func (r *WordRepository) FindByEnglish(english string) (*model.Word, error) {
-	word := &model.Word{}
-	var chinese, pronunciation sql.NullString
-	var deletedAt sql.NullInt64
-
+// GetSyncCheckpoint retrieves the resume point of an interrupted transfer, nil if there is none
+func (r *WordRepository) GetSyncCheckpoint(peerAddr, stream string) (*model.SyncCheckpoint, error) {
+	checkpoint := &model.SyncCheckpoint{}
 	err := r.db.QueryRow(`
-		SELECT id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at
-		FROM words WHERE english = ? AND deleted_at IS NULL
-	`, english).Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt)
+		SELECT peer_addr, stream, cursor_updated_at, cursor_id, cursor_sub_id, updated_at
+		FROM sync_checkpoints WHERE peer_addr = ? AND stream = ?
+	`, peerAddr, stream).Scan(&checkpoint.PeerAddr, &checkpoint.Stream, &checkpoint.CursorUpdatedAt,
+		&checkpoint.CursorID, &checkpoint.CursorSubID, &checkpoint.UpdatedAt)
 
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get sync checkpoint: %w", err)
 	}
 
-	if chinese.Valid {
-		word.Chinese = &chinese.String
-	}
-	if pronunciation.Valid {
-		word.Pronunciation = &pronunciation.String
+	return checkpoint, nil
+}
+
+// SaveSyncCheckpoint records how far a transfer from a peer has been applied
+func (r *WordRepository) SaveSyncCheckpoint(checkpoint *model.SyncCheckpoint) error {
+	_, err := r.db.Exec(`
+		INSERT INTO sync_checkpoints (peer_addr, stream, cursor_updated_at, cursor_id, cursor_sub_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(peer_addr, stream) DO UPDATE SET
+			cursor_updated_at = excluded.cursor_updated_at,
+			cursor_id = excluded.cursor_id,
+			cursor_sub_id = excluded.cursor_sub_id,
+			updated_at = excluded.updated_at
+	`, checkpoint.PeerAddr, checkpoint.Stream, checkpoint.CursorUpdatedAt, checkpoint.CursorID, checkpoint.CursorSubID, checkpoint.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save sync checkpoint: %w", err)
 	}
-	if deletedAt.Valid {
-		word.DeletedAt = &deletedAt.Int64
+
+	return nil
+}
+
+// DeleteSyncCheckpoints removes all checkpoints of a peer once a sync has completed
+func (r *WordRepository) DeleteSyncCheckpoints(peerAddr string) error {
+	_, err := r.db.Exec(`DELETE FROM sync_checkpoints WHERE peer_addr = ?`, peerAddr)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync checkpoints: %w", err)
 	}
+	return nil
+}
 
-	return word, nil
+func (r *WordRepository) FindByEnglish(sourceLanguage, english string) (*model.Word, error) {
+	return scanWord(r.db.QueryRow(`
+		SELECT `+wordColumns+`
+		FROM words WHERE source_language = ? AND english = ? AND deleted_at IS NULL
+	`, sourceLanguage, english))
 }
 
 func (r *WordRepository) FindModifiedSince(timestamp int64) ([]*model.Word, error) {
 	rows, err := r.db.Query(`
-		SELECT id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at
+		SELECT `+wordColumns+`
 		FROM words WHERE updated_at > ?
 		ORDER BY updated_at DESC
 	`, timestamp)
@@ -262,25 +519,10 @@ func (r *WordRepository) FindModifiedSince(timestamp int64) ([]*model.Word, erro
 
 	var words []*model.Word
 	for rows.Next() {
-		word := &model.Word{}
-		var chinese, pronunciation sql.NullString
-		var deletedAt sql.NullInt64
-
-		err := rows.Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt)
+		word, err := scanWord(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if chinese.Valid {
-			word.Chinese = &chinese.String
-		}
-		if pronunciation.Valid {
-			word.Pronunciation = &pronunciation.String
-		}
-		if deletedAt.Valid {
-			word.DeletedAt = &deletedAt.Int64
-		}
-
 		words = append(words, word)
 	}
 
@@ -290,40 +532,42 @@ func (r *WordRepository) FindModifiedSince(timestamp int64) ([]*model.Word, erro
 // FindModifiedSinceBatch retrieves words modified after a timestamp in batches
 // Callback function receives each batch and should return true to continue, false to stop
 func (r *WordRepository) FindModifiedSinceBatch(timestamp int64, batchSize int, callback func([]*model.Word) (bool, error)) error {
-	offset := 0
+	return r.FindModifiedAfterBatch(timestamp, "", batchSize, callback)
+}
+
+// FindModifiedAfterBatch retrieves words after the (updated_at, id) cursor in batches ordered by (updated_at, id).
+// An empty afterID means every word with updated_at > timestamp.
+// Callback function receives each batch and should return true to continue, false to stop
+func (r *WordRepository) FindModifiedAfterBatch(timestamp int64, afterID string, batchSize int, callback func([]*model.Word) (bool, error)) error {
 	for {
-		rows, err := r.db.Query(`
-			SELECT id, english, chinese, pronunciation, created_at, load_count, updated_at, deleted_at
-			FROM words WHERE updated_at > ?
-			ORDER BY updated_at ASC
-			LIMIT ? OFFSET ?
-		`, timestamp, batchSize, offset)
+		var rows *sql.Rows
+		var err error
+		if afterID == "" {
+			rows, err = r.db.Query(`
+				SELECT `+wordColumns+`
+				FROM words WHERE updated_at > ?
+				ORDER BY updated_at ASC, id ASC
+				LIMIT ?
+			`, timestamp, batchSize)
+		} else {
+			rows, err = r.db.Query(`
+				SELECT `+wordColumns+`
+				FROM words WHERE updated_at > ? OR (updated_at = ? AND id > ?)
+				ORDER BY updated_at ASC, id ASC
+				LIMIT ?
+			`, timestamp, timestamp, afterID, batchSize)
+		}
 		if err != nil {
 			return err
 		}
 
 		var batch []*model.Word
 		for rows.Next() {
-			word := &model.Word{}
-			var chinese, pronunciation sql.NullString
-			var deletedAt sql.NullInt64
-
-			err := rows.Scan(&word.ID, &word.English, &chinese, &pronunciation, &word.CreatedAt, &word.LoadCount, &word.UpdatedAt, &deletedAt)
+			word, err := scanWord(rows)
 			if err != nil {
 				rows.Close()
 				return err
 			}
-
-			if chinese.Valid {
-				word.Chinese = &chinese.String
-			}
-			if pronunciation.Valid {
-				word.Pronunciation = &pronunciation.String
-			}
-			if deletedAt.Valid {
-				word.DeletedAt = &deletedAt.Int64
-			}
-
 			batch = append(batch, word)
 		}
 		rows.Close()
@@ -347,16 +591,36 @@ func (r *WordRepository) FindModifiedSinceBatch(timestamp int64, batchSize int,
 			break
 		}
 
-		offset += batchSize
+		// Move the cursor to the last row of this batch
+		last := batch[len(batch)-1]
+		timestamp, afterID = last.UpdatedAt, last.ID
 	}
 
 	return nil
 }
 
+// userDictColumns is the column list scanned by scanUserDict
+const userDictColumns = "user_id, word_id, query_count, already_acquainted, note, tags, translation, created_at, updated_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUserDict(row rowScanner) (*model.UserDict, error) {
+	userDict := &model.UserDict{}
+	err := row.Scan(&userDict.UserId, &userDict.WordId, &userDict.QueryCount, &userDict.AlreadyAcquainted,
+		&userDict.Note, &userDict.Tags, &userDict.Translation, &userDict.CreatedAt, &userDict.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return userDict, nil
+}
+
 // FindUserDictsModifiedSince retrieves all user_dicts records modified after a given timestamp
 func (r *WordRepository) FindUserDictsModifiedSince(timestamp int64) ([]*model.UserDict, error) {
 	rows, err := r.db.Query(`
-		SELECT user_id, word_id, query_count, already_acquainted, created_at, updated_at
+		SELECT `+userDictColumns+`
 		FROM user_dicts WHERE updated_at > ?
 		ORDER BY updated_at DESC
 	`, timestamp)
@@ -367,8 +631,7 @@ func (r *WordRepository) FindUserDictsModifiedSince(timestamp int64) ([]*model.U
 
 	var userDicts []*model.UserDict
 	for rows.Next() {
-		userDict := &model.UserDict{}
-		err := rows.Scan(&userDict.UserId, &userDict.WordId, &userDict.QueryCount, &userDict.AlreadyAcquainted, &userDict.CreatedAt, &userDict.UpdatedAt)
+		userDict, err := scanUserDict(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -381,22 +644,41 @@ func (r *WordRepository) FindUserDictsModifiedSince(timestamp int64) ([]*model.U
 // FindUserDictsModifiedSinceBatch retrieves user_dicts modified after a timestamp in batches
 // Callback function receives each batch and should return true to continue, false to stop
 func (r *WordRepository) FindUserDictsModifiedSinceBatch(timestamp int64, batchSize int, callback func([]*model.UserDict) (bool, error)) error {
-	offset := 0
+	return r.FindUserDictsModifiedAfterBatch(timestamp, "", "", batchSize, callback)
+}
+
+// FindUserDictsModifiedAfterBatch retrieves user_dicts after the (updated_at, user_id, word_id) cursor in batches
+// ordered by (updated_at, user_id, word_id). An empty afterUserID means every record with updated_at > timestamp.
+// Callback function receives each batch and should return true to continue, false to stop
+func (r *WordRepository) FindUserDictsModifiedAfterBatch(timestamp int64, afterUserID, afterWordID string, batchSize int, callback func([]*model.UserDict) (bool, error)) error {
 	for {
-		rows, err := r.db.Query(`
-			SELECT user_id, word_id, query_count, already_acquainted, created_at, updated_at
-			FROM user_dicts WHERE updated_at > ?
-			ORDER BY updated_at ASC
-			LIMIT ? OFFSET ?
-		`, timestamp, batchSize, offset)
+		var rows *sql.Rows
+		var err error
+		if afterUserID == "" {
+			rows, err = r.db.Query(`
+				SELECT `+userDictColumns+`
+				FROM user_dicts WHERE updated_at > ?
+				ORDER BY updated_at ASC, user_id ASC, word_id ASC
+				LIMIT ?
+			`, timestamp, batchSize)
+		} else {
+			rows, err = r.db.Query(`
+				SELECT `+userDictColumns+`
+				FROM user_dicts
+				WHERE updated_at > ?
+					OR (updated_at = ? AND user_id > ?)
+					OR (updated_at = ? AND user_id = ? AND word_id > ?)
+				ORDER BY updated_at ASC, user_id ASC, word_id ASC
+				LIMIT ?
+			`, timestamp, timestamp, afterUserID, timestamp, afterUserID, afterWordID, batchSize)
+		}
 		if err != nil {
 			return err
 		}
 
 		var batch []*model.UserDict
 		for rows.Next() {
-			userDict := &model.UserDict{}
-			err := rows.Scan(&userDict.UserId, &userDict.WordId, &userDict.QueryCount, &userDict.AlreadyAcquainted, &userDict.CreatedAt, &userDict.UpdatedAt)
+			userDict, err := scanUserDict(rows)
 			if err != nil {
 				rows.Close()
 				return err
@@ -424,34 +706,78 @@ func (r *WordRepository) FindUserDictsModifiedSinceBatch(timestamp int64, batchS
 			break
 		}
 
-		offset += batchSize
+		// Move the cursor to the last row of this batch
+		last := batch[len(batch)-1]
+		timestamp, afterUserID, afterWordID = last.UpdatedAt, last.UserId, last.WordId
 	}
 
 	return nil
 }
 
+const upsertUserDictSQL = `
+	INSERT INTO user_dicts (user_id, word_id, query_count, already_acquainted, note, tags, translation, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(user_id, word_id) DO UPDATE SET
+		query_count = excluded.query_count,
+		already_acquainted = excluded.already_acquainted,
+		note = excluded.note,
+		tags = excluded.tags,
+		translation = excluded.translation,
+		updated_at = excluded.updated_at
+`
+
 // UpsertUserDict inserts or updates a user_dict record
 func (r *WordRepository) UpsertUserDict(userDict *model.UserDict) error {
-	_, err := r.db.Exec(`
-		INSERT INTO user_dicts (user_id, word_id, query_count, already_acquainted, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT(user_id, word_id) DO UPDATE SET
-			query_count = excluded.query_count,
-			already_acquainted = excluded.already_acquainted,
-			updated_at = excluded.updated_at
-	`, userDict.UserId, userDict.WordId, userDict.QueryCount, userDict.AlreadyAcquainted, userDict.CreatedAt, userDict.UpdatedAt)
+	_, err := r.db.Exec(upsertUserDictSQL, userDict.UserId, userDict.WordId, userDict.QueryCount, userDict.AlreadyAcquainted,
+		userDict.Note, userDict.Tags, userDict.Translation, userDict.CreatedAt, userDict.UpdatedAt)
 
 	return err
 }
 
+// MergeWords moves the user_dicts and lookup_events of word sourceID onto targetID and soft-deletes
+// the source word, in one transaction. merged are the user_dicts of the target after the merge,
+// which the caller computes; the source's own user_dicts are deleted.
+func (r *WordRepository) MergeWords(sourceID, targetID string, merged []*model.UserDict, now int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, userDict := range merged {
+		if userDict.WordId != targetID {
+			return fmt.Errorf("merged user_dict of word %s, want %s", userDict.WordId, targetID)
+		}
+		_, err := tx.Exec(upsertUserDictSQL, userDict.UserId, userDict.WordId, userDict.QueryCount, userDict.AlreadyAcquainted,
+			userDict.Note, userDict.Tags, userDict.Translation, userDict.CreatedAt, userDict.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert merged user_dict: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM user_dicts WHERE word_id = ?`, sourceID); err != nil {
+		return fmt.Errorf("failed to delete source user_dicts: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE lookup_events SET word_id = ? WHERE word_id = ?`, targetID, sourceID); err != nil {
+		return fmt.Errorf("failed to move lookup_events: %w", err)
+	}
+	result, err := tx.Exec(`UPDATE words SET deleted_at = ?, updated_at = ? WHERE id = ?`, now, now, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete source word: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
 // FindUserDict finds a specific user_dict record
 func (r *WordRepository) FindUserDict(userId, wordId string) (*model.UserDict, error) {
-	userDict := &model.UserDict{}
-	err := r.db.QueryRow(`
-		SELECT user_id, word_id, query_count, already_acquainted, created_at, updated_at
+	userDict, err := scanUserDict(r.db.QueryRow(`
+		SELECT `+userDictColumns+`
 		FROM user_dicts WHERE user_id = ? AND word_id = ?
-	`, userId, wordId).Scan(&userDict.UserId, &userDict.WordId, &userDict.QueryCount, &userDict.AlreadyAcquainted, &userDict.CreatedAt, &userDict.UpdatedAt)
-
+	`, userId, wordId))
 	if err != nil {
 		return nil, err
 	}
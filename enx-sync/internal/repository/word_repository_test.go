@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -86,12 +87,32 @@ func TestSoftDelete(t *testing.T) {
 	}
 	repo.Create(word)
 
-	err := repo.SoftDelete(word.ID, time.Now().UnixMilli())
+	deletedAt := now + 10
+	err := repo.SoftDelete(word.ID, deletedAt)
 	assert.NoError(t, err)
 
 	found, err := repo.FindByID(word.ID)
 	require.NoError(t, err)
 	assert.NotNil(t, found.DeletedAt)
+	// The deletion must be picked up by incremental sync
+	assert.Equal(t, deletedAt, found.UpdatedAt)
+}
+
+func TestSetFrequencyRanks(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	require.NoError(t, repo.Create(&model.Word{ID: "word-1", English: "the", CreatedAt: 1, UpdatedAt: 1}))
+	changed, err := repo.SetFrequencyRanks(map[string]int{"word-1": 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), changed)
+
+	var rank int
+	require.NoError(t, repo.db.QueryRow(`SELECT frequency_rank FROM words WHERE id = 'word-1'`).Scan(&rank))
+	assert.Equal(t, 1, rank)
+	found, err := repo.FindByID("word-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), found.UpdatedAt)
 }
 
 func TestFindAll(t *testing.T) {
@@ -115,6 +136,157 @@ func TestFindAll(t *testing.T) {
 
 // ==================== UserDict Tests ====================
 
+func TestGlosses(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	word := &model.Word{ID: uuid.New().String(), English: "harbor", CreatedAt: now, UpdatedAt: now}
+	word.SetGloss("ja", "港")
+	word.SetGloss(model.ChineseLanguage, "港口")
+	require.NoError(t, repo.Create(word))
+
+	found, err := repo.FindByID(word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ja": "港", "zh-CHS": "港口"}, found.Glosses)
+	assert.Equal(t, "港口", *found.Chinese)
+
+	// a word written with chinese only, as by versions predating glosses
+	legacy := "旧"
+	_, err = repo.db.Exec(`INSERT INTO words (id, english, chinese, created_at, updated_at) VALUES (?, 'old', ?, ?, ?)`,
+		uuid.New().String(), legacy, now, now)
+	require.NoError(t, err)
+	found, err = repo.FindByEnglish("en", "old")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"zh-CHS": "旧"}, found.Glosses)
+}
+
+func TestGlosses_MigratesOldSchema(t *testing.T) {
+	dbPath := "/tmp/test_enx_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE words (
+			id TEXT PRIMARY KEY,
+			english TEXT NOT NULL UNIQUE,
+			chinese TEXT,
+			pronunciation TEXT,
+			created_at INTEGER,
+			load_count INTEGER NOT NULL DEFAULT 0,
+			updated_at INTEGER NOT NULL,
+			deleted_at INTEGER
+		)
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO words (id, english, chinese, created_at, updated_at) VALUES ('word-1', 'test', '测试', 1, 1)`)
+	require.NoError(t, err)
+	db.Close()
+
+	repo, err := NewWordRepository(dbPath)
+	require.NoError(t, err)
+	defer repo.Close()
+	found, err := repo.FindByID("word-1")
+	require.NoError(t, err)
+	assert.Equal(t, "测试", found.Glosses[model.ChineseLanguage])
+}
+
+func TestSourceLanguages_MigratesUniqueEnglish(t *testing.T) {
+	dbPath := "/tmp/test_enx_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+
+	// as written by enx-api to a shared file, with a column and an index enx-sync doesn't know
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS words (
+			id TEXT PRIMARY KEY,
+			english TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			chinese TEXT,
+			pronunciation TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			deleted_at INTEGER,
+			load_count INTEGER DEFAULT 0,
+			frequency_rank INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX idx_words_updated_at ON words(updated_at);
+		INSERT INTO words (id, english, chinese, created_at, updated_at, frequency_rank) VALUES ('word-1', 'die', '死', 1, 1, 42);
+	`)
+	require.NoError(t, err)
+	db.Close()
+
+	repo, err := NewWordRepository(dbPath)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	english, err := repo.FindByEnglish("en", "die")
+	require.NoError(t, err)
+	assert.Equal(t, "word-1", english.ID)
+	assert.Equal(t, "en", english.SourceLanguage)
+
+	// the German word is another word, the same word twice in a language is still rejected
+	german := &model.Word{ID: "word-2", English: "die", SourceLanguage: "de", CreatedAt: 2, UpdatedAt: 2}
+	require.NoError(t, repo.Create(german))
+	found, err := repo.FindByEnglish("de", "die")
+	require.NoError(t, err)
+	assert.Equal(t, "word-2", found.ID)
+	assert.Error(t, repo.Create(&model.Word{ID: "word-3", English: "die", CreatedAt: 3, UpdatedAt: 3}))
+
+	var rank, indexes int
+	require.NoError(t, repo.db.QueryRow(`SELECT frequency_rank FROM words WHERE id = 'word-1'`).Scan(&rank))
+	assert.Equal(t, 42, rank)
+	require.NoError(t, repo.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_words_updated_at'`).Scan(&indexes))
+	assert.Equal(t, 1, indexes)
+}
+
+func TestWordsSearch(t *testing.T) {
+	dbPath := "/tmp/test_enx_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+
+	// enx-api's search index of words, see enx-api/schema.sql
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE words (
+			id TEXT PRIMARY KEY,
+			english TEXT NOT NULL,
+			chinese TEXT,
+			pronunciation TEXT,
+			created_at INTEGER,
+			load_count INTEGER NOT NULL DEFAULT 0,
+			updated_at INTEGER NOT NULL,
+			deleted_at INTEGER,
+			glosses TEXT NOT NULL DEFAULT '{}',
+			source_language TEXT NOT NULL DEFAULT 'en'
+		)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE VIRTUAL TABLE words_fts USING fts5(english, chinese, glosses, content='words')`)
+	if err != nil {
+		// built without FTS5: a table of that name stands in for the index
+		_, err = db.Exec(`CREATE TABLE words_fts (english, chinese, glosses)`)
+		require.NoError(t, err)
+		db.Close()
+		_, err = NewWordRepository(dbPath)
+		assert.ErrorContains(t, err, "sqlite_fts5")
+		return
+	}
+	_, err = db.Exec(`CREATE TRIGGER words_fts_insert AFTER INSERT ON words BEGIN
+		INSERT INTO words_fts(rowid, english, chinese, glosses) VALUES (new.rowid, new.english, new.chinese, new.glosses);
+	END`)
+	require.NoError(t, err)
+	db.Close()
+
+	repo, err := NewWordRepository(dbPath)
+	require.NoError(t, err)
+	defer repo.Close()
+	require.NoError(t, repo.Create(&model.Word{ID: "word-1", English: "harbor", CreatedAt: 1, UpdatedAt: 1}))
+	var id string
+	require.NoError(t, repo.db.QueryRow(`SELECT w.id FROM words_fts JOIN words w ON w.rowid = words_fts.rowid WHERE words_fts MATCH 'harb*'`).Scan(&id))
+	assert.Equal(t, "word-1", id)
+}
+
 func TestUserDict_UpsertAndFind(t *testing.T) {
 	repo, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -174,6 +346,73 @@ func TestUserDict_Upsert_Update(t *testing.T) {
 	assert.Equal(t, laterTime, found.UpdatedAt)
 }
 
+func TestUserDict_Annotations(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	userDict := &model.UserDict{
+		UserId:      "user-789",
+		WordId:      uuid.New().String(),
+		QueryCount:  2,
+		Note:        "seen in chapter 3",
+		Tags:        "novel,verbs",
+		Translation: "跑",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	require.NoError(t, repo.UpsertUserDict(userDict))
+
+	found, err := repo.FindUserDict(userDict.UserId, userDict.WordId)
+	require.NoError(t, err)
+	assert.Equal(t, "seen in chapter 3", found.Note)
+	assert.Equal(t, "novel,verbs", found.Tags)
+	assert.Equal(t, "跑", found.Translation)
+
+	var synced []*model.UserDict
+	err = repo.FindUserDictsModifiedSinceBatch(now-1, 10, func(batch []*model.UserDict) (bool, error) {
+		synced = append(synced, batch...)
+		return true, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, synced, 1)
+	assert.Equal(t, "跑", synced[0].Translation)
+}
+
+func TestUserDict_MigratesOldSchema(t *testing.T) {
+	dbPath := "/tmp/test_enx_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE user_dicts (
+			user_id TEXT NOT NULL,
+			word_id TEXT NOT NULL,
+			query_count INTEGER DEFAULT 0,
+			already_acquainted INTEGER DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (user_id, word_id)
+		)
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_dicts VALUES ('user-1', 'word-1', 4, 0, 1, 1)`)
+	require.NoError(t, err)
+	db.Close()
+
+	// Opening twice checks the migration is idempotent
+	for i := 0; i < 2; i++ {
+		repo, err := NewWordRepository(dbPath)
+		require.NoError(t, err)
+		found, err := repo.FindUserDict("user-1", "word-1")
+		require.NoError(t, err)
+		assert.Equal(t, 4, found.QueryCount)
+		assert.Equal(t, "", found.Note)
+		repo.Close()
+	}
+}
+
 func TestUserDict_FindModifiedSince(t *testing.T) {
 	repo, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -251,3 +490,92 @@ func TestUserDict_MultipleUsersOneWord(t *testing.T) {
 		assert.Equal(t, i%2, found.AlreadyAcquainted)
 	}
 }
+
+func TestFindModifiedAfterBatch_Cursor(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	ids := []string{"a", "b", "c", "d", "e"}
+	for _, id := range ids {
+		require.NoError(t, repo.Create(&model.Word{ID: id, English: "word-" + id, CreatedAt: now, UpdatedAt: now}))
+	}
+
+	// Same updated_at everywhere: batches must still page through every row exactly once
+	var seen []string
+	err := repo.FindModifiedSinceBatch(now-1, 2, func(batch []*model.Word) (bool, error) {
+		for _, w := range batch {
+			seen = append(seen, w.ID)
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ids, seen)
+
+	// Resume after "b"
+	seen = nil
+	err = repo.FindModifiedAfterBatch(now, "b", 10, func(batch []*model.Word) (bool, error) {
+		for _, w := range batch {
+			seen = append(seen, w.ID)
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "e"}, seen)
+}
+
+func TestSyncCheckpoint_SaveGetDelete(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	checkpoint, err := repo.GetSyncCheckpoint("peer:50051", "user_dicts")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	require.NoError(t, repo.SaveSyncCheckpoint(&model.SyncCheckpoint{
+		PeerAddr: "peer:50051", Stream: "user_dicts", CursorUpdatedAt: 100, CursorID: "user-1", CursorSubID: "word-1", UpdatedAt: 1,
+	}))
+	require.NoError(t, repo.SaveSyncCheckpoint(&model.SyncCheckpoint{
+		PeerAddr: "peer:50051", Stream: "user_dicts", CursorUpdatedAt: 200, CursorID: "user-2", CursorSubID: "word-2", UpdatedAt: 2,
+	}))
+
+	checkpoint, err = repo.GetSyncCheckpoint("peer:50051", "user_dicts")
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, int64(200), checkpoint.CursorUpdatedAt)
+	assert.Equal(t, "word-2", checkpoint.CursorSubID)
+
+	require.NoError(t, repo.DeleteSyncCheckpoints("peer:50051"))
+	checkpoint, err = repo.GetSyncCheckpoint("peer:50051", "user_dicts")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+func TestLookupEvents_Prune(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i, createdAt := range []int64{100, 200, 300} {
+		inserted, err := repo.AppendLookupEvent(&model.LookupEvent{
+			ID: uuid.New().String(), UserId: "user-1", WordId: "word-1", Kind: model.EventLookup, CreatedAt: createdAt,
+			Source: "ui", PageHost: "example.com",
+		})
+		require.NoError(t, err)
+		assert.True(t, inserted, "event %d", i)
+	}
+
+	deleted, err := repo.PruneLookupEvents(250)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	var left []*model.LookupEvent
+	require.NoError(t, repo.FindLookupEventsAfterBatch(0, 10, func(batch []*model.LookupEvent) (bool, error) {
+		left = append(left, batch...)
+		return true, nil
+	}))
+	require.Len(t, left, 1)
+	assert.Equal(t, int64(300), left[0].CreatedAt)
+	assert.Equal(t, int64(3), left[0].Seq)
+	assert.Equal(t, "ui", left[0].Source)
+	assert.Equal(t, "example.com", left[0].PageHost)
+}
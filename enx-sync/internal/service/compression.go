@@ -0,0 +1,8 @@
+package service
+
+import (
+	// Register the compressors peers may negotiate for sync streams.
+	// The server answers with the same compressor the client used.
+	_ "github.com/mostynb/go-grpc-compression/nonclobbering/zstd"
+	_ "google.golang.org/grpc/encoding/gzip"
+)
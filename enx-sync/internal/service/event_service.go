@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"enx-sync/internal/model"
+	pb "enx-sync/proto"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *WordService) AppendLookupEvent(ctx context.Context, req *pb.AppendLookupEventRequest) (*pb.AppendLookupEventResponse, error) {
+	if req.Event == nil || req.Event.UserId == "" || req.Event.WordId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id and word_id are required")
+	}
+	switch req.Event.Kind {
+	case model.EventLookup, model.EventMark, model.EventUnmark:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown event kind: %q", req.Event.Kind)
+	}
+
+	event := convertLookupEventProtoToModel(req.Event)
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().UnixMilli()
+	}
+	if _, err := s.repo.AppendLookupEvent(event); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to append lookup event: %v", err)
+	}
+	return &pb.AppendLookupEventResponse{Event: convertLookupEventModelToProto(event)}, nil
+}
+
+// PruneLookupEvents deletes the events created before req.Before. It's meant for the local enx-api's
+// retention job, other callers have to present the sync token.
+func (s *WordService) PruneLookupEvents(ctx context.Context, req *pb.PruneLookupEventsRequest) (*pb.PruneLookupEventsResponse, error) {
+	if err := s.authenticateLocal(ctx); err != nil {
+		log.Printf("❌ PruneLookupEvents refused: %v", err)
+		return nil, err
+	}
+	if req.Before <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "before is required")
+	}
+	deleted, err := s.repo.PruneLookupEvents(req.Before)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to prune lookup events: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Pruned %d lookup events created before %d", deleted, req.Before)
+	}
+	return &pb.PruneLookupEventsResponse{Deleted: deleted}, nil
+}
+
+// SyncLookupEvents streams the lookup history of every user, with the pages words were looked up
+// on, to a peer presenting the sync token
+func (s *WordService) SyncLookupEvents(req *pb.SyncLookupEventsRequest, stream pb.DataService_SyncLookupEventsServer) error {
+	clientAddr := "unknown"
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		clientAddr = p.Addr.String()
+	}
+
+	log.Printf("📥 SyncLookupEvents request from %s (after_seq: %d, batch_size: %d)", clientAddr, req.AfterSeq, req.BatchSize)
+	if err := s.authenticatePeer(stream.Context()); err != nil {
+		log.Printf("❌ SyncLookupEvents refused for %s: %v", clientAddr, err)
+		return err
+	}
+
+	totalSent := 0
+	err := s.repo.FindLookupEventsAfterBatch(req.AfterSeq, syncBatchSize(req.BatchSize), func(batch []*model.LookupEvent) (bool, error) {
+		events := make([]*pb.LookupEvent, len(batch))
+		for i, event := range batch {
+			events[i] = convertLookupEventModelToProto(event)
+		}
+		if err := stream.Send(&pb.SyncLookupEventsResponse{Events: events}); err != nil {
+			log.Printf("❌ Failed to send lookup events to %s: %v", clientAddr, err)
+			return false, status.Errorf(codes.Internal, "failed to send lookup events: %v", err)
+		}
+		totalSent += len(batch)
+		return true, nil
+	})
+	if err != nil {
+		log.Printf("❌ SyncLookupEvents failed for %s: %v", clientAddr, err)
+		return err
+	}
+
+	log.Printf("✅ SyncLookupEvents completed for %s (%d events sent)", clientAddr, totalSent)
+	return nil
+}
+
+func convertLookupEventModelToProto(event *model.LookupEvent) *pb.LookupEvent {
+	return &pb.LookupEvent{
+		Id:        event.ID,
+		UserId:    event.UserId,
+		WordId:    event.WordId,
+		Kind:      event.Kind,
+		CreatedAt: event.CreatedAt,
+		Seq:       event.Seq,
+		Source:    event.Source,
+		PageHost:  event.PageHost,
+	}
+}
+
+func convertLookupEventProtoToModel(event *pb.LookupEvent) *model.LookupEvent {
+	return &model.LookupEvent{
+		ID:        event.Id,
+		UserId:    event.UserId,
+		WordId:    event.WordId,
+		Kind:      event.Kind,
+		CreatedAt: event.CreatedAt,
+		Source:    event.Source,
+		PageHost:  event.PageHost,
+	}
+}
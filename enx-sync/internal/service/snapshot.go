@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	pb "enx-sync/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultSnapshotChunkSize = 256 * 1024
+	maxSnapshotChunkSize     = 1024 * 1024
+)
+
+// SetSyncToken sets the secret peers authenticate with to download a snapshot or lookup events,
+// see node.sync_token. Both are refused while it is empty.
+func (s *WordService) SetSyncToken(token string) {
+	s.syncToken = token
+}
+
+// authenticatePeer checks the "authorization: Bearer <sync token>" metadata of a request
+func (s *WordService) authenticatePeer(ctx context.Context) error {
+	if s.syncToken == "" {
+		return status.Error(codes.PermissionDenied, "peer authentication is disabled, node.sync_token is not set")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.syncToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid sync token")
+}
+
+// authenticateLocal lets through requests from the same host, which is where enx-api shares the
+// database file, and otherwise asks for the sync token like authenticatePeer
+func (s *WordService) authenticateLocal(ctx context.Context) error {
+	if p, ok := peer.FromContext(ctx); ok {
+		if addr, ok := p.Addr.(*net.TCPAddr); ok && addr.IP.IsLoopback() {
+			return nil
+		}
+	}
+	return s.authenticatePeer(ctx)
+}
+
+// GetSnapshot streams a consistent copy of the replicated tables, taken with SQLite's online backup
+// API, to a peer presenting the sync token. The first message carries the snapshot info (size,
+// checksum and change cursors).
+func (s *WordService) GetSnapshot(req *pb.GetSnapshotRequest, stream pb.DataService_GetSnapshotServer) error {
+	clientAddr := "unknown"
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		clientAddr = p.Addr.String()
+	}
+	log.Printf("📥 GetSnapshot request from %s", clientAddr)
+	if err := s.authenticatePeer(stream.Context()); err != nil {
+		log.Printf("❌ GetSnapshot refused for %s: %v", clientAddr, err)
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "enx-snapshot-*.db")
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create snapshot file: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	snapshot, err := s.repo.Snapshot(stream.Context(), tmpPath)
+	if err != nil {
+		log.Printf("❌ Snapshot failed for %s: %v", clientAddr, err)
+		return status.Errorf(codes.Internal, "failed to create snapshot: %v", err)
+	}
+
+	file, err := os.Open(tmpPath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to hash snapshot: %v", err)
+	}
+	snapshot.Size = size
+	snapshot.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "failed to rewind snapshot: %v", err)
+	}
+
+	chunkSize := defaultSnapshotChunkSize
+	if req.ChunkSize > 0 && req.ChunkSize <= maxSnapshotChunkSize {
+		chunkSize = int(req.ChunkSize)
+	}
+
+	info := &pb.SnapshotInfo{
+		Size:            snapshot.Size,
+		Sha256:          snapshot.SHA256,
+		WordsCursor:     snapshot.WordsCursor,
+		UserDictsCursor: snapshot.UserDictsCursor,
+		CreatedAt:       snapshot.CreatedAt,
+		EventsCursor:    snapshot.EventsCursor,
+	}
+	log.Printf("📤 Sending snapshot to %s (%d bytes, words_cursor: %d, user_dicts_cursor: %d, events_cursor: %d)",
+		clientAddr, info.Size, info.WordsCursor, info.UserDictsCursor, info.EventsCursor)
+
+	buf := make([]byte, chunkSize)
+	first := true
+	for {
+		n, err := file.Read(buf)
+		if n > 0 || first {
+			resp := &pb.GetSnapshotResponse{Chunk: buf[:n]}
+			if first {
+				resp.Info = info
+				first = false
+			}
+			if err := stream.Send(resp); err != nil {
+				log.Printf("❌ Failed to send snapshot to %s: %v", clientAddr, err)
+				return status.Errorf(codes.Internal, "failed to send snapshot: %v", err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read snapshot: %v", err)
+		}
+	}
+
+	log.Printf("✅ GetSnapshot completed for %s", clientAddr)
+	return nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"time"
 
@@ -15,9 +17,14 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// maxSyncBatchSize is the largest number of rows read (and sent in one message) per batch
+const maxSyncBatchSize = 1000
+
 type WordService struct {
 	pb.UnimplementedDataServiceServer
 	repo *repository.WordRepository
+	// secret of the nodes a peer presents to download a snapshot, see SetSyncToken
+	syncToken string
 }
 
 func NewWordService(repo *repository.WordRepository) *WordService {
@@ -27,11 +34,12 @@ func NewWordService(repo *repository.WordRepository) *WordService {
 func (s *WordService) CreateWord(ctx context.Context, req *pb.CreateWordRequest) (*pb.CreateWordResponse, error) {
 	now := time.Now().UnixMilli()
 	word := &model.Word{
-		ID:        uuid.New().String(),
-		English:   req.English,
-		CreatedAt: now,
-		UpdatedAt: now,
-		LoadCount: 0,
+		ID:             uuid.New().String(),
+		English:        req.English,
+		SourceLanguage: req.SourceLanguage,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		LoadCount:      0,
 	}
 
 	if req.Chinese != "" {
@@ -40,6 +48,9 @@ func (s *WordService) CreateWord(ctx context.Context, req *pb.CreateWordRequest)
 	if req.Pronunciation != "" {
 		word.Pronunciation = &req.Pronunciation
 	}
+	for language, gloss := range req.Glosses {
+		word.SetGloss(language, gloss)
+	}
 
 	if err := s.repo.Create(word); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create word: %v", err)
@@ -67,8 +78,11 @@ func (s *WordService) UpdateWord(ctx context.Context, req *pb.UpdateWordRequest)
 		word.English = req.Word.English
 	}
 	if req.Word.Chinese != "" {
-		chinese := req.Word.Chinese
-		word.Chinese = &chinese
+		word.SetGloss(model.ChineseLanguage, req.Word.Chinese)
+	}
+	// glosses of languages missing from the request are kept
+	for language, gloss := range req.Word.Glosses {
+		word.SetGloss(language, gloss)
 	}
 	if req.Word.Pronunciation != "" {
 		pronunciation := req.Word.Pronunciation
@@ -110,6 +124,106 @@ func (s *WordService) ListWords(ctx context.Context, req *pb.ListWordsRequest) (
 	return &pb.ListWordsResponse{Words: protoWords, Total: int32(len(words))}, nil
 }
 
+// MergeWords moves the user dicts and lookup events of a word onto another one and soft-deletes it,
+// in one transaction, see MergeWordsRequest
+func (s *WordService) MergeWords(ctx context.Context, req *pb.MergeWordsRequest) (*pb.MergeWordsResponse, error) {
+	if req.SourceId == "" || req.TargetId == "" || req.SourceId == req.TargetId {
+		return nil, status.Errorf(codes.InvalidArgument, "distinct source_id and target_id are required")
+	}
+	if _, err := s.repo.FindByID(req.TargetId); errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "target word not found: %s", req.TargetId)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get target word: %v", err)
+	}
+
+	now := time.Now().UnixMilli()
+	merged := make([]*model.UserDict, len(req.UserDicts))
+	for i, userDict := range req.UserDicts {
+		merged[i] = &model.UserDict{
+			UserId:            userDict.UserId,
+			WordId:            userDict.WordId,
+			QueryCount:        int(userDict.QueryCount),
+			AlreadyAcquainted: int(userDict.AlreadyAcquainted),
+			Note:              userDict.Note,
+			Tags:              userDict.Tags,
+			Translation:       userDict.Translation,
+			CreatedAt:         userDict.CreatedAt,
+			UpdatedAt:         now,
+		}
+		if merged[i].CreatedAt == 0 {
+			merged[i].CreatedAt = now
+		}
+	}
+
+	err := s.repo.MergeWords(req.SourceId, req.TargetId, merged, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "source word not found: %s", req.SourceId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to merge words: %v", err)
+	}
+	return &pb.MergeWordsResponse{SourceId: req.SourceId, TargetId: req.TargetId}, nil
+}
+
+// SetFrequencyRanks stores ranks of enx-api's word frequency list, see SetFrequencyRanksRequest
+func (s *WordService) SetFrequencyRanks(ctx context.Context, req *pb.SetFrequencyRanksRequest) (*pb.SetFrequencyRanksResponse, error) {
+	ranks := make(map[string]int, len(req.Ranks))
+	for id, rank := range req.Ranks {
+		ranks[id] = int(rank)
+	}
+	changed, err := s.repo.SetFrequencyRanks(ranks)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to set frequency ranks: %v", err)
+	}
+	return &pb.SetFrequencyRanksResponse{Changed: changed}, nil
+}
+
+func (s *WordService) GetUserDict(ctx context.Context, req *pb.GetUserDictRequest) (*pb.GetUserDictResponse, error) {
+	userDict, err := s.repo.FindUserDict(req.UserId, req.WordId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "user_dict not found: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user_dict: %v", err)
+	}
+	return &pb.GetUserDictResponse{UserDict: convertUserDictModelToProto(userDict)}, nil
+}
+
+func (s *WordService) UpsertUserDict(ctx context.Context, req *pb.UpsertUserDictRequest) (*pb.UpsertUserDictResponse, error) {
+	if req.UserDict == nil || req.UserDict.UserId == "" || req.UserDict.WordId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id and word_id are required")
+	}
+
+	now := time.Now().UnixMilli()
+	userDict := &model.UserDict{
+		UserId:            req.UserDict.UserId,
+		WordId:            req.UserDict.WordId,
+		QueryCount:        int(req.UserDict.QueryCount),
+		AlreadyAcquainted: int(req.UserDict.AlreadyAcquainted),
+		Note:              req.UserDict.Note,
+		Tags:              req.UserDict.Tags,
+		Translation:       req.UserDict.Translation,
+		CreatedAt:         req.UserDict.CreatedAt,
+		UpdatedAt:         req.UserDict.UpdatedAt,
+	}
+	// Keep the original creation time of an existing record
+	if existing, err := s.repo.FindUserDict(userDict.UserId, userDict.WordId); err == nil {
+		userDict.CreatedAt = existing.CreatedAt
+	}
+	if userDict.CreatedAt == 0 {
+		userDict.CreatedAt = now
+	}
+	if userDict.UpdatedAt == 0 {
+		userDict.UpdatedAt = now
+	}
+
+	if err := s.repo.UpsertUserDict(userDict); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to upsert user_dict: %v", err)
+	}
+
+	return &pb.UpsertUserDictResponse{UserDict: convertUserDictModelToProto(userDict)}, nil
+}
+
 func (s *WordService) SyncWords(req *pb.SyncWordsRequest, stream pb.DataService_SyncWordsServer) error {
 	// Get client address from context
 	clientAddr := "unknown"
@@ -117,15 +231,29 @@ func (s *WordService) SyncWords(req *pb.SyncWordsRequest, stream pb.DataService_
 		clientAddr = p.Addr.String()
 	}
 
-	log.Printf("📥 SyncWords request from %s (since: %d)", clientAddr, req.SinceTimestamp)
+	log.Printf("📥 SyncWords request from %s (since: %d, since_id: %q, batch_size: %d)", clientAddr, req.SinceTimestamp, req.SinceId, req.BatchSize)
 
-	const batchSize = 1000 // Process 1000 words at a time
+	batchSize := syncBatchSize(req.BatchSize)
 	totalSent := 0
 
 	// Process words in batches to avoid loading everything into memory
-	err := s.repo.FindModifiedSinceBatch(req.SinceTimestamp, batchSize, func(batch []*model.Word) (bool, error) {
+	err := s.repo.FindModifiedAfterBatch(req.SinceTimestamp, req.SinceId, batchSize, func(batch []*model.Word) (bool, error) {
 		log.Printf("📤 Sending batch of %d words to %s (total so far: %d)", len(batch), clientAddr, totalSent)
 
+		// Batched clients get the whole batch in one message
+		if req.BatchSize > 0 {
+			words := make([]*pb.Word, len(batch))
+			for i, word := range batch {
+				words[i] = convertModelToProto(word)
+			}
+			if err := stream.Send(&pb.SyncWordsResponse{Words: words}); err != nil {
+				log.Printf("❌ Failed to send word batch to %s: %v", clientAddr, err)
+				return false, status.Errorf(codes.Internal, "failed to send words: %v", err)
+			}
+			totalSent += len(batch)
+			return true, nil
+		}
+
 		for _, word := range batch {
 			if err := stream.Send(&pb.SyncWordsResponse{
 				Word: convertModelToProto(word),
@@ -156,15 +284,30 @@ func (s *WordService) SyncUserDicts(req *pb.SyncUserDictsRequest, stream pb.Data
 		clientAddr = p.Addr.String()
 	}
 
-	log.Printf("📥 SyncUserDicts request from %s (since: %d)", clientAddr, req.SinceTimestamp)
+	log.Printf("📥 SyncUserDicts request from %s (since: %d, since_user_id: %q, since_word_id: %q, batch_size: %d)",
+		clientAddr, req.SinceTimestamp, req.SinceUserId, req.SinceWordId, req.BatchSize)
 
-	const batchSize = 1000 // Process 1000 user_dicts at a time
+	batchSize := syncBatchSize(req.BatchSize)
 	totalSent := 0
 
 	// Process user_dicts in batches to avoid loading everything into memory
-	err := s.repo.FindUserDictsModifiedSinceBatch(req.SinceTimestamp, batchSize, func(batch []*model.UserDict) (bool, error) {
+	err := s.repo.FindUserDictsModifiedAfterBatch(req.SinceTimestamp, req.SinceUserId, req.SinceWordId, batchSize, func(batch []*model.UserDict) (bool, error) {
 		log.Printf("📤 Sending batch of %d user_dicts to %s (total so far: %d)", len(batch), clientAddr, totalSent)
 
+		// Batched clients get the whole batch in one message
+		if req.BatchSize > 0 {
+			userDicts := make([]*pb.UserDict, len(batch))
+			for i, userDict := range batch {
+				userDicts[i] = convertUserDictModelToProto(userDict)
+			}
+			if err := stream.Send(&pb.SyncUserDictsResponse{UserDicts: userDicts}); err != nil {
+				log.Printf("❌ Failed to send user_dict batch to %s: %v", clientAddr, err)
+				return false, status.Errorf(codes.Internal, "failed to send user_dicts: %v", err)
+			}
+			totalSent += len(batch)
+			return true, nil
+		}
+
 		for _, userDict := range batch {
 			if err := stream.Send(&pb.SyncUserDictsResponse{
 				UserDict: convertUserDictModelToProto(userDict),
@@ -188,13 +331,23 @@ func (s *WordService) SyncUserDicts(req *pb.SyncUserDictsRequest, stream pb.Data
 	return nil
 }
 
+// syncBatchSize returns the repository batch size for a requested message batch size
+func syncBatchSize(requested int32) int {
+	if requested <= 0 || requested > maxSyncBatchSize {
+		return maxSyncBatchSize
+	}
+	return int(requested)
+}
+
 func convertModelToProto(word *model.Word) *pb.Word {
 	pbWord := &pb.Word{
-		Id:        word.ID,
-		English:   word.English,
-		CreatedAt: word.CreatedAt,
-		UpdatedAt: word.UpdatedAt,
-		LoadCount: int32(word.LoadCount),
+		Id:             word.ID,
+		English:        word.English,
+		CreatedAt:      word.CreatedAt,
+		UpdatedAt:      word.UpdatedAt,
+		LoadCount:      int32(word.LoadCount),
+		Glosses:        word.Glosses,
+		SourceLanguage: word.SourceLanguage,
 	}
 
 	if word.Chinese != nil {
@@ -216,6 +369,9 @@ func convertUserDictModelToProto(userDict *model.UserDict) *pb.UserDict {
 		WordId:            userDict.WordId,
 		QueryCount:        int32(userDict.QueryCount),
 		AlreadyAcquainted: int32(userDict.AlreadyAcquainted),
+		Note:              userDict.Note,
+		Tags:              userDict.Tags,
+		Translation:       userDict.Translation,
 		CreatedAt:         userDict.CreatedAt,
 		UpdatedAt:         userDict.UpdatedAt,
 	}
@@ -2,16 +2,22 @@ package service
 
 import (
 	"context"
+	"net"
 	"os"
 	"testing"
 	"time"
 
+	"enx-sync/internal/model"
 	"enx-sync/internal/repository"
 	pb "enx-sync/proto"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func setupTestService(t *testing.T) (*WordService, func()) {
@@ -40,6 +46,24 @@ func TestCreateWord(t *testing.T) {
 	assert.NotEmpty(t, resp.Word.Id)
 	assert.Equal(t, "test", resp.Word.English)
 	assert.Equal(t, "测试", resp.Word.Chinese)
+	assert.Equal(t, "en", resp.Word.SourceLanguage)
+}
+
+func TestCreateWord_SourceLanguage(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	english, err := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "gift", Chinese: "礼物"})
+	require.NoError(t, err)
+	german, err := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "gift", Chinese: "毒药", SourceLanguage: "de"})
+	require.NoError(t, err)
+	assert.NotEqual(t, english.Word.Id, german.Word.Id)
+
+	found, err := svc.GetWord(ctx, &pb.GetWordRequest{Id: german.Word.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "de", found.Word.SourceLanguage)
+	assert.Equal(t, "毒药", found.Word.Chinese)
 }
 
 func TestGetWord(t *testing.T) {
@@ -89,6 +113,90 @@ func TestDeleteWord(t *testing.T) {
 	assert.Greater(t, found.Word.DeletedAt, int64(0))
 }
 
+func TestMergeWords(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	source, _ := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "colour"})
+	target, _ := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "color"})
+	for _, userID := range []string{"user-1", "user-2"} {
+		_, err := svc.UpsertUserDict(ctx, &pb.UpsertUserDictRequest{UserDict: &pb.UserDict{UserId: userID, WordId: source.Word.Id, QueryCount: 2}})
+		require.NoError(t, err)
+	}
+	_, err := svc.AppendLookupEvent(ctx, &pb.AppendLookupEventRequest{Event: &pb.LookupEvent{UserId: "user-1", WordId: source.Word.Id, Kind: "lookup"}})
+	require.NoError(t, err)
+
+	_, err = svc.MergeWords(ctx, &pb.MergeWordsRequest{SourceId: source.Word.Id, TargetId: source.Word.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = svc.MergeWords(ctx, &pb.MergeWordsRequest{SourceId: "missing", TargetId: target.Word.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = svc.MergeWords(ctx, &pb.MergeWordsRequest{SourceId: source.Word.Id, TargetId: target.Word.Id, UserDicts: []*pb.UserDict{
+		{UserId: "user-1", WordId: target.Word.Id, QueryCount: 2},
+		{UserId: "user-2", WordId: target.Word.Id, QueryCount: 2},
+	}})
+	require.NoError(t, err)
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		_, err := svc.GetUserDict(ctx, &pb.GetUserDictRequest{UserId: userID, WordId: source.Word.Id})
+		assert.Equal(t, codes.NotFound, status.Code(err), userID)
+		merged, err := svc.GetUserDict(ctx, &pb.GetUserDictRequest{UserId: userID, WordId: target.Word.Id})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), merged.UserDict.QueryCount)
+	}
+	var wordIDs []string
+	err = svc.repo.FindLookupEventsAfterBatch(0, 10, func(events []*model.LookupEvent) (bool, error) {
+		for _, event := range events {
+			wordIDs = append(wordIDs, event.WordId)
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{target.Word.Id}, wordIDs)
+	found, _ := svc.GetWord(ctx, &pb.GetWordRequest{Id: source.Word.Id})
+	assert.Greater(t, found.Word.DeletedAt, int64(0))
+}
+
+func TestSetFrequencyRanks(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, _ := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "the"})
+
+	for _, want := range []int64{1, 0} {
+		resp, err := svc.SetFrequencyRanks(ctx, &pb.SetFrequencyRanksRequest{Ranks: map[string]int32{created.Word.Id: 1, "missing": 2}})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Changed)
+	}
+	// ranks aren't replicated
+	found, _ := svc.GetWord(ctx, &pb.GetWordRequest{Id: created.Word.Id})
+	assert.Equal(t, created.Word.UpdatedAt, found.Word.UpdatedAt)
+}
+
+func TestPruneLookupEvents_LocalOrSyncToken(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+	svc.SetSyncToken("secret")
+
+	remote := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 40000}})
+	local := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40000}})
+	req := &pb.PruneLookupEventsRequest{Before: time.Now().UnixMilli()}
+
+	_, err := svc.PruneLookupEvents(remote, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	wrong := metadata.NewIncomingContext(remote, metadata.Pairs("authorization", "Bearer wrong"))
+	_, err = svc.PruneLookupEvents(wrong, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = svc.PruneLookupEvents(local, req)
+	require.NoError(t, err)
+	authorized := metadata.NewIncomingContext(remote, metadata.Pairs("authorization", "Bearer secret"))
+	_, err = svc.PruneLookupEvents(authorized, req)
+	require.NoError(t, err)
+}
+
 func TestListWords(t *testing.T) {
 	svc, cleanup := setupTestService(t)
 	defer cleanup()
@@ -103,6 +211,44 @@ func TestListWords(t *testing.T) {
 	assert.Len(t, resp.Words, 3)
 }
 
+func TestUpsertAndGetUserDict(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := svc.GetUserDict(ctx, &pb.GetUserDictRequest{UserId: "user-1", WordId: "word-1"})
+	assert.Error(t, err)
+
+	created, err := svc.UpsertUserDict(ctx, &pb.UpsertUserDictRequest{
+		UserDict: &pb.UserDict{UserId: "user-1", WordId: "word-1", QueryCount: 1},
+	})
+	require.NoError(t, err)
+	assert.Greater(t, created.UserDict.CreatedAt, int64(0))
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = svc.UpsertUserDict(ctx, &pb.UpsertUserDictRequest{
+		UserDict: &pb.UserDict{UserId: "user-1", WordId: "word-1", QueryCount: 2, AlreadyAcquainted: 1},
+	})
+	require.NoError(t, err)
+
+	found, err := svc.GetUserDict(ctx, &pb.GetUserDictRequest{UserId: "user-1", WordId: "word-1"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), found.UserDict.QueryCount)
+	assert.Equal(t, int32(1), found.UserDict.AlreadyAcquainted)
+	assert.Equal(t, created.UserDict.CreatedAt, found.UserDict.CreatedAt)
+	assert.Greater(t, found.UserDict.UpdatedAt, created.UserDict.UpdatedAt)
+}
+
+func TestUpsertUserDict_MissingKey(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	_, err := svc.UpsertUserDict(context.Background(), &pb.UpsertUserDictRequest{
+		UserDict: &pb.UserDict{UserId: "user-1"},
+	})
+	assert.Error(t, err)
+}
+
 func TestSyncWords(t *testing.T) {
 	svc, cleanup := setupTestService(t)
 	defer cleanup()
@@ -149,3 +295,21 @@ func (m *mockSyncStream) Send(resp *pb.SyncWordsResponse) error {
 func (m *mockSyncStream) Context() context.Context {
 	return context.Background()
 }
+
+func TestUpdateWord_MergesGlosses(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, err := svc.CreateWord(ctx, &pb.CreateWordRequest{English: "harbor", Glosses: map[string]string{"ja": "港"}})
+	require.NoError(t, err)
+
+	// an older client sends chinese only, a newer one a single language
+	_, err = svc.UpdateWord(ctx, &pb.UpdateWordRequest{Word: &pb.Word{Id: created.Word.Id, Chinese: "港口"}})
+	require.NoError(t, err)
+	resp, err := svc.UpdateWord(ctx, &pb.UpdateWordRequest{Word: &pb.Word{Id: created.Word.Id, Glosses: map[string]string{"es": "puerto"}}})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"ja": "港", "zh-CHS": "港口", "es": "puerto"}, resp.Word.Glosses)
+	assert.Equal(t, "港口", resp.Word.Chinese)
+}
@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"enx-sync/internal/repository"
+	pb "enx-sync/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Bootstrap seeds the database at dbPath with a snapshot from peerAddr, authenticated with the
+// nodes' sync token. The replicated tables of the snapshot are copied into the database, which has
+// to have none of their rows yet; its other tables, e.g. the users of enx-api, are kept. The sync
+// state is set to the snapshot's cursors so the next incremental sync with the peer only pulls
+// later changes.
+func Bootstrap(ctx context.Context, peerAddr, dbPath, syncToken string) error {
+	log.Printf("Bootstrapping %s from %s", dbPath, peerAddr)
+
+	conn, err := grpc.NewClient(peerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewDataServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+syncToken)
+	stream, err := client.GetSnapshot(ctx, &pb.GetSnapshotRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to start snapshot stream: %w", err)
+	}
+
+	// Download next to the target, the copy is attached to it afterwards
+	tmpPath := dbPath + ".bootstrap"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	var info *pb.SnapshotInfo
+	hash := sha256.New()
+	writer := io.MultiWriter(file, hash)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("snapshot receive error: %w", err)
+		}
+		if resp.Info != nil {
+			info = resp.Info
+		}
+		if _, err := writer.Write(resp.Chunk); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write bootstrap file: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write bootstrap file: %w", err)
+	}
+
+	if info == nil {
+		return fmt.Errorf("snapshot stream from %s had no snapshot info", peerAddr)
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != info.Sha256 {
+		return fmt.Errorf("snapshot checksum mismatch (expected %s, got %s)", info.Sha256, checksum)
+	}
+
+	// Drop the peer's own sync progress and continue from the snapshot's cursor
+	repo, err := repository.NewWordRepository(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	cursor := convertProtoToSnapshotModel(info).Cursor()
+	if err := repo.ResetSyncState(); err != nil {
+		repo.Close()
+		return err
+	}
+	if err := repo.UpdateLastSyncTime(peerAddr, cursor); err != nil {
+		repo.Close()
+		return err
+	}
+	if err := repo.UpdateEventsCursor(peerAddr, info.EventsCursor, time.Now().UnixMilli()); err != nil {
+		repo.Close()
+		return err
+	}
+	if err := repo.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	target, err := repository.NewWordRepository(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer target.Close()
+	empty, err := target.IsEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("database %s already has words, user dicts or lookup events", dbPath)
+	}
+	if err := target.RestoreSnapshot(ctx, tmpPath); err != nil {
+		return fmt.Errorf("failed to install snapshot: %w", err)
+	}
+
+	log.Printf("✅ Bootstrapped %s from %s (%d bytes, cursor: %d)", dbPath, peerAddr, info.Size, cursor)
+	return nil
+}
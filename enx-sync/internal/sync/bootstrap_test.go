@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"enx-sync/internal/model"
+	"enx-sync/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBootstrap_SeedsEmptyDatabase(t *testing.T) {
+	coord1, _, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	word := &model.Word{ID: uuid.New().String(), English: "hello", Chinese: stringPtr("你好"), CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, coord1.repo.Create(word))
+	require.NoError(t, coord1.repo.UpsertUserDict(&model.UserDict{
+		UserId: "user-1", WordId: word.ID, QueryCount: 2, CreatedAt: now, UpdatedAt: now + 5,
+	}))
+	event := &model.LookupEvent{ID: uuid.New().String(), UserId: "user-1", WordId: word.ID, Kind: model.EventLookup, CreatedAt: now}
+	_, err := coord1.repo.AppendLookupEvent(event)
+	require.NoError(t, err)
+	// Node 1's own sync progress must not leak into the new node
+	require.NoError(t, coord1.repo.UpdateLastSyncTime("other-peer:50051", now))
+
+	dbPath := "/tmp/test_bootstrap_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+
+	err = Bootstrap(context.Background(), node1Addr, dbPath, testSyncToken)
+	require.NoError(t, err)
+
+	repo, err := repository.NewWordRepository(dbPath)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	found, err := repo.FindByID(word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "你好", *found.Chinese)
+
+	userDict, err := repo.FindUserDict("user-1", word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, userDict.QueryCount)
+
+	// Incremental sync continues from the snapshot cursor
+	lastSync, err := repo.GetLastSyncTime(node1Addr)
+	require.NoError(t, err)
+	assert.Equal(t, now, lastSync)
+	eventsCursor, err := repo.GetEventsCursor(node1Addr)
+	require.NoError(t, err)
+	assert.Equal(t, event.Seq, eventsCursor)
+	otherSync, err := repo.GetLastSyncTime("other-peer:50051")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), otherSync)
+}
+
+func TestBootstrap_RequiresSyncToken(t *testing.T) {
+	_, _, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	for _, token := range []string{"", "wrong-token"} {
+		dbPath := "/tmp/test_bootstrap_" + uuid.New().String() + ".db"
+		err := Bootstrap(context.Background(), node1Addr, dbPath, token)
+		assert.Equal(t, codes.Unauthenticated, status.Code(errors.Unwrap(err)), "token %q: %v", token, err)
+		_, err = os.Stat(dbPath)
+		assert.True(t, os.IsNotExist(err), "token %q", token)
+	}
+}
+
+func TestBootstrap_KeepsLocalTables(t *testing.T) {
+	coord1, _, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	word := &model.Word{ID: uuid.New().String(), English: "hello", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, coord1.repo.Create(word))
+
+	// enx-api already created its users on the new node, without any words yet
+	dbPath := "/tmp/test_bootstrap_" + uuid.New().String() + ".db"
+	defer os.Remove(dbPath)
+	local, err := repository.NewWordRepository(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, local.Close())
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT);
+		INSERT INTO users VALUES ('user-1', 'alice')`)
+	require.NoError(t, err)
+
+	require.NoError(t, Bootstrap(context.Background(), node1Addr, dbPath, testSyncToken))
+
+	var name string
+	require.NoError(t, db.QueryRow(`SELECT name FROM users WHERE id = 'user-1'`).Scan(&name))
+	assert.Equal(t, "alice", name)
+	var english string
+	require.NoError(t, db.QueryRow(`SELECT english FROM words WHERE id = ?`, word.ID).Scan(&english))
+	assert.Equal(t, "hello", english)
+
+	// a database with replicated rows isn't overwritten
+	err = Bootstrap(context.Background(), node1Addr, dbPath, testSyncToken)
+	assert.ErrorContains(t, err, "already has")
+}
@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"log"
+
+	"github.com/mostynb/go-grpc-compression/nonclobbering/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+// CompressionNone disables compression of sync streams
+const CompressionNone = "none"
+
+// DefaultCompression is tried first for peers without a configured compressor
+const DefaultCompression = zstd.Name
+
+// SetCompression sets the preferred compressor for a peer: "zstd", "gzip" or "none".
+// An empty name keeps the default.
+func (c *Coordinator) SetCompression(peerAddr, compressor string) {
+	if compressor == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compression[peerAddr] = compressor
+}
+
+// Compression returns the compressor currently used for a peer
+func (c *Coordinator) Compression(peerAddr string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if compressor, ok := c.compression[peerAddr]; ok {
+		return compressor
+	}
+	return DefaultCompression
+}
+
+// withCompression runs fn with the peer's compressor. A peer that can't decode it
+// answers Unimplemented; the compressor is then lowered (zstd -> gzip -> none),
+// remembered for the peer and fn is retried.
+func (c *Coordinator) withCompression(peerAddr string, fn func(opts []grpc.CallOption) error) error {
+	for {
+		compressor := c.Compression(peerAddr)
+
+		var opts []grpc.CallOption
+		if compressor != CompressionNone {
+			opts = append(opts, grpc.UseCompressor(compressor))
+		}
+
+		err := fn(opts)
+		if status.Code(err) != codes.Unimplemented || compressor == CompressionNone {
+			return err
+		}
+
+		fallback := fallbackCompression(compressor)
+		log.Printf("[%s] Peer %s rejected %s compression, falling back to %s", c.nodeID, peerAddr, compressor, fallback)
+		c.mu.Lock()
+		c.compression[peerAddr] = fallback
+		c.mu.Unlock()
+	}
+}
+
+// fallbackCompression returns the next compressor to try after a peer rejected one
+func fallbackCompression(compressor string) string {
+	if compressor == zstd.Name {
+		return gzip.Name
+	}
+	return CompressionNone
+}
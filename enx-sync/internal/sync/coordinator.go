@@ -2,8 +2,10 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,24 +14,51 @@ import (
 	pb "enx-sync/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	// syncBatchSize is the number of rows requested per sync message
+	syncBatchSize = 500
+
+	streamWords     = "words"
+	streamUserDicts = "user_dicts"
+)
+
+// errEventsUnsupported is returned by peers running a version without the lookup event log
+var errEventsUnsupported = errors.New("peer does not support lookup event sync")
+
+// errEventsRefused is returned by peers that don't accept the sync token for lookup events
+var errEventsRefused = errors.New("peer refused lookup event sync")
+
 // Coordinator orchestrates P2P synchronization between nodes
 type Coordinator struct {
 	repo   *repository.WordRepository
 	nodeID string
 	mu     sync.RWMutex
+	// compressor used for each peer, negotiated down when the peer doesn't support it
+	compression map[string]string
+	// presented to peers for their lookup events, see node.sync_token
+	syncToken string
 }
 
 // NewCoordinator creates a new sync coordinator
 func NewCoordinator(repo *repository.WordRepository, nodeID string) *Coordinator {
 	return &Coordinator{
-		repo:   repo,
-		nodeID: nodeID,
+		repo:        repo,
+		nodeID:      nodeID,
+		compression: make(map[string]string),
 	}
 }
 
+// SetSyncToken sets the secret presented to peers to pull their lookup events, see node.sync_token
+func (c *Coordinator) SetSyncToken(token string) {
+	c.syncToken = token
+}
+
 // SyncWithPeer performs bidirectional sync with a peer node
 func (c *Coordinator) SyncWithPeer(ctx context.Context, peerAddr string) error {
 	log.Printf("[%s] Starting sync with peer: %s", c.nodeID, peerAddr)
@@ -54,13 +83,25 @@ func (c *Coordinator) SyncWithPeer(ctx context.Context, peerAddr string) error {
 		return fmt.Errorf("failed to pull user_dict changes from peer: %w", err)
 	}
 
+	// PULL: Get new lookup events from peer (append-only, resumes from its own cursor)
+	appliedEvents, err := c.pullLookupEventsFromPeer(ctx, peerAddr)
+	if err != nil {
+		return fmt.Errorf("failed to pull lookup events from peer: %w", err)
+	}
+
 	// Update last sync time in database
 	now := time.Now().UnixMilli()
 	if err := c.repo.UpdateLastSyncTime(peerAddr, now); err != nil {
 		return fmt.Errorf("failed to update last sync time: %w", err)
 	}
 
-	log.Printf("[%s] Sync complete with %s: applied_words=%d, applied_user_dicts=%d", c.nodeID, peerAddr, appliedWords, appliedUserDicts)
+	// Transfer completed, the next sync starts from last_sync_time again
+	if err := c.repo.DeleteSyncCheckpoints(peerAddr); err != nil {
+		log.Printf("[%s] ⚠️  Failed to clear sync checkpoints for %s: %v", c.nodeID, peerAddr, err)
+	}
+
+	log.Printf("[%s] Sync complete with %s: applied_words=%d, applied_user_dicts=%d, applied_events=%d",
+		c.nodeID, peerAddr, appliedWords, appliedUserDicts, appliedEvents)
 	return nil
 }
 
@@ -74,32 +115,71 @@ func (c *Coordinator) pullChangesFromPeer(ctx context.Context, peerAddr string,
 
 	client := pb.NewDataServiceClient(conn)
 
-	stream, err := client.SyncWords(ctx, &pb.SyncWordsRequest{
-		SinceTimestamp: sinceTimestamp,
-	})
-	if err != nil {
-		return 0, fmt.Errorf("failed to start sync stream: %w", err)
-	}
-
 	appliedCount := 0
 	skippedCount := 0
 
-	for {
-		resp, err := stream.Recv()
+	err = c.withCompression(peerAddr, func(opts []grpc.CallOption) error {
+		req := &pb.SyncWordsRequest{
+			SinceTimestamp: sinceTimestamp,
+			BatchSize:      syncBatchSize,
+		}
+
+		// Resume an interrupted transfer from the last checkpoint
+		checkpoint, err := c.repo.GetSyncCheckpoint(peerAddr, streamWords)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
-			return appliedCount, fmt.Errorf("stream receive error: %w", err)
+			return err
+		}
+		if checkpoint != nil {
+			log.Printf("[%s] Resuming words from %s at checkpoint (%d, %s)", c.nodeID, peerAddr, checkpoint.CursorUpdatedAt, checkpoint.CursorID)
+			req.SinceTimestamp = checkpoint.CursorUpdatedAt
+			req.SinceId = checkpoint.CursorID
 		}
 
-		// Apply the change with conflict resolution
-		if err := c.applyRemoteChange(resp.Word); err != nil {
-			// Silently skip - mostly due to local version being newer
-			skippedCount++
-			continue
+		stream, err := client.SyncWords(ctx, req, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to start sync stream: %w", err)
 		}
-		appliedCount++
+
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				return fmt.Errorf("stream receive error: %w", err)
+			}
+
+			words := resp.Words
+			if len(words) == 0 && resp.Word != nil {
+				// Peers without batching send one row per message
+				words = []*pb.Word{resp.Word}
+			}
+
+			for _, word := range words {
+				// Apply the change with conflict resolution
+				if err := c.applyRemoteChange(word); err != nil {
+					// Silently skip - mostly due to local version being newer
+					skippedCount++
+					continue
+				}
+				appliedCount++
+			}
+
+			// Batches arrive in (updated_at, id) order, so the last row is a safe resume point
+			if len(resp.Words) > 0 {
+				last := resp.Words[len(resp.Words)-1]
+				c.saveCheckpoint(&model.SyncCheckpoint{
+					PeerAddr:        peerAddr,
+					Stream:          streamWords,
+					CursorUpdatedAt: last.UpdatedAt,
+					CursorID:        last.Id,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return appliedCount, err
 	}
 
 	if appliedCount > 0 || skippedCount > 0 {
@@ -118,40 +198,177 @@ func (c *Coordinator) pullUserDictsFromPeer(ctx context.Context, peerAddr string
 
 	client := pb.NewDataServiceClient(conn)
 
-	stream, err := client.SyncUserDicts(ctx, &pb.SyncUserDictsRequest{
-		SinceTimestamp: sinceTimestamp,
+	appliedCount := 0
+	skippedCount := 0
+
+	err = c.withCompression(peerAddr, func(opts []grpc.CallOption) error {
+		req := &pb.SyncUserDictsRequest{
+			SinceTimestamp: sinceTimestamp,
+			BatchSize:      syncBatchSize,
+		}
+
+		// Resume an interrupted transfer from the last checkpoint
+		checkpoint, err := c.repo.GetSyncCheckpoint(peerAddr, streamUserDicts)
+		if err != nil {
+			return err
+		}
+		if checkpoint != nil {
+			log.Printf("[%s] Resuming user_dicts from %s at checkpoint (%d, %s, %s)",
+				c.nodeID, peerAddr, checkpoint.CursorUpdatedAt, checkpoint.CursorID, checkpoint.CursorSubID)
+			req.SinceTimestamp = checkpoint.CursorUpdatedAt
+			req.SinceUserId = checkpoint.CursorID
+			req.SinceWordId = checkpoint.CursorSubID
+		}
+
+		stream, err := client.SyncUserDicts(ctx, req, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to start user_dicts sync stream: %w", err)
+		}
+
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				return fmt.Errorf("stream receive error: %w", err)
+			}
+
+			userDicts := resp.UserDicts
+			if len(userDicts) == 0 && resp.UserDict != nil {
+				// Peers without batching send one row per message
+				userDicts = []*pb.UserDict{resp.UserDict}
+			}
+
+			for _, userDict := range userDicts {
+				// Apply the change with conflict resolution
+				if err := c.applyRemoteUserDict(userDict); err != nil {
+					// Silently skip - mostly due to local version being newer
+					skippedCount++
+					continue
+				}
+				appliedCount++
+			}
+
+			// Batches arrive in (updated_at, user_id, word_id) order, so the last row is a safe resume point
+			if len(resp.UserDicts) > 0 {
+				last := resp.UserDicts[len(resp.UserDicts)-1]
+				c.saveCheckpoint(&model.SyncCheckpoint{
+					PeerAddr:        peerAddr,
+					Stream:          streamUserDicts,
+					CursorUpdatedAt: last.UpdatedAt,
+					CursorID:        last.UserId,
+					CursorSubID:     last.WordId,
+				})
+			}
+		}
+		return nil
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to start user_dicts sync stream: %w", err)
+		return appliedCount, err
+	}
+
+	if appliedCount > 0 || skippedCount > 0 {
+		log.Printf("[%s] Pulled user_dicts from %s: applied=%d, skipped=%d", c.nodeID, peerAddr, appliedCount, skippedCount)
+	}
+	return appliedCount, nil
+}
+
+// pullLookupEventsFromPeer appends the peer's events after the last pulled seq.
+// Events already known (relayed through another peer) are skipped by id.
+func (c *Coordinator) pullLookupEventsFromPeer(ctx context.Context, peerAddr string) (int, error) {
+	conn, err := grpc.NewClient(peerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to peer: %w", err)
 	}
+	defer conn.Close()
+
+	client := pb.NewDataServiceClient(conn)
 
 	appliedCount := 0
 	skippedCount := 0
 
-	for {
-		resp, err := stream.Recv()
+	err = c.withCompression(peerAddr, func(opts []grpc.CallOption) error {
+		cursor, err := c.repo.GetEventsCursor(peerAddr)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
-			return appliedCount, fmt.Errorf("stream receive error: %w", err)
+			return err
 		}
 
-		// Apply the change with conflict resolution
-		if err := c.applyRemoteUserDict(resp.UserDict); err != nil {
-			// Silently skip - mostly due to local version being newer
-			skippedCount++
-			continue
+		ctx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.syncToken)
+		stream, err := client.SyncLookupEvents(ctx, &pb.SyncLookupEventsRequest{
+			AfterSeq:  cursor,
+			BatchSize: syncBatchSize,
+		}, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to start lookup events sync stream: %w", err)
 		}
-		appliedCount++
+
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				// Not a compression problem, so don't let withCompression retry with another one
+				if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented &&
+					(strings.Contains(s.Message(), "unknown method") || strings.Contains(s.Message(), "not implemented")) {
+					return errEventsUnsupported
+				}
+				if code := status.Code(err); code == codes.Unauthenticated || code == codes.PermissionDenied {
+					return fmt.Errorf("%w: %v", errEventsRefused, err)
+				}
+				return fmt.Errorf("stream receive error: %w", err)
+			}
+
+			for _, event := range resp.Events {
+				inserted, err := c.repo.AppendLookupEvent(convertProtoToLookupEventModel(event))
+				if err != nil {
+					return fmt.Errorf("failed to append lookup event: %w", err)
+				}
+				if inserted {
+					appliedCount++
+				} else {
+					skippedCount++
+				}
+			}
+
+			// Events arrive in the peer's seq order, the last one is where the next pull continues
+			if len(resp.Events) > 0 {
+				last := resp.Events[len(resp.Events)-1]
+				if err := c.repo.UpdateEventsCursor(peerAddr, last.Seq, time.Now().UnixMilli()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errEventsUnsupported) {
+		log.Printf("[%s] ⚠️  Peer %s does not support lookup event sync yet, skipping", c.nodeID, peerAddr)
+		return 0, nil
+	}
+	// Words and user_dicts still sync, check node.sync_token on both nodes
+	if errors.Is(err, errEventsRefused) {
+		log.Printf("[%s] ⚠️  Skipping lookup events of %s: %v", c.nodeID, peerAddr, err)
+		return 0, nil
+	}
+	if err != nil {
+		return appliedCount, err
 	}
 
 	if appliedCount > 0 || skippedCount > 0 {
-		log.Printf("[%s] Pulled user_dicts from %s: applied=%d, skipped=%d", c.nodeID, peerAddr, appliedCount, skippedCount)
+		log.Printf("[%s] Pulled lookup events from %s: applied=%d, skipped=%d", c.nodeID, peerAddr, appliedCount, skippedCount)
 	}
 	return appliedCount, nil
 }
 
+// saveCheckpoint records transfer progress; a failure only means a resumed sync re-sends more rows
+func (c *Coordinator) saveCheckpoint(checkpoint *model.SyncCheckpoint) {
+	checkpoint.UpdatedAt = time.Now().UnixMilli()
+	if err := c.repo.SaveSyncCheckpoint(checkpoint); err != nil {
+		log.Printf("[%s] ⚠️  Failed to save %s checkpoint for %s: %v", c.nodeID, checkpoint.Stream, checkpoint.PeerAddr, err)
+	}
+}
+
 // applyRemoteChange applies a change from peer with conflict resolution
 func (c *Coordinator) applyRemoteChange(remoteWord *pb.Word) error {
 	// Check if word exists locally
@@ -202,10 +419,12 @@ func (c *Coordinator) GetSyncStatus() map[string]interface{} {
 // Helper functions
 func convertModelToProto(word *model.Word) *pb.Word {
 	pbWord := &pb.Word{
-		Id:        word.ID,
-		English:   word.English,
-		CreatedAt: word.CreatedAt,
-		UpdatedAt: word.UpdatedAt,
+		Id:             word.ID,
+		English:        word.English,
+		CreatedAt:      word.CreatedAt,
+		UpdatedAt:      word.UpdatedAt,
+		Glosses:        word.Glosses,
+		SourceLanguage: word.SourceLanguage,
 	}
 	if word.Chinese != nil {
 		pbWord.Chinese = *word.Chinese
@@ -218,10 +437,12 @@ func convertModelToProto(word *model.Word) *pb.Word {
 
 func convertProtoToModel(pbWord *pb.Word) *model.Word {
 	word := &model.Word{
-		ID:        pbWord.Id,
-		English:   pbWord.English,
-		CreatedAt: pbWord.CreatedAt,
-		UpdatedAt: pbWord.UpdatedAt,
+		ID:             pbWord.Id,
+		English:        pbWord.English,
+		CreatedAt:      pbWord.CreatedAt,
+		UpdatedAt:      pbWord.UpdatedAt,
+		Glosses:        pbWord.Glosses,
+		SourceLanguage: pbWord.SourceLanguage,
 	}
 	if pbWord.Chinese != "" {
 		word.Chinese = &pbWord.Chinese
@@ -238,7 +459,33 @@ func convertProtoToUserDictModel(pbUserDict *pb.UserDict) *model.UserDict {
 		WordId:            pbUserDict.WordId,
 		QueryCount:        int(pbUserDict.QueryCount),
 		AlreadyAcquainted: int(pbUserDict.AlreadyAcquainted),
+		Note:              pbUserDict.Note,
+		Tags:              pbUserDict.Tags,
+		Translation:       pbUserDict.Translation,
 		CreatedAt:         pbUserDict.CreatedAt,
 		UpdatedAt:         pbUserDict.UpdatedAt,
 	}
 }
+
+func convertProtoToLookupEventModel(pbEvent *pb.LookupEvent) *model.LookupEvent {
+	return &model.LookupEvent{
+		ID:        pbEvent.Id,
+		UserId:    pbEvent.UserId,
+		WordId:    pbEvent.WordId,
+		Kind:      pbEvent.Kind,
+		CreatedAt: pbEvent.CreatedAt,
+		Source:    pbEvent.Source,
+		PageHost:  pbEvent.PageHost,
+	}
+}
+
+func convertProtoToSnapshotModel(pbSnapshot *pb.SnapshotInfo) *model.Snapshot {
+	return &model.Snapshot{
+		Size:            pbSnapshot.Size,
+		SHA256:          pbSnapshot.Sha256,
+		WordsCursor:     pbSnapshot.WordsCursor,
+		UserDictsCursor: pbSnapshot.UserDictsCursor,
+		EventsCursor:    pbSnapshot.EventsCursor,
+		CreatedAt:       pbSnapshot.CreatedAt,
+	}
+}
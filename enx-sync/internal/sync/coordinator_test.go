@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"testing"
@@ -16,8 +17,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const testSyncToken = "test-sync-token"
+
 func setupTestNodes(t *testing.T) (*Coordinator, *Coordinator, string, string, func()) {
 	dbPath1 := "/tmp/test_node1_" + uuid.New().String() + ".db"
 	dbPath2 := "/tmp/test_node2_" + uuid.New().String() + ".db"
@@ -35,6 +40,7 @@ func setupTestNodes(t *testing.T) (*Coordinator, *Coordinator, string, string, f
 
 	server1 := grpc.NewServer()
 	wordService1 := service.NewWordService(repo1)
+	wordService1.SetSyncToken(testSyncToken)
 	pb.RegisterDataServiceServer(server1, wordService1)
 	go server1.Serve(lis1)
 
@@ -45,11 +51,14 @@ func setupTestNodes(t *testing.T) (*Coordinator, *Coordinator, string, string, f
 
 	server2 := grpc.NewServer()
 	wordService2 := service.NewWordService(repo2)
+	wordService2.SetSyncToken(testSyncToken)
 	pb.RegisterDataServiceServer(server2, wordService2)
 	go server2.Serve(lis2)
 
 	coord1 := NewCoordinator(repo1, "node1")
+	coord1.SetSyncToken(testSyncToken)
 	coord2 := NewCoordinator(repo2, "node2")
+	coord2.SetSyncToken(testSyncToken)
 
 	cleanup := func() {
 		server1.Stop()
@@ -72,11 +81,14 @@ func TestSyncWithPeer_InitialSync(t *testing.T) {
 		ID:        uuid.New().String(),
 		English:   "hello",
 		Chinese:   stringPtr("你好"),
+		Glosses:   map[string]string{"ja": "こんにちは"},
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 	err := coord1.repo.Create(word1)
 	require.NoError(t, err)
+	german := &model.Word{ID: uuid.New().String(), English: "hallo", SourceLanguage: "de", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, coord1.repo.Create(german))
 
 	// Node 2 syncs from Node 1 (pulls changes)
 	err = coord2.SyncWithPeer(context.Background(), node1Addr)
@@ -86,6 +98,11 @@ func TestSyncWithPeer_InitialSync(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "hello", found.English)
 	assert.Equal(t, "你好", *found.Chinese)
+	assert.Equal(t, map[string]string{"zh-CHS": "你好", "ja": "こんにちは"}, found.Glosses)
+	assert.Equal(t, "en", found.SourceLanguage)
+	found, err = coord2.repo.FindByID(german.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "de", found.SourceLanguage)
 }
 
 func TestSyncWithPeer_ConflictResolution_RemoteNewer(t *testing.T) {
@@ -321,3 +338,178 @@ func TestSyncWithPeer_BothWordsAndUserDicts(t *testing.T) {
 	assert.Equal(t, 7, foundUserDict.QueryCount)
 	assert.Equal(t, 1, foundUserDict.AlreadyAcquainted)
 }
+
+func TestSyncWithPeer_BatchedAcrossMessages(t *testing.T) {
+	coord1, coord2, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	// More rows than one batch, all with the same updated_at to exercise the (updated_at, id) cursor
+	now := time.Now().UnixMilli()
+	total := syncBatchSize + 20
+	for i := 0; i < total; i++ {
+		require.NoError(t, coord1.repo.Create(&model.Word{
+			ID:        uuid.New().String(),
+			English:   fmt.Sprintf("word-%d", i),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}))
+	}
+
+	coord2.SetCompression(node1Addr, "gzip")
+	err := coord2.SyncWithPeer(context.Background(), node1Addr)
+	require.NoError(t, err)
+
+	words, err := coord2.repo.FindAll()
+	require.NoError(t, err)
+	assert.Len(t, words, total)
+
+	// Completed sync leaves no checkpoint behind
+	checkpoint, err := coord2.repo.GetSyncCheckpoint(node1Addr, streamWords)
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+func TestSyncWithPeer_ResumeFromCheckpoint(t *testing.T) {
+	coord1, coord2, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	baseTime := time.Now().UnixMilli()
+	applied := &model.Word{ID: uuid.New().String(), English: "applied", CreatedAt: baseTime, UpdatedAt: baseTime}
+	pending := &model.Word{ID: uuid.New().String(), English: "pending", CreatedAt: baseTime, UpdatedAt: baseTime + 1000}
+	require.NoError(t, coord1.repo.Create(applied))
+	require.NoError(t, coord1.repo.Create(pending))
+
+	// Simulate a transfer interrupted right after "applied"
+	require.NoError(t, coord2.repo.SaveSyncCheckpoint(&model.SyncCheckpoint{
+		PeerAddr:        node1Addr,
+		Stream:          streamWords,
+		CursorUpdatedAt: applied.UpdatedAt,
+		CursorID:        applied.ID,
+		UpdatedAt:       baseTime,
+	}))
+
+	err := coord2.SyncWithPeer(context.Background(), node1Addr)
+	require.NoError(t, err)
+
+	_, err = coord2.repo.FindByID(applied.ID)
+	assert.Error(t, err, "rows before the checkpoint should not be sent again")
+	found, err := coord2.repo.FindByID(pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", found.English)
+}
+
+func TestSyncWithPeer_LookupEvents(t *testing.T) {
+	coord1, coord2, node1Addr, node2Addr, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	var events []*model.LookupEvent
+	for i, kind := range []string{model.EventLookup, model.EventLookup, model.EventMark} {
+		event := &model.LookupEvent{ID: uuid.New().String(), UserId: "user-1", WordId: "word-1", Kind: kind, CreatedAt: now + int64(i)}
+		_, err := coord1.repo.AppendLookupEvent(event)
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+
+	require.NoError(t, coord2.SyncWithPeer(context.Background(), node1Addr))
+	cursor, err := coord2.repo.GetEventsCursor(node1Addr)
+	require.NoError(t, err)
+	assert.Equal(t, events[2].Seq, cursor)
+
+	// A second sync only pulls events appended since
+	later := &model.LookupEvent{ID: uuid.New().String(), UserId: "user-1", WordId: "word-2", Kind: model.EventUnmark, CreatedAt: now + 10,
+		Source: "extension", PageHost: "example.com"}
+	_, err = coord1.repo.AppendLookupEvent(later)
+	require.NoError(t, err)
+	require.NoError(t, coord2.SyncWithPeer(context.Background(), node1Addr))
+
+	var pulled []*model.LookupEvent
+	require.NoError(t, coord2.repo.FindLookupEventsAfterBatch(0, 100, func(batch []*model.LookupEvent) (bool, error) {
+		pulled = append(pulled, batch...)
+		return true, nil
+	}))
+	require.Len(t, pulled, 4)
+	assert.Equal(t, events[0].ID, pulled[0].ID)
+	assert.Equal(t, later.ID, pulled[3].ID)
+	assert.Equal(t, model.EventUnmark, pulled[3].Kind)
+	assert.Equal(t, "extension", pulled[3].Source)
+	assert.Equal(t, "example.com", pulled[3].PageHost)
+
+	// Pulling node 1's own events back from node 2 doesn't duplicate them
+	require.NoError(t, coord1.SyncWithPeer(context.Background(), node2Addr))
+	var own int
+	require.NoError(t, coord1.repo.FindLookupEventsAfterBatch(0, 100, func(batch []*model.LookupEvent) (bool, error) {
+		own += len(batch)
+		return true, nil
+	}))
+	assert.Equal(t, 4, own)
+}
+
+func TestSyncWithPeer_LookupEventsRequireSyncToken(t *testing.T) {
+	coord1, coord2, node1Addr, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	_, err := coord1.repo.AppendLookupEvent(&model.LookupEvent{ID: uuid.New().String(), UserId: "user-1", WordId: "word-1",
+		Kind: model.EventLookup, CreatedAt: now, PageHost: "example.com"})
+	require.NoError(t, err)
+	word := &model.Word{ID: uuid.New().String(), English: "hello", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, coord1.repo.Create(word))
+
+	// words still sync, the lookup history isn't handed out
+	coord2.SetSyncToken("wrong-token")
+	require.NoError(t, coord2.SyncWithPeer(context.Background(), node1Addr))
+	_, err = coord2.repo.FindByID(word.ID)
+	require.NoError(t, err)
+	var pulled int
+	require.NoError(t, coord2.repo.FindLookupEventsAfterBatch(0, 100, func(batch []*model.LookupEvent) (bool, error) {
+		pulled += len(batch)
+		return true, nil
+	}))
+	assert.Equal(t, 0, pulled)
+}
+
+// legacyDataService is a peer from before the lookup event log
+type legacyDataService struct {
+	pb.UnimplementedDataServiceServer
+}
+
+func (legacyDataService) SyncWords(*pb.SyncWordsRequest, pb.DataService_SyncWordsServer) error {
+	return nil
+}
+
+func (legacyDataService) SyncUserDicts(*pb.SyncUserDictsRequest, pb.DataService_SyncUserDictsServer) error {
+	return nil
+}
+
+func TestSyncWithPeer_LookupEventsUnsupportedByPeer(t *testing.T) {
+	_, coord2, _, _, cleanup := setupTestNodes(t)
+	defer cleanup()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterDataServiceServer(server, legacyDataService{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	require.NoError(t, coord2.SyncWithPeer(context.Background(), lis.Addr().String()))
+	assert.Equal(t, DefaultCompression, coord2.Compression(lis.Addr().String()), "compression must not be lowered")
+}
+
+func TestWithCompression_FallsBackOnUnimplemented(t *testing.T) {
+	coord := NewCoordinator(nil, "node")
+	peerAddr := "peer:50051"
+
+	var tried []string
+	err := coord.withCompression(peerAddr, func(opts []grpc.CallOption) error {
+		tried = append(tried, coord.Compression(peerAddr))
+		if len(tried) < 3 {
+			return status.Error(codes.Unimplemented, "grpc: Decompressor is not installed")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zstd", "gzip", CompressionNone}, tried)
+	assert.Equal(t, CompressionNone, coord.Compression(peerAddr))
+}
@@ -25,15 +25,20 @@ const (
 type Word struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                 // UUID v4
-	English       string                 `protobuf:"bytes,2,opt,name=english,proto3" json:"english,omitempty"`                       // English word (required, unique)
+	English       string                 `protobuf:"bytes,2,opt,name=english,proto3" json:"english,omitempty"`                       // Word in source_language (required, unique within the language)
 	Chinese       string                 `protobuf:"bytes,3,opt,name=chinese,proto3" json:"chinese,omitempty"`                       // Chinese translation (optional)
 	Pronunciation string                 `protobuf:"bytes,4,opt,name=pronunciation,proto3" json:"pronunciation,omitempty"`           // Pronunciation guide (optional)
 	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix timestamp in milliseconds
 	LoadCount     int32                  `protobuf:"varint,6,opt,name=load_count,json=loadCount,proto3" json:"load_count,omitempty"` // Usage counter
 	UpdatedAt     int64                  `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // Unix timestamp in milliseconds (required)
 	DeletedAt     int64                  `protobuf:"varint,8,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"` // Soft delete timestamp (0 = not deleted)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// Translations by target language code, e.g. zh-CHS, ja, es.
+	// The zh-CHS gloss is mirrored in chinese for peers and clients predating glosses.
+	Glosses map[string]string `protobuf:"bytes,9,rep,name=glosses,proto3" json:"glosses,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Language of english, e.g. en, de, fr. Empty from peers predating source languages, meaning en.
+	SourceLanguage string `protobuf:"bytes,10,opt,name=source_language,json=sourceLanguage,proto3" json:"source_language,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Word) Reset() {
@@ -122,6 +127,20 @@ func (x *Word) GetDeletedAt() int64 {
 	return 0
 }
 
+func (x *Word) GetGlosses() map[string]string {
+	if x != nil {
+		return x.Glosses
+	}
+	return nil
+}
+
+func (x *Word) GetSourceLanguage() string {
+	if x != nil {
+		return x.SourceLanguage
+	}
+	return ""
+}
+
 type GetWordRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -211,12 +230,14 @@ func (x *GetWordResponse) GetWord() *Word {
 }
 
 type CreateWordRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	English       string                 `protobuf:"bytes,1,opt,name=english,proto3" json:"english,omitempty"`
-	Chinese       string                 `protobuf:"bytes,2,opt,name=chinese,proto3" json:"chinese,omitempty"`
-	Pronunciation string                 `protobuf:"bytes,3,opt,name=pronunciation,proto3" json:"pronunciation,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	English        string                 `protobuf:"bytes,1,opt,name=english,proto3" json:"english,omitempty"`
+	Chinese        string                 `protobuf:"bytes,2,opt,name=chinese,proto3" json:"chinese,omitempty"`
+	Pronunciation  string                 `protobuf:"bytes,3,opt,name=pronunciation,proto3" json:"pronunciation,omitempty"`
+	Glosses        map[string]string      `protobuf:"bytes,4,rep,name=glosses,proto3" json:"glosses,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Translations by target language code, see Word.glosses
+	SourceLanguage string                 `protobuf:"bytes,5,opt,name=source_language,json=sourceLanguage,proto3" json:"source_language,omitempty"`                                       // Language of english, en when empty
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *CreateWordRequest) Reset() {
@@ -270,6 +291,20 @@ func (x *CreateWordRequest) GetPronunciation() string {
 	return ""
 }
 
+func (x *CreateWordRequest) GetGlosses() map[string]string {
+	if x != nil {
+		return x.Glosses
+	}
+	return nil
+}
+
+func (x *CreateWordRequest) GetSourceLanguage() string {
+	if x != nil {
+		return x.SourceLanguage
+	}
+	return ""
+}
+
 type CreateWordResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Word          *Word                  `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
@@ -314,6 +349,8 @@ func (x *CreateWordResponse) GetWord() *Word {
 	return nil
 }
 
+// UpdateWordRequest changes the non-empty fields of word; glosses are merged by language.
+// The source language of a word doesn't change.
 type UpdateWordRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Word          *Word                  `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
@@ -594,27 +631,32 @@ func (x *ListWordsResponse) GetTotal() int32 {
 	return 0
 }
 
-type SyncWordsRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	SinceTimestamp int64                  `protobuf:"varint,1,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Unix timestamp in milliseconds
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+// MergeWordsRequest moves the user dicts and lookup events of source onto target and soft-deletes
+// source, in one transaction. user_dicts are the target's rows after the merge, computed by the
+// caller; the source's own rows are deleted.
+type MergeWordsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	TargetId      string                 `protobuf:"bytes,2,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	UserDicts     []*UserDict            `protobuf:"bytes,3,rep,name=user_dicts,json=userDicts,proto3" json:"user_dicts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SyncWordsRequest) Reset() {
-	*x = SyncWordsRequest{}
+func (x *MergeWordsRequest) Reset() {
+	*x = MergeWordsRequest{}
 	mi := &file_data_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SyncWordsRequest) String() string {
+func (x *MergeWordsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SyncWordsRequest) ProtoMessage() {}
+func (*MergeWordsRequest) ProtoMessage() {}
 
-func (x *SyncWordsRequest) ProtoReflect() protoreflect.Message {
+func (x *MergeWordsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -626,39 +668,54 @@ func (x *SyncWordsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SyncWordsRequest.ProtoReflect.Descriptor instead.
-func (*SyncWordsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use MergeWordsRequest.ProtoReflect.Descriptor instead.
+func (*MergeWordsRequest) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *SyncWordsRequest) GetSinceTimestamp() int64 {
+func (x *MergeWordsRequest) GetSourceId() string {
 	if x != nil {
-		return x.SinceTimestamp
+		return x.SourceId
 	}
-	return 0
+	return ""
 }
 
-type SyncWordsResponse struct {
+func (x *MergeWordsRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *MergeWordsRequest) GetUserDicts() []*UserDict {
+	if x != nil {
+		return x.UserDicts
+	}
+	return nil
+}
+
+type MergeWordsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Word          *Word                  `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	TargetId      string                 `protobuf:"bytes,2,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SyncWordsResponse) Reset() {
-	*x = SyncWordsResponse{}
+func (x *MergeWordsResponse) Reset() {
+	*x = MergeWordsResponse{}
 	mi := &file_data_service_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SyncWordsResponse) String() string {
+func (x *MergeWordsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SyncWordsResponse) ProtoMessage() {}
+func (*MergeWordsResponse) ProtoMessage() {}
 
-func (x *SyncWordsResponse) ProtoReflect() protoreflect.Message {
+func (x *MergeWordsResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -670,39 +727,48 @@ func (x *SyncWordsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SyncWordsResponse.ProtoReflect.Descriptor instead.
-func (*SyncWordsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use MergeWordsResponse.ProtoReflect.Descriptor instead.
+func (*MergeWordsResponse) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *SyncWordsResponse) GetWord() *Word {
+func (x *MergeWordsResponse) GetSourceId() string {
 	if x != nil {
-		return x.Word
+		return x.SourceId
 	}
-	return nil
+	return ""
 }
 
-type SyncUserDictsRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	SinceTimestamp int64                  `protobuf:"varint,1,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Unix timestamp in milliseconds
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *MergeWordsResponse) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
 }
 
-func (x *SyncUserDictsRequest) Reset() {
-	*x = SyncUserDictsRequest{}
+// SetFrequencyRanksRequest stores the ranks of words in enx-api's word frequency list. Ranks are
+// local to a node: they aren't replicated and don't move updated_at.
+type SetFrequencyRanksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ranks         map[string]int32       `protobuf:"bytes,1,rep,name=ranks,proto3" json:"ranks,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Rank by word id, 0 = not in the list
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetFrequencyRanksRequest) Reset() {
+	*x = SetFrequencyRanksRequest{}
 	mi := &file_data_service_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SyncUserDictsRequest) String() string {
+func (x *SetFrequencyRanksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SyncUserDictsRequest) ProtoMessage() {}
+func (*SetFrequencyRanksRequest) ProtoMessage() {}
 
-func (x *SyncUserDictsRequest) ProtoReflect() protoreflect.Message {
+func (x *SetFrequencyRanksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -714,39 +780,39 @@ func (x *SyncUserDictsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SyncUserDictsRequest.ProtoReflect.Descriptor instead.
-func (*SyncUserDictsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetFrequencyRanksRequest.ProtoReflect.Descriptor instead.
+func (*SetFrequencyRanksRequest) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *SyncUserDictsRequest) GetSinceTimestamp() int64 {
+func (x *SetFrequencyRanksRequest) GetRanks() map[string]int32 {
 	if x != nil {
-		return x.SinceTimestamp
+		return x.Ranks
 	}
-	return 0
+	return nil
 }
 
-type SyncUserDictsResponse struct {
+type SetFrequencyRanksResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`
+	Changed       int64                  `protobuf:"varint,1,opt,name=changed,proto3" json:"changed,omitempty"` // Number of words whose rank changed
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SyncUserDictsResponse) Reset() {
-	*x = SyncUserDictsResponse{}
+func (x *SetFrequencyRanksResponse) Reset() {
+	*x = SetFrequencyRanksResponse{}
 	mi := &file_data_service_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SyncUserDictsResponse) String() string {
+func (x *SetFrequencyRanksResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SyncUserDictsResponse) ProtoMessage() {}
+func (*SetFrequencyRanksResponse) ProtoMessage() {}
 
-func (x *SyncUserDictsResponse) ProtoReflect() protoreflect.Message {
+func (x *SetFrequencyRanksResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -758,45 +824,45 @@ func (x *SyncUserDictsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SyncUserDictsResponse.ProtoReflect.Descriptor instead.
-func (*SyncUserDictsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetFrequencyRanksResponse.ProtoReflect.Descriptor instead.
+func (*SetFrequencyRanksResponse) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *SyncUserDictsResponse) GetUserDict() *UserDict {
+func (x *SetFrequencyRanksResponse) GetChanged() int64 {
 	if x != nil {
-		return x.UserDict
+		return x.Changed
 	}
-	return nil
+	return 0
 }
 
-// UserDict message for user-specific word data
-type UserDict struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	UserId            string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                                   // User UUID
-	WordId            string                 `protobuf:"bytes,2,opt,name=word_id,json=wordId,proto3" json:"word_id,omitempty"`                                   // Word UUID (foreign key to words.id)
-	QueryCount        int32                  `protobuf:"varint,3,opt,name=query_count,json=queryCount,proto3" json:"query_count,omitempty"`                      // Number of times user queried this word
-	AlreadyAcquainted int32                  `protobuf:"varint,4,opt,name=already_acquainted,json=alreadyAcquainted,proto3" json:"already_acquainted,omitempty"` // 0 = learning, 1 = already knows
-	CreatedAt         int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                         // Unix timestamp in milliseconds
-	UpdatedAt         int64                  `protobuf:"varint,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`                         // Unix timestamp in milliseconds
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type SyncWordsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SinceTimestamp int64                  `protobuf:"varint,1,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Unix timestamp in milliseconds
+	// Resume cursor: when set, only rows after (since_timestamp, since_id) are sent.
+	// Used to continue an interrupted transfer from the last checkpoint.
+	SinceId string `protobuf:"bytes,2,opt,name=since_id,json=sinceId,proto3" json:"since_id,omitempty"`
+	// When > 0, rows are sent in batches of up to batch_size in SyncWordsResponse.words.
+	// When 0, one row per message in SyncWordsResponse.word (legacy clients).
+	BatchSize     int32 `protobuf:"varint,3,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UserDict) Reset() {
-	*x = UserDict{}
+func (x *SyncWordsRequest) Reset() {
+	*x = SyncWordsRequest{}
 	mi := &file_data_service_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UserDict) String() string {
+func (x *SyncWordsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UserDict) ProtoMessage() {}
+func (*SyncWordsRequest) ProtoMessage() {}
 
-func (x *UserDict) ProtoReflect() protoreflect.Message {
+func (x *SyncWordsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -808,75 +874,54 @@ func (x *UserDict) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UserDict.ProtoReflect.Descriptor instead.
-func (*UserDict) Descriptor() ([]byte, []int) {
+// Deprecated: Use SyncWordsRequest.ProtoReflect.Descriptor instead.
+func (*SyncWordsRequest) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *UserDict) GetUserId() string {
-	if x != nil {
-		return x.UserId
-	}
-	return ""
-}
-
-func (x *UserDict) GetWordId() string {
-	if x != nil {
-		return x.WordId
-	}
-	return ""
-}
-
-func (x *UserDict) GetQueryCount() int32 {
-	if x != nil {
-		return x.QueryCount
-	}
-	return 0
-}
-
-func (x *UserDict) GetAlreadyAcquainted() int32 {
+func (x *SyncWordsRequest) GetSinceTimestamp() int64 {
 	if x != nil {
-		return x.AlreadyAcquainted
+		return x.SinceTimestamp
 	}
 	return 0
 }
 
-func (x *UserDict) GetCreatedAt() int64 {
+func (x *SyncWordsRequest) GetSinceId() string {
 	if x != nil {
-		return x.CreatedAt
+		return x.SinceId
 	}
-	return 0
+	return ""
 }
 
-func (x *UserDict) GetUpdatedAt() int64 {
+func (x *SyncWordsRequest) GetBatchSize() int32 {
 	if x != nil {
-		return x.UpdatedAt
+		return x.BatchSize
 	}
 	return 0
 }
 
-type GetUserDictRequest struct {
+type SyncWordsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	WordId        string                 `protobuf:"bytes,2,opt,name=word_id,json=wordId,proto3" json:"word_id,omitempty"`
+	Word          *Word                  `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`   // Single row (legacy, batch_size = 0)
+	Words         []*Word                `protobuf:"bytes,2,rep,name=words,proto3" json:"words,omitempty"` // Batched rows, ordered by (updated_at, id)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetUserDictRequest) Reset() {
-	*x = GetUserDictRequest{}
+func (x *SyncWordsResponse) Reset() {
+	*x = SyncWordsResponse{}
 	mi := &file_data_service_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetUserDictRequest) String() string {
+func (x *SyncWordsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetUserDictRequest) ProtoMessage() {}
+func (*SyncWordsResponse) ProtoMessage() {}
 
-func (x *GetUserDictRequest) ProtoReflect() protoreflect.Message {
+func (x *SyncWordsResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -888,46 +933,51 @@ func (x *GetUserDictRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetUserDictRequest.ProtoReflect.Descriptor instead.
-func (*GetUserDictRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SyncWordsResponse.ProtoReflect.Descriptor instead.
+func (*SyncWordsResponse) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *GetUserDictRequest) GetUserId() string {
+func (x *SyncWordsResponse) GetWord() *Word {
 	if x != nil {
-		return x.UserId
+		return x.Word
 	}
-	return ""
+	return nil
 }
 
-func (x *GetUserDictRequest) GetWordId() string {
+func (x *SyncWordsResponse) GetWords() []*Word {
 	if x != nil {
-		return x.WordId
+		return x.Words
 	}
-	return ""
+	return nil
 }
 
-type GetUserDictResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`
+type SyncUserDictsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SinceTimestamp int64                  `protobuf:"varint,1,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Unix timestamp in milliseconds
+	// Resume cursor: when set, only rows after (since_timestamp, since_user_id, since_word_id) are sent
+	SinceUserId string `protobuf:"bytes,2,opt,name=since_user_id,json=sinceUserId,proto3" json:"since_user_id,omitempty"`
+	SinceWordId string `protobuf:"bytes,3,opt,name=since_word_id,json=sinceWordId,proto3" json:"since_word_id,omitempty"`
+	// When > 0, rows are sent in batches of up to batch_size in SyncUserDictsResponse.user_dicts
+	BatchSize     int32 `protobuf:"varint,4,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetUserDictResponse) Reset() {
-	*x = GetUserDictResponse{}
+func (x *SyncUserDictsRequest) Reset() {
+	*x = SyncUserDictsRequest{}
 	mi := &file_data_service_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetUserDictResponse) String() string {
+func (x *SyncUserDictsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetUserDictResponse) ProtoMessage() {}
+func (*SyncUserDictsRequest) ProtoMessage() {}
 
-func (x *GetUserDictResponse) ProtoReflect() protoreflect.Message {
+func (x *SyncUserDictsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -939,39 +989,61 @@ func (x *GetUserDictResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetUserDictResponse.ProtoReflect.Descriptor instead.
-func (*GetUserDictResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SyncUserDictsRequest.ProtoReflect.Descriptor instead.
+func (*SyncUserDictsRequest) Descriptor() ([]byte, []int) {
 	return file_data_service_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *GetUserDictResponse) GetUserDict() *UserDict {
+func (x *SyncUserDictsRequest) GetSinceTimestamp() int64 {
 	if x != nil {
-		return x.UserDict
+		return x.SinceTimestamp
 	}
-	return nil
+	return 0
 }
 
-type UpsertUserDictRequest struct {
+func (x *SyncUserDictsRequest) GetSinceUserId() string {
+	if x != nil {
+		return x.SinceUserId
+	}
+	return ""
+}
+
+func (x *SyncUserDictsRequest) GetSinceWordId() string {
+	if x != nil {
+		return x.SinceWordId
+	}
+	return ""
+}
+
+func (x *SyncUserDictsRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+type SyncUserDictsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`
+	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`    // Single row (legacy, batch_size = 0)
+	UserDicts     []*UserDict            `protobuf:"bytes,2,rep,name=user_dicts,json=userDicts,proto3" json:"user_dicts,omitempty"` // Batched rows, ordered by (updated_at, user_id, word_id)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpsertUserDictRequest) Reset() {
-	*x = UpsertUserDictRequest{}
+func (x *SyncUserDictsResponse) Reset() {
+	*x = SyncUserDictsResponse{}
 	mi := &file_data_service_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpsertUserDictRequest) String() string {
+func (x *SyncUserDictsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpsertUserDictRequest) ProtoMessage() {}
+func (*SyncUserDictsResponse) ProtoMessage() {}
 
-func (x *UpsertUserDictRequest) ProtoReflect() protoreflect.Message {
+func (x *SyncUserDictsResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_data_service_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -983,9 +1055,542 @@ func (x *UpsertUserDictRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpsertUserDictRequest.ProtoReflect.Descriptor instead.
-func (*UpsertUserDictRequest) Descriptor() ([]byte, []int) {
-	return file_data_service_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use SyncUserDictsResponse.ProtoReflect.Descriptor instead.
+func (*SyncUserDictsResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SyncUserDictsResponse) GetUserDict() *UserDict {
+	if x != nil {
+		return x.UserDict
+	}
+	return nil
+}
+
+func (x *SyncUserDictsResponse) GetUserDicts() []*UserDict {
+	if x != nil {
+		return x.UserDicts
+	}
+	return nil
+}
+
+type SyncLookupEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AfterSeq      int64                  `protobuf:"varint,1,opt,name=after_seq,json=afterSeq,proto3" json:"after_seq,omitempty"`    // Only events with seq > after_seq (the sender's own append order)
+	BatchSize     int32                  `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"` // Events per message (0 = server default)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncLookupEventsRequest) Reset() {
+	*x = SyncLookupEventsRequest{}
+	mi := &file_data_service_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncLookupEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncLookupEventsRequest) ProtoMessage() {}
+
+func (x *SyncLookupEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncLookupEventsRequest.ProtoReflect.Descriptor instead.
+func (*SyncLookupEventsRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SyncLookupEventsRequest) GetAfterSeq() int64 {
+	if x != nil {
+		return x.AfterSeq
+	}
+	return 0
+}
+
+func (x *SyncLookupEventsRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+type SyncLookupEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*LookupEvent         `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"` // Ordered by seq
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncLookupEventsResponse) Reset() {
+	*x = SyncLookupEventsResponse{}
+	mi := &file_data_service_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncLookupEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncLookupEventsResponse) ProtoMessage() {}
+
+func (x *SyncLookupEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncLookupEventsResponse.ProtoReflect.Descriptor instead.
+func (*SyncLookupEventsResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SyncLookupEventsResponse) GetEvents() []*LookupEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type GetSnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkSize     int32                  `protobuf:"varint,1,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"` // Bytes per message (0 = server default)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnapshotRequest) Reset() {
+	*x = GetSnapshotRequest{}
+	mi := &file_data_service_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnapshotRequest) ProtoMessage() {}
+
+func (x *GetSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*GetSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetSnapshotRequest) GetChunkSize() int32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+// SnapshotInfo describes the database copy and the change cursor it corresponds to
+type SnapshotInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Size            int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`                                                // Size of the database file in bytes
+	Sha256          string                 `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`                                             // Hex SHA-256 of the database file
+	WordsCursor     int64                  `protobuf:"varint,3,opt,name=words_cursor,json=wordsCursor,proto3" json:"words_cursor,omitempty"`               // Max words.updated_at contained in the copy
+	UserDictsCursor int64                  `protobuf:"varint,4,opt,name=user_dicts_cursor,json=userDictsCursor,proto3" json:"user_dicts_cursor,omitempty"` // Max user_dicts.updated_at contained in the copy
+	CreatedAt       int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                     // Unix timestamp in milliseconds
+	EventsCursor    int64                  `protobuf:"varint,6,opt,name=events_cursor,json=eventsCursor,proto3" json:"events_cursor,omitempty"`            // Max lookup_events.seq contained in the copy
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SnapshotInfo) Reset() {
+	*x = SnapshotInfo{}
+	mi := &file_data_service_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotInfo) ProtoMessage() {}
+
+func (x *SnapshotInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotInfo.ProtoReflect.Descriptor instead.
+func (*SnapshotInfo) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SnapshotInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *SnapshotInfo) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *SnapshotInfo) GetWordsCursor() int64 {
+	if x != nil {
+		return x.WordsCursor
+	}
+	return 0
+}
+
+func (x *SnapshotInfo) GetUserDictsCursor() int64 {
+	if x != nil {
+		return x.UserDictsCursor
+	}
+	return 0
+}
+
+func (x *SnapshotInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *SnapshotInfo) GetEventsCursor() int64 {
+	if x != nil {
+		return x.EventsCursor
+	}
+	return 0
+}
+
+type GetSnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Info          *SnapshotInfo          `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`   // Only set in the first message
+	Chunk         []byte                 `protobuf:"bytes,2,opt,name=chunk,proto3" json:"chunk,omitempty"` // Next part of the database file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnapshotResponse) Reset() {
+	*x = GetSnapshotResponse{}
+	mi := &file_data_service_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnapshotResponse) ProtoMessage() {}
+
+func (x *GetSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*GetSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetSnapshotResponse) GetInfo() *SnapshotInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *GetSnapshotResponse) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+// UserDict message for user-specific word data
+type UserDict struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	UserId            string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                                   // User UUID
+	WordId            string                 `protobuf:"bytes,2,opt,name=word_id,json=wordId,proto3" json:"word_id,omitempty"`                                   // Word UUID (foreign key to words.id)
+	QueryCount        int32                  `protobuf:"varint,3,opt,name=query_count,json=queryCount,proto3" json:"query_count,omitempty"`                      // Number of times user queried this word
+	AlreadyAcquainted int32                  `protobuf:"varint,4,opt,name=already_acquainted,json=alreadyAcquainted,proto3" json:"already_acquainted,omitempty"` // 0 = learning, 1 = already knows
+	CreatedAt         int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                         // Unix timestamp in milliseconds
+	UpdatedAt         int64                  `protobuf:"varint,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`                         // Unix timestamp in milliseconds
+	Note              string                 `protobuf:"bytes,7,opt,name=note,proto3" json:"note,omitempty"`                                                     // Personal note
+	Tags              string                 `protobuf:"bytes,8,opt,name=tags,proto3" json:"tags,omitempty"`                                                     // Comma-separated personal tags
+	Translation       string                 `protobuf:"bytes,9,opt,name=translation,proto3" json:"translation,omitempty"`                                       // Personal translation, preferred over Word.chinese
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *UserDict) Reset() {
+	*x = UserDict{}
+	mi := &file_data_service_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserDict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDict) ProtoMessage() {}
+
+func (x *UserDict) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDict.ProtoReflect.Descriptor instead.
+func (*UserDict) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UserDict) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserDict) GetWordId() string {
+	if x != nil {
+		return x.WordId
+	}
+	return ""
+}
+
+func (x *UserDict) GetQueryCount() int32 {
+	if x != nil {
+		return x.QueryCount
+	}
+	return 0
+}
+
+func (x *UserDict) GetAlreadyAcquainted() int32 {
+	if x != nil {
+		return x.AlreadyAcquainted
+	}
+	return 0
+}
+
+func (x *UserDict) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *UserDict) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *UserDict) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *UserDict) GetTags() string {
+	if x != nil {
+		return x.Tags
+	}
+	return ""
+}
+
+func (x *UserDict) GetTranslation() string {
+	if x != nil {
+		return x.Translation
+	}
+	return ""
+}
+
+type GetUserDictRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	WordId        string                 `protobuf:"bytes,2,opt,name=word_id,json=wordId,proto3" json:"word_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserDictRequest) Reset() {
+	*x = GetUserDictRequest{}
+	mi := &file_data_service_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserDictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserDictRequest) ProtoMessage() {}
+
+func (x *GetUserDictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserDictRequest.ProtoReflect.Descriptor instead.
+func (*GetUserDictRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetUserDictRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetUserDictRequest) GetWordId() string {
+	if x != nil {
+		return x.WordId
+	}
+	return ""
+}
+
+type GetUserDictResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserDictResponse) Reset() {
+	*x = GetUserDictResponse{}
+	mi := &file_data_service_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserDictResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserDictResponse) ProtoMessage() {}
+
+func (x *GetUserDictResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserDictResponse.ProtoReflect.Descriptor instead.
+func (*GetUserDictResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetUserDictResponse) GetUserDict() *UserDict {
+	if x != nil {
+		return x.UserDict
+	}
+	return nil
+}
+
+type UpsertUserDictRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserDict      *UserDict              `protobuf:"bytes,1,opt,name=user_dict,json=userDict,proto3" json:"user_dict,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpsertUserDictRequest) Reset() {
+	*x = UpsertUserDictRequest{}
+	mi := &file_data_service_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpsertUserDictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpsertUserDictRequest) ProtoMessage() {}
+
+func (x *UpsertUserDictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpsertUserDictRequest.ProtoReflect.Descriptor instead.
+func (*UpsertUserDictRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *UpsertUserDictRequest) GetUserDict() *UserDict {
@@ -1004,7 +1609,7 @@ type UpsertUserDictResponse struct {
 
 func (x *UpsertUserDictResponse) Reset() {
 	*x = UpsertUserDictResponse{}
-	mi := &file_data_service_proto_msgTypes[19]
+	mi := &file_data_service_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1016,7 +1621,7 @@ func (x *UpsertUserDictResponse) String() string {
 func (*UpsertUserDictResponse) ProtoMessage() {}
 
 func (x *UpsertUserDictResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_data_service_proto_msgTypes[19]
+	mi := &file_data_service_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1029,7 +1634,7 @@ func (x *UpsertUserDictResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpsertUserDictResponse.ProtoReflect.Descriptor instead.
 func (*UpsertUserDictResponse) Descriptor() ([]byte, []int) {
-	return file_data_service_proto_rawDescGZIP(), []int{19}
+	return file_data_service_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *UpsertUserDictResponse) GetUserDict() *UserDict {
@@ -1039,11 +1644,290 @@ func (x *UpsertUserDictResponse) GetUserDict() *UserDict {
 	return nil
 }
 
+// LookupEvent is one entry of the append-only per-user event log
+type LookupEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                 // UUID, the same on every node
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`           // User UUID
+	WordId        string                 `protobuf:"bytes,3,opt,name=word_id,json=wordId,proto3" json:"word_id,omitempty"`           // Word UUID (foreign key to words.id)
+	Kind          string                 `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"`                             // "lookup", "mark" or "unmark"
+	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix timestamp in milliseconds
+	Seq           int64                  `protobuf:"varint,6,opt,name=seq,proto3" json:"seq,omitempty"`                              // Append position on the sending node
+	Source        string                 `protobuf:"bytes,7,opt,name=source,proto3" json:"source,omitempty"`                         // "extension", "ui" or "api", empty when unknown
+	PageHost      string                 `protobuf:"bytes,8,opt,name=page_host,json=pageHost,proto3" json:"page_host,omitempty"`     // Host of the page the word was looked up on, empty when unknown
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupEvent) Reset() {
+	*x = LookupEvent{}
+	mi := &file_data_service_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupEvent) ProtoMessage() {}
+
+func (x *LookupEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupEvent.ProtoReflect.Descriptor instead.
+func (*LookupEvent) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *LookupEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LookupEvent) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LookupEvent) GetWordId() string {
+	if x != nil {
+		return x.WordId
+	}
+	return ""
+}
+
+func (x *LookupEvent) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *LookupEvent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *LookupEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *LookupEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *LookupEvent) GetPageHost() string {
+	if x != nil {
+		return x.PageHost
+	}
+	return ""
+}
+
+type AppendLookupEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         *LookupEvent           `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"` // id and created_at are assigned when empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendLookupEventRequest) Reset() {
+	*x = AppendLookupEventRequest{}
+	mi := &file_data_service_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendLookupEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendLookupEventRequest) ProtoMessage() {}
+
+func (x *AppendLookupEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendLookupEventRequest.ProtoReflect.Descriptor instead.
+func (*AppendLookupEventRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *AppendLookupEventRequest) GetEvent() *LookupEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+type AppendLookupEventResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         *LookupEvent           `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendLookupEventResponse) Reset() {
+	*x = AppendLookupEventResponse{}
+	mi := &file_data_service_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendLookupEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendLookupEventResponse) ProtoMessage() {}
+
+func (x *AppendLookupEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendLookupEventResponse.ProtoReflect.Descriptor instead.
+func (*AppendLookupEventResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *AppendLookupEventResponse) GetEvent() *LookupEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+// PruneLookupEventsRequest deletes this node's events older than a retention cutoff.
+// Pruning is local: peers keep their events and prune by their own settings.
+type PruneLookupEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Before        int64                  `protobuf:"varint,1,opt,name=before,proto3" json:"before,omitempty"` // Delete events with created_at < before (Unix milliseconds)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneLookupEventsRequest) Reset() {
+	*x = PruneLookupEventsRequest{}
+	mi := &file_data_service_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneLookupEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneLookupEventsRequest) ProtoMessage() {}
+
+func (x *PruneLookupEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneLookupEventsRequest.ProtoReflect.Descriptor instead.
+func (*PruneLookupEventsRequest) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *PruneLookupEventsRequest) GetBefore() int64 {
+	if x != nil {
+		return x.Before
+	}
+	return 0
+}
+
+type PruneLookupEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       int64                  `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"` // Number of events deleted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneLookupEventsResponse) Reset() {
+	*x = PruneLookupEventsResponse{}
+	mi := &file_data_service_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneLookupEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneLookupEventsResponse) ProtoMessage() {}
+
+func (x *PruneLookupEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_data_service_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneLookupEventsResponse.ProtoReflect.Descriptor instead.
+func (*PruneLookupEventsResponse) Descriptor() ([]byte, []int) {
+	return file_data_service_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *PruneLookupEventsResponse) GetDeleted() int64 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
 var File_data_service_proto protoreflect.FileDescriptor
 
 const file_data_service_proto_rawDesc = "" +
 	"\n" +
-	"\x12data_service.proto\x12\venx.data.v1\"\xec\x01\n" +
+	"\x12data_service.proto\x12\venx.data.v1\"\x8b\x03\n" +
 	"\x04Word\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
 	"\aenglish\x18\x02 \x01(\tR\aenglish\x12\x18\n" +
@@ -1056,15 +1940,26 @@ const file_data_service_proto_rawDesc = "" +
 	"\n" +
 	"updated_at\x18\a \x01(\x03R\tupdatedAt\x12\x1d\n" +
 	"\n" +
-	"deleted_at\x18\b \x01(\x03R\tdeletedAt\" \n" +
+	"deleted_at\x18\b \x01(\x03R\tdeletedAt\x128\n" +
+	"\aglosses\x18\t \x03(\v2\x1e.enx.data.v1.Word.GlossesEntryR\aglosses\x12'\n" +
+	"\x0fsource_language\x18\n" +
+	" \x01(\tR\x0esourceLanguage\x1a:\n" +
+	"\fGlossesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\" \n" +
 	"\x0eGetWordRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"8\n" +
 	"\x0fGetWordResponse\x12%\n" +
-	"\x04word\x18\x01 \x01(\v2\x11.enx.data.v1.WordR\x04word\"m\n" +
+	"\x04word\x18\x01 \x01(\v2\x11.enx.data.v1.WordR\x04word\"\x99\x02\n" +
 	"\x11CreateWordRequest\x12\x18\n" +
 	"\aenglish\x18\x01 \x01(\tR\aenglish\x12\x18\n" +
 	"\achinese\x18\x02 \x01(\tR\achinese\x12$\n" +
-	"\rpronunciation\x18\x03 \x01(\tR\rpronunciation\";\n" +
+	"\rpronunciation\x18\x03 \x01(\tR\rpronunciation\x12E\n" +
+	"\aglosses\x18\x04 \x03(\v2+.enx.data.v1.CreateWordRequest.GlossesEntryR\aglosses\x12'\n" +
+	"\x0fsource_language\x18\x05 \x01(\tR\x0esourceLanguage\x1a:\n" +
+	"\fGlossesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\";\n" +
 	"\x12CreateWordResponse\x12%\n" +
 	"\x04word\x18\x01 \x01(\v2\x11.enx.data.v1.WordR\x04word\":\n" +
 	"\x11UpdateWordRequest\x12%\n" +
@@ -1080,15 +1975,61 @@ const file_data_service_proto_rawDesc = "" +
 	"\x06offset\x18\x02 \x01(\x05R\x06offset\"R\n" +
 	"\x11ListWordsResponse\x12'\n" +
 	"\x05words\x18\x01 \x03(\v2\x11.enx.data.v1.WordR\x05words\x12\x14\n" +
-	"\x05total\x18\x02 \x01(\x05R\x05total\";\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"\x83\x01\n" +
+	"\x11MergeWordsRequest\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x1b\n" +
+	"\ttarget_id\x18\x02 \x01(\tR\btargetId\x124\n" +
+	"\n" +
+	"user_dicts\x18\x03 \x03(\v2\x15.enx.data.v1.UserDictR\tuserDicts\"N\n" +
+	"\x12MergeWordsResponse\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x1b\n" +
+	"\ttarget_id\x18\x02 \x01(\tR\btargetId\"\x9c\x01\n" +
+	"\x18SetFrequencyRanksRequest\x12F\n" +
+	"\x05ranks\x18\x01 \x03(\v20.enx.data.v1.SetFrequencyRanksRequest.RanksEntryR\x05ranks\x1a8\n" +
+	"\n" +
+	"RanksEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"5\n" +
+	"\x19SetFrequencyRanksResponse\x12\x18\n" +
+	"\achanged\x18\x01 \x01(\x03R\achanged\"u\n" +
 	"\x10SyncWordsRequest\x12'\n" +
-	"\x0fsince_timestamp\x18\x01 \x01(\x03R\x0esinceTimestamp\":\n" +
+	"\x0fsince_timestamp\x18\x01 \x01(\x03R\x0esinceTimestamp\x12\x19\n" +
+	"\bsince_id\x18\x02 \x01(\tR\asinceId\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x03 \x01(\x05R\tbatchSize\"c\n" +
 	"\x11SyncWordsResponse\x12%\n" +
-	"\x04word\x18\x01 \x01(\v2\x11.enx.data.v1.WordR\x04word\"?\n" +
+	"\x04word\x18\x01 \x01(\v2\x11.enx.data.v1.WordR\x04word\x12'\n" +
+	"\x05words\x18\x02 \x03(\v2\x11.enx.data.v1.WordR\x05words\"\xa6\x01\n" +
 	"\x14SyncUserDictsRequest\x12'\n" +
-	"\x0fsince_timestamp\x18\x01 \x01(\x03R\x0esinceTimestamp\"K\n" +
+	"\x0fsince_timestamp\x18\x01 \x01(\x03R\x0esinceTimestamp\x12\"\n" +
+	"\rsince_user_id\x18\x02 \x01(\tR\vsinceUserId\x12\"\n" +
+	"\rsince_word_id\x18\x03 \x01(\tR\vsinceWordId\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x04 \x01(\x05R\tbatchSize\"\x81\x01\n" +
 	"\x15SyncUserDictsResponse\x122\n" +
-	"\tuser_dict\x18\x01 \x01(\v2\x15.enx.data.v1.UserDictR\buserDict\"\xca\x01\n" +
+	"\tuser_dict\x18\x01 \x01(\v2\x15.enx.data.v1.UserDictR\buserDict\x124\n" +
+	"\n" +
+	"user_dicts\x18\x02 \x03(\v2\x15.enx.data.v1.UserDictR\tuserDicts\"U\n" +
+	"\x17SyncLookupEventsRequest\x12\x1b\n" +
+	"\tafter_seq\x18\x01 \x01(\x03R\bafterSeq\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x02 \x01(\x05R\tbatchSize\"L\n" +
+	"\x18SyncLookupEventsResponse\x120\n" +
+	"\x06events\x18\x01 \x03(\v2\x18.enx.data.v1.LookupEventR\x06events\"3\n" +
+	"\x12GetSnapshotRequest\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x01 \x01(\x05R\tchunkSize\"\xcd\x01\n" +
+	"\fSnapshotInfo\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\tR\x06sha256\x12!\n" +
+	"\fwords_cursor\x18\x03 \x01(\x03R\vwordsCursor\x12*\n" +
+	"\x11user_dicts_cursor\x18\x04 \x01(\x03R\x0fuserDictsCursor\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12#\n" +
+	"\revents_cursor\x18\x06 \x01(\x03R\feventsCursor\"Z\n" +
+	"\x13GetSnapshotResponse\x12-\n" +
+	"\x04info\x18\x01 \x01(\v2\x19.enx.data.v1.SnapshotInfoR\x04info\x12\x14\n" +
+	"\x05chunk\x18\x02 \x01(\fR\x05chunk\"\x94\x02\n" +
 	"\bUserDict\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
 	"\aword_id\x18\x02 \x01(\tR\x06wordId\x12\x1f\n" +
@@ -1098,7 +2039,10 @@ const file_data_service_proto_rawDesc = "" +
 	"\n" +
 	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12\x1d\n" +
 	"\n" +
-	"updated_at\x18\x06 \x01(\x03R\tupdatedAt\"F\n" +
+	"updated_at\x18\x06 \x01(\x03R\tupdatedAt\x12\x12\n" +
+	"\x04note\x18\a \x01(\tR\x04note\x12\x12\n" +
+	"\x04tags\x18\b \x01(\tR\x04tags\x12 \n" +
+	"\vtranslation\x18\t \x01(\tR\vtranslation\"F\n" +
 	"\x12GetUserDictRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
 	"\aword_id\x18\x02 \x01(\tR\x06wordId\"I\n" +
@@ -1107,7 +2051,26 @@ const file_data_service_proto_rawDesc = "" +
 	"\x15UpsertUserDictRequest\x122\n" +
 	"\tuser_dict\x18\x01 \x01(\v2\x15.enx.data.v1.UserDictR\buserDict\"L\n" +
 	"\x16UpsertUserDictResponse\x122\n" +
-	"\tuser_dict\x18\x01 \x01(\v2\x15.enx.data.v1.UserDictR\buserDict2\xe1\x05\n" +
+	"\tuser_dict\x18\x01 \x01(\v2\x15.enx.data.v1.UserDictR\buserDict\"\xc9\x01\n" +
+	"\vLookupEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x17\n" +
+	"\aword_id\x18\x03 \x01(\tR\x06wordId\x12\x12\n" +
+	"\x04kind\x18\x04 \x01(\tR\x04kind\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12\x10\n" +
+	"\x03seq\x18\x06 \x01(\x03R\x03seq\x12\x16\n" +
+	"\x06source\x18\a \x01(\tR\x06source\x12\x1b\n" +
+	"\tpage_host\x18\b \x01(\tR\bpageHost\"J\n" +
+	"\x18AppendLookupEventRequest\x12.\n" +
+	"\x05event\x18\x01 \x01(\v2\x18.enx.data.v1.LookupEventR\x05event\"K\n" +
+	"\x19AppendLookupEventResponse\x12.\n" +
+	"\x05event\x18\x01 \x01(\v2\x18.enx.data.v1.LookupEventR\x05event\"2\n" +
+	"\x18PruneLookupEventsRequest\x12\x16\n" +
+	"\x06before\x18\x01 \x01(\x03R\x06before\"5\n" +
+	"\x19PruneLookupEventsResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\x03R\adeleted2\x93\n" +
+	"\n" +
 	"\vDataService\x12D\n" +
 	"\aGetWord\x12\x1b.enx.data.v1.GetWordRequest\x1a\x1c.enx.data.v1.GetWordResponse\x12M\n" +
 	"\n" +
@@ -1116,11 +2079,18 @@ const file_data_service_proto_rawDesc = "" +
 	"UpdateWord\x12\x1e.enx.data.v1.UpdateWordRequest\x1a\x1f.enx.data.v1.UpdateWordResponse\x12M\n" +
 	"\n" +
 	"DeleteWord\x12\x1e.enx.data.v1.DeleteWordRequest\x1a\x1f.enx.data.v1.DeleteWordResponse\x12J\n" +
-	"\tListWords\x12\x1d.enx.data.v1.ListWordsRequest\x1a\x1e.enx.data.v1.ListWordsResponse\x12P\n" +
+	"\tListWords\x12\x1d.enx.data.v1.ListWordsRequest\x1a\x1e.enx.data.v1.ListWordsResponse\x12M\n" +
+	"\n" +
+	"MergeWords\x12\x1e.enx.data.v1.MergeWordsRequest\x1a\x1f.enx.data.v1.MergeWordsResponse\x12b\n" +
+	"\x11SetFrequencyRanks\x12%.enx.data.v1.SetFrequencyRanksRequest\x1a&.enx.data.v1.SetFrequencyRanksResponse\x12P\n" +
 	"\vGetUserDict\x12\x1f.enx.data.v1.GetUserDictRequest\x1a .enx.data.v1.GetUserDictResponse\x12Y\n" +
-	"\x0eUpsertUserDict\x12\".enx.data.v1.UpsertUserDictRequest\x1a#.enx.data.v1.UpsertUserDictResponse\x12L\n" +
+	"\x0eUpsertUserDict\x12\".enx.data.v1.UpsertUserDictRequest\x1a#.enx.data.v1.UpsertUserDictResponse\x12b\n" +
+	"\x11AppendLookupEvent\x12%.enx.data.v1.AppendLookupEventRequest\x1a&.enx.data.v1.AppendLookupEventResponse\x12b\n" +
+	"\x11PruneLookupEvents\x12%.enx.data.v1.PruneLookupEventsRequest\x1a&.enx.data.v1.PruneLookupEventsResponse\x12L\n" +
 	"\tSyncWords\x12\x1d.enx.data.v1.SyncWordsRequest\x1a\x1e.enx.data.v1.SyncWordsResponse0\x01\x12X\n" +
-	"\rSyncUserDicts\x12!.enx.data.v1.SyncUserDictsRequest\x1a\".enx.data.v1.SyncUserDictsResponse0\x01B\vZ\tenx/protob\x06proto3"
+	"\rSyncUserDicts\x12!.enx.data.v1.SyncUserDictsRequest\x1a\".enx.data.v1.SyncUserDictsResponse0\x01\x12a\n" +
+	"\x10SyncLookupEvents\x12$.enx.data.v1.SyncLookupEventsRequest\x1a%.enx.data.v1.SyncLookupEventsResponse0\x01\x12R\n" +
+	"\vGetSnapshot\x12\x1f.enx.data.v1.GetSnapshotRequest\x1a .enx.data.v1.GetSnapshotResponse0\x01B\vZ\tenx/protob\x06proto3"
 
 var (
 	file_data_service_proto_rawDescOnce sync.Once
@@ -1134,63 +2104,102 @@ func file_data_service_proto_rawDescGZIP() []byte {
 	return file_data_service_proto_rawDescData
 }
 
-var file_data_service_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_data_service_proto_msgTypes = make([]protoimpl.MessageInfo, 37)
 var file_data_service_proto_goTypes = []any{
-	(*Word)(nil),                   // 0: enx.data.v1.Word
-	(*GetWordRequest)(nil),         // 1: enx.data.v1.GetWordRequest
-	(*GetWordResponse)(nil),        // 2: enx.data.v1.GetWordResponse
-	(*CreateWordRequest)(nil),      // 3: enx.data.v1.CreateWordRequest
-	(*CreateWordResponse)(nil),     // 4: enx.data.v1.CreateWordResponse
-	(*UpdateWordRequest)(nil),      // 5: enx.data.v1.UpdateWordRequest
-	(*UpdateWordResponse)(nil),     // 6: enx.data.v1.UpdateWordResponse
-	(*DeleteWordRequest)(nil),      // 7: enx.data.v1.DeleteWordRequest
-	(*DeleteWordResponse)(nil),     // 8: enx.data.v1.DeleteWordResponse
-	(*ListWordsRequest)(nil),       // 9: enx.data.v1.ListWordsRequest
-	(*ListWordsResponse)(nil),      // 10: enx.data.v1.ListWordsResponse
-	(*SyncWordsRequest)(nil),       // 11: enx.data.v1.SyncWordsRequest
-	(*SyncWordsResponse)(nil),      // 12: enx.data.v1.SyncWordsResponse
-	(*SyncUserDictsRequest)(nil),   // 13: enx.data.v1.SyncUserDictsRequest
-	(*SyncUserDictsResponse)(nil),  // 14: enx.data.v1.SyncUserDictsResponse
-	(*UserDict)(nil),               // 15: enx.data.v1.UserDict
-	(*GetUserDictRequest)(nil),     // 16: enx.data.v1.GetUserDictRequest
-	(*GetUserDictResponse)(nil),    // 17: enx.data.v1.GetUserDictResponse
-	(*UpsertUserDictRequest)(nil),  // 18: enx.data.v1.UpsertUserDictRequest
-	(*UpsertUserDictResponse)(nil), // 19: enx.data.v1.UpsertUserDictResponse
+	(*Word)(nil),                      // 0: enx.data.v1.Word
+	(*GetWordRequest)(nil),            // 1: enx.data.v1.GetWordRequest
+	(*GetWordResponse)(nil),           // 2: enx.data.v1.GetWordResponse
+	(*CreateWordRequest)(nil),         // 3: enx.data.v1.CreateWordRequest
+	(*CreateWordResponse)(nil),        // 4: enx.data.v1.CreateWordResponse
+	(*UpdateWordRequest)(nil),         // 5: enx.data.v1.UpdateWordRequest
+	(*UpdateWordResponse)(nil),        // 6: enx.data.v1.UpdateWordResponse
+	(*DeleteWordRequest)(nil),         // 7: enx.data.v1.DeleteWordRequest
+	(*DeleteWordResponse)(nil),        // 8: enx.data.v1.DeleteWordResponse
+	(*ListWordsRequest)(nil),          // 9: enx.data.v1.ListWordsRequest
+	(*ListWordsResponse)(nil),         // 10: enx.data.v1.ListWordsResponse
+	(*MergeWordsRequest)(nil),         // 11: enx.data.v1.MergeWordsRequest
+	(*MergeWordsResponse)(nil),        // 12: enx.data.v1.MergeWordsResponse
+	(*SetFrequencyRanksRequest)(nil),  // 13: enx.data.v1.SetFrequencyRanksRequest
+	(*SetFrequencyRanksResponse)(nil), // 14: enx.data.v1.SetFrequencyRanksResponse
+	(*SyncWordsRequest)(nil),          // 15: enx.data.v1.SyncWordsRequest
+	(*SyncWordsResponse)(nil),         // 16: enx.data.v1.SyncWordsResponse
+	(*SyncUserDictsRequest)(nil),      // 17: enx.data.v1.SyncUserDictsRequest
+	(*SyncUserDictsResponse)(nil),     // 18: enx.data.v1.SyncUserDictsResponse
+	(*SyncLookupEventsRequest)(nil),   // 19: enx.data.v1.SyncLookupEventsRequest
+	(*SyncLookupEventsResponse)(nil),  // 20: enx.data.v1.SyncLookupEventsResponse
+	(*GetSnapshotRequest)(nil),        // 21: enx.data.v1.GetSnapshotRequest
+	(*SnapshotInfo)(nil),              // 22: enx.data.v1.SnapshotInfo
+	(*GetSnapshotResponse)(nil),       // 23: enx.data.v1.GetSnapshotResponse
+	(*UserDict)(nil),                  // 24: enx.data.v1.UserDict
+	(*GetUserDictRequest)(nil),        // 25: enx.data.v1.GetUserDictRequest
+	(*GetUserDictResponse)(nil),       // 26: enx.data.v1.GetUserDictResponse
+	(*UpsertUserDictRequest)(nil),     // 27: enx.data.v1.UpsertUserDictRequest
+	(*UpsertUserDictResponse)(nil),    // 28: enx.data.v1.UpsertUserDictResponse
+	(*LookupEvent)(nil),               // 29: enx.data.v1.LookupEvent
+	(*AppendLookupEventRequest)(nil),  // 30: enx.data.v1.AppendLookupEventRequest
+	(*AppendLookupEventResponse)(nil), // 31: enx.data.v1.AppendLookupEventResponse
+	(*PruneLookupEventsRequest)(nil),  // 32: enx.data.v1.PruneLookupEventsRequest
+	(*PruneLookupEventsResponse)(nil), // 33: enx.data.v1.PruneLookupEventsResponse
+	nil,                               // 34: enx.data.v1.Word.GlossesEntry
+	nil,                               // 35: enx.data.v1.CreateWordRequest.GlossesEntry
+	nil,                               // 36: enx.data.v1.SetFrequencyRanksRequest.RanksEntry
 }
 var file_data_service_proto_depIdxs = []int32{
-	0,  // 0: enx.data.v1.GetWordResponse.word:type_name -> enx.data.v1.Word
-	0,  // 1: enx.data.v1.CreateWordResponse.word:type_name -> enx.data.v1.Word
-	0,  // 2: enx.data.v1.UpdateWordRequest.word:type_name -> enx.data.v1.Word
-	0,  // 3: enx.data.v1.UpdateWordResponse.word:type_name -> enx.data.v1.Word
-	0,  // 4: enx.data.v1.ListWordsResponse.words:type_name -> enx.data.v1.Word
-	0,  // 5: enx.data.v1.SyncWordsResponse.word:type_name -> enx.data.v1.Word
-	15, // 6: enx.data.v1.SyncUserDictsResponse.user_dict:type_name -> enx.data.v1.UserDict
-	15, // 7: enx.data.v1.GetUserDictResponse.user_dict:type_name -> enx.data.v1.UserDict
-	15, // 8: enx.data.v1.UpsertUserDictRequest.user_dict:type_name -> enx.data.v1.UserDict
-	15, // 9: enx.data.v1.UpsertUserDictResponse.user_dict:type_name -> enx.data.v1.UserDict
-	1,  // 10: enx.data.v1.DataService.GetWord:input_type -> enx.data.v1.GetWordRequest
-	3,  // 11: enx.data.v1.DataService.CreateWord:input_type -> enx.data.v1.CreateWordRequest
-	5,  // 12: enx.data.v1.DataService.UpdateWord:input_type -> enx.data.v1.UpdateWordRequest
-	7,  // 13: enx.data.v1.DataService.DeleteWord:input_type -> enx.data.v1.DeleteWordRequest
-	9,  // 14: enx.data.v1.DataService.ListWords:input_type -> enx.data.v1.ListWordsRequest
-	16, // 15: enx.data.v1.DataService.GetUserDict:input_type -> enx.data.v1.GetUserDictRequest
-	18, // 16: enx.data.v1.DataService.UpsertUserDict:input_type -> enx.data.v1.UpsertUserDictRequest
-	11, // 17: enx.data.v1.DataService.SyncWords:input_type -> enx.data.v1.SyncWordsRequest
-	13, // 18: enx.data.v1.DataService.SyncUserDicts:input_type -> enx.data.v1.SyncUserDictsRequest
-	2,  // 19: enx.data.v1.DataService.GetWord:output_type -> enx.data.v1.GetWordResponse
-	4,  // 20: enx.data.v1.DataService.CreateWord:output_type -> enx.data.v1.CreateWordResponse
-	6,  // 21: enx.data.v1.DataService.UpdateWord:output_type -> enx.data.v1.UpdateWordResponse
-	8,  // 22: enx.data.v1.DataService.DeleteWord:output_type -> enx.data.v1.DeleteWordResponse
-	10, // 23: enx.data.v1.DataService.ListWords:output_type -> enx.data.v1.ListWordsResponse
-	17, // 24: enx.data.v1.DataService.GetUserDict:output_type -> enx.data.v1.GetUserDictResponse
-	19, // 25: enx.data.v1.DataService.UpsertUserDict:output_type -> enx.data.v1.UpsertUserDictResponse
-	12, // 26: enx.data.v1.DataService.SyncWords:output_type -> enx.data.v1.SyncWordsResponse
-	14, // 27: enx.data.v1.DataService.SyncUserDicts:output_type -> enx.data.v1.SyncUserDictsResponse
-	19, // [19:28] is the sub-list for method output_type
-	10, // [10:19] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	34, // 0: enx.data.v1.Word.glosses:type_name -> enx.data.v1.Word.GlossesEntry
+	0,  // 1: enx.data.v1.GetWordResponse.word:type_name -> enx.data.v1.Word
+	35, // 2: enx.data.v1.CreateWordRequest.glosses:type_name -> enx.data.v1.CreateWordRequest.GlossesEntry
+	0,  // 3: enx.data.v1.CreateWordResponse.word:type_name -> enx.data.v1.Word
+	0,  // 4: enx.data.v1.UpdateWordRequest.word:type_name -> enx.data.v1.Word
+	0,  // 5: enx.data.v1.UpdateWordResponse.word:type_name -> enx.data.v1.Word
+	0,  // 6: enx.data.v1.ListWordsResponse.words:type_name -> enx.data.v1.Word
+	24, // 7: enx.data.v1.MergeWordsRequest.user_dicts:type_name -> enx.data.v1.UserDict
+	36, // 8: enx.data.v1.SetFrequencyRanksRequest.ranks:type_name -> enx.data.v1.SetFrequencyRanksRequest.RanksEntry
+	0,  // 9: enx.data.v1.SyncWordsResponse.word:type_name -> enx.data.v1.Word
+	0,  // 10: enx.data.v1.SyncWordsResponse.words:type_name -> enx.data.v1.Word
+	24, // 11: enx.data.v1.SyncUserDictsResponse.user_dict:type_name -> enx.data.v1.UserDict
+	24, // 12: enx.data.v1.SyncUserDictsResponse.user_dicts:type_name -> enx.data.v1.UserDict
+	29, // 13: enx.data.v1.SyncLookupEventsResponse.events:type_name -> enx.data.v1.LookupEvent
+	22, // 14: enx.data.v1.GetSnapshotResponse.info:type_name -> enx.data.v1.SnapshotInfo
+	24, // 15: enx.data.v1.GetUserDictResponse.user_dict:type_name -> enx.data.v1.UserDict
+	24, // 16: enx.data.v1.UpsertUserDictRequest.user_dict:type_name -> enx.data.v1.UserDict
+	24, // 17: enx.data.v1.UpsertUserDictResponse.user_dict:type_name -> enx.data.v1.UserDict
+	29, // 18: enx.data.v1.AppendLookupEventRequest.event:type_name -> enx.data.v1.LookupEvent
+	29, // 19: enx.data.v1.AppendLookupEventResponse.event:type_name -> enx.data.v1.LookupEvent
+	1,  // 20: enx.data.v1.DataService.GetWord:input_type -> enx.data.v1.GetWordRequest
+	3,  // 21: enx.data.v1.DataService.CreateWord:input_type -> enx.data.v1.CreateWordRequest
+	5,  // 22: enx.data.v1.DataService.UpdateWord:input_type -> enx.data.v1.UpdateWordRequest
+	7,  // 23: enx.data.v1.DataService.DeleteWord:input_type -> enx.data.v1.DeleteWordRequest
+	9,  // 24: enx.data.v1.DataService.ListWords:input_type -> enx.data.v1.ListWordsRequest
+	11, // 25: enx.data.v1.DataService.MergeWords:input_type -> enx.data.v1.MergeWordsRequest
+	13, // 26: enx.data.v1.DataService.SetFrequencyRanks:input_type -> enx.data.v1.SetFrequencyRanksRequest
+	25, // 27: enx.data.v1.DataService.GetUserDict:input_type -> enx.data.v1.GetUserDictRequest
+	27, // 28: enx.data.v1.DataService.UpsertUserDict:input_type -> enx.data.v1.UpsertUserDictRequest
+	30, // 29: enx.data.v1.DataService.AppendLookupEvent:input_type -> enx.data.v1.AppendLookupEventRequest
+	32, // 30: enx.data.v1.DataService.PruneLookupEvents:input_type -> enx.data.v1.PruneLookupEventsRequest
+	15, // 31: enx.data.v1.DataService.SyncWords:input_type -> enx.data.v1.SyncWordsRequest
+	17, // 32: enx.data.v1.DataService.SyncUserDicts:input_type -> enx.data.v1.SyncUserDictsRequest
+	19, // 33: enx.data.v1.DataService.SyncLookupEvents:input_type -> enx.data.v1.SyncLookupEventsRequest
+	21, // 34: enx.data.v1.DataService.GetSnapshot:input_type -> enx.data.v1.GetSnapshotRequest
+	2,  // 35: enx.data.v1.DataService.GetWord:output_type -> enx.data.v1.GetWordResponse
+	4,  // 36: enx.data.v1.DataService.CreateWord:output_type -> enx.data.v1.CreateWordResponse
+	6,  // 37: enx.data.v1.DataService.UpdateWord:output_type -> enx.data.v1.UpdateWordResponse
+	8,  // 38: enx.data.v1.DataService.DeleteWord:output_type -> enx.data.v1.DeleteWordResponse
+	10, // 39: enx.data.v1.DataService.ListWords:output_type -> enx.data.v1.ListWordsResponse
+	12, // 40: enx.data.v1.DataService.MergeWords:output_type -> enx.data.v1.MergeWordsResponse
+	14, // 41: enx.data.v1.DataService.SetFrequencyRanks:output_type -> enx.data.v1.SetFrequencyRanksResponse
+	26, // 42: enx.data.v1.DataService.GetUserDict:output_type -> enx.data.v1.GetUserDictResponse
+	28, // 43: enx.data.v1.DataService.UpsertUserDict:output_type -> enx.data.v1.UpsertUserDictResponse
+	31, // 44: enx.data.v1.DataService.AppendLookupEvent:output_type -> enx.data.v1.AppendLookupEventResponse
+	33, // 45: enx.data.v1.DataService.PruneLookupEvents:output_type -> enx.data.v1.PruneLookupEventsResponse
+	16, // 46: enx.data.v1.DataService.SyncWords:output_type -> enx.data.v1.SyncWordsResponse
+	18, // 47: enx.data.v1.DataService.SyncUserDicts:output_type -> enx.data.v1.SyncUserDictsResponse
+	20, // 48: enx.data.v1.DataService.SyncLookupEvents:output_type -> enx.data.v1.SyncLookupEventsResponse
+	23, // 49: enx.data.v1.DataService.GetSnapshot:output_type -> enx.data.v1.GetSnapshotResponse
+	35, // [35:50] is the sub-list for method output_type
+	20, // [20:35] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_data_service_proto_init() }
@@ -1204,7 +2213,7 @@ func file_data_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_data_service_proto_rawDesc), len(file_data_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   20,
+			NumMessages:   37,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
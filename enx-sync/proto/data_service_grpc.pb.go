@@ -19,15 +19,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DataService_GetWord_FullMethodName        = "/enx.data.v1.DataService/GetWord"
-	DataService_CreateWord_FullMethodName     = "/enx.data.v1.DataService/CreateWord"
-	DataService_UpdateWord_FullMethodName     = "/enx.data.v1.DataService/UpdateWord"
-	DataService_DeleteWord_FullMethodName     = "/enx.data.v1.DataService/DeleteWord"
-	DataService_ListWords_FullMethodName      = "/enx.data.v1.DataService/ListWords"
-	DataService_GetUserDict_FullMethodName    = "/enx.data.v1.DataService/GetUserDict"
-	DataService_UpsertUserDict_FullMethodName = "/enx.data.v1.DataService/UpsertUserDict"
-	DataService_SyncWords_FullMethodName      = "/enx.data.v1.DataService/SyncWords"
-	DataService_SyncUserDicts_FullMethodName  = "/enx.data.v1.DataService/SyncUserDicts"
+	DataService_GetWord_FullMethodName           = "/enx.data.v1.DataService/GetWord"
+	DataService_CreateWord_FullMethodName        = "/enx.data.v1.DataService/CreateWord"
+	DataService_UpdateWord_FullMethodName        = "/enx.data.v1.DataService/UpdateWord"
+	DataService_DeleteWord_FullMethodName        = "/enx.data.v1.DataService/DeleteWord"
+	DataService_ListWords_FullMethodName         = "/enx.data.v1.DataService/ListWords"
+	DataService_MergeWords_FullMethodName        = "/enx.data.v1.DataService/MergeWords"
+	DataService_SetFrequencyRanks_FullMethodName = "/enx.data.v1.DataService/SetFrequencyRanks"
+	DataService_GetUserDict_FullMethodName       = "/enx.data.v1.DataService/GetUserDict"
+	DataService_UpsertUserDict_FullMethodName    = "/enx.data.v1.DataService/UpsertUserDict"
+	DataService_AppendLookupEvent_FullMethodName = "/enx.data.v1.DataService/AppendLookupEvent"
+	DataService_PruneLookupEvents_FullMethodName = "/enx.data.v1.DataService/PruneLookupEvents"
+	DataService_SyncWords_FullMethodName         = "/enx.data.v1.DataService/SyncWords"
+	DataService_SyncUserDicts_FullMethodName     = "/enx.data.v1.DataService/SyncUserDicts"
+	DataService_SyncLookupEvents_FullMethodName  = "/enx.data.v1.DataService/SyncLookupEvents"
+	DataService_GetSnapshot_FullMethodName       = "/enx.data.v1.DataService/GetSnapshot"
 )
 
 // DataServiceClient is the client API for DataService service.
@@ -40,12 +46,20 @@ type DataServiceClient interface {
 	UpdateWord(ctx context.Context, in *UpdateWordRequest, opts ...grpc.CallOption) (*UpdateWordResponse, error)
 	DeleteWord(ctx context.Context, in *DeleteWordRequest, opts ...grpc.CallOption) (*DeleteWordResponse, error)
 	ListWords(ctx context.Context, in *ListWordsRequest, opts ...grpc.CallOption) (*ListWordsResponse, error)
+	MergeWords(ctx context.Context, in *MergeWordsRequest, opts ...grpc.CallOption) (*MergeWordsResponse, error)
+	SetFrequencyRanks(ctx context.Context, in *SetFrequencyRanksRequest, opts ...grpc.CallOption) (*SetFrequencyRanksResponse, error)
 	// User dictionary operations
 	GetUserDict(ctx context.Context, in *GetUserDictRequest, opts ...grpc.CallOption) (*GetUserDictResponse, error)
 	UpsertUserDict(ctx context.Context, in *UpsertUserDictRequest, opts ...grpc.CallOption) (*UpsertUserDictResponse, error)
+	// Lookup event log (append-only)
+	AppendLookupEvent(ctx context.Context, in *AppendLookupEventRequest, opts ...grpc.CallOption) (*AppendLookupEventResponse, error)
+	PruneLookupEvents(ctx context.Context, in *PruneLookupEventsRequest, opts ...grpc.CallOption) (*PruneLookupEventsResponse, error)
 	// Sync operations
 	SyncWords(ctx context.Context, in *SyncWordsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SyncWordsResponse], error)
 	SyncUserDicts(ctx context.Context, in *SyncUserDictsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SyncUserDictsResponse], error)
+	SyncLookupEvents(ctx context.Context, in *SyncLookupEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SyncLookupEventsResponse], error)
+	// Bootstrap: stream a consistent copy of the whole database to a new node
+	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetSnapshotResponse], error)
 }
 
 type dataServiceClient struct {
@@ -106,6 +120,26 @@ func (c *dataServiceClient) ListWords(ctx context.Context, in *ListWordsRequest,
 	return out, nil
 }
 
+func (c *dataServiceClient) MergeWords(ctx context.Context, in *MergeWordsRequest, opts ...grpc.CallOption) (*MergeWordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeWordsResponse)
+	err := c.cc.Invoke(ctx, DataService_MergeWords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) SetFrequencyRanks(ctx context.Context, in *SetFrequencyRanksRequest, opts ...grpc.CallOption) (*SetFrequencyRanksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetFrequencyRanksResponse)
+	err := c.cc.Invoke(ctx, DataService_SetFrequencyRanks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dataServiceClient) GetUserDict(ctx context.Context, in *GetUserDictRequest, opts ...grpc.CallOption) (*GetUserDictResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetUserDictResponse)
@@ -126,6 +160,26 @@ func (c *dataServiceClient) UpsertUserDict(ctx context.Context, in *UpsertUserDi
 	return out, nil
 }
 
+func (c *dataServiceClient) AppendLookupEvent(ctx context.Context, in *AppendLookupEventRequest, opts ...grpc.CallOption) (*AppendLookupEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AppendLookupEventResponse)
+	err := c.cc.Invoke(ctx, DataService_AppendLookupEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) PruneLookupEvents(ctx context.Context, in *PruneLookupEventsRequest, opts ...grpc.CallOption) (*PruneLookupEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PruneLookupEventsResponse)
+	err := c.cc.Invoke(ctx, DataService_PruneLookupEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dataServiceClient) SyncWords(ctx context.Context, in *SyncWordsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SyncWordsResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &DataService_ServiceDesc.Streams[0], DataService_SyncWords_FullMethodName, cOpts...)
@@ -164,6 +218,44 @@ func (c *dataServiceClient) SyncUserDicts(ctx context.Context, in *SyncUserDicts
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type DataService_SyncUserDictsClient = grpc.ServerStreamingClient[SyncUserDictsResponse]
 
+func (c *dataServiceClient) SyncLookupEvents(ctx context.Context, in *SyncLookupEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SyncLookupEventsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DataService_ServiceDesc.Streams[2], DataService_SyncLookupEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SyncLookupEventsRequest, SyncLookupEventsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_SyncLookupEventsClient = grpc.ServerStreamingClient[SyncLookupEventsResponse]
+
+func (c *dataServiceClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetSnapshotResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DataService_ServiceDesc.Streams[3], DataService_GetSnapshot_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetSnapshotRequest, GetSnapshotResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_GetSnapshotClient = grpc.ServerStreamingClient[GetSnapshotResponse]
+
 // DataServiceServer is the server API for DataService service.
 // All implementations must embed UnimplementedDataServiceServer
 // for forward compatibility.
@@ -174,12 +266,20 @@ type DataServiceServer interface {
 	UpdateWord(context.Context, *UpdateWordRequest) (*UpdateWordResponse, error)
 	DeleteWord(context.Context, *DeleteWordRequest) (*DeleteWordResponse, error)
 	ListWords(context.Context, *ListWordsRequest) (*ListWordsResponse, error)
+	MergeWords(context.Context, *MergeWordsRequest) (*MergeWordsResponse, error)
+	SetFrequencyRanks(context.Context, *SetFrequencyRanksRequest) (*SetFrequencyRanksResponse, error)
 	// User dictionary operations
 	GetUserDict(context.Context, *GetUserDictRequest) (*GetUserDictResponse, error)
 	UpsertUserDict(context.Context, *UpsertUserDictRequest) (*UpsertUserDictResponse, error)
+	// Lookup event log (append-only)
+	AppendLookupEvent(context.Context, *AppendLookupEventRequest) (*AppendLookupEventResponse, error)
+	PruneLookupEvents(context.Context, *PruneLookupEventsRequest) (*PruneLookupEventsResponse, error)
 	// Sync operations
 	SyncWords(*SyncWordsRequest, grpc.ServerStreamingServer[SyncWordsResponse]) error
 	SyncUserDicts(*SyncUserDictsRequest, grpc.ServerStreamingServer[SyncUserDictsResponse]) error
+	SyncLookupEvents(*SyncLookupEventsRequest, grpc.ServerStreamingServer[SyncLookupEventsResponse]) error
+	// Bootstrap: stream a consistent copy of the whole database to a new node
+	GetSnapshot(*GetSnapshotRequest, grpc.ServerStreamingServer[GetSnapshotResponse]) error
 	mustEmbedUnimplementedDataServiceServer()
 }
 
@@ -205,18 +305,36 @@ func (UnimplementedDataServiceServer) DeleteWord(context.Context, *DeleteWordReq
 func (UnimplementedDataServiceServer) ListWords(context.Context, *ListWordsRequest) (*ListWordsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListWords not implemented")
 }
+func (UnimplementedDataServiceServer) MergeWords(context.Context, *MergeWordsRequest) (*MergeWordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeWords not implemented")
+}
+func (UnimplementedDataServiceServer) SetFrequencyRanks(context.Context, *SetFrequencyRanksRequest) (*SetFrequencyRanksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFrequencyRanks not implemented")
+}
 func (UnimplementedDataServiceServer) GetUserDict(context.Context, *GetUserDictRequest) (*GetUserDictResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserDict not implemented")
 }
 func (UnimplementedDataServiceServer) UpsertUserDict(context.Context, *UpsertUserDictRequest) (*UpsertUserDictResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpsertUserDict not implemented")
 }
+func (UnimplementedDataServiceServer) AppendLookupEvent(context.Context, *AppendLookupEventRequest) (*AppendLookupEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendLookupEvent not implemented")
+}
+func (UnimplementedDataServiceServer) PruneLookupEvents(context.Context, *PruneLookupEventsRequest) (*PruneLookupEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PruneLookupEvents not implemented")
+}
 func (UnimplementedDataServiceServer) SyncWords(*SyncWordsRequest, grpc.ServerStreamingServer[SyncWordsResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SyncWords not implemented")
 }
 func (UnimplementedDataServiceServer) SyncUserDicts(*SyncUserDictsRequest, grpc.ServerStreamingServer[SyncUserDictsResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SyncUserDicts not implemented")
 }
+func (UnimplementedDataServiceServer) SyncLookupEvents(*SyncLookupEventsRequest, grpc.ServerStreamingServer[SyncLookupEventsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SyncLookupEvents not implemented")
+}
+func (UnimplementedDataServiceServer) GetSnapshot(*GetSnapshotRequest, grpc.ServerStreamingServer[GetSnapshotResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
 func (UnimplementedDataServiceServer) mustEmbedUnimplementedDataServiceServer() {}
 func (UnimplementedDataServiceServer) testEmbeddedByValue()                     {}
 
@@ -328,6 +446,42 @@ func _DataService_ListWords_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataService_MergeWords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeWordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).MergeWords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_MergeWords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).MergeWords(ctx, req.(*MergeWordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_SetFrequencyRanks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFrequencyRanksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).SetFrequencyRanks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_SetFrequencyRanks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).SetFrequencyRanks(ctx, req.(*SetFrequencyRanksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DataService_GetUserDict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetUserDictRequest)
 	if err := dec(in); err != nil {
@@ -364,6 +518,42 @@ func _DataService_UpsertUserDict_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataService_AppendLookupEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendLookupEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).AppendLookupEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_AppendLookupEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).AppendLookupEvent(ctx, req.(*AppendLookupEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_PruneLookupEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PruneLookupEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).PruneLookupEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_PruneLookupEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).PruneLookupEvents(ctx, req.(*PruneLookupEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DataService_SyncWords_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(SyncWordsRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -386,6 +576,28 @@ func _DataService_SyncUserDicts_Handler(srv interface{}, stream grpc.ServerStrea
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type DataService_SyncUserDictsServer = grpc.ServerStreamingServer[SyncUserDictsResponse]
 
+func _DataService_SyncLookupEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SyncLookupEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataServiceServer).SyncLookupEvents(m, &grpc.GenericServerStream[SyncLookupEventsRequest, SyncLookupEventsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_SyncLookupEventsServer = grpc.ServerStreamingServer[SyncLookupEventsResponse]
+
+func _DataService_GetSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataServiceServer).GetSnapshot(m, &grpc.GenericServerStream[GetSnapshotRequest, GetSnapshotResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_GetSnapshotServer = grpc.ServerStreamingServer[GetSnapshotResponse]
+
 // DataService_ServiceDesc is the grpc.ServiceDesc for DataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -413,6 +625,14 @@ var DataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListWords",
 			Handler:    _DataService_ListWords_Handler,
 		},
+		{
+			MethodName: "MergeWords",
+			Handler:    _DataService_MergeWords_Handler,
+		},
+		{
+			MethodName: "SetFrequencyRanks",
+			Handler:    _DataService_SetFrequencyRanks_Handler,
+		},
 		{
 			MethodName: "GetUserDict",
 			Handler:    _DataService_GetUserDict_Handler,
@@ -421,6 +641,14 @@ var DataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpsertUserDict",
 			Handler:    _DataService_UpsertUserDict_Handler,
 		},
+		{
+			MethodName: "AppendLookupEvent",
+			Handler:    _DataService_AppendLookupEvent_Handler,
+		},
+		{
+			MethodName: "PruneLookupEvents",
+			Handler:    _DataService_PruneLookupEvents_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -433,6 +661,16 @@ var DataService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _DataService_SyncUserDicts_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "SyncLookupEvents",
+			Handler:       _DataService_SyncLookupEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetSnapshot",
+			Handler:       _DataService_GetSnapshot_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "data_service.proto",
 }